@@ -0,0 +1,559 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"rewards/core"
+	"rewards/core/model"
+	storage "rewards/driven/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// adjustmentCode and adjustmentBuildingBlock identify the rewards granted or debited by the operators in the history
+	adjustmentCode          string = "adjustment"
+	adjustmentBuildingBlock string = "rewardsctl"
+)
+
+// command is a rewardsctl command. Its name has one or two words
+type command struct {
+	name        string
+	description string
+	run         func(ctx context.Context, ctl *controller, args []string) error
+}
+
+var commands = []command{
+	{name: "types list", description: "list the reward types", run: listRewardTypes},
+	{name: "types create", description: "create a reward type", run: createRewardType},
+	{name: "operations list", description: "list the reward operations", run: listRewardOperations},
+	{name: "operations create", description: "create a reward operation", run: createRewardOperation},
+	{name: "inventories list", description: "list the reward inventories", run: listRewardInventories},
+	{name: "inventories create", description: "create a reward inventory", run: createRewardInventory},
+	{name: "wallet", description: "show the balance, latest rewards and claims of a user", run: showWallet},
+	{name: "adjust", description: "grant an adjustment to a user or debit an over-grant", run: postAdjustment},
+	{name: "rebuild", description: "rebuild the leaderboard scores from the history", run: rebuildLeaderboards},
+	{name: "migrate", description: "apply the pending storage migrations", run: runMigrations},
+	{name: "export", description: "export the history, claims or inventories as NDJSON", run: exportData},
+	{name: "config export", description: "export the org configuration", run: exportOrgConfig},
+	{name: "config import", description: "import an org configuration", run: importOrgConfig},
+}
+
+// findCommand gives the command named by the first arguments and the rest of the arguments
+func findCommand(args []string) (*command, []string) {
+	for i := range commands {
+		words := strings.Fields(commands[i].name)
+		if len(args) < len(words) {
+			continue
+		}
+		if strings.Join(args[:len(words)], " ") == commands[i].name {
+			return &commands[i], args[len(words):]
+		}
+	}
+	return nil, nil
+}
+
+// controller runs the commands against the services
+type controller struct {
+	services     core.Services
+	migrate      func(dryRun bool) ([]storage.MigrationResult, error)
+	out          io.Writer
+	actor        string
+	defaultOrgID string
+}
+
+// flags creates the flags of a command with the org flag
+func (ctl *controller) flags(name string, orgID *string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	if orgID != nil {
+		flags.StringVar(orgID, "org", ctl.defaultOrgID, "the org id")
+	}
+	return flags
+}
+
+// parse parses the flags and checks the org and the required flags are set
+func (ctl *controller) parse(flags *flag.FlagSet, args []string, orgID *string, required ...string) error {
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	if orgID != nil && *orgID == "" {
+		return errors.New("missing -org")
+	}
+	for _, name := range required {
+		if flags.Lookup(name).Value.String() == "" {
+			return fmt.Errorf("missing -%s", name)
+		}
+	}
+	return nil
+}
+
+// audit records a change in the audit log like the changes made through the admin APIs
+func (ctl *controller) audit(ctx context.Context, commandName string, orgID string, action string, resource string, resourceID string, after interface{}) {
+	var state map[string]interface{}
+	data, err := json.Marshal(after)
+	if err == nil {
+		json.Unmarshal(data, &state)
+	}
+
+	entry := model.AuditLogEntry{ActorType: model.AuditActorTypeOperator, Actor: ctl.actor, Action: action, Resource: resource,
+		ResourceID: resourceID, Method: "CLI", Path: "rewardsctl " + commandName, After: state, RequestID: uuid.NewString()}
+	_, err = ctl.services.CreateAuditLogEntry(ctx, orgID, entry)
+	if err != nil {
+		log.Printf("Error on rewardsctl.audit(ctx, %s): %s", commandName, err)
+	}
+}
+
+func (ctl *controller) printJSON(value interface{}) error {
+	encoder := json.NewEncoder(ctl.out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func (ctl *controller) printTable(header []string, rows [][]string) error {
+	writer := tabwriter.NewWriter(ctl.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+func listRewardTypes(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("types list", &orgID)
+	asJSON := flags.Bool("json", false, "print json instead of a table")
+	err := ctl.parse(flags, args, &orgID)
+	if err != nil {
+		return err
+	}
+
+	items, err := ctl.services.GetRewardTypes(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return ctl.printJSON(items)
+	}
+
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = []string{item.ID, item.RewardType, item.DisplayName, strconv.FormatBool(item.Active),
+			strconv.FormatBool(item.Currency), strconv.FormatBool(item.InventoryBacked), item.AllocationStrategy}
+	}
+	return ctl.printTable([]string{"ID", "TYPE", "DISPLAY NAME", "ACTIVE", "CURRENCY", "INVENTORY BACKED", "STRATEGY"}, rows)
+}
+
+func createRewardType(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("types create", &orgID)
+	rewardType := flags.String("type", "", "the reward type name")
+	displayName := flags.String("display-name", "", "the display name")
+	description := flags.String("description", "", "the description")
+	active := flags.Bool("active", true, "the reward type is active")
+	currency := flags.Bool("currency", false, "the reward type is a point currency spent on catalog items")
+	inventoryBacked := flags.String("inventory-backed", "", "true or false - by default true for all reward types except currencies")
+	strategy := flags.String("allocation-strategy", "", "the inventory allocation strategy - fifo by default")
+	err := ctl.parse(flags, args, &orgID, "type")
+	if err != nil {
+		return err
+	}
+
+	backed := !*currency
+	if *inventoryBacked != "" {
+		backed, err = strconv.ParseBool(*inventoryBacked)
+		if err != nil {
+			return fmt.Errorf("invalid -inventory-backed %s", *inventoryBacked)
+		}
+	}
+
+	item := model.RewardType{RewardType: *rewardType, DisplayName: *displayName, Description: *description, Active: *active,
+		Currency: *currency, InventoryBacked: backed, AllocationStrategy: *strategy}
+	created, err := ctl.services.CreateRewardType(ctx, orgID, item)
+	if err != nil {
+		return err
+	}
+	ctl.audit(ctx, "types create", orgID, "create", "types", created.ID, created)
+	return ctl.printJSON(created)
+}
+
+func listRewardOperations(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("operations list", &orgID)
+	archived := flags.Bool("archived", false, "list the archived operations instead")
+	asJSON := flags.Bool("json", false, "print json instead of a table")
+	err := ctl.parse(flags, args, &orgID)
+	if err != nil {
+		return err
+	}
+
+	items, err := ctl.services.GetRewardOperations(ctx, orgID, archived)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return ctl.printJSON(items)
+	}
+
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = []string{item.ID, item.Code, item.BuildingBlock, item.RewardType, strconv.Itoa(item.Amount), item.Description}
+	}
+	return ctl.printTable([]string{"ID", "CODE", "BUILDING BLOCK", "TYPE", "AMOUNT", "DESCRIPTION"}, rows)
+}
+
+func createRewardOperation(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("operations create", &orgID)
+	rewardType := flags.String("type", "", "the reward type granted by the operation")
+	code := flags.String("code", "", "the operation code")
+	buildingBlock := flags.String("building-block", "", "the building block which grants the rewards")
+	amount := flags.Int("amount", 0, "the granted amount, a negative amount is debited from the balance")
+	description := flags.String("description", "", "the description")
+	err := ctl.parse(flags, args, &orgID, "type", "code", "building-block")
+	if err != nil {
+		return err
+	}
+	if *amount <= 0 {
+		return errors.New("-amount must be greater than 0")
+	}
+
+	item := model.RewardOperation{RewardType: *rewardType, Code: *code, BuildingBlock: *buildingBlock, Amount: *amount, Description: *description}
+	created, err := ctl.services.CreateRewardOperation(ctx, orgID, item)
+	if err != nil {
+		return err
+	}
+	ctl.audit(ctx, "operations create", orgID, "create", "operations", created.ID, created)
+	return ctl.printJSON(created)
+}
+
+func listRewardInventories(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("inventories list", &orgID)
+	rewardType := flags.String("type", "", "filter by reward type")
+	archived := flags.Bool("archived", false, "list the archived inventories instead")
+	asJSON := flags.Bool("json", false, "print json instead of a table")
+	err := ctl.parse(flags, args, &orgID)
+	if err != nil {
+		return err
+	}
+
+	var rewardTypeFilter *string
+	if *rewardType != "" {
+		rewardTypeFilter = rewardType
+	}
+	items, err := ctl.services.GetRewardInventories(ctx, orgID, nil, rewardTypeFilter, nil, nil, nil, archived, nil, nil)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return ctl.printJSON(items)
+	}
+
+	rows := make([][]string, len(items))
+	for i, item := range items {
+		rows[i] = []string{item.ID, item.RewardType, strconv.FormatBool(item.InStock), strconv.Itoa(item.AmountTotal),
+			strconv.Itoa(item.AmountGranted), strconv.Itoa(item.AmountClaimed), item.Description}
+	}
+	return ctl.printTable([]string{"ID", "TYPE", "IN STOCK", "TOTAL", "GRANTED", "CLAIMED", "DESCRIPTION"}, rows)
+}
+
+func createRewardInventory(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("inventories create", &orgID)
+	rewardType := flags.String("type", "", "the reward type stocked by the inventory")
+	amount := flags.Int("amount", 0, "the total amount")
+	inStock := flags.Bool("in-stock", true, "the inventory is in stock")
+	locations := flags.String("locations", "", "comma separated ids of the pickup locations which stock the inventory")
+	description := flags.String("description", "", "the description")
+	err := ctl.parse(flags, args, &orgID, "type")
+	if err != nil {
+		return err
+	}
+
+	var locationIDs []string
+	if *locations != "" {
+		locationIDs = strings.Split(*locations, ",")
+	}
+	item := model.RewardInventory{RewardType: *rewardType, InStock: *inStock, AmountTotal: *amount, LocationIDs: locationIDs, Description: *description}
+	created, err := ctl.services.CreateRewardInventory(ctx, orgID, item)
+	if err != nil {
+		return err
+	}
+	ctl.audit(ctx, "inventories create", orgID, "create", "inventories", created.ID, created)
+	return ctl.printJSON(created)
+}
+
+func showWallet(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("wallet", &orgID)
+	userID := flags.String("user", "", "the user id")
+	limit := flags.Int64("limit", 10, "the number of the latest rewards and claims")
+	err := ctl.parse(flags, args, &orgID, "user")
+	if err != nil {
+		return err
+	}
+
+	balance, err := ctl.services.GetUserBalance(ctx, orgID, *userID)
+	if err != nil {
+		return err
+	}
+	history, err := ctl.services.GetUserRewardsHistory(ctx, orgID, *userID, nil, nil, nil, limit, nil)
+	if err != nil {
+		return err
+	}
+	claims, err := ctl.services.GetRewardClaims(ctx, orgID, nil, userID, nil, nil, limit, nil)
+	if err != nil {
+		return err
+	}
+
+	rows := make([][]string, len(balance))
+	for i, item := range balance {
+		rows[i] = []string{item.RewardType, strconv.Itoa(item.Amount)}
+	}
+	fmt.Fprintln(ctl.out, "BALANCE")
+	err = ctl.printTable([]string{"TYPE", "AMOUNT"}, rows)
+	if err != nil {
+		return err
+	}
+
+	rows = make([][]string, len(history))
+	for i, item := range history {
+		rows[i] = []string{item.DateCreated.Format("2006-01-02 15:04:05"), item.RewardType, strconv.Itoa(item.Amount), item.Code, item.BuildingBlock, item.Description}
+	}
+	fmt.Fprintln(ctl.out, "\nLATEST REWARDS")
+	err = ctl.printTable([]string{"DATE", "TYPE", "AMOUNT", "CODE", "BUILDING BLOCK", "DESCRIPTION"}, rows)
+	if err != nil {
+		return err
+	}
+
+	rows = make([][]string, len(claims))
+	for i, item := range claims {
+		amounts := make([]string, len(item.Items))
+		for j, claimItem := range item.Items {
+			amounts[j] = fmt.Sprintf("%s:%d", claimItem.RewardType, claimItem.Amount)
+		}
+		rows[i] = []string{item.DateCreated.Format("2006-01-02 15:04:05"), item.ID, item.Status, strings.Join(amounts, " ")}
+	}
+	fmt.Fprintln(ctl.out, "\nLATEST CLAIMS")
+	return ctl.printTable([]string{"DATE", "ID", "STATUS", "ITEMS"}, rows)
+}
+
+func postAdjustment(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("adjust", &orgID)
+	userID := flags.String("user", "", "the user id")
+	rewardType := flags.String("type", "", "the reward type")
+	amount := flags.Int("amount", 0, "the granted amount, a negative amount is debited from the balance")
+	description := flags.String("description", "", "the reason of the adjustment which is kept in the history")
+	err := ctl.parse(flags, args, &orgID, "user", "type", "description")
+	if err != nil {
+		return err
+	}
+
+	item := model.Reward{UserID: *userID, RewardType: *rewardType, Amount: *amount, Code: adjustmentCode,
+		BuildingBlock: adjustmentBuildingBlock, Description: *description}
+	var created *model.Reward
+	if *amount < 0 {
+		item.Amount = -*amount
+		created, err = ctl.services.DebitReward(ctx, orgID, item)
+	} else {
+		created, err = ctl.services.CreateReward(ctx, orgID, item)
+	}
+	if err != nil {
+		return err
+	}
+	ctl.audit(ctx, "adjust", orgID, "adjust", "history", created.ID, created)
+	return ctl.printJSON(created)
+}
+
+// rebuildLeaderboards rebuilds the leaderboard scores. The balances are always computed from the history and
+// the claims so they do not need to be rebuilt
+func rebuildLeaderboards(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("rebuild", &orgID)
+	err := ctl.parse(flags, args, &orgID)
+	if err != nil {
+		return err
+	}
+
+	count, err := ctl.services.RebuildLeaderboards(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	ctl.audit(ctx, "rebuild", orgID, "rebuild", "leaderboards", "", map[string]interface{}{"scores": count})
+	fmt.Fprintf(ctl.out, "rebuilt %d leaderboard scores\n", count)
+	return nil
+}
+
+func runMigrations(ctx context.Context, ctl *controller, args []string) error {
+	flags := ctl.flags("migrate", nil)
+	dryRun := flags.Bool("dry-run", false, "report the pending migrations and their changes without applying them")
+	err := ctl.parse(flags, args, nil)
+	if err != nil {
+		return err
+	}
+
+	results, err := ctl.migrate(*dryRun)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Fprintln(ctl.out, "no pending migrations")
+		return nil
+	}
+
+	failed := false
+	for _, result := range results {
+		fmt.Fprintf(ctl.out, "%d %s: %s\n", result.Version, result.Description, result.Status)
+		for _, change := range result.Changes {
+			fmt.Fprintf(ctl.out, "\t%s\n", change)
+		}
+		if result.Status == storage.MigrationStatusFailed {
+			fmt.Fprintf(ctl.out, "\terror: %s\n", result.Error)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("some migrations failed")
+	}
+	return nil
+}
+
+// exportData writes the records as NDJSON in the order they were created
+func exportData(ctx context.Context, ctl *controller, args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New("missing the data to export - history, claims or inventories")
+	}
+	kind := args[0]
+
+	var orgID string
+	flags := ctl.flags("export "+kind, &orgID)
+	userID := flags.String("user", "", "filter by user - history and claims")
+	rewardType := flags.String("type", "", "filter by reward type")
+	limit := flags.Int64("limit", 0, "limit the number of records")
+	err := ctl.parse(flags, args[1:], &orgID)
+	if err != nil {
+		return err
+	}
+
+	var userFilter, rewardTypeFilter *string
+	var limitFilter *int64
+	if *userID != "" {
+		userFilter = userID
+	}
+	if *rewardType != "" {
+		rewardTypeFilter = rewardType
+	}
+	if *limit > 0 {
+		limitFilter = limit
+	}
+
+	writer := bufio.NewWriter(ctl.out)
+	encoder := json.NewEncoder(writer)
+	switch kind {
+	case "history":
+		err = ctl.services.ExportRewardHistory(ctx, orgID, userFilter, rewardTypeFilter, nil, nil, nil, nil, limitFilter, nil, func(item model.Reward) error {
+			return encoder.Encode(item)
+		})
+	case "claims":
+		err = ctl.services.ExportRewardClaims(ctx, orgID, nil, userFilter, rewardTypeFilter, nil, limitFilter, nil, func(item model.RewardClaim) error {
+			return encoder.Encode(item)
+		})
+	case "inventories":
+		notArchived := false
+		err = ctl.services.ExportRewardInventories(ctx, orgID, nil, rewardTypeFilter, nil, nil, nil, &notArchived, limitFilter, nil, func(item model.RewardInventory) error {
+			return encoder.Encode(item)
+		})
+	default:
+		return fmt.Errorf("unknown export %s, expected history, claims or inventories", kind)
+	}
+	flushErr := writer.Flush()
+	if err != nil {
+		return err
+	}
+	return flushErr
+}
+
+func exportOrgConfig(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("config export", &orgID)
+	err := ctl.parse(flags, args, &orgID)
+	if err != nil {
+		return err
+	}
+
+	config, err := ctl.services.ExportOrgConfig(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	return ctl.printJSON(config)
+}
+
+func importOrgConfig(ctx context.Context, ctl *controller, args []string) error {
+	var orgID string
+	flags := ctl.flags("config import", &orgID)
+	file := flags.String("file", "", "the exported configuration")
+	dryRun := flags.Bool("dry-run", false, "preview the changes without applying them")
+	err := ctl.parse(flags, args, &orgID, "file")
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	var config model.OrgConfig
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&config)
+	if err != nil {
+		return fmt.Errorf("invalid configuration %s - %s", *file, err)
+	}
+
+	report, err := ctl.services.ImportOrgConfig(ctx, orgID, config, *dryRun)
+	if err != nil {
+		return err
+	}
+	if report.Applied {
+		ctl.audit(ctx, "config import", orgID, "import", "config", "", report)
+	}
+	err = ctl.printJSON(report)
+	if err != nil {
+		return err
+	}
+	if !report.Valid {
+		return errors.New("the configuration has invalid changes, nothing was applied")
+	}
+	return nil
+}
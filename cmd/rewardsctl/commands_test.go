@@ -0,0 +1,213 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"rewards/core"
+	"rewards/core/model"
+	"strconv"
+	"strings"
+	"testing"
+
+	cacheadapter "rewards/driven/cache"
+)
+
+// ctlStorage keeps the reward types, history and audit log entries of the commands in memory
+type ctlStorage struct {
+	core.Storage
+	types   []model.RewardType
+	history []model.Reward
+	entries []model.AuditLogEntry
+}
+
+func (s *ctlStorage) GetRewardTypes(ctx context.Context, orgID string) ([]model.RewardType, error) {
+	var result []model.RewardType
+	for _, item := range s.types {
+		if item.OrgID == orgID {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (s *ctlStorage) GetRewardTypeByType(ctx context.Context, orgID string, rewardType string) (*model.RewardType, error) {
+	for _, item := range s.types {
+		if item.OrgID == orgID && item.RewardType == rewardType {
+			return &item, nil
+		}
+	}
+	return nil, model.NewNotFoundError("reward type %s", rewardType)
+}
+
+func (s *ctlStorage) CreateRewardType(ctx context.Context, orgID string, item model.RewardType) (*model.RewardType, error) {
+	item.ID = item.RewardType + "-id"
+	item.OrgID = orgID
+	s.types = append(s.types, item)
+	return &item, nil
+}
+
+func (s *ctlStorage) GetRewardOperationByCode(ctx context.Context, orgID string, code string, buildingBlock string) (*model.RewardOperation, error) {
+	return nil, model.NewNotFoundError("reward operation %s", code)
+}
+
+func (s *ctlStorage) CreateUserReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
+	item.ID = "reward-" + strconv.Itoa(len(s.history))
+	item.OrgID = orgID
+	s.history = append(s.history, item)
+	return &item, nil
+}
+
+func (s *ctlStorage) CreateUserRewardDebit(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
+	balance := 0
+	for _, reward := range s.history {
+		if reward.OrgID == orgID && reward.UserID == item.UserID && reward.RewardType == item.RewardType {
+			balance += reward.Amount
+		}
+	}
+	if balance < -item.Amount {
+		return nil, model.NewInsufficientBalanceError("not enough %s", item.RewardType)
+	}
+	return s.CreateUserReward(ctx, orgID, item)
+}
+
+func (s *ctlStorage) CreateAuditLogEntry(ctx context.Context, orgID string, item model.AuditLogEntry) (*model.AuditLogEntry, error) {
+	item.OrgID = orgID
+	s.entries = append(s.entries, item)
+	return &item, nil
+}
+
+func newTestController(storage *ctlStorage) (*controller, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	application := core.NewApplication("test", "test", storage, cacheadapter.NewCacheAdapter(""))
+	return &controller{services: application.Services, out: out, actor: "operator", defaultOrgID: "org"}, out
+}
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		args []string
+		name string
+		rest []string
+	}{
+		{[]string{"types", "list", "-json"}, "types list", []string{"-json"}},
+		{[]string{"wallet", "-user", "u"}, "wallet", []string{"-user", "u"}},
+		{[]string{"export", "history"}, "export", []string{"history"}},
+		{[]string{"types"}, "", nil},
+		{[]string{"unknown"}, "", nil},
+	}
+
+	for _, test := range tests {
+		cmd, rest := findCommand(test.args)
+		if test.name == "" {
+			if cmd != nil {
+				t.Errorf("%v: expected no command, got %s", test.args, cmd.name)
+			}
+			continue
+		}
+		if cmd == nil || cmd.name != test.name || strings.Join(rest, " ") != strings.Join(test.rest, " ") {
+			t.Errorf("%v: expected %s %v, got %v %v", test.args, test.name, test.rest, cmd, rest)
+		}
+	}
+}
+
+func TestCreateAndListRewardTypes(t *testing.T) {
+	storage := &ctlStorage{}
+	ctl, out := newTestController(storage)
+
+	err := createRewardType(context.Background(), ctl, []string{"-type", "points", "-currency"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(storage.types) != 1 || !storage.types[0].Currency || storage.types[0].InventoryBacked {
+		t.Fatalf("expected an unbacked currency, got %v", storage.types)
+	}
+	if len(storage.entries) != 1 || storage.entries[0].ActorType != model.AuditActorTypeOperator || storage.entries[0].Path != "rewardsctl types create" {
+		t.Errorf("expected an operator audit log entry, got %v", storage.entries)
+	}
+
+	err = createRewardType(context.Background(), ctl, []string{"-type", "points"})
+	if !model.IsErrorCode(err, model.ErrorCodeConflict) {
+		t.Errorf("expected a conflict for the duplicate reward type, got %v", err)
+	}
+
+	out.Reset()
+	err = listRewardTypes(context.Background(), ctl, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(out.String(), "points-id") {
+		t.Errorf("expected the created reward type in the table, got %s", out.String())
+	}
+}
+
+func TestPostAdjustment(t *testing.T) {
+	storage := &ctlStorage{types: []model.RewardType{{ID: "points-id", OrgID: "org", RewardType: "points", Currency: true}}}
+	ctl, _ := newTestController(storage)
+
+	err := postAdjustment(context.Background(), ctl, []string{"-user", "user", "-type", "points", "-amount", "10", "-description", "missed check in"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	err = postAdjustment(context.Background(), ctl, []string{"-user", "user", "-type", "points", "-amount", "-4", "-description", "granted twice"})
+	if err != nil {
+		t.Fatalf("unexpected debit error: %s", err)
+	}
+	if len(storage.history) != 2 || storage.history[0].Amount != 10 || storage.history[1].Amount != -4 {
+		t.Fatalf("expected a credit and a debit in the history, got %+v", storage.history)
+	}
+	for _, item := range storage.history {
+		if item.Code != adjustmentCode || item.BuildingBlock != adjustmentBuildingBlock {
+			t.Errorf("expected an operator adjustment, got %+v", item)
+		}
+	}
+
+	err = postAdjustment(context.Background(), ctl, []string{"-user", "user", "-type", "points", "-amount", "-7", "-description", "too much"})
+	if !model.IsErrorCode(err, model.ErrorCodeInsufficientBalance) {
+		t.Errorf("expected the debit over the balance to be refused, got %v", err)
+	}
+	err = postAdjustment(context.Background(), ctl, []string{"-user", "user", "-type", "points", "-amount", "0", "-description", "nothing"})
+	if !model.IsErrorCode(err, model.ErrorCodeValidation) {
+		t.Errorf("expected a zero adjustment to be refused, got %v", err)
+	}
+	if len(storage.history) != 2 || len(storage.entries) != 2 {
+		t.Errorf("expected only the applied adjustments in the history and the audit log, got %d and %d", len(storage.history), len(storage.entries))
+	}
+}
+
+func TestCommandFlags(t *testing.T) {
+	ctl, _ := newTestController(&ctlStorage{})
+	ctl.defaultOrgID = ""
+
+	err := listRewardTypes(context.Background(), ctl, nil)
+	if err == nil || err.Error() != "missing -org" {
+		t.Errorf("expected the missing org error, got %v", err)
+	}
+	err = createRewardType(context.Background(), ctl, []string{"-org", "org"})
+	if err == nil || err.Error() != "missing -type" {
+		t.Errorf("expected the missing type error, got %v", err)
+	}
+	err = listRewardTypes(context.Background(), ctl, []string{"-org", "org", "extra"})
+	if err == nil || !strings.HasPrefix(err.Error(), "unexpected arguments") {
+		t.Errorf("expected the unexpected arguments error, got %v", err)
+	}
+
+	ctl.defaultOrgID = "org"
+	err = listRewardTypes(context.Background(), ctl, []string{"-h"})
+	if err != flag.ErrHelp {
+		t.Errorf("expected the help error, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// rewardsctl is the command line tool of the operators. It uses the services of the rewards building block
+// through the storage adapter so it needs the database access instead of the Core BB admin tokens.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"os/user"
+	"strings"
+	"syscall"
+	"time"
+
+	"rewards/core"
+	cacheadapter "rewards/driven/cache"
+	storage "rewards/driven/storage"
+	"rewards/utils/logging"
+)
+
+// stopTimeout limits closing the storage after a command
+const stopTimeout = 5 * time.Second
+
+var (
+	// Version : version of this executable
+	Version string
+	// Build : build date of this executable
+	Build string
+)
+
+func main() {
+	if len(Version) == 0 {
+		Version = "dev"
+	}
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		printUsage()
+		return
+	}
+
+	cmd, args := findCommand(os.Args[1:])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n\n", strings.Join(os.Args[1:], " "))
+		printUsage()
+		os.Exit(2)
+	}
+
+	// the logs go to stderr so they do not mix with the output, only the warnings and errors by default
+	log.SetOutput(os.Stderr)
+	logLevel, ok := os.LookupEnv("LOG_LEVEL")
+	if !ok {
+		logLevel = "warn"
+	}
+	logging.SetLevel(logLevel)
+
+	mongoDBAuth := getEnvKey("MONGO_AUTH", true)
+	mongoDBName := getEnvKey("MONGO_DATABASE", true)
+	mongoTimeout := getEnvKey("MONGO_TIMEOUT", false)
+	defaultOrgID := getEnvKey("DEFAULT_ORG_ID", false)
+	storageAdapter := storage.NewStorageAdapter(mongoDBAuth, mongoDBName, mongoTimeout, defaultOrgID)
+
+	// the migrations are applied only by the migrate command
+	err := storageAdapter.Connect()
+	if err != nil {
+		log.Fatal("Cannot start the mongoDB adapter - " + err.Error())
+	}
+
+	application := core.NewApplication(Version, Build, storageAdapter, cacheadapter.NewCacheAdapter(""))
+
+	ctl := &controller{services: application.Services, migrate: storageAdapter.Migrate, out: os.Stdout,
+		actor: currentUser(), defaultOrgID: defaultOrgID}
+	// an interrupted command cancels its queries
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err = cmd.run(ctx, ctl, args)
+	stop()
+
+	stopContext, cancel := context.WithTimeout(context.Background(), stopTimeout)
+	stopErr := storageAdapter.Stop(stopContext)
+	cancel()
+	if stopErr != nil {
+		log.Printf("Error on stopping the mongoDB adapter - %s", stopErr)
+	}
+
+	if err == flag.ErrHelp {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", cmd.name, err)
+		os.Exit(1)
+	}
+}
+
+func printUsage() {
+	fmt.Fprintln(os.Stderr, "usage: rewardsctl <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-22s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Run rewardsctl <command> -h for the flags of a command. The org is DEFAULT_ORG_ID unless -org is given.")
+	fmt.Fprintln(os.Stderr, "The database is set with the MONGO_AUTH, MONGO_DATABASE and MONGO_TIMEOUT environment variables.")
+}
+
+// currentUser gives the system user who is recorded as the actor of the changes
+func currentUser() string {
+	current, err := user.Current()
+	if err != nil {
+		return "unknown"
+	}
+	return current.Username
+}
+
+func getEnvKey(key string, required bool) string {
+	value, exist := os.LookupEnv(key)
+	if !exist && required {
+		log.Fatal("No provided environment variable for " + key)
+	}
+	return value
+}
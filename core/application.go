@@ -27,6 +27,8 @@ type Application struct {
 
 	storage      Storage
 	cacheAdapter *cacheadapter.CacheAdapter
+
+	listeners []ApplicationListener
 }
 
 // Start starts the core part of the application
@@ -34,6 +36,11 @@ func (app *Application) Start() {
 	app.storage.SetListener(app)
 }
 
+// AddListener adds application listener
+func (app *Application) AddListener(listener ApplicationListener) {
+	app.listeners = append(app.listeners, listener)
+}
+
 // NewApplication creates new Application
 func NewApplication(version string, build string, storage Storage, cacheadapter *cacheadapter.CacheAdapter) *Application {
 	application := Application{
@@ -15,185 +15,479 @@
 package core
 
 import (
+	"context"
 	"rewards/core/model"
 	"rewards/driven/storage"
+	"time"
 )
 
 // Services exposes APIs for the driver adapters
 type Services interface {
 	GetVersion() string
+	CheckReadiness(ctx context.Context) *model.HealthReport
+
+	GetRewardTypes(ctx context.Context, orgID string) ([]model.RewardType, error)
+	GetRewardType(ctx context.Context, orgID string, id string) (*model.RewardType, error)
+	CreateRewardType(ctx context.Context, orgID string, item model.RewardType) (*model.RewardType, error)
+	UpdateRewardType(ctx context.Context, orgID string, id string, item model.RewardType) (*model.RewardType, error)
+	DeleteRewardType(ctx context.Context, orgID string, id string) error
+
+	GetRewardOperations(ctx context.Context, orgID string, archived *bool) ([]model.RewardOperation, error)
+	GetRewardOperationByID(ctx context.Context, orgID string, id string) (*model.RewardOperation, error)
+	GetRewardOperationByCode(ctx context.Context, orgID string, code string, buildingBlock string) (*model.RewardOperation, error)
+	CreateRewardOperation(ctx context.Context, orgID string, item model.RewardOperation) (*model.RewardOperation, error)
+	UpdateRewardOperation(ctx context.Context, orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error)
+	DeleteRewardOperation(ctx context.Context, orgID string, id string) error
+	ArchiveRewardOperation(ctx context.Context, orgID string, id string, archived bool) (*model.RewardOperation, error)
+
+	GetRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64) ([]model.RewardInventory, error)
+	GetRewardInventory(ctx context.Context, orgID string, id string) (*model.RewardInventory, error)
+	CreateRewardInventory(ctx context.Context, orgID string, item model.RewardInventory) (*model.RewardInventory, error)
+	UpdateRewardInventory(ctx context.Context, orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error)
+	ArchiveRewardInventory(ctx context.Context, orgID string, id string, archived bool) (*model.RewardInventory, error)
+
+	GetRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error)
+	GetRewardClaim(ctx context.Context, orgID string, id string) (*model.RewardClaim, error)
+	CreateRewardClaim(ctx context.Context, orgID string, item model.RewardClaim) (*model.RewardClaim, error)
+	UpdateRewardClaim(ctx context.Context, orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error)
+	RedeemRewardClaimPickupCode(ctx context.Context, orgID string, actor string, code string) (*model.RewardClaim, error)
+	GetRewardClaimPickupAttempts(ctx context.Context, orgID string, claimID string) ([]model.RewardClaimPickupAttempt, error)
+
+	GetRewardCatalogItems(ctx context.Context, orgID string, active *bool) ([]model.RewardCatalogItem, error)
+	GetRewardCatalogItem(ctx context.Context, orgID string, id string) (*model.RewardCatalogItem, error)
+	CreateRewardCatalogItem(ctx context.Context, orgID string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error)
+	UpdateRewardCatalogItem(ctx context.Context, orgID string, id string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error)
+	DeleteRewardCatalogItem(ctx context.Context, orgID string, id string) error
+	GetAvailableRewardCatalogItems(ctx context.Context, orgID string) ([]model.RewardCatalogItem, error)
+
+	GetPickupLocations(ctx context.Context, orgID string) ([]model.PickupLocation, error)
+	GetPickupLocation(ctx context.Context, orgID string, id string) (*model.PickupLocation, error)
+	CreatePickupLocation(ctx context.Context, orgID string, item model.PickupLocation) (*model.PickupLocation, error)
+	UpdatePickupLocation(ctx context.Context, orgID string, id string, item model.PickupLocation) (*model.PickupLocation, error)
+	DeletePickupLocation(ctx context.Context, orgID string, id string) error
+	GetRewardClaimsByLocation(ctx context.Context, orgID string, locationID string, slotID *string, status *string) ([]model.RewardClaim, error)
+	GetActivePickupLocations(ctx context.Context, orgID string) ([]model.PickupLocation, error)
+
+	GetAuthorizationPolicies(ctx context.Context) ([]model.AuthorizationPolicy, error)
+
+	GetInternalCredentials(ctx context.Context, orgID string) ([]model.InternalCredential, error)
+	GetInternalCredential(ctx context.Context, orgID string, id string) (*model.InternalCredential, error)
+	CreateInternalCredential(ctx context.Context, orgID string, item model.InternalCredential) (*model.InternalCredential, error)
+	RotateInternalCredential(ctx context.Context, orgID string, id string) (*model.InternalCredential, error)
+	RevokeInternalCredential(ctx context.Context, orgID string, id string) error
+	AuthenticateInternalCredential(ctx context.Context, key string) (*model.InternalCredential, error)
+
+	GetAuditLogEntries(ctx context.Context, orgID string, resource *string, resourceID *string, actor *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64) ([]model.AuditLogEntry, error)
+	CreateAuditLogEntry(ctx context.Context, orgID string, item model.AuditLogEntry) (*model.AuditLogEntry, error)
+
+	GetLeaderboard(ctx context.Context, orgID string, rewardType string, buildingBlock string, window string, admin bool, limit int64) (*model.Leaderboard, error)
+	GetLeaderboardProfile(ctx context.Context, orgID string, userID string) (*model.LeaderboardProfile, error)
+	UpdateLeaderboardProfile(ctx context.Context, orgID string, userID string, item model.LeaderboardProfile) (*model.LeaderboardProfile, error)
+	RebuildLeaderboards(ctx context.Context, orgID string) (int, error)
+
+	GetGrantsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.GrantsBucket, error)
+	GetEarnersAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.EarnersBucket, error)
+	GetClaimsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.ClaimsBucket, error)
+	GetRedemptionAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.RedemptionSummary, error)
+
+	ExportRewardHistory(ctx context.Context, orgID string, userID *string, rewardType *string, code *string, buildingBlock *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64, each func(model.Reward) error) error
+	ExportRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64, each func(model.RewardClaim) error) error
+	ExportRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64, each func(model.RewardInventory) error) error
+
+	ImportRewardTypes(ctx context.Context, orgID string, items []model.RewardType, dryRun bool) (*model.ImportReport, error)
+	ImportRewardOperations(ctx context.Context, orgID string, items []model.RewardOperation, dryRun bool) (*model.ImportReport, error)
+	ImportRewardInventories(ctx context.Context, orgID string, items []model.RewardInventory, dryRun bool) (*model.ImportReport, error)
+
+	ExportOrgConfig(ctx context.Context, orgID string) (*model.OrgConfig, error)
+	ImportOrgConfig(ctx context.Context, orgID string, config model.OrgConfig, dryRun bool) (*model.OrgConfigImportReport, error)
+
+	CreateReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error)
+	DebitReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error)
+
+	GetUserBalance(ctx context.Context, orgID string, userID string) ([]model.RewardTypeAmount, error)
+	GetUserRewardsHistory(ctx context.Context, orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error)
+
+	GetRewardQuantity(ctx context.Context, orgID string, rewardType string) (*model.RewardQuantityState, error)
+}
 
-	GetRewardTypes(orgID string) ([]model.RewardType, error)
-	GetRewardType(orgID string, id string) (*model.RewardType, error)
-	CreateRewardType(orgID string, item model.RewardType) (*model.RewardType, error)
-	UpdateRewardType(orgID string, id string, item model.RewardType) (*model.RewardType, error)
-	DeleteRewardType(orgID string, id string) error
+type servicesImpl struct {
+	app *Application
+}
 
-	GetRewardOperations(orgID string) ([]model.RewardOperation, error)
-	GetRewardOperationByID(orgID string, id string) (*model.RewardOperation, error)
-	GetRewardOperationByCode(orgID string, code string) (*model.RewardOperation, error)
-	CreateRewardOperation(orgID string, item model.RewardOperation) (*model.RewardOperation, error)
-	UpdateRewardOperation(orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error)
-	DeleteRewardOperation(orgID string, id string) error
+func (s *servicesImpl) GetVersion() string {
+	return s.app.getVersion()
+}
 
-	GetRewardInventories(orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, limit *int64, offset *int64) ([]model.RewardInventory, error)
-	GetRewardInventory(orgID string, id string) (*model.RewardInventory, error)
-	CreateRewardInventory(orgID string, item model.RewardInventory) (*model.RewardInventory, error)
-	UpdateRewardInventory(orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error)
+func (s *servicesImpl) GetRewardTypes(ctx context.Context, orgID string) ([]model.RewardType, error) {
+	return s.app.getRewardTypes(ctx, orgID)
+}
 
-	GetRewardClaims(orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error)
-	GetRewardClaim(orgID string, id string) (*model.RewardClaim, error)
-	CreateRewardClaim(orgID string, item model.RewardClaim) (*model.RewardClaim, error)
-	UpdateRewardClaim(orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error)
+func (s *servicesImpl) GetRewardType(ctx context.Context, orgID string, id string) (*model.RewardType, error) {
+	return s.app.getRewardType(ctx, orgID, id)
+}
 
-	CreateReward(orgID string, item model.Reward) (*model.Reward, error)
+func (s *servicesImpl) CreateRewardType(ctx context.Context, orgID string, item model.RewardType) (*model.RewardType, error) {
+	return s.app.createRewardType(ctx, orgID, item)
+}
 
-	GetUserBalance(orgID string, userID string) ([]model.RewardTypeAmount, error)
-	GetUserRewardsHistory(orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error)
+func (s *servicesImpl) UpdateRewardType(ctx context.Context, orgID string, id string, item model.RewardType) (*model.RewardType, error) {
+	return s.app.updateRewardType(ctx, orgID, id, item)
+}
 
-	GetRewardQuantity(orgID string, rewardType string) (*model.RewardQuantityState, error)
+func (s *servicesImpl) DeleteRewardType(ctx context.Context, orgID string, id string) error {
+	return s.app.deleteRewardType(ctx, orgID, id)
 }
 
-type servicesImpl struct {
-	app *Application
+func (s *servicesImpl) GetRewardOperations(ctx context.Context, orgID string, archived *bool) ([]model.RewardOperation, error) {
+	return s.app.getRewardOperations(ctx, orgID, archived)
 }
 
-func (s *servicesImpl) GetVersion() string {
-	return s.app.getVersion()
+func (s *servicesImpl) GetRewardOperationByID(ctx context.Context, orgID string, id string) (*model.RewardOperation, error) {
+	return s.app.getRewardOperationByID(ctx, orgID, id)
+}
+
+func (s *servicesImpl) GetRewardOperationByCode(ctx context.Context, orgID string, code string, buildingBlock string) (*model.RewardOperation, error) {
+	return s.app.getRewardOperationByCode(ctx, orgID, code, buildingBlock)
+}
+
+func (s *servicesImpl) CreateRewardOperation(ctx context.Context, orgID string, item model.RewardOperation) (*model.RewardOperation, error) {
+	return s.app.createRewardOperation(ctx, orgID, item)
+}
+
+func (s *servicesImpl) UpdateRewardOperation(ctx context.Context, orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error) {
+	return s.app.updateRewardOperation(ctx, orgID, id, item)
+}
+
+func (s *servicesImpl) DeleteRewardOperation(ctx context.Context, orgID string, id string) error {
+	return s.app.deleteRewardOperation(ctx, orgID, id)
+}
+
+func (s *servicesImpl) ArchiveRewardOperation(ctx context.Context, orgID string, id string, archived bool) (*model.RewardOperation, error) {
+	return s.app.archiveRewardOperation(ctx, orgID, id, archived)
+}
+
+func (s *servicesImpl) GetRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64) ([]model.RewardInventory, error) {
+	return s.app.getRewardInventories(ctx, orgID, ids, rewardType, inStock, grantDepleted, claimDepleted, archived, limit, offset)
+}
+
+func (s *servicesImpl) GetRewardInventory(ctx context.Context, orgID string, id string) (*model.RewardInventory, error) {
+	return s.app.getRewardInventory(ctx, orgID, id)
+}
+
+func (s *servicesImpl) CreateRewardInventory(ctx context.Context, orgID string, item model.RewardInventory) (*model.RewardInventory, error) {
+	return s.app.createRewardInventory(ctx, orgID, item)
+}
+
+func (s *servicesImpl) UpdateRewardInventory(ctx context.Context, orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error) {
+	return s.app.updateRewardInventory(ctx, orgID, id, item)
+}
+
+func (s *servicesImpl) DeleteRewardInventory(ctx context.Context, orgID string, id string) error {
+	return s.app.deleteRewardInventory(ctx, orgID, id)
+}
+
+func (s *servicesImpl) ArchiveRewardInventory(ctx context.Context, orgID string, id string, archived bool) (*model.RewardInventory, error) {
+	return s.app.archiveRewardInventory(ctx, orgID, id, archived)
+}
+
+func (s *servicesImpl) CreateReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
+	return s.app.createReward(ctx, orgID, item)
+}
+
+func (s *servicesImpl) DebitReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
+	return s.app.debitReward(ctx, orgID, item)
+}
+
+func (s *servicesImpl) GetRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error) {
+	return s.app.getRewardClaims(ctx, orgID, ids, userID, rewardType, status, limit, offset)
+}
+
+func (s *servicesImpl) GetRewardClaim(ctx context.Context, orgID string, id string) (*model.RewardClaim, error) {
+	return s.app.getRewardClaim(ctx, orgID, id)
+}
+
+func (s *servicesImpl) CreateRewardClaim(ctx context.Context, orgID string, item model.RewardClaim) (*model.RewardClaim, error) {
+	return s.app.createRewardClaim(ctx, orgID, item)
+}
+
+func (s *servicesImpl) UpdateRewardClaim(ctx context.Context, orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error) {
+	return s.app.updateRewardClaim(ctx, orgID, id, item)
 }
 
-func (s *servicesImpl) GetRewardTypes(orgID string) ([]model.RewardType, error) {
-	return s.app.getRewardTypes(orgID)
+func (s *servicesImpl) RedeemRewardClaimPickupCode(ctx context.Context, orgID string, actor string, code string) (*model.RewardClaim, error) {
+	return s.app.redeemRewardClaimPickupCode(ctx, orgID, actor, code)
 }
 
-func (s *servicesImpl) GetRewardType(orgID string, id string) (*model.RewardType, error) {
-	return s.app.getRewardType(orgID, id)
+func (s *servicesImpl) GetRewardClaimPickupAttempts(ctx context.Context, orgID string, claimID string) ([]model.RewardClaimPickupAttempt, error) {
+	return s.app.getRewardClaimPickupAttempts(ctx, orgID, claimID)
 }
 
-func (s *servicesImpl) CreateRewardType(orgID string, item model.RewardType) (*model.RewardType, error) {
-	return s.app.createRewardType(orgID, item)
+func (s *servicesImpl) GetRewardCatalogItems(ctx context.Context, orgID string, active *bool) ([]model.RewardCatalogItem, error) {
+	return s.app.getRewardCatalogItems(ctx, orgID, active)
 }
 
-func (s *servicesImpl) UpdateRewardType(orgID string, id string, item model.RewardType) (*model.RewardType, error) {
-	return s.app.updateRewardType(orgID, id, item)
+func (s *servicesImpl) GetRewardCatalogItem(ctx context.Context, orgID string, id string) (*model.RewardCatalogItem, error) {
+	return s.app.getRewardCatalogItem(ctx, orgID, id)
 }
 
-func (s *servicesImpl) DeleteRewardType(orgID string, id string) error {
-	return s.app.deleteRewardTypes(orgID, id)
+func (s *servicesImpl) CreateRewardCatalogItem(ctx context.Context, orgID string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error) {
+	return s.app.createRewardCatalogItem(ctx, orgID, item)
 }
 
-func (s *servicesImpl) GetRewardOperations(orgID string) ([]model.RewardOperation, error) {
-	return s.app.getRewardOperations(orgID)
+func (s *servicesImpl) UpdateRewardCatalogItem(ctx context.Context, orgID string, id string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error) {
+	return s.app.updateRewardCatalogItem(ctx, orgID, id, item)
 }
 
-func (s *servicesImpl) GetRewardOperationByID(orgID string, id string) (*model.RewardOperation, error) {
-	return s.app.getRewardOperationByID(orgID, id)
+func (s *servicesImpl) DeleteRewardCatalogItem(ctx context.Context, orgID string, id string) error {
+	return s.app.deleteRewardCatalogItem(ctx, orgID, id)
 }
 
-func (s *servicesImpl) GetRewardOperationByCode(orgID string, code string) (*model.RewardOperation, error) {
-	return s.app.getRewardOperationByCode(orgID, code)
+func (s *servicesImpl) GetAvailableRewardCatalogItems(ctx context.Context, orgID string) ([]model.RewardCatalogItem, error) {
+	return s.app.getAvailableRewardCatalogItems(ctx, orgID)
 }
 
-func (s *servicesImpl) CreateRewardOperation(orgID string, item model.RewardOperation) (*model.RewardOperation, error) {
-	return s.app.createRewardOperation(orgID, item)
+func (s *servicesImpl) GetPickupLocations(ctx context.Context, orgID string) ([]model.PickupLocation, error) {
+	return s.app.getPickupLocations(ctx, orgID)
 }
 
-func (s *servicesImpl) UpdateRewardOperation(orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error) {
-	return s.app.updateRewardOperation(orgID, id, item)
+func (s *servicesImpl) GetPickupLocation(ctx context.Context, orgID string, id string) (*model.PickupLocation, error) {
+	return s.app.getPickupLocation(ctx, orgID, id)
 }
 
-func (s *servicesImpl) DeleteRewardOperation(orgID string, id string) error {
-	return s.app.deleteRewardOperation(orgID, id)
+func (s *servicesImpl) CreatePickupLocation(ctx context.Context, orgID string, item model.PickupLocation) (*model.PickupLocation, error) {
+	return s.app.createPickupLocation(ctx, orgID, item)
 }
 
-func (s *servicesImpl) GetRewardInventories(orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, limit *int64, offset *int64) ([]model.RewardInventory, error) {
-	return s.app.getRewardInventories(orgID, ids, rewardType, inStock, grantDepleted, claimDepleted, limit, offset)
+func (s *servicesImpl) UpdatePickupLocation(ctx context.Context, orgID string, id string, item model.PickupLocation) (*model.PickupLocation, error) {
+	return s.app.updatePickupLocation(ctx, orgID, id, item)
 }
 
-func (s *servicesImpl) GetRewardInventory(orgID string, id string) (*model.RewardInventory, error) {
-	return s.app.getRewardInventory(orgID, id)
+func (s *servicesImpl) DeletePickupLocation(ctx context.Context, orgID string, id string) error {
+	return s.app.deletePickupLocation(ctx, orgID, id)
 }
 
-func (s *servicesImpl) CreateRewardInventory(orgID string, item model.RewardInventory) (*model.RewardInventory, error) {
-	return s.app.createRewardInventory(orgID, item)
+func (s *servicesImpl) GetRewardClaimsByLocation(ctx context.Context, orgID string, locationID string, slotID *string, status *string) ([]model.RewardClaim, error) {
+	return s.app.getRewardClaimsByLocation(ctx, orgID, locationID, slotID, status)
 }
 
-func (s *servicesImpl) UpdateRewardInventory(orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error) {
-	return s.app.updateRewardInventory(orgID, id, item)
+func (s *servicesImpl) GetActivePickupLocations(ctx context.Context, orgID string) ([]model.PickupLocation, error) {
+	return s.app.getActivePickupLocations(ctx, orgID)
 }
 
-func (s *servicesImpl) DeleteRewardInventory(orgID string, id string) error {
-	return s.app.deleteRewardTypes(orgID, id)
+func (s *servicesImpl) GetAuthorizationPolicies(ctx context.Context) ([]model.AuthorizationPolicy, error) {
+	return s.app.getAuthorizationPolicies(ctx)
 }
 
-func (s *servicesImpl) CreateReward(orgID string, item model.Reward) (*model.Reward, error) {
-	return s.app.createReward(orgID, item)
+func (s *servicesImpl) GetInternalCredentials(ctx context.Context, orgID string) ([]model.InternalCredential, error) {
+	return s.app.getInternalCredentials(ctx, orgID)
 }
 
-func (s *servicesImpl) GetRewardClaims(orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error) {
-	return s.app.getRewardClaims(orgID, ids, userID, rewardType, status, limit, offset)
+func (s *servicesImpl) GetInternalCredential(ctx context.Context, orgID string, id string) (*model.InternalCredential, error) {
+	return s.app.getInternalCredential(ctx, orgID, id)
 }
 
-func (s *servicesImpl) GetRewardClaim(orgID string, id string) (*model.RewardClaim, error) {
-	return s.app.getRewardClaim(orgID, id)
+func (s *servicesImpl) CreateInternalCredential(ctx context.Context, orgID string, item model.InternalCredential) (*model.InternalCredential, error) {
+	return s.app.createInternalCredential(ctx, orgID, item)
 }
 
-func (s *servicesImpl) CreateRewardClaim(orgID string, item model.RewardClaim) (*model.RewardClaim, error) {
-	return s.app.createRewardClaim(orgID, item)
+func (s *servicesImpl) RotateInternalCredential(ctx context.Context, orgID string, id string) (*model.InternalCredential, error) {
+	return s.app.rotateInternalCredential(ctx, orgID, id)
 }
 
-func (s *servicesImpl) UpdateRewardClaim(orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error) {
-	return s.app.updateRewardClaim(orgID, id, item)
+func (s *servicesImpl) RevokeInternalCredential(ctx context.Context, orgID string, id string) error {
+	return s.app.revokeInternalCredential(ctx, orgID, id)
 }
 
-func (s *servicesImpl) GetUserBalance(orgID string, userID string) ([]model.RewardTypeAmount, error) {
-	return s.app.getUserBalance(orgID, userID)
+func (s *servicesImpl) AuthenticateInternalCredential(ctx context.Context, key string) (*model.InternalCredential, error) {
+	return s.app.authenticateInternalCredential(ctx, key)
 }
 
-func (s *servicesImpl) GetUserRewardsHistory(orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error) {
-	return s.app.getUserRewardsHistory(orgID, userID, rewardType, code, buildingBlock, limit, offset)
+func (s *servicesImpl) GetAuditLogEntries(ctx context.Context, orgID string, resource *string, resourceID *string, actor *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64) ([]model.AuditLogEntry, error) {
+	return s.app.getAuditLogEntries(ctx, orgID, resource, resourceID, actor, startDate, endDate, limit, offset)
 }
 
-func (s *servicesImpl) GetRewardQuantity(orgID string, rewardType string) (*model.RewardQuantityState, error) {
-	return s.app.getRewardQuantity(orgID, rewardType)
+func (s *servicesImpl) CreateAuditLogEntry(ctx context.Context, orgID string, item model.AuditLogEntry) (*model.AuditLogEntry, error) {
+	return s.app.createAuditLogEntry(ctx, orgID, item)
+}
+
+func (s *servicesImpl) GetLeaderboard(ctx context.Context, orgID string, rewardType string, buildingBlock string, window string, admin bool, limit int64) (*model.Leaderboard, error) {
+	return s.app.getLeaderboard(ctx, orgID, rewardType, buildingBlock, window, admin, limit)
+}
+
+func (s *servicesImpl) GetLeaderboardProfile(ctx context.Context, orgID string, userID string) (*model.LeaderboardProfile, error) {
+	return s.app.getLeaderboardProfile(ctx, orgID, userID)
+}
+
+func (s *servicesImpl) UpdateLeaderboardProfile(ctx context.Context, orgID string, userID string, item model.LeaderboardProfile) (*model.LeaderboardProfile, error) {
+	return s.app.updateLeaderboardProfile(ctx, orgID, userID, item)
+}
+
+func (s *servicesImpl) RebuildLeaderboards(ctx context.Context, orgID string) (int, error) {
+	return s.app.rebuildLeaderboards(ctx, orgID)
+}
+
+func (s *servicesImpl) GetGrantsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.GrantsBucket, error) {
+	return s.app.getGrantsAnalytics(ctx, orgID, query)
+}
+
+func (s *servicesImpl) GetEarnersAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.EarnersBucket, error) {
+	return s.app.getEarnersAnalytics(ctx, orgID, query)
+}
+
+func (s *servicesImpl) GetClaimsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.ClaimsBucket, error) {
+	return s.app.getClaimsAnalytics(ctx, orgID, query)
+}
+
+func (s *servicesImpl) GetRedemptionAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.RedemptionSummary, error) {
+	return s.app.getRedemptionAnalytics(ctx, orgID, query)
+}
+
+func (s *servicesImpl) ExportRewardHistory(ctx context.Context, orgID string, userID *string, rewardType *string, code *string, buildingBlock *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64, each func(model.Reward) error) error {
+	return s.app.exportRewardHistory(ctx, orgID, userID, rewardType, code, buildingBlock, startDate, endDate, limit, offset, each)
+}
+
+func (s *servicesImpl) ExportRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64, each func(model.RewardClaim) error) error {
+	return s.app.exportRewardClaims(ctx, orgID, ids, userID, rewardType, status, limit, offset, each)
+}
+
+func (s *servicesImpl) ExportRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64, each func(model.RewardInventory) error) error {
+	return s.app.exportRewardInventories(ctx, orgID, ids, rewardType, inStock, grantDepleted, claimDepleted, archived, limit, offset, each)
+}
+
+func (s *servicesImpl) ImportRewardTypes(ctx context.Context, orgID string, items []model.RewardType, dryRun bool) (*model.ImportReport, error) {
+	return s.app.importRewardTypes(ctx, orgID, items, dryRun)
+}
+
+func (s *servicesImpl) ImportRewardOperations(ctx context.Context, orgID string, items []model.RewardOperation, dryRun bool) (*model.ImportReport, error) {
+	return s.app.importRewardOperations(ctx, orgID, items, dryRun)
+}
+
+func (s *servicesImpl) ImportRewardInventories(ctx context.Context, orgID string, items []model.RewardInventory, dryRun bool) (*model.ImportReport, error) {
+	return s.app.importRewardInventories(ctx, orgID, items, dryRun)
+}
+
+func (s *servicesImpl) ExportOrgConfig(ctx context.Context, orgID string) (*model.OrgConfig, error) {
+	return s.app.exportOrgConfig(ctx, orgID)
+}
+
+func (s *servicesImpl) ImportOrgConfig(ctx context.Context, orgID string, config model.OrgConfig, dryRun bool) (*model.OrgConfigImportReport, error) {
+	return s.app.importOrgConfig(ctx, orgID, config, dryRun)
+}
+
+func (s *servicesImpl) CheckReadiness(ctx context.Context) *model.HealthReport {
+	return s.app.checkReadiness(ctx)
+}
+
+func (s *servicesImpl) GetUserBalance(ctx context.Context, orgID string, userID string) ([]model.RewardTypeAmount, error) {
+	return s.app.getUserBalance(ctx, orgID, userID)
+}
+
+func (s *servicesImpl) GetUserRewardsHistory(ctx context.Context, orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error) {
+	return s.app.getUserRewardsHistory(ctx, orgID, userID, rewardType, code, buildingBlock, limit, offset)
+}
+
+func (s *servicesImpl) GetRewardQuantity(ctx context.Context, orgID string, rewardType string) (*model.RewardQuantityState, error) {
+	return s.app.getRewardQuantity(ctx, orgID, rewardType)
 }
 
 // Storage is used by core to storage data - DB storage adapter, file storage adapter etc
 type Storage interface {
-	GetRewardTypes(orgID string) ([]model.RewardType, error)
-	GetRewardType(orgID string, id string) (*model.RewardType, error)
-	GetRewardTypeByType(orgID string, rewardType string) (*model.RewardType, error)
-	CreateRewardType(orgID string, item model.RewardType) (*model.RewardType, error)
-	UpdateRewardType(orgID string, id string, item model.RewardType) (*model.RewardType, error)
-	DeleteRewardType(orgID string, id string) error
-
-	GetRewardOperations(orgID string) ([]model.RewardOperation, error)
-	GetRewardOperationByID(orgID string, id string) (*model.RewardOperation, error)
-	GetRewardOperationByCode(orgID string, code string) (*model.RewardOperation, error)
-	CreateRewardOperation(orgID string, item model.RewardOperation) (*model.RewardOperation, error)
-	UpdateRewardOperation(orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error)
-	DeleteRewardOperation(orgID string, id string) error
-
-	GetRewardInventories(orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, limit *int64, offset *int64) ([]model.RewardInventory, error)
-	GetRewardInventory(orgID string, id string) (*model.RewardInventory, error)
-	CreateRewardInventory(orgID string, item model.RewardInventory) (*model.RewardInventory, error)
-	UpdateRewardInventory(orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error)
-
-	GetRewardClaims(orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error)
-	GetRewardClaim(orgID string, id string) (*model.RewardClaim, error)
-	CreateRewardClaim(orgID string, item model.RewardClaim) (*model.RewardClaim, error)
-	UpdateRewardClaim(orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error)
-
-	GetUserRewardsHistory(orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error)
-	GetUserRewardByID(orgID string, userID, id string) (*model.Reward, error)
-	CreateUserReward(orgID string, item model.Reward) (*model.Reward, error)
+	Ping(ctx context.Context) error
+	GetChangeStreams() map[string]bool
+
+	GetRewardTypes(ctx context.Context, orgID string) ([]model.RewardType, error)
+	GetRewardType(ctx context.Context, orgID string, id string) (*model.RewardType, error)
+	GetRewardTypeByType(ctx context.Context, orgID string, rewardType string) (*model.RewardType, error)
+	CreateRewardType(ctx context.Context, orgID string, item model.RewardType) (*model.RewardType, error)
+	UpdateRewardType(ctx context.Context, orgID string, id string, item model.RewardType) (*model.RewardType, error)
+	DeleteRewardType(ctx context.Context, orgID string, id string) error
+	SoftDeleteRewardType(ctx context.Context, orgID string, id string) error
+	GetRewardTypeReferences(ctx context.Context, orgID string, rewardType string) ([]model.Reference, error)
+
+	GetRewardOperations(ctx context.Context, orgID string, archived *bool) ([]model.RewardOperation, error)
+	GetRewardOperationByID(ctx context.Context, orgID string, id string) (*model.RewardOperation, error)
+	GetRewardOperationByCode(ctx context.Context, orgID string, code string, buildingBlock string) (*model.RewardOperation, error)
+	CreateRewardOperation(ctx context.Context, orgID string, item model.RewardOperation) (*model.RewardOperation, error)
+	UpdateRewardOperation(ctx context.Context, orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error)
+	DeleteRewardOperation(ctx context.Context, orgID string, id string) error
+	SetRewardOperationArchived(ctx context.Context, orgID string, id string, archived bool) error
+	SoftDeleteRewardOperation(ctx context.Context, orgID string, id string) error
+	GetRewardOperationReferences(ctx context.Context, orgID string, code string, buildingBlock string) ([]model.Reference, error)
+
+	GetRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64) ([]model.RewardInventory, error)
+	GetRewardInventory(ctx context.Context, orgID string, id string) (*model.RewardInventory, error)
+	CreateRewardInventory(ctx context.Context, orgID string, item model.RewardInventory) (*model.RewardInventory, error)
+	UpdateRewardInventory(ctx context.Context, orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error)
+	DeleteRewardInventory(ctx context.Context, orgID string, id string) error
+	SetRewardInventoryArchived(ctx context.Context, orgID string, id string, archived bool) error
+
+	GetRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error)
+	GetRewardClaim(ctx context.Context, orgID string, id string) (*model.RewardClaim, error)
+	CreateRewardClaim(ctx context.Context, orgID string, item model.RewardClaim) (*model.RewardClaim, error)
+	UpdateRewardClaim(ctx context.Context, orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error)
+	SetRewardClaimPickupCode(ctx context.Context, orgID string, id string, code string) error
+	GetRewardClaimByPickupCode(ctx context.Context, orgID string, code string) (*model.RewardClaim, error)
+	FulfillRewardClaim(ctx context.Context, orgID string, id string) (bool, error)
+	GetRewardClaimPickupAttempts(ctx context.Context, orgID string, claimID string) ([]model.RewardClaimPickupAttempt, error)
+	CreateRewardClaimPickupAttempt(ctx context.Context, orgID string, item model.RewardClaimPickupAttempt) (*model.RewardClaimPickupAttempt, error)
+
+	GetRewardCatalogItems(ctx context.Context, orgID string, active *bool) ([]model.RewardCatalogItem, error)
+	GetRewardCatalogItem(ctx context.Context, orgID string, id string) (*model.RewardCatalogItem, error)
+	CreateRewardCatalogItem(ctx context.Context, orgID string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error)
+	UpdateRewardCatalogItem(ctx context.Context, orgID string, id string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error)
+	DeleteRewardCatalogItem(ctx context.Context, orgID string, id string) error
+
+	GetPickupLocations(ctx context.Context, orgID string) ([]model.PickupLocation, error)
+	GetPickupLocation(ctx context.Context, orgID string, id string) (*model.PickupLocation, error)
+	CreatePickupLocation(ctx context.Context, orgID string, item model.PickupLocation) (*model.PickupLocation, error)
+	UpdatePickupLocation(ctx context.Context, orgID string, id string, item model.PickupLocation) (*model.PickupLocation, error)
+	DeletePickupLocation(ctx context.Context, orgID string, id string) error
+	GetRewardClaimsByLocation(ctx context.Context, orgID string, locationID string, slotID *string, status *string) ([]model.RewardClaim, error)
+
+	GetUserRewardsHistory(ctx context.Context, orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error)
+	GetUserRewardByID(ctx context.Context, orgID string, userID, id string) (*model.Reward, error)
+	CreateUserReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error)
+	CreateUserRewardDebit(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error)
 
 	// Quantities
-	GetRewardQuantityState(orgID string, rewardType string, inStock *bool) (*model.RewardQuantityState, error)
+	GetRewardQuantityState(ctx context.Context, orgID string, rewardType string, inStock *bool) (*model.RewardQuantityState, error)
 
 	// User APIs
-	GetUserRewardsAmount(orgID string, userID string, rewardType *string) ([]model.RewardTypeAmount, error)
-	GetUserClaimsAmount(orgID string, userID string, rewardType *string) ([]model.RewardTypeAmount, error)
+	GetUserRewardsAmount(ctx context.Context, orgID string, userID string, rewardType *string) ([]model.RewardTypeAmount, error)
+	GetUserClaimsAmount(ctx context.Context, orgID string, userID string, rewardType *string) ([]model.RewardTypeAmount, error)
+
+	GetAuthorizationPolicies(ctx context.Context) ([]model.AuthorizationPolicy, error)
+
+	GetInternalCredentials(ctx context.Context, orgID string) ([]model.InternalCredential, error)
+	GetInternalCredential(ctx context.Context, orgID string, id string) (*model.InternalCredential, error)
+	GetInternalCredentialByKeyHash(ctx context.Context, keyHash string) (*model.InternalCredential, error)
+	CreateInternalCredential(ctx context.Context, orgID string, item model.InternalCredential) (*model.InternalCredential, error)
+	UpdateInternalCredentialKey(ctx context.Context, orgID string, id string, keyHash string, keyPrefix string) error
+	RevokeInternalCredential(ctx context.Context, orgID string, id string) error
+
+	GetAuditLogEntries(ctx context.Context, orgID string, resource *string, resourceID *string, actor *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64) ([]model.AuditLogEntry, error)
+	CreateAuditLogEntry(ctx context.Context, orgID string, item model.AuditLogEntry) (*model.AuditLogEntry, error)
+
+	GetLeaderboardProfile(ctx context.Context, orgID string, userID string) (*model.LeaderboardProfile, error)
+	SaveLeaderboardProfile(ctx context.Context, orgID string, item model.LeaderboardProfile) (*model.LeaderboardProfile, error)
+	GetLeaderboardScores(ctx context.Context, orgID string, rewardType string, buildingBlock string, window string, windowStart time.Time, optedIn *bool, limit int64) ([]model.LeaderboardScore, error)
+	RebuildLeaderboardScores(ctx context.Context, orgID string) (int, error)
+
+	GetGrantsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.GrantsBucket, error)
+	GetEarnersAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.EarnersBucket, error)
+	GetClaimsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.ClaimsBucket, error)
+	GetRedemptionAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.RedemptionSummary, error)
+
+	ExportRewardHistory(ctx context.Context, orgID string, userID *string, rewardType *string, code *string, buildingBlock *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64, each func(model.Reward) error) error
+	ExportRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64, each func(model.RewardClaim) error) error
+	ExportRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64, each func(model.RewardInventory) error) error
+
+	ApplyImport(ctx context.Context, orgID string, batch model.ImportBatch) (*model.ImportBatch, error)
 
 	SetListener(listener storage.Listener)
 }
+
+// ApplicationListener represents application listener
+type ApplicationListener interface {
+	OnAuthorizationPoliciesChanged()
+}
@@ -0,0 +1,189 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	// AllocationStrategyFIFO draws from the oldest inventories first. It is the default strategy
+	AllocationStrategyFIFO string = "fifo"
+	// AllocationStrategyLIFO draws from the newest inventories first
+	AllocationStrategyLIFO string = "lifo"
+	// AllocationStrategyMostStocked draws from the inventories with the largest available amount first
+	AllocationStrategyMostStocked string = "most_stocked"
+	// AllocationStrategySpecificFirst draws from the preferred inventories of the reward type first and then from the oldest ones
+	AllocationStrategySpecificFirst string = "specific_first"
+)
+
+// InventoryAllocation is the amount drawn from a single inventory by a grant or a claim
+type InventoryAllocation struct {
+	InventoryID string `json:"inventory_id" bson:"inventory_id"`
+	RewardType  string `json:"reward_type" bson:"reward_type"`
+	Amount      int    `json:"amount" bson:"amount"`
+	Strategy    string `json:"strategy" bson:"strategy"`
+} // @name InventoryAllocation
+
+// AllocationStrategy decides the order in which the inventories of a reward type are drawn from
+type AllocationStrategy interface {
+	Name() string
+	// Order gives the inventories in the order they are drawn from. available gives the amount which can be drawn from an inventory
+	Order(inventories []RewardInventory, available func(RewardInventory) int) []RewardInventory
+}
+
+// AllocationStrategyFactory creates the strategy configured by a reward type
+type AllocationStrategyFactory func(rewardType RewardType) AllocationStrategy
+
+var (
+	allocationStrategiesLock sync.RWMutex
+	allocationStrategies     = map[string]AllocationStrategyFactory{
+		AllocationStrategyFIFO: func(RewardType) AllocationStrategy { return fifoAllocation{} },
+		AllocationStrategyLIFO: func(RewardType) AllocationStrategy { return lifoAllocation{} },
+		AllocationStrategyMostStocked: func(RewardType) AllocationStrategy {
+			return mostStockedAllocation{}
+		},
+		AllocationStrategySpecificFirst: func(rewardType RewardType) AllocationStrategy {
+			return specificFirstAllocation{inventoryIDs: rewardType.PreferredInventoryIDs}
+		},
+	}
+)
+
+// RegisterAllocationStrategy adds or replaces an allocation strategy which reward types can choose by name
+func RegisterAllocationStrategy(name string, factory AllocationStrategyFactory) {
+	allocationStrategiesLock.Lock()
+	defer allocationStrategiesLock.Unlock()
+
+	allocationStrategies[name] = factory
+}
+
+// NewAllocationStrategy creates the allocation strategy chosen by the reward type. FIFO is used if none is chosen
+func NewAllocationStrategy(rewardType RewardType) (AllocationStrategy, error) {
+	name := rewardType.AllocationStrategy
+	if name == "" {
+		name = AllocationStrategyFIFO
+	}
+
+	allocationStrategiesLock.RLock()
+	factory := allocationStrategies[name]
+	allocationStrategiesLock.RUnlock()
+
+	if factory == nil {
+		return nil, NewValidationError("unknown allocation strategy %s", name)
+	}
+	return factory(rewardType), nil
+}
+
+// Allocate draws amount from the inventories in the order of the strategy. It fails with an insufficient
+// inventory error if the inventories do not have enough available amount in total.
+func Allocate(strategy AllocationStrategy, rewardType string, inventories []RewardInventory, amount int, available func(RewardInventory) int) ([]InventoryAllocation, error) {
+	allocations := []InventoryAllocation{}
+	remaining := amount
+	for _, inventory := range strategy.Order(inventories, available) {
+		if remaining == 0 {
+			break
+		}
+		availableAmount := available(inventory)
+		if availableAmount <= 0 {
+			continue
+		}
+		if availableAmount > remaining {
+			availableAmount = remaining
+		}
+		allocations = append(allocations, InventoryAllocation{InventoryID: inventory.ID, RewardType: rewardType, Amount: availableAmount, Strategy: strategy.Name()})
+		remaining -= availableAmount
+	}
+
+	if remaining > 0 {
+		return nil, NewInsufficientInventoryError("not enough available quantity for %s", rewardType)
+	}
+	return allocations, nil
+}
+
+// sortedInventories gives a sorted copy of the inventories so the callers keep their order
+func sortedInventories(inventories []RewardInventory, less func(a RewardInventory, b RewardInventory) bool) []RewardInventory {
+	sorted := make([]RewardInventory, len(inventories))
+	copy(sorted, inventories)
+	sort.SliceStable(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	return sorted
+}
+
+type fifoAllocation struct{}
+
+func (fifoAllocation) Name() string {
+	return AllocationStrategyFIFO
+}
+
+func (fifoAllocation) Order(inventories []RewardInventory, available func(RewardInventory) int) []RewardInventory {
+	return sortedInventories(inventories, func(a RewardInventory, b RewardInventory) bool {
+		return a.DateCreated.Before(b.DateCreated)
+	})
+}
+
+type lifoAllocation struct{}
+
+func (lifoAllocation) Name() string {
+	return AllocationStrategyLIFO
+}
+
+func (lifoAllocation) Order(inventories []RewardInventory, available func(RewardInventory) int) []RewardInventory {
+	return sortedInventories(inventories, func(a RewardInventory, b RewardInventory) bool {
+		return a.DateCreated.After(b.DateCreated)
+	})
+}
+
+type mostStockedAllocation struct{}
+
+func (mostStockedAllocation) Name() string {
+	return AllocationStrategyMostStocked
+}
+
+func (mostStockedAllocation) Order(inventories []RewardInventory, available func(RewardInventory) int) []RewardInventory {
+	return sortedInventories(inventories, func(a RewardInventory, b RewardInventory) bool {
+		availableA, availableB := available(a), available(b)
+		if availableA != availableB {
+			return availableA > availableB
+		}
+		return a.DateCreated.Before(b.DateCreated)
+	})
+}
+
+type specificFirstAllocation struct {
+	inventoryIDs []string // the preferred inventories in the order they are drawn from
+}
+
+func (specificFirstAllocation) Name() string {
+	return AllocationStrategySpecificFirst
+}
+
+func (s specificFirstAllocation) Order(inventories []RewardInventory, available func(RewardInventory) int) []RewardInventory {
+	positions := map[string]int{}
+	for i, id := range s.inventoryIDs {
+		positions[id] = i
+	}
+
+	return sortedInventories(inventories, func(a RewardInventory, b RewardInventory) bool {
+		positionA, preferredA := positions[a.ID]
+		positionB, preferredB := positions[b.ID]
+		if preferredA && preferredB {
+			return positionA < positionB
+		}
+		if preferredA != preferredB {
+			return preferredA
+		}
+		return a.DateCreated.Before(b.DateCreated)
+	})
+}
@@ -0,0 +1,118 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testInventories() []RewardInventory {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []RewardInventory{
+		{ID: "middle", AmountTotal: 10, AmountGranted: 5, DateCreated: now.Add(time.Hour)},
+		{ID: "oldest", AmountTotal: 3, DateCreated: now},
+		{ID: "newest", AmountTotal: 20, AmountGranted: 18, DateCreated: now.Add(2 * time.Hour)},
+		{ID: "empty", AmountTotal: 4, AmountGranted: 4, DateCreated: now.Add(-time.Hour)},
+	}
+}
+
+func grantable(inventory RewardInventory) int {
+	return inventory.GetGrantableAmount()
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name        string
+		rewardType  RewardType
+		amount      int
+		allocations []InventoryAllocation
+	}{
+		{"default fifo", RewardType{}, 5, []InventoryAllocation{
+			{InventoryID: "oldest", Amount: 3}, {InventoryID: "middle", Amount: 2}}},
+		{"lifo", RewardType{AllocationStrategy: AllocationStrategyLIFO}, 5, []InventoryAllocation{
+			{InventoryID: "newest", Amount: 2}, {InventoryID: "middle", Amount: 3}}},
+		{"most stocked", RewardType{AllocationStrategy: AllocationStrategyMostStocked}, 7, []InventoryAllocation{
+			{InventoryID: "middle", Amount: 5}, {InventoryID: "oldest", Amount: 2}}},
+		{"specific first", RewardType{AllocationStrategy: AllocationStrategySpecificFirst, PreferredInventoryIDs: []string{"newest", "middle"}}, 8, []InventoryAllocation{
+			{InventoryID: "newest", Amount: 2}, {InventoryID: "middle", Amount: 5}, {InventoryID: "oldest", Amount: 1}}},
+		{"exact total", RewardType{}, 10, []InventoryAllocation{
+			{InventoryID: "oldest", Amount: 3}, {InventoryID: "middle", Amount: 5}, {InventoryID: "newest", Amount: 2}}},
+	}
+
+	for _, test := range tests {
+		strategy, err := NewAllocationStrategy(test.rewardType)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		inventories := testInventories()
+		allocations, err := Allocate(strategy, "tshirt", inventories, test.amount, grantable)
+		if err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+
+		for i := range test.allocations {
+			test.allocations[i].RewardType = "tshirt"
+			test.allocations[i].Strategy = strategy.Name()
+		}
+		if !reflect.DeepEqual(allocations, test.allocations) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.allocations, allocations)
+		}
+		if !reflect.DeepEqual(inventories, testInventories()) {
+			t.Errorf("%s: expected the inventories to keep their order", test.name)
+		}
+	}
+}
+
+func TestAllocateInsufficient(t *testing.T) {
+	strategy, _ := NewAllocationStrategy(RewardType{})
+	_, err := Allocate(strategy, "tshirt", testInventories(), 11, grantable)
+	if !IsErrorCode(err, ErrorCodeInsufficientInventory) {
+		t.Errorf("expected an insufficient inventory error, got %v", err)
+	}
+
+	_, err = Allocate(strategy, "tshirt", nil, 1, grantable)
+	if !IsErrorCode(err, ErrorCodeInsufficientInventory) {
+		t.Errorf("expected an insufficient inventory error without inventories, got %v", err)
+	}
+}
+
+type reverseIDAllocation struct{}
+
+func (reverseIDAllocation) Name() string {
+	return "reverse_id"
+}
+
+func (reverseIDAllocation) Order(inventories []RewardInventory, available func(RewardInventory) int) []RewardInventory {
+	return sortedInventories(inventories, func(a RewardInventory, b RewardInventory) bool { return a.ID > b.ID })
+}
+
+func TestRegisterAllocationStrategy(t *testing.T) {
+	_, err := NewAllocationStrategy(RewardType{AllocationStrategy: "reverse_id"})
+	if !IsErrorCode(err, ErrorCodeValidation) {
+		t.Errorf("expected an unknown strategy to be a validation error, got %v", err)
+	}
+
+	RegisterAllocationStrategy("reverse_id", func(RewardType) AllocationStrategy { return reverseIDAllocation{} })
+	strategy, err := NewAllocationStrategy(RewardType{AllocationStrategy: "reverse_id"})
+	if err != nil {
+		t.Fatalf("expected the registered strategy, got %s", err)
+	}
+	allocations, err := Allocate(strategy, "tshirt", testInventories(), 1, grantable)
+	if err != nil || allocations[0].InventoryID != "oldest" || allocations[0].Strategy != "reverse_id" {
+		t.Errorf("expected the registered strategy to draw from oldest, got %v %v", allocations, err)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+const (
+	// AnalyticsBucketHour buckets by the hour
+	AnalyticsBucketHour string = "hour"
+	// AnalyticsBucketDay buckets by the day. It is the default bucket
+	AnalyticsBucketDay string = "day"
+	// AnalyticsBucketWeek buckets by the ISO week starting on Monday
+	AnalyticsBucketWeek string = "week"
+
+	// maxAnalyticsBuckets limits the number of buckets a query may span
+	maxAnalyticsBuckets = 2000
+	// defaultAnalyticsRange is the date range of a query without a start date
+	defaultAnalyticsRange = 30 * 24 * time.Hour
+)
+
+// analyticsBucketFormats are the $dateToString formats of the bucket keys
+var analyticsBucketFormats = map[string]string{
+	AnalyticsBucketHour: "%Y-%m-%dT%H",
+	AnalyticsBucketDay:  "%Y-%m-%d",
+	AnalyticsBucketWeek: "%G-W%V",
+}
+
+// analyticsTimezonePattern is UTC or an Area/Location Olson name
+var analyticsTimezonePattern = regexp.MustCompile(`^(UTC|[A-Z][A-Za-z_]+(/[A-Za-z0-9_+-]+){1,2})$`)
+
+var analyticsBucketDurations = map[string]time.Duration{
+	AnalyticsBucketHour: time.Hour,
+	AnalyticsBucketDay:  24 * time.Hour,
+	AnalyticsBucketWeek: 7 * 24 * time.Hour,
+}
+
+// AnalyticsQuery is the time bucketing and the date range [StartDate, EndDate) of an analytics request
+type AnalyticsQuery struct {
+	Bucket     string
+	Timezone   string
+	Location   *time.Location
+	StartDate  time.Time
+	EndDate    time.Time
+	RewardType *string
+}
+
+// NewAnalyticsQuery validates the analytics parameters. The range defaults to the last 30 days until now
+func NewAnalyticsQuery(bucket string, timezone string, startDate *time.Time, endDate *time.Time, rewardType *string, now time.Time) (*AnalyticsQuery, error) {
+	if bucket == "" {
+		bucket = AnalyticsBucketDay
+	}
+	duration, ok := analyticsBucketDurations[bucket]
+	if !ok {
+		return nil, NewValidationError("unknown bucket %s, expected hour, day or week", bucket)
+	}
+
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	// the timezone is given to MongoDB, which knows the Olson names but not the local time of the service
+	if !analyticsTimezonePattern.MatchString(timezone) {
+		return nil, NewValidationError("unknown timezone %s, expected UTC or an Olson name like America/Chicago", timezone)
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, NewValidationError("unknown timezone %s", timezone)
+	}
+
+	query := AnalyticsQuery{Bucket: bucket, Timezone: location.String(), Location: location, EndDate: now, RewardType: rewardType}
+	if endDate != nil {
+		query.EndDate = *endDate
+	}
+	query.StartDate = query.EndDate.Add(-defaultAnalyticsRange)
+	if startDate != nil {
+		query.StartDate = *startDate
+	}
+	if !query.StartDate.Before(query.EndDate) {
+		return nil, NewValidationError("start_date must be before end_date")
+	}
+	if query.EndDate.Sub(query.StartDate)/duration > maxAnalyticsBuckets {
+		return nil, NewValidationError("the date range spans more than %d %s buckets", maxAnalyticsBuckets, bucket)
+	}
+	return &query, nil
+}
+
+// BucketFormat gives the $dateToString format of the bucket keys
+func (q *AnalyticsQuery) BucketFormat() string {
+	return analyticsBucketFormats[q.Bucket]
+}
+
+// BucketStart gives the start of the bucket with a key formatted by BucketFormat in the timezone of the query
+func (q *AnalyticsQuery) BucketStart(key string) (time.Time, error) {
+	switch q.Bucket {
+	case AnalyticsBucketHour:
+		return time.ParseInLocation("2006-01-02T15", key, q.Location)
+	case AnalyticsBucketDay:
+		return time.ParseInLocation("2006-01-02", key, q.Location)
+	case AnalyticsBucketWeek:
+		var year, week int
+		_, err := fmt.Sscanf(key, "%d-W%d", &year, &week)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid week bucket %s: %s", key, err)
+		}
+		// January 4th is always in the first ISO week
+		jan4 := time.Date(year, time.January, 4, 0, 0, 0, 0, q.Location)
+		monday := jan4.AddDate(0, 0, -((int(jan4.Weekday()) + 6) % 7))
+		return monday.AddDate(0, 0, (week-1)*7), nil
+	}
+	return time.Time{}, NewValidationError("unknown bucket %s", q.Bucket)
+}
+
+// GrantsBucket is the number and the amount of the rewards granted by an operation in a bucket
+type GrantsBucket struct {
+	BucketStart   time.Time `json:"bucket_start"`
+	Code          string    `json:"code"`
+	BuildingBlock string    `json:"building_block"`
+	Count         int       `json:"count"`
+	Amount        int       `json:"amount"`
+} // @name GrantsBucket
+
+// EarnersBucket is the number of distinct users who got rewards in a bucket
+type EarnersBucket struct {
+	BucketStart   time.Time `json:"bucket_start"`
+	UniqueEarners int       `json:"unique_earners"`
+} // @name EarnersBucket
+
+// ClaimsBucket is the number of the claims with a status created in a bucket
+type ClaimsBucket struct {
+	BucketStart time.Time `json:"bucket_start"`
+	Status      string    `json:"status"`
+	Count       int       `json:"count"`
+	Amount      int       `json:"amount"`
+} // @name ClaimsBucket
+
+// RedemptionSummary compares the granted and the claimed amount of a reward type in the date range
+type RedemptionSummary struct {
+	RewardType     string  `json:"reward_type"`
+	GrantedAmount  int     `json:"granted_amount"`
+	ClaimedAmount  int     `json:"claimed_amount"`
+	UniqueEarners  int     `json:"unique_earners"`
+	UniqueClaimers int     `json:"unique_claimers"`
+	RedemptionRate float64 `json:"redemption_rate"` // claimed amount / granted amount, 0 if nothing is granted
+} // @name RedemptionSummary
+
+// SetRedemptionRate computes the redemption rate from the amounts
+func (rs *RedemptionSummary) SetRedemptionRate() {
+	rs.RedemptionRate = 0
+	if rs.GrantedAmount > 0 {
+		rs.RedemptionRate = float64(rs.ClaimedAmount) / float64(rs.GrantedAmount)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewAnalyticsQuery(t *testing.T) {
+	now := time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)
+	start := now.AddDate(0, 0, -7)
+	tooEarly := now.AddDate(0, -6, 0)
+
+	query, err := NewAnalyticsQuery("", "", nil, nil, nil, now)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+	if query.Bucket != AnalyticsBucketDay || query.Timezone != "UTC" || !query.EndDate.Equal(now) || !query.StartDate.Equal(now.AddDate(0, 0, -30)) {
+		t.Errorf("expected the daily buckets of the last 30 days in UTC, got %+v", query)
+	}
+
+	tests := []struct {
+		name      string
+		bucket    string
+		timezone  string
+		startDate *time.Time
+		endDate   *time.Time
+		valid     bool
+	}{
+		{"week in chicago", AnalyticsBucketWeek, "America/Chicago", &start, nil, true},
+		{"unknown bucket", "month", "", nil, nil, false},
+		{"unknown timezone", AnalyticsBucketDay, "Mars/Olympus", nil, nil, false},
+		{"local timezone", AnalyticsBucketDay, "Local", nil, nil, false},
+		{"timezone file", AnalyticsBucketDay, "localtime", nil, nil, false},
+		{"etc timezone", AnalyticsBucketDay, "Etc/GMT+5", nil, nil, true},
+		{"three part timezone", AnalyticsBucketDay, "America/Argentina/Buenos_Aires", nil, nil, true},
+		{"start after end", AnalyticsBucketDay, "", &now, &start, false},
+		{"too many hours", AnalyticsBucketHour, "", &tooEarly, nil, false},
+		{"days of six months", AnalyticsBucketDay, "", &tooEarly, nil, true},
+	}
+	for _, test := range tests {
+		_, err := NewAnalyticsQuery(test.bucket, test.timezone, test.startDate, test.endDate, nil, now)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: expected valid %t, got %v", test.name, test.valid, err)
+		}
+		if err != nil && !IsErrorCode(err, ErrorCodeValidation) {
+			t.Errorf("%s: expected a validation error, got %v", test.name, err)
+		}
+	}
+}
+
+func TestAnalyticsBucketStart(t *testing.T) {
+	chicago, _ := time.LoadLocation("America/Chicago")
+	tests := []struct {
+		bucket string
+		key    string
+		start  time.Time
+	}{
+		{AnalyticsBucketHour, "2024-03-10T21", time.Date(2024, 3, 10, 21, 0, 0, 0, chicago)},
+		{AnalyticsBucketDay, "2024-03-10", time.Date(2024, 3, 10, 0, 0, 0, 0, chicago)},
+		{AnalyticsBucketWeek, "2024-W11", time.Date(2024, 3, 11, 0, 0, 0, 0, chicago)},
+		{AnalyticsBucketWeek, "2021-W01", time.Date(2021, 1, 4, 0, 0, 0, 0, chicago)},
+		{AnalyticsBucketWeek, "2020-W53", time.Date(2020, 12, 28, 0, 0, 0, 0, chicago)},
+	}
+	for _, test := range tests {
+		query := AnalyticsQuery{Bucket: test.bucket, Timezone: chicago.String(), Location: chicago}
+		start, err := query.BucketStart(test.key)
+		if err != nil {
+			t.Errorf("%s %s: unexpected error %s", test.bucket, test.key, err)
+			continue
+		}
+		if !start.Equal(test.start) {
+			t.Errorf("%s %s: expected %s, got %s", test.bucket, test.key, test.start, start)
+		}
+	}
+}
+
+func TestRedemptionRate(t *testing.T) {
+	summary := RedemptionSummary{GrantedAmount: 200, ClaimedAmount: 50}
+	summary.SetRedemptionRate()
+	if summary.RedemptionRate != 0.25 {
+		t.Errorf("expected 0.25, got %f", summary.RedemptionRate)
+	}
+
+	summary = RedemptionSummary{ClaimedAmount: 50}
+	summary.SetRedemptionRate()
+	if summary.RedemptionRate != 0 {
+		t.Errorf("expected no rate without grants, got %f", summary.RedemptionRate)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "time"
+
+const (
+	// AuditActorTypeAdmin admin user calling the admin APIs
+	AuditActorTypeAdmin = "admin"
+	// AuditActorTypeInternal building block calling the internal APIs
+	AuditActorTypeInternal = "internal"
+	// AuditActorTypeOperator operator running the rewardsctl command line tool
+	AuditActorTypeOperator = "operator"
+)
+
+// AuditLogEntry records a single mutation made through the admin or internal APIs or the command line tool
+type AuditLogEntry struct {
+	ID          string                 `json:"id" bson:"_id"`
+	OrgID       string                 `json:"org_id" bson:"org_id"`
+	ActorType   string                 `json:"actor_type" bson:"actor_type"`
+	Actor       string                 `json:"actor" bson:"actor"` // account id of the admin, building block of the internal caller or system user of the operator
+	Action      string                 `json:"action" bson:"action"`
+	Resource    string                 `json:"resource" bson:"resource"`
+	ResourceID  string                 `json:"resource_id,omitempty" bson:"resource_id,omitempty"`
+	Method      string                 `json:"method" bson:"method"`
+	Path        string                 `json:"path" bson:"path"`
+	Status      int                    `json:"status" bson:"status"`
+	Before      map[string]interface{} `json:"before,omitempty" bson:"before,omitempty"`
+	After       map[string]interface{} `json:"after,omitempty" bson:"after,omitempty"`
+	Changes     []AuditChange          `json:"changes,omitempty" bson:"changes,omitempty"`
+	RequestID   string                 `json:"request_id" bson:"request_id"`
+	DateCreated time.Time              `json:"date_created" bson:"date_created"`
+} // @name AuditLogEntry
+
+// AuditChange is a field which differs between the before and after state of a resource
+type AuditChange struct {
+	Field  string      `json:"field" bson:"field"`
+	Before interface{} `json:"before" bson:"before"`
+	After  interface{} `json:"after" bson:"after"`
+} // @name AuditChange
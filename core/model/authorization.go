@@ -0,0 +1,29 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "time"
+
+// AuthorizationPolicy is a single admin access control rule. It grants the Core BB permission in Subject
+// the methods in Action (regex, e.g. "(GET)|(PUT)") on the routes matching Object (e.g. /rewards/api/admin/claims/*)
+type AuthorizationPolicy struct {
+	ID          string    `json:"id" bson:"_id"`
+	Subject     string    `json:"subject" bson:"subject"`
+	Object      string    `json:"object" bson:"object"`
+	Action      string    `json:"action" bson:"action"`
+	Description string    `json:"description" bson:"description"`
+	DateCreated time.Time `json:"date_created" bson:"date_created"`
+	DateUpdated time.Time `json:"date_updated" bson:"date_updated"`
+} // @name AuthorizationPolicy
@@ -0,0 +1,61 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "time"
+
+// RewardCatalogItem wraps a redeemable item which is bought with currency reward types
+type RewardCatalogItem struct {
+	ID            string               `json:"id" bson:"_id"`
+	OrgID         string               `json:"org_id" bson:"org_id"`
+	RewardType    string               `json:"reward_type" bson:"reward_type"` // the inventory to draw from - tshirt
+	DisplayName   string               `json:"display_name" bson:"display_name"`
+	Description   string               `json:"description" bson:"description"`
+	Price         []RewardCatalogPrice `json:"price" bson:"price"`
+	Active        bool                 `json:"active" bson:"active"`
+	AvailableFrom *time.Time           `json:"available_from" bson:"available_from"`
+	AvailableTo   *time.Time           `json:"available_to" bson:"available_to"`
+	UserLimit     int                  `json:"user_limit" bson:"user_limit"` // 0 - unlimited
+	DateCreated   time.Time            `json:"date_created" bson:"date_created"`
+	DateUpdated   time.Time            `json:"date_updated" bson:"date_updated"`
+} // @name RewardCatalogItem
+
+// IsAvailable checks if the item is active and within its availability dates
+func (ci *RewardCatalogItem) IsAvailable(now time.Time) bool {
+	if !ci.Active {
+		return false
+	}
+	if ci.AvailableFrom != nil && now.Before(*ci.AvailableFrom) {
+		return false
+	}
+	if ci.AvailableTo != nil && now.After(*ci.AvailableTo) {
+		return false
+	}
+	return true
+}
+
+// CheckUserLimit checks the quantity the user purchased before and the new quantity are within the user limit
+func (ci *RewardCatalogItem) CheckUserLimit(purchased int, quantity int) error {
+	if ci.UserLimit > 0 && purchased+quantity > ci.UserLimit {
+		return NewConflictError("the purchase limit of %d for catalog item %s is exceeded", ci.UserLimit, ci.ID)
+	}
+	return nil
+}
+
+// RewardCatalogPrice wraps the price of a catalog item in a single reward type
+type RewardCatalogPrice struct {
+	RewardType string `json:"reward_type" bson:"reward_type"` // points
+	Amount     int    `json:"amount" bson:"amount"`
+} // @name RewardCatalogPrice
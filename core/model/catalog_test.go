@@ -0,0 +1,58 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRewardCatalogItemIsAvailable(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	before := now.Add(-time.Hour)
+	after := now.Add(time.Hour)
+
+	tests := []struct {
+		name      string
+		item      RewardCatalogItem
+		available bool
+	}{
+		{"active", RewardCatalogItem{Active: true}, true},
+		{"inactive", RewardCatalogItem{Active: false}, false},
+		{"within the dates", RewardCatalogItem{Active: true, AvailableFrom: &before, AvailableTo: &after}, true},
+		{"not yet available", RewardCatalogItem{Active: true, AvailableFrom: &after}, false},
+		{"no longer available", RewardCatalogItem{Active: true, AvailableTo: &before}, false},
+	}
+	for _, test := range tests {
+		if available := test.item.IsAvailable(now); available != test.available {
+			t.Errorf("%s: expected available=%t, got %t", test.name, test.available, available)
+		}
+	}
+}
+
+func TestRewardCatalogItemCheckUserLimit(t *testing.T) {
+	limited := RewardCatalogItem{ID: "item", UserLimit: 3}
+	if err := limited.CheckUserLimit(1, 2); err != nil {
+		t.Errorf("expected a purchase up to the limit to be allowed, got %s", err)
+	}
+	if err := limited.CheckUserLimit(2, 2); !IsErrorCode(err, ErrorCodeConflict) {
+		t.Errorf("expected a conflict over the limit, got %v", err)
+	}
+
+	unlimited := RewardCatalogItem{ID: "item"}
+	if err := unlimited.CheckUserLimit(100, 100); err != nil {
+		t.Errorf("expected no limit, got %s", err)
+	}
+}
@@ -14,13 +14,21 @@
 
 package model
 
+import "time"
+
 // JSONData wrapper struct
 type JSONData map[string]interface{}
 
 // Config the main config structure
 type Config struct {
 	AuthKeys          string
-	InternalAPIKey    string
 	CoreBBHost        string
 	RewardsServiceURL string
+
+	AuthorizationPolicySource string // file or mongo
+	AuthorizationPolicyPath   string
+
+	ReadTimeout  time.Duration // reading a whole request
+	WriteTimeout time.Duration // writing a response - the exports are not limited
+	IdleTimeout  time.Duration // keeping an idle keep-alive connection
 }
@@ -0,0 +1,38 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "time"
+
+// InternalCredential is the API key of a building block calling the internal APIs of an org.
+// Only the hash of the key is stored. The key itself is returned once on create and rotate.
+type InternalCredential struct {
+	ID            string     `json:"id" bson:"_id"`
+	OrgID         string     `json:"org_id" bson:"org_id"`
+	BuildingBlock string     `json:"building_block" bson:"building_block"`
+	KeyHash       string     `json:"-" bson:"key_hash"`
+	KeyPrefix     string     `json:"key_prefix" bson:"key_prefix"` // first characters of the key for identification
+	Key           string     `json:"key,omitempty" bson:"-"`
+	Description   string     `json:"description" bson:"description"`
+	DateCreated   time.Time  `json:"date_created" bson:"date_created"`
+	DateUpdated   time.Time  `json:"date_updated" bson:"date_updated"`
+	DateRotated   *time.Time `json:"date_rotated" bson:"date_rotated"`
+	DateRevoked   *time.Time `json:"date_revoked" bson:"date_revoked"`
+} // @name InternalCredential
+
+// IsActive checks if the credential is not revoked
+func (c *InternalCredential) IsActive() bool {
+	return c.DateRevoked == nil
+}
@@ -0,0 +1,125 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// ErrorCodeNotFound the requested entity does not exist
+	ErrorCodeNotFound = "not_found"
+	// ErrorCodeValidation the request data is not valid
+	ErrorCodeValidation = "validation_failed"
+	// ErrorCodeInsufficientBalance the user has not enough rewards
+	ErrorCodeInsufficientBalance = "insufficient_balance"
+	// ErrorCodeInsufficientInventory there is not enough quantity in the inventories
+	ErrorCodeInsufficientInventory = "insufficient_inventory"
+	// ErrorCodeConflict the request conflicts with the current state
+	ErrorCodeConflict = "conflict"
+	// ErrorCodeForbidden the caller is not allowed to perform the operation
+	ErrorCodeForbidden = "forbidden"
+	// ErrorCodeUnauthorized the caller is not authenticated
+	ErrorCodeUnauthorized = "unauthorized"
+	// ErrorCodeInternal unexpected failure
+	ErrorCodeInternal = "internal_error"
+)
+
+// Error is a domain error with a machine readable code. Its message is safe to be returned to the caller.
+type Error struct {
+	Code    string
+	Message string
+	Fields  []FieldError // the invalid fields of a validation error
+
+	References []Reference // the entities which block the operation
+}
+
+// FieldError describes why a single request field is not valid
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+} // @name FieldError
+
+// Reference describes the entities of a resource which refer to another entity
+type Reference struct {
+	Resource string   `json:"resource"`
+	Count    int64    `json:"count"`
+	IDs      []string `json:"ids"` // a sample of the referring entities
+} // @name Reference
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewNotFoundError creates a not found error
+func NewNotFoundError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeNotFound, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewValidationError creates a validation error
+func NewValidationError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeValidation, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewFieldValidationError creates a validation error listing the invalid fields
+func NewFieldValidationError(fields []FieldError) *Error {
+	return &Error{Code: ErrorCodeValidation, Message: "invalid request fields", Fields: fields}
+}
+
+// NewInsufficientBalanceError creates an insufficient balance error
+func NewInsufficientBalanceError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeInsufficientBalance, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewInsufficientInventoryError creates an insufficient inventory error
+func NewInsufficientInventoryError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeInsufficientInventory, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewConflictError creates a conflict error
+func NewConflictError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeConflict, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewReferencedError creates a conflict error listing the references which block the operation
+func NewReferencedError(references []Reference, format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeConflict, Message: fmt.Sprintf(format, args...), References: references}
+}
+
+// NewForbiddenError creates a forbidden error
+func NewForbiddenError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeForbidden, Message: fmt.Sprintf(format, args...)}
+}
+
+// NewUnauthorizedError creates an unauthorized error
+func NewUnauthorizedError(format string, args ...interface{}) *Error {
+	return &Error{Code: ErrorCodeUnauthorized, Message: fmt.Sprintf(format, args...)}
+}
+
+// AsError gives the domain error in the error chain or nil if err is not a domain error
+func AsError(err error) *Error {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr
+	}
+	return nil
+}
+
+// IsErrorCode checks if err is a domain error with the code
+func IsErrorCode(err error, code string) bool {
+	domainErr := AsError(err)
+	return domainErr != nil && domainErr.Code == code
+}
@@ -0,0 +1,48 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+const (
+	// HealthStatusOK the service or the dependency is healthy
+	HealthStatusOK string = "ok"
+	// HealthStatusUnavailable the service or the dependency cannot serve requests
+	HealthStatusUnavailable string = "unavailable"
+)
+
+// HealthReport gives the status of the service and of the checked dependencies
+type HealthReport struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"` // dependency -> ok or the failure
+} // @name HealthReport
+
+// NewHealthReport creates a healthy report without checks
+func NewHealthReport() *HealthReport {
+	return &HealthReport{Status: HealthStatusOK, Checks: map[string]string{}}
+}
+
+// AddCheck adds the result of a dependency check. A failed check makes the report unavailable
+func (hr *HealthReport) AddCheck(name string, err error) {
+	if err == nil {
+		hr.Checks[name] = HealthStatusOK
+		return
+	}
+	hr.Checks[name] = err.Error()
+	hr.Status = HealthStatusUnavailable
+}
+
+// Healthy tells if all the checks passed
+func (hr *HealthReport) Healthy() bool {
+	return hr.Status == HealthStatusOK
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+const (
+	// ImportKindTypes imports reward types matched by their reward type name
+	ImportKindTypes string = "types"
+	// ImportKindOperations imports reward operations matched by their code and building block
+	ImportKindOperations string = "operations"
+	// ImportKindInventories imports reward inventories matched by their id. Rows without an id create new inventories
+	ImportKindInventories string = "inventories"
+
+	// ImportActionCreate the row creates a new entity
+	ImportActionCreate string = "create"
+	// ImportActionUpdate the row updates the entity with the same natural key
+	ImportActionUpdate string = "update"
+)
+
+// ImportRow is the outcome of a single imported row. Rows are numbered from 1 in the order they are given
+type ImportRow struct {
+	Row    int      `json:"row"`
+	Key    string   `json:"key"`
+	Action string   `json:"action"`
+	ID     string   `json:"id,omitempty"` // empty for the created entities of a dry run
+	Errors []string `json:"errors,omitempty"`
+} // @name ImportRow
+
+// ImportReport is the row level report of an import. Nothing is applied unless all the rows are valid
+type ImportReport struct {
+	Kind    string      `json:"kind"`
+	DryRun  bool        `json:"dry_run"`
+	Valid   bool        `json:"valid"`
+	Applied bool        `json:"applied"`
+	Created int         `json:"created"`
+	Updated int         `json:"updated"`
+	Failed  int         `json:"failed"`
+	Rows    []ImportRow `json:"rows"`
+} // @name ImportReport
+
+// NewImportReport creates an empty report of an import
+func NewImportReport(kind string, dryRun bool) *ImportReport {
+	return &ImportReport{Kind: kind, DryRun: dryRun, Valid: true, Rows: []ImportRow{}}
+}
+
+// AddRow adds the outcome of the next row and counts it
+func (ir *ImportReport) AddRow(key string, action string, id string, errors []string) {
+	ir.Rows = append(ir.Rows, ImportRow{Row: len(ir.Rows) + 1, Key: key, Action: action, ID: id, Errors: errors})
+	switch {
+	case len(errors) > 0:
+		ir.Failed++
+	case action == ImportActionCreate:
+		ir.Created++
+	case action == ImportActionUpdate:
+		ir.Updated++
+	}
+	ir.Valid = ir.Failed == 0
+}
+
+// ImportBatch wraps the validated entities an import creates and updates. They are applied in a single transaction.
+// The inventories are created first and their ids given in the batch are replaced with the new ids in the preferred
+// inventories of the reward types
+type ImportBatch struct {
+	CreateTypes []RewardType
+	UpdateTypes []RewardType
+
+	CreateOperations []RewardOperation
+	UpdateOperations []RewardOperation
+
+	CreateInventories []RewardInventory
+	UpdateInventories []RewardInventory
+}
@@ -0,0 +1,131 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+const (
+	// LeaderboardWindowAllTime ranks the rewards since the beginning
+	LeaderboardWindowAllTime string = "all_time"
+	// LeaderboardWindowDay ranks the rewards of the current UTC day
+	LeaderboardWindowDay string = "day"
+	// LeaderboardWindowWeek ranks the rewards of the current UTC week starting on Monday
+	LeaderboardWindowWeek string = "week"
+	// LeaderboardWindowMonth ranks the rewards of the current UTC month
+	LeaderboardWindowMonth string = "month"
+
+	// LeaderboardAllBuildingBlocks is the building block of the scores which sum the rewards of all building blocks
+	LeaderboardAllBuildingBlocks string = ""
+)
+
+// LeaderboardWindows lists the time windows the scores are kept for
+var LeaderboardWindows = []string{LeaderboardWindowAllTime, LeaderboardWindowDay, LeaderboardWindowWeek, LeaderboardWindowMonth}
+
+var displayHandlePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{3,32}$`)
+
+// LeaderboardProfile is the leaderboard setting of a user. Users are shown on the leaderboards only after they opt in
+type LeaderboardProfile struct {
+	ID            string    `json:"id" bson:"_id"`
+	OrgID         string    `json:"org_id" bson:"org_id"`
+	UserID        string    `json:"user_id" bson:"user_id"`
+	OptedIn       bool      `json:"opted_in" bson:"opted_in"`
+	DisplayHandle string    `json:"display_handle" bson:"display_handle"` // shown instead of the user id
+	DateCreated   time.Time `json:"date_created" bson:"date_created"`
+	DateUpdated   time.Time `json:"date_updated" bson:"date_updated"`
+} // @name LeaderboardProfile
+
+// Validate checks the display handle is set and valid when the user opts in
+func (lp *LeaderboardProfile) Validate() error {
+	if !lp.OptedIn && lp.DisplayHandle == "" {
+		return nil
+	}
+	if !displayHandlePattern.MatchString(lp.DisplayHandle) {
+		return NewValidationError("display handle must have 3 to 32 letters, digits, '_', '.' or '-'")
+	}
+	return nil
+}
+
+// LeaderboardScore is the incrementally maintained sum of the rewards of a user in a time window. The opt in
+// and the display handle are copied from the profile so the leaderboards are read with a single query
+type LeaderboardScore struct {
+	ID            string    `json:"id" bson:"_id"`
+	OrgID         string    `json:"org_id" bson:"org_id"`
+	UserID        string    `json:"user_id" bson:"user_id"`
+	RewardType    string    `json:"reward_type" bson:"reward_type"`
+	BuildingBlock string    `json:"building_block" bson:"building_block"` // empty for the sum of all building blocks
+	Window        string    `json:"window" bson:"window"`
+	WindowStart   time.Time `json:"window_start" bson:"window_start"`
+	Amount        int       `json:"amount" bson:"amount"`
+	OptedIn       bool      `json:"opted_in" bson:"opted_in"`
+	DisplayHandle string    `json:"display_handle" bson:"display_handle"`
+	DateUpdated   time.Time `json:"date_updated" bson:"date_updated"`
+}
+
+// Leaderboard wraps the ranked users of a reward type in a time window
+type Leaderboard struct {
+	RewardType    string             `json:"reward_type"`
+	BuildingBlock string             `json:"building_block,omitempty"`
+	Window        string             `json:"window"`
+	WindowStart   time.Time          `json:"window_start"`
+	Entries       []LeaderboardEntry `json:"entries"`
+} // @name Leaderboard
+
+// LeaderboardEntry is a ranked user. The user id is given only to the admins
+type LeaderboardEntry struct {
+	Rank          int    `json:"rank"`
+	UserID        string `json:"user_id,omitempty"`
+	DisplayHandle string `json:"display_handle"`
+	OptedIn       bool   `json:"opted_in"`
+	Amount        int    `json:"amount"`
+} // @name LeaderboardEntry
+
+// LeaderboardWindowStart gives the start of the time window which contains t
+func LeaderboardWindowStart(window string, t time.Time) (time.Time, error) {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch window {
+	case LeaderboardWindowAllTime:
+		return time.Time{}, nil
+	case LeaderboardWindowDay:
+		return day, nil
+	case LeaderboardWindowWeek:
+		weekday := (int(day.Weekday()) + 6) % 7 // days since Monday
+		return day.AddDate(0, 0, -weekday), nil
+	case LeaderboardWindowMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.Time{}, NewValidationError("unknown leaderboard window %s", window)
+}
+
+// RankLeaderboardScores ranks the scores which are ordered by amount descending. Users with the same amount
+// share the rank and the next rank is skipped (1, 1, 3)
+func RankLeaderboardScores(scores []LeaderboardScore, withUserIDs bool) []LeaderboardEntry {
+	entries := make([]LeaderboardEntry, 0, len(scores))
+	for i, score := range scores {
+		rank := i + 1
+		if i > 0 && score.Amount == scores[i-1].Amount {
+			rank = entries[i-1].Rank
+		}
+		entry := LeaderboardEntry{Rank: rank, DisplayHandle: score.DisplayHandle, OptedIn: score.OptedIn, Amount: score.Amount}
+		if withUserIDs {
+			entry.UserID = score.UserID
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLeaderboardWindowStart(t *testing.T) {
+	// a Sunday evening in Chicago is already Monday in UTC
+	now := time.Date(2024, 3, 10, 20, 30, 0, 0, time.FixedZone("CDT", -5*60*60))
+	tests := []struct {
+		window string
+		start  time.Time
+	}{
+		{LeaderboardWindowAllTime, time.Time{}},
+		{LeaderboardWindowDay, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{LeaderboardWindowWeek, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)},
+		{LeaderboardWindowMonth, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		start, err := LeaderboardWindowStart(test.window, now)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", test.window, err)
+			continue
+		}
+		if !start.Equal(test.start) {
+			t.Errorf("%s: expected %s, got %s", test.window, test.start, start)
+		}
+	}
+
+	sunday := time.Date(2024, 3, 17, 23, 0, 0, 0, time.UTC)
+	start, _ := LeaderboardWindowStart(LeaderboardWindowWeek, sunday)
+	if !start.Equal(time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the week of a Sunday to start on the Monday before, got %s", start)
+	}
+
+	_, err := LeaderboardWindowStart("year", now)
+	if !IsErrorCode(err, ErrorCodeValidation) {
+		t.Errorf("expected a validation error for an unknown window, got %v", err)
+	}
+}
+
+func TestRankLeaderboardScores(t *testing.T) {
+	scores := []LeaderboardScore{
+		{UserID: "a", DisplayHandle: "alpha", OptedIn: true, Amount: 10},
+		{UserID: "b", DisplayHandle: "bravo", OptedIn: true, Amount: 10},
+		{UserID: "c", DisplayHandle: "charlie", OptedIn: true, Amount: 7},
+		{UserID: "d", Amount: 3},
+	}
+
+	entries := RankLeaderboardScores(scores, false)
+	ranks := []int{}
+	for _, entry := range entries {
+		ranks = append(ranks, entry.Rank)
+		if entry.UserID != "" {
+			t.Errorf("expected no user ids for the clients, got %s", entry.UserID)
+		}
+	}
+	if !reflect.DeepEqual(ranks, []int{1, 1, 3, 4}) {
+		t.Errorf("expected competition ranks, got %v", ranks)
+	}
+
+	entries = RankLeaderboardScores(scores, true)
+	if entries[3].UserID != "d" || entries[3].OptedIn {
+		t.Errorf("expected the admins to get the user ids, got %+v", entries[3])
+	}
+}
+
+func TestLeaderboardProfileValidate(t *testing.T) {
+	tests := []struct {
+		profile LeaderboardProfile
+		valid   bool
+	}{
+		{LeaderboardProfile{}, true},
+		{LeaderboardProfile{OptedIn: true}, false},
+		{LeaderboardProfile{OptedIn: true, DisplayHandle: "ab"}, false},
+		{LeaderboardProfile{OptedIn: true, DisplayHandle: "top earner"}, false},
+		{LeaderboardProfile{OptedIn: true, DisplayHandle: "top_earner.42"}, true},
+		{LeaderboardProfile{OptedIn: false, DisplayHandle: "top_earner"}, true},
+	}
+	for _, test := range tests {
+		err := test.profile.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %t, got %v", test.profile, test.valid, err)
+		}
+	}
+}
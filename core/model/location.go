@@ -0,0 +1,49 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import "time"
+
+// PickupLocation wraps a place where physical rewards are handed out
+type PickupLocation struct {
+	ID           string       `json:"id" bson:"_id"`
+	OrgID        string       `json:"org_id" bson:"org_id"`
+	Name         string       `json:"name" bson:"name"`
+	Address      string       `json:"address" bson:"address"`
+	OpeningHours string       `json:"opening_hours" bson:"opening_hours"` // Mon-Fri 10:00-16:00
+	Active       bool         `json:"active" bson:"active"`
+	Slots        []PickupSlot `json:"slots" bson:"slots"`
+	Description  string       `json:"description" bson:"description"`
+	DateCreated  time.Time    `json:"date_created" bson:"date_created"`
+	DateUpdated  time.Time    `json:"date_updated" bson:"date_updated"`
+} // @name PickupLocation
+
+// GetSlot Gets a time slot by id
+func (pl *PickupLocation) GetSlot(id string) *PickupSlot {
+	for i := range pl.Slots {
+		if pl.Slots[i].ID == id {
+			return &pl.Slots[i]
+		}
+	}
+	return nil
+}
+
+// PickupSlot wraps a pickup time slot with limited capacity
+type PickupSlot struct {
+	ID        string    `json:"id" bson:"id"`
+	StartTime time.Time `json:"start_time" bson:"start_time"`
+	EndTime   time.Time `json:"end_time" bson:"end_time"`
+	Capacity  int       `json:"capacity" bson:"capacity"` // 0 - unlimited
+} // @name PickupSlot
@@ -0,0 +1,133 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"time"
+)
+
+const (
+	// OrgConfigVersion is the version of the org configuration document. It is increased on incompatible changes
+	OrgConfigVersion int = 1
+
+	// OrgConfigActionCreate the entity is created in the target org
+	OrgConfigActionCreate string = "create"
+	// OrgConfigActionUpdate the entity of the target org with the same natural key is updated
+	OrgConfigActionUpdate string = "update"
+	// OrgConfigActionUnchanged the entity of the target org with the same natural key is the same
+	OrgConfigActionUnchanged string = "unchanged"
+)
+
+// OrgConfig is the portable configuration of an org. The ids are the ids of the source org and are remapped on import.
+// The authorization policies are shared by all orgs so they are not part of it
+type OrgConfig struct {
+	Version      int                        `json:"version"`
+	OrgID        string                     `json:"org_id"` // the source org
+	DateExported time.Time                  `json:"date_exported"`
+	RewardTypes  []OrgConfigRewardType      `json:"reward_types"`
+	Operations   []OrgConfigRewardOperation `json:"operations"`
+	Inventories  []OrgConfigRewardInventory `json:"inventories"`
+} // @name OrgConfig
+
+// OrgConfigRewardType is a reward type with its allocation policy. It is matched by its name
+type OrgConfigRewardType struct {
+	ID                    string   `json:"id"`
+	RewardType            string   `json:"reward_type"`
+	DisplayName           string   `json:"display_name"`
+	Active                bool     `json:"active"`
+	Currency              bool     `json:"currency"`
+	Description           string   `json:"description"`
+	InventoryBacked       bool     `json:"inventory_backed"`
+	AllocationStrategy    string   `json:"allocation_strategy"`
+	PreferredInventoryIDs []string `json:"preferred_inventory_ids"` // ids of the inventories of the document
+} // @name OrgConfigRewardType
+
+// OrgConfigRewardOperation is a reward operation. It is matched by its code and building block
+type OrgConfigRewardOperation struct {
+	ID            string `json:"id"`
+	RewardType    string `json:"reward_type"`
+	Code          string `json:"code"`
+	BuildingBlock string `json:"building_block"`
+	Amount        int    `json:"amount"`
+	Description   string `json:"description"`
+} // @name OrgConfigRewardOperation
+
+// OrgConfigRewardInventory is the definition of an inventory without the granted and claimed counters. It is
+// matched by its id in the source org and else by its reward type and description as the inventories have no natural key
+type OrgConfigRewardInventory struct {
+	ID          string `json:"id"`
+	RewardType  string `json:"reward_type"`
+	InStock     bool   `json:"in_stock"`
+	AmountTotal int    `json:"amount_total"`
+	Description string `json:"description"`
+} // @name OrgConfigRewardInventory
+
+// OrgConfigChange is the change an import makes to an entity of the target org
+type OrgConfigChange struct {
+	Resource string   `json:"resource"` // types, operations or inventories
+	Key      string   `json:"key"`
+	Action   string   `json:"action"`
+	SourceID string   `json:"source_id,omitempty"`
+	TargetID string   `json:"target_id,omitempty"` // empty for the created entities of a dry run
+	Fields   []string `json:"fields,omitempty"`    // the changed fields of an update
+	Errors   []string `json:"errors,omitempty"`
+} // @name OrgConfigChange
+
+// OrgConfigImportReport is the diff of an org configuration import. Nothing is applied unless all the changes are valid
+type OrgConfigImportReport struct {
+	Version   int               `json:"version"`
+	SourceOrg string            `json:"source_org"`
+	DryRun    bool              `json:"dry_run"`
+	Valid     bool              `json:"valid"`
+	Applied   bool              `json:"applied"`
+	Changes   []OrgConfigChange `json:"changes"`
+	IDMapping map[string]string `json:"id_mapping"` // the source ids to the target ids of the matched entities and, once applied, of the created ones
+} // @name OrgConfigImportReport
+
+// AddChange adds a change to the report
+func (r *OrgConfigImportReport) AddChange(change OrgConfigChange) {
+	if len(change.Errors) > 0 {
+		r.Valid = false
+	}
+	r.Changes = append(r.Changes, change)
+}
+
+// DiffFields gives the names of the fields whose values differ. The values are given as name, old, new triples
+func DiffFields(values ...interface{}) []string {
+	fields := []string{}
+	for i := 0; i+2 < len(values); i += 3 {
+		if !equalValues(values[i+1], values[i+2]) {
+			fields = append(fields, values[i].(string))
+		}
+	}
+	return fields
+}
+
+func equalValues(a interface{}, b interface{}) bool {
+	aList, aIsList := a.([]string)
+	bList, bIsList := b.([]string)
+	if aIsList || bIsList {
+		if len(aList) != len(bList) {
+			return false
+		}
+		for i := range aList {
+			if aList[i] != bList[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
@@ -0,0 +1,41 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"testing"
+)
+
+func TestDiffFields(t *testing.T) {
+	fields := DiffFields("amount", 1, 2, "description", "same", "same", "ids", []string{"a"}, []string{"a"}, "other_ids", []string{"a"}, []string{"b"})
+	if len(fields) != 2 || fields[0] != "amount" || fields[1] != "other_ids" {
+		t.Errorf("expected amount and other_ids to differ, got %v", fields)
+	}
+	if fields := DiffFields("ids", []string(nil), []string{}); len(fields) != 0 {
+		t.Errorf("expected nil and empty lists to be equal, got %v", fields)
+	}
+}
+
+func TestOrgConfigImportReportAddChange(t *testing.T) {
+	report := OrgConfigImportReport{Valid: true}
+	report.AddChange(OrgConfigChange{Resource: "types", Key: "tshirt", Action: OrgConfigActionCreate})
+	if !report.Valid {
+		t.Fatalf("expected a valid report")
+	}
+	report.AddChange(OrgConfigChange{Resource: "types", Key: "points", Action: OrgConfigActionCreate, Errors: []string{"invalid"}})
+	if report.Valid || len(report.Changes) != 2 {
+		t.Errorf("expected an invalid report with 2 changes, got %+v", report)
+	}
+}
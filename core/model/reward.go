@@ -18,16 +18,30 @@ import "time"
 
 // RewardType wraps the reward type
 type RewardType struct {
-	ID          string    `json:"id" bson:"_id"`
-	OrgID       string    `json:"org_id" bson:"org_id"`
-	RewardType  string    `json:"reward_type" bson:"reward_type"`   // tshirt
-	DisplayName string    `json:"display_name" bson:"display_name"` //
-	Active      bool      `json:"active" bson:"active"`
-	Description string    `json:"description" bson:"description"`
+	ID          string `json:"id" bson:"_id"`
+	OrgID       string `json:"org_id" bson:"org_id"`
+	RewardType  string `json:"reward_type" bson:"reward_type"`   // tshirt
+	DisplayName string `json:"display_name" bson:"display_name"` //
+	Active      bool   `json:"active" bson:"active"`
+	Currency    bool   `json:"currency" bson:"currency"` // points, spent on catalog items
+	Description string `json:"description" bson:"description"`
+
+	InventoryBacked bool `json:"inventory_backed" bson:"inventory_backed"` // granted and claimed from inventories, false for pure point currencies
+
+	AllocationStrategy    string   `json:"allocation_strategy" bson:"allocation_strategy"`         // the order the inventories are drawn from - fifo by default
+	PreferredInventoryIDs []string `json:"preferred_inventory_ids" bson:"preferred_inventory_ids"` // drawn from first by the specific_first strategy
+
 	DateCreated time.Time `json:"date_created" bson:"date_created"`
 	DateUpdated time.Time `json:"date_updated" bson:"date_updated"`
+
+	DateDeleted *time.Time `json:"date_deleted,omitempty" bson:"date_deleted,omitempty"` // set when deleted while the history refers to it
 } // @name RewardType
 
+// IsDeleted checks if the reward type is kept only for the history which refers to it
+func (rt *RewardType) IsDeleted() bool {
+	return rt.DateDeleted != nil
+}
+
 // RewardOperation wraps reward operation (defines amount of reward, BB and the type)
 type RewardOperation struct {
 	ID            string    `json:"id" bson:"_id"`
@@ -39,8 +53,18 @@ type RewardOperation struct {
 	Description   string    `json:"description" bson:"description"`
 	DateCreated   time.Time `json:"date_created" bson:"date_created"`
 	DateUpdated   time.Time `json:"date_updated" bson:"date_updated"`
+
+	Archived   bool       `json:"archived" bson:"archived"` // archived operations do not grant rewards
+	ArchivedAt *time.Time `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
+
+	DateDeleted *time.Time `json:"date_deleted,omitempty" bson:"date_deleted,omitempty"` // set when deleted while the history refers to it
 } // @name RewardOperation
 
+// IsDeleted checks if the reward operation is kept only for the history which refers to it
+func (ro *RewardOperation) IsDeleted() bool {
+	return ro.DateDeleted != nil
+}
+
 // RewardInventory defines physical amount (availability) of a single award type
 type RewardInventory struct {
 	ID            string    `json:"id" bson:"_id"`
@@ -52,9 +76,13 @@ type RewardInventory struct {
 	AmountClaimed int       `json:"amount_claimed" bson:"amount_claimed"`
 	GrantDepleted bool      `json:"grant_depleted" bson:"grant_depleted"`
 	ClaimDepleted bool      `json:"claim_depleted" bson:"claim_depleted"`
+	LocationIDs   []string  `json:"location_ids" bson:"location_ids"` // pickup locations which stock the inventory
 	Description   string    `json:"description" bson:"description"`
 	DateCreated   time.Time `json:"date_created" bson:"date_created"`
 	DateUpdated   time.Time `json:"date_updated" bson:"date_updated"`
+
+	Archived   bool       `json:"archived" bson:"archived"` // archived inventories are not drawn from
+	ArchivedAt *time.Time `json:"archived_at,omitempty" bson:"archived_at,omitempty"`
 } // @name RewardInventory
 
 // GetGrantableAmount Gets grantable amount
@@ -67,6 +95,16 @@ func (ri *RewardInventory) GetClaimableAmount() int {
 	return ri.AmountTotal - ri.AmountClaimed
 }
 
+// IsStockedAt checks if the inventory is stocked at the pickup location
+func (ri *RewardInventory) IsStockedAt(locationID string) bool {
+	for _, id := range ri.LocationIDs {
+		if id == locationID {
+			return true
+		}
+	}
+	return false
+}
+
 // Reward wraps the history entry
 type Reward struct {
 	ID            string    `json:"id" bson:"_id"`
@@ -79,27 +117,83 @@ type Reward struct {
 	Description   string    `json:"description" bson:"description"`
 	DateCreated   time.Time `json:"date_created" bson:"date_created"`
 	DateUpdated   time.Time `json:"date_updated" bson:"date_updated"`
+
+	Allocations []InventoryAllocation `json:"allocations,omitempty" bson:"allocations,omitempty"` // the inventories the reward is drawn from
 } // @name Reward
 
-// RewardQuantityState wraps current reward inventory state
+// RewardQuantityState wraps current reward inventory state. The quantities are not set for reward types
+// which are not inventory backed as they are not limited
 type RewardQuantityState struct {
 	RewardType        string `json:"reward_type" bson:"reward_type"`
-	GrantableQuantity int    `json:"grantable_quantity" bson:"grantable_quantity"`
-	ClaimableQuantity int    `json:"claimable_quantity" bson:"claimable_quantity"`
+	InventoryBacked   bool   `json:"inventory_backed" bson:"inventory_backed"`
+	GrantableQuantity *int   `json:"grantable_quantity,omitempty" bson:"grantable_quantity,omitempty"`
+	ClaimableQuantity *int   `json:"claimable_quantity,omitempty" bson:"claimable_quantity,omitempty"`
+}
+
+// GetGrantableQuantity gives the grantable quantity, zero if it is not set
+func (rq *RewardQuantityState) GetGrantableQuantity() int {
+	if rq == nil || rq.GrantableQuantity == nil {
+		return 0
+	}
+	return *rq.GrantableQuantity
+}
+
+// GetClaimableQuantity gives the claimable quantity, zero if it is not set
+func (rq *RewardQuantityState) GetClaimableQuantity() int {
+	if rq == nil || rq.ClaimableQuantity == nil {
+		return 0
+	}
+	return *rq.ClaimableQuantity
 }
 
 // RewardClaim wraps a claim that is made by a user
 type RewardClaim struct {
-	ID          string            `json:"id" bson:"_id"`
-	OrgID       string            `json:"org_id" bson:"org_id"`
-	UserID      string            `json:"user_id" bson:"user_id"`
-	Items       []RewardClaimItem `json:"items" bson:"items"`
-	Status      string            `json:"status" bson:"status"`
-	Description string            `json:"description" bson:"description"`
-	DateCreated time.Time         `json:"date_created" bson:"date_created"`
-	DateUpdated time.Time         `json:"date_updated" bson:"date_updated"`
+	ID          string               `json:"id" bson:"_id"`
+	OrgID       string               `json:"org_id" bson:"org_id"`
+	UserID      string               `json:"user_id" bson:"user_id"`
+	Items       []RewardClaimItem    `json:"items" bson:"items"`
+	Purchase    *RewardClaimPurchase `json:"purchase,omitempty" bson:"purchase,omitempty"`
+	Pickup      *RewardClaimPickup   `json:"pickup,omitempty" bson:"pickup,omitempty"`
+	Status      string               `json:"status" bson:"status"`
+	PickupCode  string               `json:"pickup_code,omitempty" bson:"pickup_code,omitempty"` // set when the claim is approved
+	Description string               `json:"description" bson:"description"`
+	DateCreated time.Time            `json:"date_created" bson:"date_created"`
+	DateUpdated time.Time            `json:"date_updated" bson:"date_updated"`
+
+	DateFulfilled *time.Time `json:"date_fulfilled,omitempty" bson:"date_fulfilled,omitempty"`
+
+	Allocations []InventoryAllocation `json:"allocations,omitempty" bson:"allocations,omitempty"` // the inventories the claim is drawn from
 } // @name RewardClaim
 
+const (
+	// RewardClaimStatusPending the claim is waiting for approval
+	RewardClaimStatusPending string = "pending"
+	// RewardClaimStatusApproved the claim is approved and can be picked up
+	RewardClaimStatusApproved string = "approved"
+	// RewardClaimStatusFulfilled the claim has been handed out
+	RewardClaimStatusFulfilled string = "fulfilled"
+)
+
+// GetAmounts gives the claimed amount of each reward type. An item may repeat a reward type so its amounts are summed
+func (c RewardClaim) GetAmounts() map[string]int {
+	amounts := map[string]int{}
+	for _, item := range c.Items {
+		amounts[item.RewardType] += item.Amount
+	}
+	return amounts
+}
+
+// RewardClaimPickupAttempt wraps a pickup code redemption attempt
+type RewardClaimPickupAttempt struct {
+	ID          string    `json:"id" bson:"_id"`
+	OrgID       string    `json:"org_id" bson:"org_id"`
+	ClaimID     string    `json:"claim_id" bson:"claim_id"`
+	Actor       string    `json:"actor" bson:"actor"`
+	Success     bool      `json:"success" bson:"success"`
+	Reason      string    `json:"reason" bson:"reason"`
+	DateCreated time.Time `json:"date_created" bson:"date_created"`
+} // @name RewardClaimPickupAttempt
+
 // RewardClaimItem wraps a claim  entry that consists reward type and amount
 type RewardClaimItem struct {
 	RewardType  string `json:"reward_type" bson:"reward_type"`
@@ -107,3 +201,16 @@ type RewardClaimItem struct {
 
 	Amount int `json:"amount" bson:"amount"`
 } // @name RewardClaimItem
+
+// RewardClaimPurchase wraps a catalog item bought with a claim. The price is debited through the claim items
+type RewardClaimPurchase struct {
+	CatalogItemID string `json:"catalog_item_id" bson:"catalog_item_id"`
+	RewardType    string `json:"reward_type" bson:"reward_type"`
+	Quantity      int    `json:"quantity" bson:"quantity"`
+} // @name RewardClaimPurchase
+
+// RewardClaimPickup wraps the pickup location and the optional time slot chosen by the user
+type RewardClaimPickup struct {
+	LocationID string `json:"location_id" bson:"location_id"`
+	SlotID     string `json:"slot_id,omitempty" bson:"slot_id,omitempty"`
+} // @name RewardClaimPickup
@@ -0,0 +1,45 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRewardClaimGetAmounts(t *testing.T) {
+	claim := RewardClaim{Items: []RewardClaimItem{
+		{RewardType: "coins", Amount: 5},
+		{RewardType: "tshirt", Amount: 1},
+		{RewardType: "coins", Amount: 5},
+	}}
+	expected := map[string]int{"coins": 10, "tshirt": 1}
+	if amounts := claim.GetAmounts(); !reflect.DeepEqual(amounts, expected) {
+		t.Errorf("expected the repeated reward types to be summed %v, got %v", expected, amounts)
+	}
+}
+
+func TestRewardInventoryIsStockedAt(t *testing.T) {
+	inventory := RewardInventory{LocationIDs: []string{"union", "library"}}
+	if !inventory.IsStockedAt("library") {
+		t.Error("expected the inventory to be stocked at the library")
+	}
+	if inventory.IsStockedAt("stadium") {
+		t.Error("expected the inventory not to be stocked at the stadium")
+	}
+	if (&RewardInventory{}).IsStockedAt("union") {
+		t.Error("expected an inventory without locations not to be stocked anywhere")
+	}
+}
@@ -15,167 +15,715 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"rewards/core/model"
+	"rewards/utils"
+	"rewards/utils/logging"
+	"rewards/utils/metrics"
+	"strings"
+	"time"
+)
+
+const (
+	pickupCodeLength = 8
+
+	internalKeyLength       = 40
+	internalKeyPrefixLength = 6
 )
 
 func (app *Application) getVersion() string {
 	return app.version
 }
 
-func (app *Application) getRewardTypes(orgID string) ([]model.RewardType, error) {
-	types := app.cacheAdapter.GetRewardTypes()
+func (app *Application) getRewardTypes(ctx context.Context, orgID string) ([]model.RewardType, error) {
+	types := app.cacheAdapter.GetRewardTypes(orgID)
 	if types != nil {
 		return types, nil
 	}
 
-	storedTypes, err := app.storage.GetRewardTypes(orgID)
+	storedTypes, err := app.storage.GetRewardTypes(ctx, orgID)
 	if err == nil && storedTypes != nil {
-		app.cacheAdapter.SetRewardTypes(storedTypes)
+		app.cacheAdapter.SetRewardTypes(orgID, storedTypes)
 	}
 	return storedTypes, err
 }
 
-func (app *Application) getRewardType(orgID string, id string) (*model.RewardType, error) {
-	return app.storage.GetRewardType(orgID, id)
+func (app *Application) getRewardType(ctx context.Context, orgID string, id string) (*model.RewardType, error) {
+	return app.storage.GetRewardType(ctx, orgID, id)
 }
 
-func (app *Application) createRewardType(orgID string, item model.RewardType) (*model.RewardType, error) {
-	return app.storage.CreateRewardType(orgID, item)
+func (app *Application) createRewardType(ctx context.Context, orgID string, item model.RewardType) (*model.RewardType, error) {
+	err := app.validateRewardType(item)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := app.storage.GetRewardTypeByType(ctx, orgID, item.RewardType)
+	if err != nil && !model.IsErrorCode(err, model.ErrorCodeNotFound) {
+		return nil, fmt.Errorf("Error on app.createRewardType() - %w", err)
+	}
+	if existing != nil && existing.IsDeleted() {
+		return nil, model.NewConflictError("reward type %s was deleted and is kept for the history which refers to it", item.RewardType)
+	}
+	if existing != nil {
+		return nil, model.NewConflictError("reward type %s already exists", item.RewardType)
+	}
+	return app.storage.CreateRewardType(ctx, orgID, item)
 }
 
-func (app *Application) updateRewardType(orgID string, id string, item model.RewardType) (*model.RewardType, error) {
-	return app.storage.UpdateRewardType(orgID, id, item)
+func (app *Application) updateRewardType(ctx context.Context, orgID string, id string, item model.RewardType) (*model.RewardType, error) {
+	err := app.validateRewardType(item)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = app.storage.UpdateRewardType(ctx, orgID, id, item)
+	if err != nil {
+		return nil, err
+	}
+	return app.storage.GetRewardType(ctx, orgID, id)
+}
+
+func (app *Application) validateRewardType(item model.RewardType) error {
+	if item.Currency && item.InventoryBacked {
+		return model.NewValidationError("currency %s cannot be inventory backed", item.RewardType)
+	}
+	_, err := model.NewAllocationStrategy(item)
+	return err
+}
+
+// deleteRewardType refuses to delete a reward type which is still used by operations, inventories or
+// catalog items. A reward type which only the history and the claims refer to is soft deleted.
+func (app *Application) deleteRewardType(ctx context.Context, orgID string, id string) error {
+	rewardType, err := app.storage.GetRewardType(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	references, err := app.storage.GetRewardTypeReferences(ctx, orgID, rewardType.RewardType)
+	if err != nil {
+		return fmt.Errorf("Error on app.deleteRewardType() - %w", err)
+	}
+
+	blocking := []model.Reference{}
+	for _, reference := range references {
+		if reference.Resource != "history" && reference.Resource != "claims" {
+			blocking = append(blocking, reference)
+		}
+	}
+	if len(blocking) > 0 {
+		return model.NewReferencedError(blocking, "reward type %s is in use", rewardType.RewardType)
+	}
+
+	if len(references) > 0 {
+		return app.storage.SoftDeleteRewardType(ctx, orgID, id)
+	}
+	return app.storage.DeleteRewardType(ctx, orgID, id)
 }
 
-func (app *Application) deleteRewardTypes(orgID string, id string) error {
-	return app.storage.DeleteRewardType(orgID, id)
+func (app *Application) getRewardOperations(ctx context.Context, orgID string, archived *bool) ([]model.RewardOperation, error) {
+	return app.storage.GetRewardOperations(ctx, orgID, archived)
 }
 
-func (app *Application) getRewardOperations(orgID string) ([]model.RewardOperation, error) {
-	return app.storage.GetRewardOperations(orgID)
+func (app *Application) getRewardOperationByID(ctx context.Context, orgID string, id string) (*model.RewardOperation, error) {
+	return app.storage.GetRewardOperationByID(ctx, orgID, id)
 }
 
-func (app *Application) getRewardOperationByID(orgID string, id string) (*model.RewardOperation, error) {
-	return app.storage.GetRewardOperationByID(orgID, id)
+func (app *Application) getRewardOperationByCode(ctx context.Context, orgID string, code string, buildingBlock string) (*model.RewardOperation, error) {
+	return app.storage.GetRewardOperationByCode(ctx, orgID, code, buildingBlock)
 }
 
-func (app *Application) getRewardOperationByCode(orgID string, code string) (*model.RewardOperation, error) {
-	return app.storage.GetRewardOperationByCode(orgID, code)
+func (app *Application) createRewardOperation(ctx context.Context, orgID string, item model.RewardOperation) (*model.RewardOperation, error) {
+	existing, err := app.storage.GetRewardOperationByCode(ctx, orgID, item.Code, item.BuildingBlock)
+	if err != nil && !model.IsErrorCode(err, model.ErrorCodeNotFound) {
+		return nil, fmt.Errorf("Error on app.createRewardOperation() - %w", err)
+	}
+	if existing != nil {
+		return nil, model.NewConflictError("reward operation %s already exists for %s", item.Code, item.BuildingBlock)
+	}
+	return app.storage.CreateRewardOperation(ctx, orgID, item)
 }
 
-func (app *Application) createRewardOperation(orgID string, item model.RewardOperation) (*model.RewardOperation, error) {
-	return app.storage.CreateRewardOperation(orgID, item)
+func (app *Application) updateRewardOperation(ctx context.Context, orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error) {
+	_, err := app.storage.UpdateRewardOperation(ctx, orgID, id, item)
+	if err != nil {
+		return nil, err
+	}
+	return app.storage.GetRewardOperationByID(ctx, orgID, id)
 }
 
-func (app *Application) updateRewardOperation(orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error) {
-	return app.storage.UpdateRewardOperation(orgID, id, item)
+// deleteRewardOperation soft deletes a reward operation which the history refers to
+func (app *Application) deleteRewardOperation(ctx context.Context, orgID string, id string) error {
+	operation, err := app.storage.GetRewardOperationByID(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+
+	references, err := app.storage.GetRewardOperationReferences(ctx, orgID, operation.Code, operation.BuildingBlock)
+	if err != nil {
+		return fmt.Errorf("Error on app.deleteRewardOperation() - %w", err)
+	}
+
+	if len(references) > 0 {
+		return app.storage.SoftDeleteRewardOperation(ctx, orgID, id)
+	}
+	return app.storage.DeleteRewardOperation(ctx, orgID, id)
 }
 
-func (app *Application) deleteRewardOperation(orgID string, id string) error {
-	return app.storage.DeleteRewardOperation(orgID, id)
+func (app *Application) archiveRewardOperation(ctx context.Context, orgID string, id string, archived bool) (*model.RewardOperation, error) {
+	err := app.storage.SetRewardOperationArchived(ctx, orgID, id, archived)
+	if err != nil {
+		return nil, err
+	}
+	return app.storage.GetRewardOperationByID(ctx, orgID, id)
 }
 
-func (app *Application) createReward(orgID string, item model.Reward) (*model.Reward, error) {
+func (app *Application) createReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
 	if item.RewardType != "" && item.UserID != "" {
-		rewardType, err := app.storage.GetRewardTypeByType(orgID, item.RewardType)
+		rewardType, err := app.storage.GetRewardTypeByType(ctx, orgID, item.RewardType)
 		if err != nil {
-			log.Printf("Error Application.createReward(): %s", err)
-			return nil, fmt.Errorf("Error Application.createReward(): %s", err)
+			logging.FromContext(ctx).Errorf("Error Application.createReward(): %s", err)
+			return nil, fmt.Errorf("Error Application.createReward(): %w", err)
 		}
 
-		if rewardType == nil {
-			log.Printf("Error Application.createReward() unable to find reward type '%s'", item.RewardType)
-			return nil, fmt.Errorf("Error Application.createReward() unable to find reward type '%s'", item.RewardType)
+		if rewardType == nil || rewardType.IsDeleted() {
+			logging.FromContext(ctx).Errorf("Error Application.createReward() unable to find reward type '%s'", item.RewardType)
+			return nil, model.NewNotFoundError("unable to find reward type '%s'", item.RewardType)
 		}
 
 		if item.Amount <= 0 {
-			log.Printf("Error Application.createReward() amount is zero or a negative value")
-			return nil, fmt.Errorf("Error Application.createReward() amount is zero or a negative value")
+			logging.FromContext(ctx).Errorf("Error Application.createReward() amount is zero or a negative value")
+			return nil, model.NewValidationError("amount is zero or a negative value")
 		}
 
-		//TBD: Check for available quantity!!!
-		quantity, err := app.storage.GetRewardQuantityState(orgID, item.RewardType, nil)
+		if item.Code != "" {
+			operation, err := app.storage.GetRewardOperationByCode(ctx, orgID, item.Code, item.BuildingBlock)
+			if err != nil && !model.IsErrorCode(err, model.ErrorCodeNotFound) {
+				logging.FromContext(ctx).Errorf("Error Application.createReward(): %s", err)
+				return nil, fmt.Errorf("Error Application.createReward(): %w", err)
+			}
+			if operation != nil && operation.Archived {
+				logging.FromContext(ctx).Errorf("Error Application.createReward() reward operation %s is archived", item.Code)
+				return nil, model.NewConflictError("reward operation %s is archived", item.Code)
+			}
+		}
+
+		if !rewardType.InventoryBacked {
+			// pure point currencies have no stock to draw from
+			return app.storeReward(ctx, orgID, item)
+		}
+
+		quantity, err := app.storage.GetRewardQuantityState(ctx, orgID, item.RewardType, nil)
 		if err != nil {
-			log.Printf("Error Application.createReward(): %s", err)
-			return nil, fmt.Errorf("Error Application.createReward(): %s", err)
+			logging.FromContext(ctx).Errorf("Error Application.createReward(): %s", err)
+			return nil, fmt.Errorf("Error Application.createReward(): %w", err)
 		}
 
-		if quantity.GrantableQuantity >= item.Amount {
-			return app.storage.CreateUserReward(orgID, item)
+		if quantity.GetGrantableQuantity() >= item.Amount {
+			return app.storeReward(ctx, orgID, item)
 		}
-		return nil, fmt.Errorf("error Application.createReward(): not enough available quantity")
+		return nil, model.NewInsufficientInventoryError("not enough available quantity for %s", item.RewardType)
+	}
+	return nil, model.NewValidationError("missing reward type or user id")
+}
+
+// debitReward takes the amount of the item back from the balance of the user, e.g. to correct an over-grant. It is
+// kept in the history as a negative reward
+func (app *Application) debitReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
+	if item.RewardType == "" || item.UserID == "" {
+		return nil, model.NewValidationError("missing reward type or user id")
+	}
+	if item.Amount <= 0 {
+		return nil, model.NewValidationError("amount is zero or a negative value")
 	}
-	return nil, fmt.Errorf("Error Application.createReward(): missing data. data dump: %+v", item)
+
+	rewardType, err := app.storage.GetRewardTypeByType(ctx, orgID, item.RewardType)
+	if err != nil {
+		return nil, fmt.Errorf("Error Application.debitReward(): %w", err)
+	}
+	if rewardType == nil || rewardType.IsDeleted() {
+		return nil, model.NewNotFoundError("unable to find reward type '%s'", item.RewardType)
+	}
+
+	item.Amount = -item.Amount
+	return app.storage.CreateUserRewardDebit(ctx, orgID, item)
+}
+
+// storeReward stores the granted reward and counts the grant by operation code
+func (app *Application) storeReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
+	reward, err := app.storage.CreateUserReward(ctx, orgID, item)
+	if err != nil {
+		return nil, err
+	}
+	metrics.Grants.WithLabelValues(reward.Code).Inc()
+	return reward, nil
 }
 
 // Reward pools
 
-func (app *Application) getRewardInventories(orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, limit *int64, offset *int64) ([]model.RewardInventory, error) {
-	return app.storage.GetRewardInventories(orgID, ids, rewardType, inStock, grantDepleted, claimDepleted, limit, offset)
+func (app *Application) getRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64) ([]model.RewardInventory, error) {
+	return app.storage.GetRewardInventories(ctx, orgID, ids, rewardType, inStock, grantDepleted, claimDepleted, archived, limit, offset)
 }
 
-func (app *Application) getRewardInventory(orgID string, id string) (*model.RewardInventory, error) {
-	return app.storage.GetRewardInventory(orgID, id)
+func (app *Application) getRewardInventory(ctx context.Context, orgID string, id string) (*model.RewardInventory, error) {
+	return app.storage.GetRewardInventory(ctx, orgID, id)
 }
 
-func (app *Application) createRewardInventory(orgID string, item model.RewardInventory) (*model.RewardInventory, error) {
-	return app.storage.CreateRewardInventory(orgID, item)
+func (app *Application) createRewardInventory(ctx context.Context, orgID string, item model.RewardInventory) (*model.RewardInventory, error) {
+	return app.storage.CreateRewardInventory(ctx, orgID, item)
 }
 
-func (app *Application) updateRewardInventory(orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error) {
-	return app.storage.UpdateRewardInventory(orgID, id, item)
+func (app *Application) updateRewardInventory(ctx context.Context, orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error) {
+	_, err := app.storage.UpdateRewardInventory(ctx, orgID, id, item)
+	if err != nil {
+		return nil, err
+	}
+	return app.storage.GetRewardInventory(ctx, orgID, id)
 }
 
-func (app *Application) getRewardClaims(orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error) {
-	return app.storage.GetRewardClaims(orgID, ids, userID, rewardType, status, limit, offset)
+func (app *Application) deleteRewardInventory(ctx context.Context, orgID string, id string) error {
+	return app.storage.DeleteRewardInventory(ctx, orgID, id)
 }
 
-func (app *Application) getRewardClaim(orgID string, id string) (*model.RewardClaim, error) {
-	return app.storage.GetRewardClaim(orgID, id)
+func (app *Application) archiveRewardInventory(ctx context.Context, orgID string, id string, archived bool) (*model.RewardInventory, error) {
+	err := app.storage.SetRewardInventoryArchived(ctx, orgID, id, archived)
+	if err != nil {
+		return nil, err
+	}
+	return app.storage.GetRewardInventory(ctx, orgID, id)
 }
 
-func (app *Application) createRewardClaim(orgID string, item model.RewardClaim) (*model.RewardClaim, error) {
-	if len(item.Items) > 0 {
-		balanceMapping, err := app.getUserBalanceMapping(orgID, item.UserID)
+func (app *Application) getRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error) {
+	return app.storage.GetRewardClaims(ctx, orgID, ids, userID, rewardType, status, limit, offset)
+}
+
+func (app *Application) getRewardClaim(ctx context.Context, orgID string, id string) (*model.RewardClaim, error) {
+	return app.storage.GetRewardClaim(ctx, orgID, id)
+}
+
+func (app *Application) createRewardClaim(ctx context.Context, orgID string, item model.RewardClaim) (*model.RewardClaim, error) {
+	if item.Purchase != nil {
+		err := app.applyCatalogPurchase(ctx, orgID, &item)
 		if err != nil {
-			return nil, fmt.Errorf("Error on app.createRewardClaim() - %s", err)
+			return nil, fmt.Errorf("Error on app.createRewardClaim() - %w", err)
 		}
+	}
 
+	if len(item.Items) > 0 {
+		// the balance is checked by the storage within the transaction which creates the claim. The price of a purchase
+		// is debited from the wallet and the purchased item was checked with the purchase
+		amounts := item.GetAmounts()
+		checked := map[string]bool{}
 		for _, claimEntry := range item.Items {
-			balance := balanceMapping[claimEntry.RewardType]
-			if balance < claimEntry.Amount {
-				return nil, fmt.Errorf("Error on app.createRewardClaim() - User(%s) not enough quantity for %s. Expected: %d, but have: %d", item.UserID, claimEntry.RewardType, claimEntry.Amount, balance)
+			if item.Purchase != nil || checked[claimEntry.RewardType] {
+				continue
+			}
+			checked[claimEntry.RewardType] = true
+			amount := amounts[claimEntry.RewardType]
+
+			rewardType, err := app.storage.GetRewardTypeByType(ctx, orgID, claimEntry.RewardType)
+			if err != nil {
+				return nil, fmt.Errorf("Error on app.createRewardClaim() - %w", err)
+			}
+			if !rewardType.InventoryBacked {
+				continue
 			}
 
 			inStock := true
-			quantity, err := app.storage.GetRewardQuantityState(orgID, claimEntry.RewardType, &inStock)
+			quantity, err := app.storage.GetRewardQuantityState(ctx, orgID, claimEntry.RewardType, &inStock)
 			if err != nil {
-				return nil, fmt.Errorf("Error on app.createRewardClaim() - %s", err)
+				return nil, fmt.Errorf("Error on app.createRewardClaim() - %w", err)
 			}
-			if quantity == nil || claimEntry.Amount > quantity.ClaimableQuantity {
-				return nil, fmt.Errorf("Error on app.createRewardClaim() - not enough quantity for %s. Expected: %d", claimEntry.RewardType, claimEntry.Amount)
+			if amount > quantity.GetClaimableQuantity() {
+				return nil, model.NewInsufficientInventoryError("not enough quantity for %s. Expected: %d", claimEntry.RewardType, amount)
+			}
+		}
+		if item.Pickup != nil {
+			err := app.validateClaimPickup(ctx, orgID, item)
+			if err != nil {
+				return nil, fmt.Errorf("Error on app.createRewardClaim() - %w", err)
 			}
 		}
-		return app.storage.CreateRewardClaim(orgID, item)
+
+		if item.Status == "" {
+			item.Status = model.RewardClaimStatusPending
+		}
+		claim, err := app.storage.CreateRewardClaim(ctx, orgID, item)
+		if err != nil {
+			return nil, err
+		}
+		metrics.Claims.WithLabelValues(claim.Status).Inc()
+		return claim, nil
 	}
-	return nil, fmt.Errorf("Error on app.createRewardClaim() - missing or zero quantity for reward items")
+	return nil, model.NewValidationError("missing or zero quantity for reward items")
 }
 
-func (app *Application) updateRewardClaim(orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error) {
-	return app.storage.UpdateRewardClaim(orgID, id, item)
+// applyCatalogPurchase validates the catalog purchase and sets the price as claim items
+func (app *Application) applyCatalogPurchase(ctx context.Context, orgID string, item *model.RewardClaim) error {
+	if item.Purchase.Quantity <= 0 {
+		return model.NewValidationError("purchase quantity is zero or a negative value")
+	}
+
+	catalogItem, err := app.storage.GetRewardCatalogItem(ctx, orgID, item.Purchase.CatalogItemID)
+	if err != nil {
+		return err
+	}
+	if !catalogItem.IsAvailable(time.Now().UTC()) {
+		return model.NewConflictError("catalog item %s is not available", catalogItem.ID)
+	}
+	if len(catalogItem.Price) == 0 {
+		return model.NewConflictError("catalog item %s has no price", catalogItem.ID)
+	}
+
+	// the user limit is checked by the storage within the transaction which creates the claim
+
+	rewardType, err := app.storage.GetRewardTypeByType(ctx, orgID, catalogItem.RewardType)
+	if err != nil {
+		return err
+	}
+	if rewardType.InventoryBacked {
+		inStock := true
+		quantity, err := app.storage.GetRewardQuantityState(ctx, orgID, catalogItem.RewardType, &inStock)
+		if err != nil {
+			return err
+		}
+		if item.Purchase.Quantity > quantity.GetClaimableQuantity() {
+			return model.NewInsufficientInventoryError("not enough quantity for %s. Expected: %d", catalogItem.RewardType, item.Purchase.Quantity)
+		}
+	}
+
+	item.Purchase.RewardType = catalogItem.RewardType
+	item.Items = []model.RewardClaimItem{}
+	for _, price := range catalogItem.Price {
+		item.Items = append(item.Items, model.RewardClaimItem{
+			RewardType: price.RewardType,
+			Amount:     price.Amount * item.Purchase.Quantity,
+		})
+	}
+	return nil
+}
+
+// validateClaimPickup checks the chosen location stocks the claimed rewards and the time slot has capacity left
+func (app *Application) validateClaimPickup(ctx context.Context, orgID string, item model.RewardClaim) error {
+	location, err := app.storage.GetPickupLocation(ctx, orgID, item.Pickup.LocationID)
+	if err != nil {
+		return err
+	}
+	if !location.Active {
+		return model.NewValidationError("pickup location %s is not active", location.ID)
+	}
+
+	if item.Pickup.SlotID != "" {
+		slot := location.GetSlot(item.Pickup.SlotID)
+		if slot == nil {
+			return model.NewValidationError("pickup location %s has no slot %s", location.ID, item.Pickup.SlotID)
+		}
+		if slot.EndTime.Before(time.Now().UTC()) {
+			return model.NewValidationError("pickup slot %s has already passed", slot.ID)
+		}
+		// the capacity of the slot is booked by the storage within the transaction which creates the claim
+	}
+
+	// the rewards which are drawn from the inventories
+	amounts := map[string]int{}
+	if item.Purchase != nil {
+		amounts[item.Purchase.RewardType] = item.Purchase.Quantity
+	} else {
+		for _, claimEntry := range item.Items {
+			amounts[claimEntry.RewardType] += claimEntry.Amount
+		}
+	}
+
+	inStock := true
+	claimDepleted := false
+	archived := false
+	for rewardType, amount := range amounts {
+		rewardTypeItem, err := app.storage.GetRewardTypeByType(ctx, orgID, rewardType)
+		if err != nil {
+			return err
+		}
+		if !rewardTypeItem.InventoryBacked {
+			continue
+		}
+
+		inventories, err := app.storage.GetRewardInventories(ctx, orgID, nil, &rewardType, &inStock, nil, &claimDepleted, &archived, nil, nil)
+		if err != nil {
+			return err
+		}
+		available := 0
+		for _, inventory := range inventories {
+			if inventory.IsStockedAt(location.ID) {
+				available += inventory.GetClaimableAmount()
+			}
+		}
+		if available < amount {
+			return model.NewInsufficientInventoryError("not enough quantity for %s at pickup location %s. Expected: %d", rewardType, location.ID, amount)
+		}
+	}
+	return nil
+}
+
+func (app *Application) updateRewardClaim(ctx context.Context, orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error) {
+	if item.Status == model.RewardClaimStatusFulfilled {
+		return nil, model.NewValidationError("claims are fulfilled by redeeming the pickup code")
+	}
+
+	existing, err := app.storage.GetRewardClaim(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if existing.Status == model.RewardClaimStatusFulfilled {
+		return nil, model.NewConflictError("claim %s is fulfilled", id)
+	}
+	updatedItem, err := app.storage.UpdateRewardClaim(ctx, orgID, id, item)
+	if err != nil {
+		return nil, err
+	}
+	if updatedItem.Status != existing.Status {
+		metrics.Claims.WithLabelValues(updatedItem.Status).Inc()
+	}
+
+	if updatedItem.Status == model.RewardClaimStatusApproved {
+		return app.assignPickupCode(ctx, orgID, id)
+	}
+	return app.storage.GetRewardClaim(ctx, orgID, id)
+}
+
+// assignPickupCode gives the approved claim a pickup code unless it already has one
+func (app *Application) assignPickupCode(ctx context.Context, orgID string, id string) (*model.RewardClaim, error) {
+	claim, err := app.storage.GetRewardClaim(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if claim.PickupCode != "" {
+		return claim, nil
+	}
+
+	// the codes are unique, so retry in the unlikely case of a collision
+	for i := 0; i < 3; i++ {
+		var code string
+		code, err = utils.GenerateCode(pickupCodeLength)
+		if err != nil {
+			break
+		}
+		err = app.storage.SetRewardClaimPickupCode(ctx, orgID, id, code)
+		if err == nil {
+			return app.storage.GetRewardClaim(ctx, orgID, id)
+		}
+	}
+	return nil, fmt.Errorf("Error on app.assignPickupCode(%s) - %s", id, err)
+}
+
+func (app *Application) redeemRewardClaimPickupCode(ctx context.Context, orgID string, actor string, code string) (*model.RewardClaim, error) {
+	attempt := model.RewardClaimPickupAttempt{Actor: actor}
+	defer func() {
+		//record the attempt also when the request is cancelled, those are the attempts to audit
+		_, err := app.storage.CreateRewardClaimPickupAttempt(context.WithoutCancel(ctx), orgID, attempt)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("Error on app.redeemRewardClaimPickupCode() - %s", err)
+		}
+	}()
+
+	claim, err := app.storage.GetRewardClaimByPickupCode(ctx, orgID, strings.ToUpper(strings.TrimSpace(code)))
+	if err != nil {
+		attempt.Reason = err.Error()
+		return nil, fmt.Errorf("Error on app.redeemRewardClaimPickupCode() - %w", err)
+	}
+	if claim == nil {
+		attempt.Reason = "unknown pickup code"
+		return nil, model.NewNotFoundError("unknown pickup code")
+	}
+	attempt.ClaimID = claim.ID
+
+	fulfilled, err := app.storage.FulfillRewardClaim(ctx, orgID, claim.ID)
+	if err != nil {
+		attempt.Reason = err.Error()
+		return nil, fmt.Errorf("Error on app.redeemRewardClaimPickupCode() - %w", err)
+	}
+	if !fulfilled {
+		attempt.Reason = fmt.Sprintf("claim status is %s", claim.Status)
+		return nil, model.NewConflictError("claim %s is %s", claim.ID, claim.Status)
+	}
+
+	attempt.Success = true
+	attempt.Reason = model.RewardClaimStatusFulfilled
+	metrics.Claims.WithLabelValues(model.RewardClaimStatusFulfilled).Inc()
+	return app.storage.GetRewardClaim(ctx, orgID, claim.ID)
+}
+
+func (app *Application) getRewardClaimPickupAttempts(ctx context.Context, orgID string, claimID string) ([]model.RewardClaimPickupAttempt, error) {
+	return app.storage.GetRewardClaimPickupAttempts(ctx, orgID, claimID)
+}
+
+func (app *Application) getRewardCatalogItems(ctx context.Context, orgID string, active *bool) ([]model.RewardCatalogItem, error) {
+	return app.storage.GetRewardCatalogItems(ctx, orgID, active)
+}
+
+func (app *Application) getRewardCatalogItem(ctx context.Context, orgID string, id string) (*model.RewardCatalogItem, error) {
+	return app.storage.GetRewardCatalogItem(ctx, orgID, id)
+}
+
+func (app *Application) createRewardCatalogItem(ctx context.Context, orgID string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error) {
+	err := app.validateRewardCatalogItem(ctx, orgID, item)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.createRewardCatalogItem() - %w", err)
+	}
+	return app.storage.CreateRewardCatalogItem(ctx, orgID, item)
+}
+
+func (app *Application) updateRewardCatalogItem(ctx context.Context, orgID string, id string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error) {
+	// the inventory of an item does not change
+	existing, err := app.storage.GetRewardCatalogItem(ctx, orgID, id)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.updateRewardCatalogItem() - %w", err)
+	}
+	item.RewardType = existing.RewardType
+
+	err = app.validateRewardCatalogItem(ctx, orgID, item)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.updateRewardCatalogItem() - %w", err)
+	}
+	_, err = app.storage.UpdateRewardCatalogItem(ctx, orgID, id, item)
+	if err != nil {
+		return nil, err
+	}
+	return app.storage.GetRewardCatalogItem(ctx, orgID, id)
+}
+
+func (app *Application) deleteRewardCatalogItem(ctx context.Context, orgID string, id string) error {
+	return app.storage.DeleteRewardCatalogItem(ctx, orgID, id)
+}
+
+func (app *Application) getAvailableRewardCatalogItems(ctx context.Context, orgID string) ([]model.RewardCatalogItem, error) {
+	active := true
+	items, err := app.storage.GetRewardCatalogItems(ctx, orgID, &active)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	result := []model.RewardCatalogItem{}
+	for _, item := range items {
+		if item.IsAvailable(now) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (app *Application) validateRewardCatalogItem(ctx context.Context, orgID string, item model.RewardCatalogItem) error {
+	if item.RewardType == "" {
+		return model.NewValidationError("missing reward type")
+	}
+	if len(item.Price) == 0 {
+		return model.NewValidationError("missing price")
+	}
+	priced := map[string]bool{}
+	for _, price := range item.Price {
+		if priced[price.RewardType] {
+			return model.NewValidationError("price for %s is repeated", price.RewardType)
+		}
+		priced[price.RewardType] = true
+		if price.Amount <= 0 {
+			return model.NewValidationError("price for %s is zero or a negative value", price.RewardType)
+		}
+		rewardType, err := app.storage.GetRewardTypeByType(ctx, orgID, price.RewardType)
+		if err != nil {
+			return err
+		}
+		if rewardType.IsDeleted() {
+			return model.NewValidationError("reward type %s is deleted", price.RewardType)
+		}
+		if !rewardType.Currency {
+			return model.NewValidationError("reward type %s is not a currency", price.RewardType)
+		}
+	}
+	if item.AvailableFrom != nil && item.AvailableTo != nil && item.AvailableTo.Before(*item.AvailableFrom) {
+		return model.NewValidationError("available_to is before available_from")
+	}
+	if item.UserLimit < 0 {
+		return model.NewValidationError("user limit is a negative value")
+	}
+	return nil
+}
+
+func (app *Application) getPickupLocations(ctx context.Context, orgID string) ([]model.PickupLocation, error) {
+	return app.storage.GetPickupLocations(ctx, orgID)
+}
+
+func (app *Application) getPickupLocation(ctx context.Context, orgID string, id string) (*model.PickupLocation, error) {
+	return app.storage.GetPickupLocation(ctx, orgID, id)
+}
+
+func (app *Application) createPickupLocation(ctx context.Context, orgID string, item model.PickupLocation) (*model.PickupLocation, error) {
+	err := validatePickupLocation(item)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.createPickupLocation() - %w", err)
+	}
+	return app.storage.CreatePickupLocation(ctx, orgID, item)
+}
+
+func (app *Application) updatePickupLocation(ctx context.Context, orgID string, id string, item model.PickupLocation) (*model.PickupLocation, error) {
+	err := validatePickupLocation(item)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.updatePickupLocation() - %w", err)
+	}
+	_, err = app.storage.UpdatePickupLocation(ctx, orgID, id, item)
+	if err != nil {
+		return nil, err
+	}
+	return app.storage.GetPickupLocation(ctx, orgID, id)
+}
+
+func (app *Application) deletePickupLocation(ctx context.Context, orgID string, id string) error {
+	return app.storage.DeletePickupLocation(ctx, orgID, id)
+}
+
+func (app *Application) getRewardClaimsByLocation(ctx context.Context, orgID string, locationID string, slotID *string, status *string) ([]model.RewardClaim, error) {
+	return app.storage.GetRewardClaimsByLocation(ctx, orgID, locationID, slotID, status)
+}
+
+func (app *Application) getActivePickupLocations(ctx context.Context, orgID string) ([]model.PickupLocation, error) {
+	locations, err := app.storage.GetPickupLocations(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	active := []model.PickupLocation{}
+	for _, location := range locations {
+		if location.Active {
+			active = append(active, location)
+		}
+	}
+	return active, nil
+}
+
+func validatePickupLocation(item model.PickupLocation) error {
+	if item.Name == "" {
+		return model.NewValidationError("missing name")
+	}
+	for _, slot := range item.Slots {
+		if !slot.EndTime.After(slot.StartTime) {
+			return model.NewValidationError("slot end time is not after its start time")
+		}
+		if slot.Capacity < 0 {
+			return model.NewValidationError("slot capacity is a negative value")
+		}
+	}
+	return nil
 }
 
-func (app *Application) getUserBalance(orgID string, userID string) ([]model.RewardTypeAmount, error) {
-	rewardsBalance, err := app.storage.GetUserRewardsAmount(orgID, userID, nil)
+func (app *Application) getUserBalance(ctx context.Context, orgID string, userID string) ([]model.RewardTypeAmount, error) {
+	rewardsBalance, err := app.storage.GetUserRewardsAmount(ctx, orgID, userID, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Error app.getUserBalance() %s", err)
+		return nil, fmt.Errorf("Error app.getUserBalance() %w", err)
 	}
 
-	claimsBalance, err := app.storage.GetUserClaimsAmount(orgID, userID, nil)
+	claimsBalance, err := app.storage.GetUserClaimsAmount(ctx, orgID, userID, nil)
 	if err != nil {
-		return nil, fmt.Errorf("Error app.getUserBalance() %s", err)
+		return nil, fmt.Errorf("Error app.getUserBalance() %w", err)
 	}
 	claimsMapping := map[string]int{}
 	if len(claimsBalance) > 0 {
@@ -195,41 +743,731 @@ func (app *Application) getUserBalance(orgID string, userID string) ([]model.Rew
 	return rewardsBalance, nil
 }
 
-func (app *Application) getUserBalanceMapping(orgID string, userID string) (map[string]int, error) {
-	rewardsBalance, err := app.storage.GetUserRewardsAmount(orgID, userID, nil)
+func (app *Application) getUserRewardsHistory(ctx context.Context, orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error) {
+	return app.storage.GetUserRewardsHistory(ctx, orgID, userID, rewardType, code, buildingBlock, limit, offset)
+}
+
+// getRewardQuantity gives the quantities of an inventory backed reward type. The quantities of the other reward
+// types are not limited, so only the reward type is reported for them
+func (app *Application) getRewardQuantity(ctx context.Context, orgID string, rewardType string) (*model.RewardQuantityState, error) {
+	item, err := app.storage.GetRewardTypeByType(ctx, orgID, rewardType)
 	if err != nil {
-		return nil, fmt.Errorf("Error app.getUserBalanceMapping() %s", err)
+		return nil, err
+	}
+	if item == nil {
+		return nil, model.NewNotFoundError("unable to find reward type: %s", rewardType)
+	}
+	if !item.InventoryBacked {
+		return &model.RewardQuantityState{RewardType: rewardType, InventoryBacked: false}, nil
+	}
+	return app.storage.GetRewardQuantityState(ctx, orgID, rewardType, nil)
+}
+
+func (app *Application) getAuthorizationPolicies(ctx context.Context) ([]model.AuthorizationPolicy, error) {
+	return app.storage.GetAuthorizationPolicies(ctx)
+}
+
+func (app *Application) getInternalCredentials(ctx context.Context, orgID string) ([]model.InternalCredential, error) {
+	return app.storage.GetInternalCredentials(ctx, orgID)
+}
+
+func (app *Application) getInternalCredential(ctx context.Context, orgID string, id string) (*model.InternalCredential, error) {
+	return app.storage.GetInternalCredential(ctx, orgID, id)
+}
+
+func (app *Application) createInternalCredential(ctx context.Context, orgID string, item model.InternalCredential) (*model.InternalCredential, error) {
+	item.BuildingBlock = strings.TrimSpace(item.BuildingBlock)
+	if item.BuildingBlock == "" {
+		return nil, model.NewValidationError("missing building block")
 	}
 
-	claimsBalance, err := app.storage.GetUserClaimsAmount(orgID, userID, nil)
+	key, err := utils.GenerateCode(internalKeyLength)
 	if err != nil {
-		return nil, fmt.Errorf("Error app.getUserBalanceMapping() %s", err)
+		return nil, fmt.Errorf("Error on app.createInternalCredential() - %w", err)
 	}
-	rewardsMapping := map[string]int{}
-	if len(rewardsBalance) > 0 {
-		for _, balance := range rewardsBalance {
-			rewardsMapping[balance.RewardType] = balance.Amount
+	item.KeyHash = utils.HashKey(key)
+	item.KeyPrefix = key[:internalKeyPrefixLength]
+	item.DateRotated = nil
+	item.DateRevoked = nil
+
+	credential, err := app.storage.CreateInternalCredential(ctx, orgID, item)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.createInternalCredential() - %w", err)
+	}
+	credential.Key = key
+	return credential, nil
+}
+
+// rotateInternalCredential replaces the key of the credential. The previous key stops working immediately.
+func (app *Application) rotateInternalCredential(ctx context.Context, orgID string, id string) (*model.InternalCredential, error) {
+	key, err := utils.GenerateCode(internalKeyLength)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.rotateInternalCredential() - %w", err)
+	}
+
+	err = app.storage.UpdateInternalCredentialKey(ctx, orgID, id, utils.HashKey(key), key[:internalKeyPrefixLength])
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.rotateInternalCredential() - %w", err)
+	}
+
+	credential, err := app.storage.GetInternalCredential(ctx, orgID, id)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.rotateInternalCredential() - %w", err)
+	}
+	credential.Key = key
+	return credential, nil
+}
+
+func (app *Application) revokeInternalCredential(ctx context.Context, orgID string, id string) error {
+	return app.storage.RevokeInternalCredential(ctx, orgID, id)
+}
+
+// authenticateInternalCredential finds the active credential for the key. Returns nil if the key is unknown or revoked.
+func (app *Application) authenticateInternalCredential(ctx context.Context, key string) (*model.InternalCredential, error) {
+	credential, err := app.storage.GetInternalCredentialByKeyHash(ctx, utils.HashKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if credential == nil || !credential.IsActive() {
+		return nil, nil
+	}
+	return credential, nil
+}
+
+func (app *Application) getAuditLogEntries(ctx context.Context, orgID string, resource *string, resourceID *string, actor *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64) ([]model.AuditLogEntry, error) {
+	return app.storage.GetAuditLogEntries(ctx, orgID, resource, resourceID, actor, startDate, endDate, limit, offset)
+}
+
+func (app *Application) createAuditLogEntry(ctx context.Context, orgID string, item model.AuditLogEntry) (*model.AuditLogEntry, error) {
+	return app.storage.CreateAuditLogEntry(ctx, orgID, item)
+}
+
+// getLeaderboard ranks the users by the amount of the reward type they got in the current time window. Only the
+// users who opted in are ranked for the clients, the admins get all users with their ids
+func (app *Application) getLeaderboard(ctx context.Context, orgID string, rewardType string, buildingBlock string, window string, admin bool, limit int64) (*model.Leaderboard, error) {
+	windowStart, err := model.LeaderboardWindowStart(window, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		return nil, model.NewValidationError("limit is zero or a negative value")
+	}
+
+	var optedIn *bool
+	if !admin {
+		optedInOnly := true
+		optedIn = &optedInOnly
+	}
+	scores, err := app.storage.GetLeaderboardScores(ctx, orgID, rewardType, buildingBlock, window, windowStart, optedIn, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.getLeaderboard() - %w", err)
+	}
+
+	return &model.Leaderboard{RewardType: rewardType, BuildingBlock: buildingBlock, Window: window, WindowStart: windowStart,
+		Entries: model.RankLeaderboardScores(scores, admin)}, nil
+}
+
+func (app *Application) getLeaderboardProfile(ctx context.Context, orgID string, userID string) (*model.LeaderboardProfile, error) {
+	profile, err := app.storage.GetLeaderboardProfile(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		// users are not shown until they opt in
+		profile = &model.LeaderboardProfile{OrgID: orgID, UserID: userID}
+	}
+	return profile, nil
+}
+
+func (app *Application) updateLeaderboardProfile(ctx context.Context, orgID string, userID string, item model.LeaderboardProfile) (*model.LeaderboardProfile, error) {
+	item.UserID = userID
+	item.DisplayHandle = strings.TrimSpace(item.DisplayHandle)
+	err := item.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return app.storage.SaveLeaderboardProfile(ctx, orgID, item)
+}
+
+func (app *Application) rebuildLeaderboards(ctx context.Context, orgID string) (int, error) {
+	return app.storage.RebuildLeaderboardScores(ctx, orgID)
+}
+
+func (app *Application) getGrantsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.GrantsBucket, error) {
+	return app.storage.GetGrantsAnalytics(ctx, orgID, query)
+}
+
+func (app *Application) getEarnersAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.EarnersBucket, error) {
+	return app.storage.GetEarnersAnalytics(ctx, orgID, query)
+}
+
+func (app *Application) getClaimsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.ClaimsBucket, error) {
+	return app.storage.GetClaimsAnalytics(ctx, orgID, query)
+}
+
+func (app *Application) getRedemptionAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.RedemptionSummary, error) {
+	return app.storage.GetRedemptionAnalytics(ctx, orgID, query)
+}
+
+func (app *Application) exportRewardHistory(ctx context.Context, orgID string, userID *string, rewardType *string, code *string, buildingBlock *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64, each func(model.Reward) error) error {
+	return app.storage.ExportRewardHistory(ctx, orgID, userID, rewardType, code, buildingBlock, startDate, endDate, limit, offset, each)
+}
+
+func (app *Application) exportRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64, each func(model.RewardClaim) error) error {
+	return app.storage.ExportRewardClaims(ctx, orgID, ids, userID, rewardType, status, limit, offset, each)
+}
+
+func (app *Application) exportRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64, each func(model.RewardInventory) error) error {
+	return app.storage.ExportRewardInventories(ctx, orgID, ids, rewardType, inStock, grantDepleted, claimDepleted, archived, limit, offset, each)
+}
+
+// importRewardTypes validates all the reward types before any of them is applied. They are matched by their name
+func (app *Application) importRewardTypes(ctx context.Context, orgID string, items []model.RewardType, dryRun bool) (*model.ImportReport, error) {
+	report := model.NewImportReport(model.ImportKindTypes, dryRun)
+	batch := model.ImportBatch{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		errs := []string{}
+		action := model.ImportActionCreate
+		if item.RewardType == "" {
+			errs = append(errs, "reward_type is required")
+		} else if seen[item.RewardType] {
+			errs = append(errs, fmt.Sprintf("reward type %s is repeated in the import", item.RewardType))
+		}
+		seen[item.RewardType] = true
+
+		err := app.validateRewardType(item)
+		if err != nil {
+			errs = append(errs, err.Error())
 		}
+
+		if item.RewardType != "" {
+			existing, err := app.storage.GetRewardTypeByType(ctx, orgID, item.RewardType)
+			if err != nil && !model.IsErrorCode(err, model.ErrorCodeNotFound) {
+				return nil, fmt.Errorf("Error on app.importRewardTypes() - %w", err)
+			}
+			if existing != nil && existing.IsDeleted() {
+				errs = append(errs, fmt.Sprintf("reward type %s was deleted and is kept for the history which refers to it", item.RewardType))
+			} else if existing != nil {
+				action = model.ImportActionUpdate
+				item.ID = existing.ID
+			}
+		}
+
+		if len(errs) == 0 && action == model.ImportActionCreate {
+			batch.CreateTypes = append(batch.CreateTypes, item)
+		} else if len(errs) == 0 {
+			batch.UpdateTypes = append(batch.UpdateTypes, item)
+		}
+		report.AddRow(item.RewardType, action, item.ID, errs)
 	}
+	return app.applyImport(ctx, orgID, report, batch)
+}
 
-	if len(claimsBalance) > 0 {
-		for _, claimdBalance := range claimsBalance {
-			rewardsMapping[claimdBalance.RewardType] -= claimdBalance.Amount
+// importRewardOperations validates all the reward operations before any of them is applied. They are matched
+// by their code and building block
+func (app *Application) importRewardOperations(ctx context.Context, orgID string, items []model.RewardOperation, dryRun bool) (*model.ImportReport, error) {
+	rewardTypes, err := app.getRewardTypes(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.importRewardOperations() - %w", err)
+	}
+	typeMapping := map[string]bool{}
+	for _, rewardType := range rewardTypes {
+		typeMapping[rewardType.RewardType] = true
+	}
+
+	report := model.NewImportReport(model.ImportKindOperations, dryRun)
+	batch := model.ImportBatch{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		key := item.Code + "/" + item.BuildingBlock
+		errs := []string{}
+		action := model.ImportActionCreate
+		if item.Code == "" || item.BuildingBlock == "" {
+			errs = append(errs, "code and building_block are required")
+		} else if seen[key] {
+			errs = append(errs, fmt.Sprintf("reward operation %s is repeated in the import", key))
+		}
+		seen[key] = true
+		if !typeMapping[item.RewardType] {
+			errs = append(errs, fmt.Sprintf("unable to find reward type '%s'", item.RewardType))
+		}
+		if item.Amount <= 0 {
+			errs = append(errs, "amount is zero or a negative value")
+		}
+
+		if item.Code != "" && item.BuildingBlock != "" {
+			existing, err := app.storage.GetRewardOperationByCode(ctx, orgID, item.Code, item.BuildingBlock)
+			if err != nil && !model.IsErrorCode(err, model.ErrorCodeNotFound) {
+				return nil, fmt.Errorf("Error on app.importRewardOperations() - %w", err)
+			}
+			if existing != nil && existing.RewardType != item.RewardType {
+				errs = append(errs, fmt.Sprintf("reward operation %s grants %s and its reward type cannot be changed", key, existing.RewardType))
+			} else if existing != nil {
+				action = model.ImportActionUpdate
+				item.ID = existing.ID
+			}
+		}
+
+		if len(errs) == 0 && action == model.ImportActionCreate {
+			batch.CreateOperations = append(batch.CreateOperations, item)
+		} else if len(errs) == 0 {
+			batch.UpdateOperations = append(batch.UpdateOperations, item)
 		}
+		report.AddRow(key, action, item.ID, errs)
 	}
+	return app.applyImport(ctx, orgID, report, batch)
+}
+
+// importRewardInventories validates all the reward inventories before any of them is applied. The inventories
+// have no natural key so they are matched by id and the rows without an id create new inventories
+func (app *Application) importRewardInventories(ctx context.Context, orgID string, items []model.RewardInventory, dryRun bool) (*model.ImportReport, error) {
+	rewardTypes, err := app.getRewardTypes(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.importRewardInventories() - %w", err)
+	}
+	typeMapping := map[string]model.RewardType{}
+	for _, rewardType := range rewardTypes {
+		typeMapping[rewardType.RewardType] = rewardType
+	}
+
+	report := model.NewImportReport(model.ImportKindInventories, dryRun)
+	batch := model.ImportBatch{}
+	seen := map[string]bool{}
+	for _, item := range items {
+		key := item.ID
+		if key == "" {
+			key = item.RewardType
+		}
+		errs := []string{}
+		action := model.ImportActionCreate
+		rewardType, ok := typeMapping[item.RewardType]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unable to find reward type '%s'", item.RewardType))
+		} else if !rewardType.InventoryBacked {
+			errs = append(errs, fmt.Sprintf("reward type %s is not inventory backed", item.RewardType))
+		}
+
+		if item.ID != "" {
+			if seen[item.ID] {
+				errs = append(errs, fmt.Sprintf("inventory %s is repeated in the import", item.ID))
+			}
+			seen[item.ID] = true
+
+			existing, err := app.storage.GetRewardInventory(ctx, orgID, item.ID)
+			if err != nil && !model.IsErrorCode(err, model.ErrorCodeNotFound) {
+				return nil, fmt.Errorf("Error on app.importRewardInventories() - %w", err)
+			}
+			if existing == nil {
+				errs = append(errs, fmt.Sprintf("unable to find reward inventory with id: %s", item.ID))
+			} else if existing.RewardType != item.RewardType {
+				errs = append(errs, fmt.Sprintf("inventory %s stocks %s and its reward type cannot be changed", item.ID, existing.RewardType))
+			} else {
+				action = model.ImportActionUpdate
+				item.OrgID = orgID
+				item.AmountGranted = existing.AmountGranted
+				item.AmountClaimed = existing.AmountClaimed
+			}
+		}
 
-	return rewardsMapping, nil
+		if item.AmountTotal <= 0 {
+			errs = append(errs, "inventory amount is zero or negative")
+		} else if item.AmountTotal < item.AmountGranted || item.AmountTotal < item.AmountClaimed {
+			errs = append(errs, fmt.Sprintf("inventory amount is less than the granted %d or claimed %d amount", item.AmountGranted, item.AmountClaimed))
+		}
+
+		if len(errs) == 0 && action == model.ImportActionCreate {
+			batch.CreateInventories = append(batch.CreateInventories, item)
+		} else if len(errs) == 0 {
+			batch.UpdateInventories = append(batch.UpdateInventories, item)
+		}
+		report.AddRow(key, action, item.ID, errs)
+	}
+	return app.applyImport(ctx, orgID, report, batch)
+}
+
+// applyImport applies the batch only when all the rows are valid and it is not a dry run. The ids of the
+// created entities are set on the report rows in their order
+func (app *Application) applyImport(ctx context.Context, orgID string, report *model.ImportReport, batch model.ImportBatch) (*model.ImportReport, error) {
+	if !report.Valid || report.DryRun {
+		return report, nil
+	}
+
+	applied, err := app.storage.ApplyImport(ctx, orgID, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	createdIDs := []string{}
+	for _, item := range applied.CreateTypes {
+		createdIDs = append(createdIDs, item.ID)
+	}
+	for _, item := range applied.CreateOperations {
+		createdIDs = append(createdIDs, item.ID)
+	}
+	for _, item := range applied.CreateInventories {
+		createdIDs = append(createdIDs, item.ID)
+	}
+	for i := range report.Rows {
+		if report.Rows[i].Action == model.ImportActionCreate && len(createdIDs) > 0 {
+			report.Rows[i].ID = createdIDs[0]
+			createdIDs = createdIDs[1:]
+		}
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// exportOrgConfig gives the configuration of the org without the archived entities and the inventory counters
+func (app *Application) exportOrgConfig(ctx context.Context, orgID string) (*model.OrgConfig, error) {
+	rewardTypes, err := app.storage.GetRewardTypes(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.exportOrgConfig() - %w", err)
+	}
+	notArchived := false
+	operations, err := app.storage.GetRewardOperations(ctx, orgID, &notArchived)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.exportOrgConfig() - %w", err)
+	}
+	inventories, err := app.storage.GetRewardInventories(ctx, orgID, nil, nil, nil, nil, nil, &notArchived, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.exportOrgConfig() - %w", err)
+	}
+	config := model.OrgConfig{Version: model.OrgConfigVersion, OrgID: orgID, DateExported: time.Now().UTC(),
+		RewardTypes: []model.OrgConfigRewardType{}, Operations: []model.OrgConfigRewardOperation{},
+		Inventories: []model.OrgConfigRewardInventory{}}
+
+	exported := map[string]bool{}
+	for _, item := range inventories {
+		exported[item.ID] = true
+		config.Inventories = append(config.Inventories, model.OrgConfigRewardInventory{ID: item.ID, RewardType: item.RewardType,
+			InStock: item.InStock, AmountTotal: item.AmountTotal, Description: item.Description})
+	}
+	for _, item := range rewardTypes {
+		// the archived inventories are not exported so they cannot be preferred
+		preferredIDs := []string{}
+		for _, id := range item.PreferredInventoryIDs {
+			if exported[id] {
+				preferredIDs = append(preferredIDs, id)
+			}
+		}
+		config.RewardTypes = append(config.RewardTypes, model.OrgConfigRewardType{ID: item.ID, RewardType: item.RewardType,
+			DisplayName: item.DisplayName, Active: item.Active, Currency: item.Currency, Description: item.Description,
+			InventoryBacked: item.InventoryBacked, AllocationStrategy: item.AllocationStrategy, PreferredInventoryIDs: preferredIDs})
+	}
+	for _, item := range operations {
+		config.Operations = append(config.Operations, model.OrgConfigRewardOperation{ID: item.ID, RewardType: item.RewardType,
+			Code: item.Code, BuildingBlock: item.BuildingBlock, Amount: item.Amount, Description: item.Description})
+	}
+	return &config, nil
+}
+
+// importOrgConfig diffs the configuration against the org and applies it in a single transaction when all the
+// changes are valid and it is not a dry run. The entities are matched by their natural keys and the created
+// ones get new ids
+func (app *Application) importOrgConfig(ctx context.Context, orgID string, config model.OrgConfig, dryRun bool) (*model.OrgConfigImportReport, error) {
+	if config.Version != model.OrgConfigVersion {
+		return nil, model.NewValidationError("unsupported org configuration version %d, expected %d", config.Version, model.OrgConfigVersion)
+	}
+
+	report := &model.OrgConfigImportReport{Version: config.Version, SourceOrg: config.OrgID, DryRun: dryRun, Valid: true,
+		Changes: []model.OrgConfigChange{}, IDMapping: map[string]string{}}
+	batch := model.ImportBatch{}
+
+	// the reward types of the document and of the org which the inventories and operations may refer to
+	rewardTypes := map[string]bool{}
+	inventoryBacked := map[string]bool{}
+	for _, item := range config.RewardTypes {
+		rewardTypes[item.RewardType] = true
+		inventoryBacked[item.RewardType] = item.InventoryBacked
+	}
+	targetTypes, err := app.storage.GetRewardTypes(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("Error on app.importOrgConfig() - %w", err)
+	}
+	for _, item := range targetTypes {
+		if !rewardTypes[item.RewardType] {
+			rewardTypes[item.RewardType] = true
+			inventoryBacked[item.RewardType] = item.InventoryBacked
+		}
+	}
+
+	err = app.diffOrgConfigInventories(ctx, orgID, config, rewardTypes, inventoryBacked, report, &batch)
+	if err != nil {
+		return nil, err
+	}
+	err = app.diffOrgConfigRewardTypes(ctx, orgID, config, report, &batch)
+	if err != nil {
+		return nil, err
+	}
+	err = app.diffOrgConfigOperations(ctx, orgID, config, rewardTypes, report, &batch)
+	if err != nil {
+		return nil, err
+	}
+
+	if !report.Valid || dryRun {
+		return report, nil
+	}
+
+	applied, err := app.storage.ApplyImport(ctx, orgID, batch)
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range batch.CreateInventories {
+		report.IDMapping[item.ID] = applied.CreateInventories[i].ID
+	}
+	for i, item := range batch.CreateTypes {
+		report.IDMapping[item.ID] = applied.CreateTypes[i].ID
+	}
+	for i, item := range batch.CreateOperations {
+		report.IDMapping[item.ID] = applied.CreateOperations[i].ID
+	}
+	for i := range report.Changes {
+		change := &report.Changes[i]
+		if change.Action == model.OrgConfigActionCreate && change.SourceID != "" {
+			change.TargetID = report.IDMapping[change.SourceID]
+		}
+	}
+	report.Applied = true
+	return report, nil
 }
 
-func (app *Application) getUserRewardsHistory(orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error) {
-	return app.storage.GetUserRewardsHistory(orgID, userID, rewardType, code, buildingBlock, limit, offset)
+// diffOrgConfigInventories matches the inventories by their id when the document comes from the same org, else by
+// reward type and description. The inventories have no natural key, so the ones which share the reward type and
+// description are created in an org which has none of them and cannot be matched in an org which has some
+func (app *Application) diffOrgConfigInventories(ctx context.Context, orgID string, config model.OrgConfig, rewardTypes map[string]bool, inventoryBacked map[string]bool,
+	report *model.OrgConfigImportReport, batch *model.ImportBatch) error {
+	notArchived := false
+	targetInventories, err := app.storage.GetRewardInventories(ctx, orgID, nil, nil, nil, nil, nil, &notArchived, nil, nil)
+	if err != nil {
+		return fmt.Errorf("Error on app.importOrgConfig() - %w", err)
+	}
+	targetsByID := map[string]model.RewardInventory{}
+	targets := map[string][]model.RewardInventory{}
+	for _, item := range targetInventories {
+		key := item.RewardType + "/" + item.Description
+		targetsByID[item.ID] = item
+		targets[key] = append(targets[key], item)
+	}
+	// the inventories of the document which are not matched by their id share the natural keys
+	matchedIDs := map[string]bool{}
+	sources := map[string]int{}
+	for _, item := range config.Inventories {
+		if _, ok := targetsByID[item.ID]; ok {
+			matchedIDs[item.ID] = true
+		} else {
+			sources[item.RewardType+"/"+item.Description]++
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, item := range config.Inventories {
+		key := item.RewardType + "/" + item.Description
+		change := model.OrgConfigChange{Resource: model.ImportKindInventories, Key: key, Action: model.OrgConfigActionCreate, SourceID: item.ID}
+		if item.ID == "" {
+			change.Errors = append(change.Errors, "id is required")
+		} else if seen[item.ID] {
+			change.Errors = append(change.Errors, fmt.Sprintf("inventory %s is repeated in the configuration", item.ID))
+		}
+		seen[item.ID] = true
+		if !rewardTypes[item.RewardType] {
+			change.Errors = append(change.Errors, fmt.Sprintf("unable to find reward type '%s'", item.RewardType))
+		} else if !inventoryBacked[item.RewardType] {
+			change.Errors = append(change.Errors, fmt.Sprintf("reward type %s is not inventory backed", item.RewardType))
+		}
+		if item.AmountTotal <= 0 {
+			change.Errors = append(change.Errors, "inventory amount is zero or negative")
+		}
+
+		inventory := model.RewardInventory{ID: item.ID, OrgID: orgID, RewardType: item.RewardType, InStock: item.InStock,
+			AmountTotal: item.AmountTotal, Description: item.Description}
+		var matched *model.RewardInventory
+		if existing, ok := targetsByID[item.ID]; ok {
+			matched = &existing
+		} else {
+			matches := []model.RewardInventory{}
+			for _, target := range targets[key] {
+				if !matchedIDs[target.ID] {
+					matches = append(matches, target)
+				}
+			}
+			if len(matches) > 1 || (len(matches) == 1 && sources[key] > 1) {
+				change.Errors = append(change.Errors, fmt.Sprintf("%d inventories of the configuration and %d of the org match %s", sources[key], len(matches), key))
+			} else if len(matches) == 1 {
+				matched = &matches[0]
+			}
+		}
+		if matched != nil {
+			existing := *matched
+			change.TargetID = existing.ID
+			report.IDMapping[item.ID] = existing.ID
+			change.Fields = model.DiffFields("in_stock", existing.InStock, item.InStock, "amount_total", existing.AmountTotal, item.AmountTotal)
+			change.Action = model.OrgConfigActionUpdate
+			if len(change.Fields) == 0 {
+				change.Action = model.OrgConfigActionUnchanged
+			}
+			if item.AmountTotal < existing.AmountGranted || item.AmountTotal < existing.AmountClaimed {
+				change.Errors = append(change.Errors, fmt.Sprintf("inventory amount is less than the granted %d or claimed %d amount", existing.AmountGranted, existing.AmountClaimed))
+			}
+			inventory.ID = existing.ID
+			inventory.AmountGranted = existing.AmountGranted
+			inventory.AmountClaimed = existing.AmountClaimed
+			inventory.LocationIDs = existing.LocationIDs
+		}
+
+		if len(change.Errors) == 0 && change.Action == model.OrgConfigActionCreate {
+			batch.CreateInventories = append(batch.CreateInventories, inventory)
+		} else if len(change.Errors) == 0 && change.Action == model.OrgConfigActionUpdate {
+			batch.UpdateInventories = append(batch.UpdateInventories, inventory)
+		}
+		report.AddChange(change)
+	}
+	return nil
 }
 
-func (app *Application) getRewardQuantity(orgID string, rewardType string) (*model.RewardQuantityState, error) {
-	return app.storage.GetRewardQuantityState(orgID, rewardType, nil)
+// diffOrgConfigRewardTypes matches the reward types by name. Their preferred inventories must be in the configuration
+func (app *Application) diffOrgConfigRewardTypes(ctx context.Context, orgID string, config model.OrgConfig, report *model.OrgConfigImportReport, batch *model.ImportBatch) error {
+	inventoryIDs := map[string]bool{}
+	for _, item := range config.Inventories {
+		inventoryIDs[item.ID] = true
+	}
+
+	seen := map[string]bool{}
+	for _, item := range config.RewardTypes {
+		change := model.OrgConfigChange{Resource: model.ImportKindTypes, Key: item.RewardType, Action: model.OrgConfigActionCreate, SourceID: item.ID}
+		if item.ID == "" || item.RewardType == "" {
+			change.Errors = append(change.Errors, "id and reward_type are required")
+		}
+		if seen[item.RewardType] {
+			change.Errors = append(change.Errors, fmt.Sprintf("reward type %s is repeated in the configuration", item.RewardType))
+		}
+		seen[item.RewardType] = true
+
+		// the matched inventories are remapped here and the created ones when they are applied
+		preferredIDs := make([]string, len(item.PreferredInventoryIDs))
+		for i, id := range item.PreferredInventoryIDs {
+			preferredIDs[i] = id
+			if !inventoryIDs[id] {
+				change.Errors = append(change.Errors, fmt.Sprintf("preferred inventory %s is not in the configuration", id))
+			} else if targetID, ok := report.IDMapping[id]; ok {
+				preferredIDs[i] = targetID
+			}
+		}
+
+		rewardType := model.RewardType{ID: item.ID, RewardType: item.RewardType, DisplayName: item.DisplayName, Active: item.Active,
+			Currency: item.Currency, Description: item.Description, InventoryBacked: item.InventoryBacked,
+			AllocationStrategy: item.AllocationStrategy, PreferredInventoryIDs: preferredIDs}
+		err := app.validateRewardType(rewardType)
+		if err != nil {
+			change.Errors = append(change.Errors, err.Error())
+		}
+
+		if item.RewardType != "" {
+			existing, err := app.storage.GetRewardTypeByType(ctx, orgID, item.RewardType)
+			if err != nil && !model.IsErrorCode(err, model.ErrorCodeNotFound) {
+				return fmt.Errorf("Error on app.importOrgConfig() - %w", err)
+			}
+			if existing != nil && existing.IsDeleted() {
+				change.Errors = append(change.Errors, fmt.Sprintf("reward type %s was deleted and is kept for the history which refers to it", item.RewardType))
+			} else if existing != nil {
+				change.TargetID = existing.ID
+				report.IDMapping[item.ID] = existing.ID
+				change.Fields = model.DiffFields("display_name", existing.DisplayName, rewardType.DisplayName, "active", existing.Active, rewardType.Active,
+					"currency", existing.Currency, rewardType.Currency, "description", existing.Description, rewardType.Description,
+					"inventory_backed", existing.InventoryBacked, rewardType.InventoryBacked, "allocation_strategy", existing.AllocationStrategy, rewardType.AllocationStrategy,
+					"preferred_inventory_ids", existing.PreferredInventoryIDs, rewardType.PreferredInventoryIDs)
+				change.Action = model.OrgConfigActionUpdate
+				if len(change.Fields) == 0 {
+					change.Action = model.OrgConfigActionUnchanged
+				}
+				rewardType.ID = existing.ID
+			}
+		}
+
+		if len(change.Errors) == 0 && change.Action == model.OrgConfigActionCreate {
+			batch.CreateTypes = append(batch.CreateTypes, rewardType)
+		} else if len(change.Errors) == 0 && change.Action == model.OrgConfigActionUpdate {
+			batch.UpdateTypes = append(batch.UpdateTypes, rewardType)
+		}
+		report.AddChange(change)
+	}
+	return nil
+}
+
+// diffOrgConfigOperations matches the reward operations by code and building block
+func (app *Application) diffOrgConfigOperations(ctx context.Context, orgID string, config model.OrgConfig, rewardTypes map[string]bool, report *model.OrgConfigImportReport, batch *model.ImportBatch) error {
+	seen := map[string]bool{}
+	for _, item := range config.Operations {
+		key := item.Code + "/" + item.BuildingBlock
+		change := model.OrgConfigChange{Resource: model.ImportKindOperations, Key: key, Action: model.OrgConfigActionCreate, SourceID: item.ID}
+		if item.ID == "" || item.Code == "" || item.BuildingBlock == "" {
+			change.Errors = append(change.Errors, "id, code and building_block are required")
+		}
+		if seen[key] {
+			change.Errors = append(change.Errors, fmt.Sprintf("reward operation %s is repeated in the configuration", key))
+		}
+		seen[key] = true
+		if !rewardTypes[item.RewardType] {
+			change.Errors = append(change.Errors, fmt.Sprintf("unable to find reward type '%s'", item.RewardType))
+		}
+		if item.Amount <= 0 {
+			change.Errors = append(change.Errors, "amount is zero or a negative value")
+		}
+
+		operation := model.RewardOperation{ID: item.ID, RewardType: item.RewardType, Code: item.Code, BuildingBlock: item.BuildingBlock,
+			Amount: item.Amount, Description: item.Description}
+		if item.Code != "" && item.BuildingBlock != "" {
+			existing, err := app.storage.GetRewardOperationByCode(ctx, orgID, item.Code, item.BuildingBlock)
+			if err != nil && !model.IsErrorCode(err, model.ErrorCodeNotFound) {
+				return fmt.Errorf("Error on app.importOrgConfig() - %w", err)
+			}
+			if existing != nil && existing.RewardType != item.RewardType {
+				change.Errors = append(change.Errors, fmt.Sprintf("reward operation %s grants %s and its reward type cannot be changed", key, existing.RewardType))
+			} else if existing != nil {
+				change.TargetID = existing.ID
+				report.IDMapping[item.ID] = existing.ID
+				change.Fields = model.DiffFields("amount", existing.Amount, item.Amount, "description", existing.Description, item.Description)
+				change.Action = model.OrgConfigActionUpdate
+				if len(change.Fields) == 0 {
+					change.Action = model.OrgConfigActionUnchanged
+				}
+				operation.ID = existing.ID
+			}
+		}
+
+		if len(change.Errors) == 0 && change.Action == model.OrgConfigActionCreate {
+			batch.CreateOperations = append(batch.CreateOperations, operation)
+		} else if len(change.Errors) == 0 && change.Action == model.OrgConfigActionUpdate {
+			batch.UpdateOperations = append(batch.UpdateOperations, operation)
+		}
+		report.AddChange(change)
+	}
+	return nil
+}
+
+// checkReadiness checks the storage is reachable and the change streams which keep the reward types cache
+// and the authorization policy up to date are open
+func (app *Application) checkReadiness(ctx context.Context) *model.HealthReport {
+	report := model.NewHealthReport()
+	report.AddCheck("mongo", app.storage.Ping(ctx))
+	for name, open := range app.storage.GetChangeStreams() {
+		var err error
+		if !open {
+			err = errors.New("change stream is closed")
+		}
+		report.AddCheck("change_stream."+name, err)
+	}
+	return report
 }
 
 // OnRewardTypesChanged callback that indicates the reward types collection is changed
 func (app *Application) OnRewardTypesChanged() {
-	app.cacheAdapter.SetRewardTypes(nil) // invalidate
+	app.cacheAdapter.InvalidateRewardTypes()
+}
+
+// OnAuthorizationPoliciesChanged callback that indicates the authorization policies collection is changed
+func (app *Application) OnAuthorizationPoliciesChanged() {
+	for _, listener := range app.listeners {
+		go listener.OnAuthorizationPoliciesChanged()
+	}
 }
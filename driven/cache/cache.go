@@ -18,6 +18,7 @@ import (
 	"github.com/patrickmn/go-cache"
 	"rewards/core/model"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -44,9 +45,11 @@ func NewCacheAdapter(defaultCacheExpirationSeconds string) *CacheAdapter {
 	}
 }
 
-// SetRewardTypes set reward types
-func (s *CacheAdapter) SetRewardTypes(tips []model.RewardType) []model.RewardType {
-	key := "reward_types"
+const rewardTypesKeyPrefix = "reward_types_"
+
+// SetRewardTypes set the reward types of an org
+func (s *CacheAdapter) SetRewardTypes(orgID string, tips []model.RewardType) []model.RewardType {
+	key := rewardTypesKeyPrefix + orgID
 	if tips == nil {
 		s.cache.Delete(key)
 	} else {
@@ -55,11 +58,20 @@ func (s *CacheAdapter) SetRewardTypes(tips []model.RewardType) []model.RewardTyp
 	return tips
 }
 
-// GetRewardTypes get all reward types
-func (s *CacheAdapter) GetRewardTypes() []model.RewardType {
-	obj, _ := s.cache.Get("reward_types")
+// GetRewardTypes get all reward types of an org
+func (s *CacheAdapter) GetRewardTypes(orgID string) []model.RewardType {
+	obj, _ := s.cache.Get(rewardTypesKeyPrefix + orgID)
 	if obj != nil {
 		return obj.([]model.RewardType)
 	}
 	return nil
 }
+
+// InvalidateRewardTypes removes the reward types of all orgs
+func (s *CacheAdapter) InvalidateRewardTypes() {
+	for key := range s.cache.Items() {
+		if strings.HasPrefix(key, rewardTypesKeyPrefix) {
+			s.cache.Delete(key)
+		}
+	}
+}
@@ -16,9 +16,12 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"rewards/core/model"
+	"rewards/utils/logging"
+	"rewards/utils/metrics"
+	"sort"
 	"strconv"
 	"time"
 
@@ -27,41 +30,98 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
+// referenceSampleSize is the max number of referring entity ids reported per resource
+const referenceSampleSize int64 = 10
+
+// exportBatchSize is the number of documents an export cursor loads at once
+const exportBatchSize int32 = 500
+
 // Adapter implements the Storage interface
 type Adapter struct {
 	db *database
 }
 
-// Start starts the storage
+// Start starts the storage and applies the pending migrations. It fails when a migration fails,
+// so the service does not run on data which is not migrated.
 func (sa *Adapter) Start() error {
+	err := sa.Connect()
+	if err != nil {
+		return err
+	}
+
+	results, err := sa.Migrate(false)
+	if err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Status == MigrationStatusFailed {
+			return fmt.Errorf("migration %d failed: %s", result.Version, result.Error)
+		}
+	}
+	return nil
+}
+
+// Connect connects to the storage without applying the migrations
+func (sa *Adapter) Connect() error {
 	err := sa.db.start()
 	return err
 }
 
+// Stop closes the change streams and disconnects from the storage
+func (sa *Adapter) Stop(ctx context.Context) error {
+	return sa.db.stop(ctx)
+}
+
+// Ping checks the storage is reachable
+func (sa *Adapter) Ping(ctx context.Context) error {
+	if sa.db.dbClient == nil {
+		return errors.New("storage is not connected")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, sa.db.mongoTimeout)
+	defer cancel()
+	return sa.db.dbClient.Ping(ctx, readpref.Primary())
+}
+
+// GetChangeStreams gives the status of the change streams by collection name - true when the stream is open
+func (sa *Adapter) GetChangeStreams() map[string]bool {
+	return sa.db.changeStreams()
+}
+
+// Migrate applies the pending migrations. In dry run mode nothing is changed and the
+// results describe the changes the pending migrations would make.
+func (sa *Adapter) Migrate(dryRun bool) ([]MigrationResult, error) {
+	return sa.db.migrate(dryRun)
+}
+
 // NewStorageAdapter creates a new storage adapter instance
-func NewStorageAdapter(mongoDBAuth string, mongoDBName string, mongoTimeout string) *Adapter {
+func NewStorageAdapter(mongoDBAuth string, mongoDBName string, mongoTimeout string, defaultOrgID string) *Adapter {
 	timeout, err := strconv.Atoi(mongoTimeout)
 	if err != nil {
-		log.Println("Set default timeout - 500")
+		logging.Logger().Warn("Set default timeout - 500")
 		timeout = 500
 	}
 	timeoutMS := time.Millisecond * time.Duration(timeout)
 
-	db := &database{mongoDBAuth: mongoDBAuth, mongoDBName: mongoDBName, mongoTimeout: timeoutMS}
+	db := &database{mongoDBAuth: mongoDBAuth, mongoDBName: mongoDBName, mongoTimeout: timeoutMS, defaultOrgID: defaultOrgID}
 	return &Adapter{db: db}
 }
 
-// GetRewardTypes Gets all reward types
-func (sa *Adapter) GetRewardTypes(orgID string) ([]model.RewardType, error) {
+// GetRewardTypes Gets all reward types which are not deleted
+func (sa *Adapter) GetRewardTypes(ctx context.Context, orgID string) ([]model.RewardType, error) {
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "date_deleted", Value: bson.M{"$exists": false}},
 	}
 	var result []model.RewardType
-	err := sa.db.rewardTypes.Find(filter, &result, nil)
+	err := sa.db.rewardTypes.Find(ctx, filter, &result, nil)
 	if err != nil {
-		log.Printf("storage.GetRewardTypes error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetRewardTypes error: %s", err)
 		return nil, fmt.Errorf("storage.GetRewardTypes error: %s", err)
 	}
 	if result == nil {
@@ -71,61 +131,64 @@ func (sa *Adapter) GetRewardTypes(orgID string) ([]model.RewardType, error) {
 }
 
 // GetRewardType Gets a reward type by id
-func (sa *Adapter) GetRewardType(orgID string, id string) (*model.RewardType, error) {
+func (sa *Adapter) GetRewardType(ctx context.Context, orgID string, id string) (*model.RewardType, error) {
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
 		primitive.E{Key: "_id", Value: id},
 	}
 	var result []model.RewardType
-	err := sa.db.rewardTypes.Find(filter, &result, nil)
+	err := sa.db.rewardTypes.Find(ctx, filter, &result, nil)
 	if err != nil {
 		return nil, err
 	}
 	if result == nil || len(result) == 0 {
-		log.Printf("storage.GetRewardType error: %s", err)
-		return nil, fmt.Errorf("storage.GetRewardType error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetRewardType error: unable to find reward type with id: %s", id)
+		return nil, model.NewNotFoundError("unable to find reward type with id: %s", id)
 	}
 	return &result[0], nil
 }
 
 // GetRewardTypeByType Gets a reward type by type
-func (sa *Adapter) GetRewardTypeByType(orgID string, rewardType string) (*model.RewardType, error) {
+func (sa *Adapter) GetRewardTypeByType(ctx context.Context, orgID string, rewardType string) (*model.RewardType, error) {
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
 		primitive.E{Key: "reward_type", Value: rewardType},
 	}
 	var result []model.RewardType
-	err := sa.db.rewardTypes.Find(filter, &result, nil)
+	err := sa.db.rewardTypes.Find(ctx, filter, &result, nil)
 	if err != nil {
 		return nil, err
 	}
 	if result == nil || len(result) == 0 {
-		log.Printf("storage.GetRewardTypeByType error: %s", err)
-		return nil, fmt.Errorf("storage.GetRewardTypeByType error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetRewardTypeByType error: unable to find reward type: %s", rewardType)
+		return nil, model.NewNotFoundError("unable to find reward type: %s", rewardType)
 	}
 	return &result[0], nil
 }
 
 // CreateRewardType creates a new reward type
-func (sa *Adapter) CreateRewardType(orgID string, item model.RewardType) (*model.RewardType, error) {
+func (sa *Adapter) CreateRewardType(ctx context.Context, orgID string, item model.RewardType) (*model.RewardType, error) {
 	now := time.Now().UTC()
 	item.ID = uuid.NewString()
 	item.OrgID = orgID
 	item.DateCreated = now
 	item.DateUpdated = now
-	_, err := sa.db.rewardTypes.InsertOne(&item)
+	_, err := sa.db.rewardTypes.InsertOne(ctx, &item)
 	if err != nil {
-		log.Printf("storage.CreateRewardType error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.CreateRewardType error: %s", err)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, model.NewConflictError("reward type %s already exists", item.RewardType)
+		}
 		return nil, fmt.Errorf("storage.CreateRewardType error: %s", err)
 	}
 	return &item, nil
 }
 
 // UpdateRewardType updates a reward type
-func (sa *Adapter) UpdateRewardType(orgID string, id string, item model.RewardType) (*model.RewardType, error) {
+func (sa *Adapter) UpdateRewardType(ctx context.Context, orgID string, id string, item model.RewardType) (*model.RewardType, error) {
 	jsonID := item.ID
 	if jsonID != id {
-		return nil, fmt.Errorf("storage.UpdateRewardType attempt to override another object")
+		return nil, model.NewValidationError("the id of the item does not match the id in the path")
 	}
 
 	now := time.Now().UTC()
@@ -137,13 +200,17 @@ func (sa *Adapter) UpdateRewardType(orgID string, id string, item model.RewardTy
 		primitive.E{Key: "$set", Value: bson.D{
 			primitive.E{Key: "display_name", Value: item.DisplayName},
 			primitive.E{Key: "active", Value: item.Active},
+			primitive.E{Key: "currency", Value: item.Currency},
 			primitive.E{Key: "description", Value: item.Description},
+			primitive.E{Key: "inventory_backed", Value: item.InventoryBacked},
+			primitive.E{Key: "allocation_strategy", Value: item.AllocationStrategy},
+			primitive.E{Key: "preferred_inventory_ids", Value: item.PreferredInventoryIDs},
 			primitive.E{Key: "date_updated", Value: now},
 		}},
 	}
-	_, err := sa.db.rewardInventories.UpdateOne(filter, update, nil)
+	_, err := sa.db.rewardTypes.UpdateOne(ctx, filter, update, nil)
 	if err != nil {
-		log.Printf("storage.UpdateRewardType error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.UpdateRewardType error: %s", err)
 		return nil, fmt.Errorf("storage.UpdateRewardType error: %s", err)
 	}
 
@@ -152,29 +219,132 @@ func (sa *Adapter) UpdateRewardType(orgID string, id string, item model.RewardTy
 	return &item, nil
 }
 
-// DeleteRewardType deletes a reward type
-func (sa *Adapter) DeleteRewardType(orgID string, id string) error {
-	// TBD check and deny if the reward type is in use!!!
-
-	filter := bson.D{primitive.E{Key: "_id", Value: id}}
-	_, err := sa.db.rewardInventories.DeleteOne(filter, nil)
+// DeleteRewardType deletes a reward type. The references to it must be checked by the caller
+func (sa *Adapter) DeleteRewardType(ctx context.Context, orgID string, id string) error {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	_, err := sa.db.rewardTypes.DeleteOne(ctx, filter, nil)
 	if err != nil {
-		log.Printf("storage.DeleteRewardType error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.DeleteRewardType error: %s", err)
 		return fmt.Errorf("storage.DeleteRewardType error: %s", err)
 	}
 
 	return nil
 }
 
-// GetRewardOperations Gets all reward operations
-func (sa *Adapter) GetRewardOperations(orgID string) ([]model.RewardOperation, error) {
+// SoftDeleteRewardType marks a reward type as deleted. It is hidden from the listings but keeps resolving by id and type
+func (sa *Adapter) SoftDeleteRewardType(ctx context.Context, orgID string, id string) error {
+	now := time.Now().UTC()
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "date_deleted", Value: now},
+			primitive.E{Key: "active", Value: false},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+	}
+	_, err := sa.db.rewardTypes.UpdateOne(ctx, filter, update, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.SoftDeleteRewardType error: %s", err)
+		return fmt.Errorf("storage.SoftDeleteRewardType error: %s", err)
+	}
+
+	return nil
+}
+
+// GetRewardTypeReferences Gets the entities which refer to a reward type grouped by resource
+func (sa *Adapter) GetRewardTypeReferences(ctx context.Context, orgID string, rewardType string) ([]model.Reference, error) {
+	queries := []struct {
+		resource   string
+		collection *collectionWrapper
+		filter     bson.D
+	}{
+		{"operations", sa.db.rewardOperations, bson.D{
+			primitive.E{Key: "org_id", Value: orgID},
+			primitive.E{Key: "reward_type", Value: rewardType},
+			primitive.E{Key: "date_deleted", Value: bson.M{"$exists": false}},
+		}},
+		{"inventories", sa.db.rewardInventories, bson.D{
+			primitive.E{Key: "org_id", Value: orgID},
+			primitive.E{Key: "reward_type", Value: rewardType},
+		}},
+		{"catalog", sa.db.rewardCatalog, bson.D{
+			primitive.E{Key: "org_id", Value: orgID},
+			primitive.E{Key: "$or", Value: bson.A{
+				bson.M{"reward_type": rewardType},
+				bson.M{"price.reward_type": rewardType},
+			}},
+		}},
+		{"history", sa.db.rewardHistory, bson.D{
+			primitive.E{Key: "org_id", Value: orgID},
+			primitive.E{Key: "reward_type", Value: rewardType},
+		}},
+		{"claims", sa.db.rewardClaims, bson.D{
+			primitive.E{Key: "org_id", Value: orgID},
+			primitive.E{Key: "items.reward_type", Value: rewardType},
+		}},
+	}
+
+	references := []model.Reference{}
+	for _, query := range queries {
+		reference, err := sa.findReferences(ctx, query.collection, query.resource, query.filter)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("storage.GetRewardTypeReferences error: %s", err)
+			return nil, fmt.Errorf("storage.GetRewardTypeReferences error: %s", err)
+		}
+		if reference != nil {
+			references = append(references, *reference)
+		}
+	}
+	return references, nil
+}
+
+// findReferences counts the entities matching the filter and gives a sample of their ids. It gives nil if there are none
+func (sa *Adapter) findReferences(ctx context.Context, collection *collectionWrapper, resource string, filter bson.D) (*model.Reference, error) {
+	count, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, nil
+	}
+
+	findOptions := options.Find()
+	findOptions.SetLimit(referenceSampleSize)
+	findOptions.SetProjection(bson.D{primitive.E{Key: "_id", Value: 1}})
+	var result []struct {
+		ID string `bson:"_id"`
+	}
+	err = collection.Find(ctx, filter, &result, findOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(result))
+	for i, item := range result {
+		ids[i] = item.ID
+	}
+	return &model.Reference{Resource: resource, Count: count, IDs: ids}, nil
+}
+
+// GetRewardOperations Gets all reward operations which are not deleted
+func (sa *Adapter) GetRewardOperations(ctx context.Context, orgID string, archived *bool) ([]model.RewardOperation, error) {
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "date_deleted", Value: bson.M{"$exists": false}},
+	}
+	if archived != nil {
+		filter = append(filter, archivedFilter(*archived))
 	}
 	var result []model.RewardOperation
-	err := sa.db.rewardOperations.Find(filter, &result, nil)
+	err := sa.db.rewardOperations.Find(ctx, filter, &result, nil)
 	if err != nil {
-		log.Printf("storage.GetRewardOperations error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetRewardOperations error: %s", err)
 		return nil, fmt.Errorf("storage.GetRewardOperations error: %s", err)
 	}
 	if result == nil {
@@ -184,61 +354,66 @@ func (sa *Adapter) GetRewardOperations(orgID string) ([]model.RewardOperation, e
 }
 
 // GetRewardOperationByID Gets a reward operation by id
-func (sa *Adapter) GetRewardOperationByID(orgID string, id string) (*model.RewardOperation, error) {
+func (sa *Adapter) GetRewardOperationByID(ctx context.Context, orgID string, id string) (*model.RewardOperation, error) {
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
 		primitive.E{Key: "_id", Value: id},
 	}
 	var result []model.RewardOperation
-	err := sa.db.rewardOperations.Find(filter, &result, nil)
+	err := sa.db.rewardOperations.Find(ctx, filter, &result, nil)
 	if err != nil {
 		return nil, err
 	}
 	if result == nil || len(result) == 0 {
-		log.Printf("storage.GetRewardOperationByID error: %s", err)
-		return nil, fmt.Errorf("storage.GetRewardOperationByID error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetRewardOperationByID error: unable to find reward operation with id: %s", id)
+		return nil, model.NewNotFoundError("unable to find reward operation with id: %s", id)
 	}
 	return &result[0], nil
 }
 
-// GetRewardOperationByCode Gets a reward type by code
-func (sa *Adapter) GetRewardOperationByCode(orgID string, code string) (*model.RewardOperation, error) {
+// GetRewardOperationByCode Gets the reward operation of a building block by code. Deleted operations are not found
+func (sa *Adapter) GetRewardOperationByCode(ctx context.Context, orgID string, code string, buildingBlock string) (*model.RewardOperation, error) {
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
 		primitive.E{Key: "code", Value: code},
+		primitive.E{Key: "building_block", Value: buildingBlock},
+		primitive.E{Key: "date_deleted", Value: bson.M{"$exists": false}},
 	}
 	var result []model.RewardOperation
-	err := sa.db.rewardOperations.Find(filter, &result, nil)
+	err := sa.db.rewardOperations.Find(ctx, filter, &result, nil)
 	if err != nil {
 		return nil, err
 	}
 	if result == nil || len(result) == 0 {
-		log.Printf("storage.GetRewardOperationByCode error: unable to find reward operation with code: %s", code)
-		return nil, fmt.Errorf("storage.GetRewardOperationByCode error: unable to find reward operation with code: %s", code)
+		logging.FromContext(ctx).Errorf("storage.GetRewardOperationByCode error: unable to find reward operation with code: %s for %s", code, buildingBlock)
+		return nil, model.NewNotFoundError("unable to find reward operation with code: %s for %s", code, buildingBlock)
 	}
 	return &result[0], nil
 }
 
 // CreateRewardOperation creates a new reward operation
-func (sa *Adapter) CreateRewardOperation(orgID string, item model.RewardOperation) (*model.RewardOperation, error) {
+func (sa *Adapter) CreateRewardOperation(ctx context.Context, orgID string, item model.RewardOperation) (*model.RewardOperation, error) {
 	now := time.Now().UTC()
 	item.ID = uuid.NewString()
 	item.OrgID = orgID
 	item.DateCreated = now
 	item.DateUpdated = now
-	_, err := sa.db.rewardOperations.InsertOne(&item)
+	_, err := sa.db.rewardOperations.InsertOne(ctx, &item)
 	if err != nil {
-		log.Printf("storage.CreateRewardOperation error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.CreateRewardOperation error: %s", err)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, model.NewConflictError("reward operation %s already exists for %s", item.Code, item.BuildingBlock)
+		}
 		return nil, fmt.Errorf("storage.CreateRewardOperation error: %s", err)
 	}
 	return &item, nil
 }
 
 // UpdateRewardOperation updates a reward operation
-func (sa *Adapter) UpdateRewardOperation(orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error) {
+func (sa *Adapter) UpdateRewardOperation(ctx context.Context, orgID string, id string, item model.RewardOperation) (*model.RewardOperation, error) {
 	jsonID := item.ID
 	if jsonID != id {
-		return nil, fmt.Errorf("storage.UpdateRewardOperation attempt to override another object")
+		return nil, model.NewValidationError("the id of the item does not match the id in the path")
 	}
 
 	now := time.Now().UTC()
@@ -254,9 +429,9 @@ func (sa *Adapter) UpdateRewardOperation(orgID string, id string, item model.Rew
 		},
 		},
 	}
-	_, err := sa.db.rewardOperations.UpdateOne(filter, update, nil)
+	_, err := sa.db.rewardOperations.UpdateOne(ctx, filter, update, nil)
 	if err != nil {
-		log.Printf("storage.UpdateRewardOperation error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.UpdateRewardOperation error: %s", err)
 		return nil, fmt.Errorf("storage.UpdateRewardOperation error: %s", err)
 	}
 
@@ -265,48 +440,76 @@ func (sa *Adapter) UpdateRewardOperation(orgID string, id string, item model.Rew
 	return &item, nil
 }
 
-// DeleteRewardOperation deletes a reward operation
-func (sa *Adapter) DeleteRewardOperation(orgID string, id string) error {
-	// TBD check and deny if the reward type is in use!!!
-
+// DeleteRewardOperation deletes a reward operation. The references to it must be checked by the caller
+func (sa *Adapter) DeleteRewardOperation(ctx context.Context, orgID string, id string) error {
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
 		primitive.E{Key: "_id", Value: id},
 	}
-	_, err := sa.db.rewardOperations.DeleteOne(filter, nil)
+	_, err := sa.db.rewardOperations.DeleteOne(ctx, filter, nil)
 	if err != nil {
-		log.Printf("storage.DeleteRewardOperation error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.DeleteRewardOperation error: %s", err)
 		return fmt.Errorf("storage.DeleteRewardOperation error: %s", err)
 	}
 
 	return nil
 }
 
-// GetRewardInventories Gets all reward inventories
-func (sa *Adapter) GetRewardInventories(orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, limit *int64, offset *int64) ([]model.RewardInventory, error) {
+// SetRewardOperationArchived archives or unarchives a reward operation
+func (sa *Adapter) SetRewardOperationArchived(ctx context.Context, orgID string, id string, archived bool) error {
+	err := sa.setArchived(ctx, sa.db.rewardOperations, orgID, id, archived)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.SetRewardOperationArchived error: %s", err)
+		return fmt.Errorf("storage.SetRewardOperationArchived error: %w", err)
+	}
+	return nil
+}
+
+// SoftDeleteRewardOperation marks a reward operation as deleted. It is hidden from the listings and cannot grant rewards
+func (sa *Adapter) SoftDeleteRewardOperation(ctx context.Context, orgID string, id string) error {
+	now := time.Now().UTC()
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
 	}
-
-	if len(ids) > 0 {
-		filter = append(filter, primitive.E{Key: "_id", Value: bson.M{"$in": ids}})
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "date_deleted", Value: now},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
 	}
-
-	if rewardType != nil {
-		filter = append(filter, primitive.E{Key: "reward_type", Value: *rewardType})
+	_, err := sa.db.rewardOperations.UpdateOne(ctx, filter, update, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.SoftDeleteRewardOperation error: %s", err)
+		return fmt.Errorf("storage.SoftDeleteRewardOperation error: %s", err)
 	}
 
-	if inStock != nil {
-		filter = append(filter, primitive.E{Key: "in_stock", Value: *inStock})
-	}
+	return nil
+}
 
-	if grantDepleted != nil {
-		filter = append(filter, primitive.E{Key: "grant_depleted", Value: *grantDepleted})
+// GetRewardOperationReferences Gets the entities which refer to a reward operation grouped by resource
+func (sa *Adapter) GetRewardOperationReferences(ctx context.Context, orgID string, code string, buildingBlock string) ([]model.Reference, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "code", Value: code},
+		primitive.E{Key: "building_block", Value: buildingBlock},
+	}
+	reference, err := sa.findReferences(ctx, sa.db.rewardHistory, "history", filter)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetRewardOperationReferences error: %s", err)
+		return nil, fmt.Errorf("storage.GetRewardOperationReferences error: %s", err)
 	}
 
-	if claimDepleted != nil {
-		filter = append(filter, primitive.E{Key: "claim_depleted", Value: *claimDepleted})
+	references := []model.Reference{}
+	if reference != nil {
+		references = append(references, *reference)
 	}
+	return references, nil
+}
+
+// GetRewardInventories Gets all reward inventories
+func (sa *Adapter) GetRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64) ([]model.RewardInventory, error) {
+	filter := rewardInventoriesFilter(orgID, ids, rewardType, inStock, grantDepleted, claimDepleted, archived)
 
 	findOptions := options.FindOptions{
 		Sort: bson.D{{Key: "date_created", Value: -1}},
@@ -318,36 +521,36 @@ func (sa *Adapter) GetRewardInventories(orgID string, ids []string, rewardType *
 		findOptions.SetSkip(*offset)
 	}
 	var result []model.RewardInventory
-	err := sa.db.rewardInventories.Find(filter, &result, &options.FindOptions{
+	err := sa.db.rewardInventories.Find(ctx, filter, &result, &options.FindOptions{
 		Sort: bson.D{{Key: "date_created", Value: 1}},
 	})
 	if err != nil {
-		log.Printf("storage.GetRewardInventories error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetRewardInventories error: %s", err)
 		return nil, fmt.Errorf("storage.GetRewardInventories error: %s", err)
 	}
 	return result, nil
 }
 
 // GetRewardInventory Gets a reward inventory by id
-func (sa *Adapter) GetRewardInventory(orgID string, id string) (*model.RewardInventory, error) {
+func (sa *Adapter) GetRewardInventory(ctx context.Context, orgID string, id string) (*model.RewardInventory, error) {
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
 		primitive.E{Key: "_id", Value: id},
 	}
 	var result []model.RewardInventory
-	err := sa.db.rewardInventories.Find(filter, &result, nil)
+	err := sa.db.rewardInventories.Find(ctx, filter, &result, nil)
 	if err != nil {
 		return nil, err
 	}
 	if result == nil || len(result) == 0 {
-		log.Printf("storage.GetRewardInventory error: %s", err)
-		return nil, fmt.Errorf("storage.GetRewardInventory error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetRewardInventory error: unable to find reward inventory with id: %s", id)
+		return nil, model.NewNotFoundError("unable to find reward inventory with id: %s", id)
 	}
 	return &result[0], nil
 }
 
 // CreateRewardInventory creates a new reward inventory
-func (sa *Adapter) CreateRewardInventory(orgID string, item model.RewardInventory) (*model.RewardInventory, error) {
+func (sa *Adapter) CreateRewardInventory(ctx context.Context, orgID string, item model.RewardInventory) (*model.RewardInventory, error) {
 	now := time.Now().UTC()
 	item.ID = uuid.NewString()
 	item.DateCreated = now
@@ -358,27 +561,19 @@ func (sa *Adapter) CreateRewardInventory(orgID string, item model.RewardInventor
 		return nil, err
 	}
 
-	_, err := sa.db.rewardInventories.InsertOne(&item)
+	_, err := sa.db.rewardInventories.InsertOne(ctx, &item)
 	if err != nil {
-		log.Printf("storage.CreateRewardInventory error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.CreateRewardInventory error: %s", err)
 		return nil, fmt.Errorf("storage.CreateRewardInventory error: %s", err)
 	}
 	return &item, nil
 }
 
 // UpdateRewardInventory updates a reward pool
-func (sa *Adapter) UpdateRewardInventory(orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error) {
-	return sa.UpdateRewardInventoryWithContext(nil, orgID, id, item)
-}
-
-// UpdateRewardInventoryWithContext updates a reward inventory with a context
-func (sa *Adapter) UpdateRewardInventoryWithContext(ctx context.Context, orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error) {
-	if ctx == nil {
-		ctx = context.Background()
-	}
+func (sa *Adapter) UpdateRewardInventory(ctx context.Context, orgID string, id string, item model.RewardInventory) (*model.RewardInventory, error) {
 	jsonID := item.ID
 	if jsonID != id || orgID != item.OrgID {
-		return nil, fmt.Errorf("storage.UpdateRewardInventory attempt to override another object")
+		return nil, model.NewValidationError("the id of the item does not match the id in the path")
 	}
 
 	if err := sa.validateInventoryCreateOrUpdate(item); err != nil {
@@ -389,7 +584,10 @@ func (sa *Adapter) UpdateRewardInventoryWithContext(ctx context.Context, orgID s
 	item.ClaimDepleted = item.AmountTotal <= item.AmountClaimed
 
 	now := time.Now().UTC()
-	filter := bson.D{primitive.E{Key: "_id", Value: id}}
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
 	update := bson.D{
 		primitive.E{Key: "$set", Value: bson.D{
 			primitive.E{Key: "date_updated", Value: now},
@@ -399,13 +597,14 @@ func (sa *Adapter) UpdateRewardInventoryWithContext(ctx context.Context, orgID s
 			primitive.E{Key: "grant_depleted", Value: item.GrantDepleted},
 			primitive.E{Key: "claim_depleted", Value: item.ClaimDepleted},
 			primitive.E{Key: "in_stock", Value: item.InStock},
+			primitive.E{Key: "location_ids", Value: item.LocationIDs},
 			primitive.E{Key: "description", Value: item.Description},
 		},
 		},
 	}
-	_, err := sa.db.rewardInventories.UpdateOneWithContext(ctx, filter, update, nil)
+	_, err := sa.db.rewardInventories.UpdateOne(ctx, filter, update, nil)
 	if err != nil {
-		log.Printf("storage.UpdateRewardInventory error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.UpdateRewardInventory error: %s", err)
 		return nil, fmt.Errorf("storage.UpdateRewardInventory error: %s", err)
 	}
 
@@ -416,51 +615,91 @@ func (sa *Adapter) UpdateRewardInventoryWithContext(ctx context.Context, orgID s
 
 func (sa *Adapter) validateInventoryCreateOrUpdate(item model.RewardInventory) error {
 	if item.AmountTotal <= 0 {
-		return fmt.Errorf("inventory amount is zero or negative")
+		return model.NewValidationError("inventory amount is zero or negative")
 	} else if item.AmountGranted > item.AmountTotal {
-		return fmt.Errorf("quantity granted is greater than the total amount")
+		return model.NewValidationError("quantity granted is greater than the total amount")
 	} else if item.AmountClaimed > item.AmountTotal {
-		return fmt.Errorf("quantity claimed is greater than the total amount")
+		return model.NewValidationError("quantity claimed is greater than the total amount")
 	}
 
 	return nil
 }
 
 // DeleteRewardInventory deletes a reward pool. Don't delete if it's in use!
-func (sa *Adapter) DeleteRewardInventory(orgID string, id string) error {
+func (sa *Adapter) DeleteRewardInventory(ctx context.Context, orgID string, id string) error {
 	// TBD check and deny if the reward type is in use!!!
 
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
 		primitive.E{Key: "_id", Value: id},
 	}
-	_, err := sa.db.rewardInventories.DeleteOne(filter, nil)
+	_, err := sa.db.rewardInventories.DeleteOne(ctx, filter, nil)
 	if err != nil {
-		log.Printf("storage.DeleteRewardInventory error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.DeleteRewardInventory error: %s", err)
 		return fmt.Errorf("storage.DeleteRewardInventory error: %s", err)
 	}
 
 	return nil
 }
 
-// GetUserRewardsHistory Gets all reward history entries
-func (sa *Adapter) GetUserRewardsHistory(orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error) {
+// SetRewardInventoryArchived archives or unarchives a reward inventory
+func (sa *Adapter) SetRewardInventoryArchived(ctx context.Context, orgID string, id string, archived bool) error {
+	err := sa.setArchived(ctx, sa.db.rewardInventories, orgID, id, archived)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.SetRewardInventoryArchived error: %s", err)
+		return fmt.Errorf("storage.SetRewardInventoryArchived error: %w", err)
+	}
+	return nil
+}
+
+// setArchived sets the archived flag and the archive date of an entity
+func (sa *Adapter) setArchived(ctx context.Context, collection *collectionWrapper, orgID string, id string, archived bool) error {
+	now := time.Now().UTC()
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "user_id", Value: userID},
+		primitive.E{Key: "_id", Value: id},
 	}
-
-	if rewardType != nil {
-		filter = append(filter, primitive.E{Key: "reward_type", Value: *rewardType})
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "archived", Value: true},
+			primitive.E{Key: "archived_at", Value: now},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+	}
+	if !archived {
+		update = bson.D{
+			primitive.E{Key: "$set", Value: bson.D{
+				primitive.E{Key: "archived", Value: false},
+				primitive.E{Key: "date_updated", Value: now},
+			}},
+			primitive.E{Key: "$unset", Value: bson.D{
+				primitive.E{Key: "archived_at", Value: ""},
+			}},
+		}
 	}
 
-	if code != nil {
-		filter = append(filter, primitive.E{Key: "code", Value: *code})
+	result, err := collection.UpdateOne(ctx, filter, update, nil)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return model.NewNotFoundError("unable to find %s with id: %s", collection.coll.Name(), id)
 	}
+	return nil
+}
 
-	if rewardType != nil {
-		filter = append(filter, primitive.E{Key: "building_block", Value: *buildingBlock})
+// archivedFilter matches the archived entities or the ones which are not archived. The entities
+// created before archiving was introduced have no archived field.
+func archivedFilter(archived bool) primitive.E {
+	if archived {
+		return primitive.E{Key: "archived", Value: true}
 	}
+	return primitive.E{Key: "archived", Value: bson.M{"$ne": true}}
+}
+
+// GetUserRewardsHistory Gets all reward history entries
+func (sa *Adapter) GetUserRewardsHistory(ctx context.Context, orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error) {
+	filter := rewardHistoryFilter(orgID, &userID, rewardType, code, buildingBlock, nil, nil)
 
 	findOptions := options.FindOptions{
 		Sort: bson.D{{Key: "date_created", Value: -1}},
@@ -473,9 +712,9 @@ func (sa *Adapter) GetUserRewardsHistory(orgID string, userID string, rewardType
 	}
 
 	var result []model.Reward
-	err := sa.db.rewardHistory.Find(filter, &result, &findOptions)
+	err := sa.db.rewardHistory.Find(ctx, filter, &result, &findOptions)
 	if err != nil {
-		log.Printf("storage.getUserRewardsHistory error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.getUserRewardsHistory error: %s", err)
 		return nil, fmt.Errorf("storage.getUserRewardsHistory error: %s", err)
 	}
 	if result == nil {
@@ -485,25 +724,26 @@ func (sa *Adapter) GetUserRewardsHistory(orgID string, userID string, rewardType
 }
 
 // GetUserRewardByID Gets a reward history entry by id
-func (sa *Adapter) GetUserRewardByID(orgID string, userID, id string) (*model.Reward, error) {
+func (sa *Adapter) GetUserRewardByID(ctx context.Context, orgID string, userID, id string) (*model.Reward, error) {
 	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
 		primitive.E{Key: "_id", Value: id},
 		primitive.E{Key: "user_id", Value: userID},
 	}
 	var result []model.Reward
-	err := sa.db.rewardHistory.Find(filter, &result, nil)
+	err := sa.db.rewardHistory.Find(ctx, filter, &result, nil)
 	if err != nil {
 		return nil, err
 	}
 	if result == nil || len(result) == 0 {
-		log.Printf("storage.GetUserRewardByID error: %s", err)
-		return nil, fmt.Errorf("storage.GetUserRewardByID error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetUserRewardByID error: unable to find reward with id: %s", id)
+		return nil, model.NewNotFoundError("unable to find reward with id: %s", id)
 	}
 	return &result[0], nil
 }
 
 // CreateUserReward creates a new reward history entry
-func (sa *Adapter) CreateUserReward(orgID string, item model.Reward) (*model.Reward, error) {
+func (sa *Adapter) CreateUserReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
 
 	now := time.Now().UTC()
 	item.ID = uuid.NewString()
@@ -511,58 +751,56 @@ func (sa *Adapter) CreateUserReward(orgID string, item model.Reward) (*model.Rew
 	item.DateUpdated = now
 	item.OrgID = orgID
 
-	err := sa.db.dbClient.UseSession(context.Background(), func(sessionContext mongo.SessionContext) error {
+	depleted := 0
+	err := sa.db.dbClient.UseSession(ctx, func(sessionContext mongo.SessionContext) error {
 		err := sessionContext.StartTransaction()
 		if err != nil {
-			log.Printf("error starting a transaction - %s", err)
+			logging.FromContext(sessionContext).Errorf("error starting a transaction - %s", err)
 			return err
 		}
 
 		grantDepleted := false
-		inventories, err := sa.GetRewardInventories(orgID, nil, &item.RewardType, nil, &grantDepleted, nil, nil, nil)
+		archived := false
+		inventories, err := sa.GetRewardInventories(sessionContext, orgID, nil, &item.RewardType, nil, &grantDepleted, nil, &archived, nil, nil)
 		if err != nil {
-			log.Printf("storage.CreateUserReward error: %s", err)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserReward error: %s", err)
 			return fmt.Errorf("storage.CreateUserReward error: %s", err)
 		}
 
-		if len(inventories) > 0 {
-			accumulatedAmount := 0
-			remaingAmnount := item.Amount
-			for _, inventory := range inventories {
-				grantableAmount := inventory.GetGrantableAmount()
-				if grantableAmount > 0 {
-					if grantableAmount < remaingAmnount {
-						inventory.AmountGranted += grantableAmount
-						accumulatedAmount += grantableAmount
-						remaingAmnount -= grantableAmount
-					} else {
-						inventory.AmountGranted += remaingAmnount
-						accumulatedAmount += remaingAmnount
-						remaingAmnount -= remaingAmnount
-					}
-					_, err = sa.UpdateRewardInventoryWithContext(sessionContext, orgID, inventory.ID, inventory)
-					if err != nil {
-						abortTransaction(sessionContext)
-						log.Printf("storage.CreateUserReward error: %s", err)
-						return fmt.Errorf("storage.CreateUserReward error: %s", err)
-					}
-					if accumulatedAmount == item.Amount {
-						break
-					}
-				}
-			}
+		grantable := func(inventory model.RewardInventory) int { return inventory.GetGrantableAmount() }
+		allocations, err := sa.allocateRewardInventories(sessionContext, orgID, item.RewardType, inventories, item.Amount, grantable)
+		if err != nil {
+			abortTransaction(sessionContext)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserReward error: %s", err)
+			return err
+		}
 
-			if accumulatedAmount < item.Amount {
+		for _, allocation := range allocations {
+			inventory := findInventory(inventories, allocation.InventoryID)
+			inventory.AmountGranted += allocation.Amount
+			_, err = sa.UpdateRewardInventory(sessionContext, orgID, inventory.ID, *inventory)
+			if err != nil {
 				abortTransaction(sessionContext)
-				log.Printf("storage.CreateUserReward insuficient amount in the inventory for: %s", item.RewardType)
-				return fmt.Errorf("storage.CreateUserReward insuficient amount in the inventory for: %s", item.RewardType)
+				logging.FromContext(sessionContext).Errorf("storage.CreateUserReward error: %s", err)
+				return fmt.Errorf("storage.CreateUserReward error: %s", err)
+			}
+			if inventory.AmountTotal <= inventory.AmountGranted {
+				depleted++
 			}
 		}
+		item.Allocations = allocations
 
-		_, err = sa.db.rewardHistory.InsertOneWithContext(sessionContext, &item)
+		_, err = sa.db.rewardHistory.InsertOne(sessionContext, &item)
 		if err != nil {
 			abortTransaction(sessionContext)
-			log.Printf("storage.CreateUserReward error: %s", err)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserReward error: %s", err)
+			return fmt.Errorf("storage.CreateUserReward error: %s", err)
+		}
+
+		err = sa.addLeaderboardScores(sessionContext, orgID, item)
+		if err != nil {
+			abortTransaction(sessionContext)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserReward error: %s", err)
 			return fmt.Errorf("storage.CreateUserReward error: %s", err)
 		}
 
@@ -570,22 +808,130 @@ func (sa *Adapter) CreateUserReward(orgID string, item model.Reward) (*model.Rew
 		err = sessionContext.CommitTransaction(sessionContext)
 		if err != nil {
 			abortTransaction(sessionContext)
-			fmt.Println(err)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserReward commit error: %s", err)
 			return err
 		}
 		return nil
 	})
 
 	if err != nil {
-		log.Printf("storage.CreateUserReward transaction error: %s", err)
-		return nil, fmt.Errorf("storage.CreateUserReward transaction error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.CreateUserReward transaction error: %s", err)
+		return nil, fmt.Errorf("storage.CreateUserReward transaction error: %w", err)
+	}
+	if depleted > 0 {
+		metrics.InventoryDepletions.WithLabelValues("grant", item.RewardType).Add(float64(depleted))
+	}
+
+	return &item, nil
+}
+
+// CreateUserRewardDebit stores a negative reward history entry which takes the amount back from the balance of the
+// user. The balance must cover the amount and the granted amount of the inventories backing the reward type is released
+func (sa *Adapter) CreateUserRewardDebit(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
+	if item.Amount >= 0 {
+		return nil, model.NewValidationError("the amount of a debit is zero or a positive value")
 	}
 
+	now := time.Now().UTC()
+	item.ID = uuid.NewString()
+	item.DateCreated = now
+	item.DateUpdated = now
+	item.OrgID = orgID
+
+	err := sa.db.dbClient.UseSession(ctx, func(sessionContext mongo.SessionContext) error {
+		err := sessionContext.StartTransaction()
+		if err != nil {
+			logging.FromContext(sessionContext).Errorf("error starting a transaction - %s", err)
+			return err
+		}
+
+		balances, err := sa.lockUserBalances(sessionContext, orgID, item.UserID)
+		if err != nil {
+			abortTransaction(sessionContext)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserRewardDebit error: %s", err)
+			return fmt.Errorf("storage.CreateUserRewardDebit error: %s", err)
+		}
+		if balance := balances[item.RewardType]; balance < -item.Amount {
+			abortTransaction(sessionContext)
+			return model.NewInsufficientBalanceError("not enough %s. Expected: %d, but have: %d", item.RewardType, -item.Amount, balance)
+		}
+
+		allocations, err := sa.releaseRewardInventories(sessionContext, orgID, item.RewardType, -item.Amount)
+		if err != nil {
+			abortTransaction(sessionContext)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserRewardDebit error: %s", err)
+			return fmt.Errorf("storage.CreateUserRewardDebit error: %s", err)
+		}
+		item.Allocations = allocations
+
+		_, err = sa.db.rewardHistory.InsertOne(sessionContext, &item)
+		if err != nil {
+			abortTransaction(sessionContext)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserRewardDebit error: %s", err)
+			return fmt.Errorf("storage.CreateUserRewardDebit error: %s", err)
+		}
+
+		err = sa.addLeaderboardScores(sessionContext, orgID, item)
+		if err != nil {
+			abortTransaction(sessionContext)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserRewardDebit error: %s", err)
+			return fmt.Errorf("storage.CreateUserRewardDebit error: %s", err)
+		}
+
+		//commit the transaction
+		err = sessionContext.CommitTransaction(sessionContext)
+		if err != nil {
+			abortTransaction(sessionContext)
+			logging.FromContext(sessionContext).Errorf("storage.CreateUserRewardDebit commit error: %s", err)
+			return err
+		}
+		return nil
+	})
+
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.CreateUserRewardDebit transaction error: %s", err)
+		return nil, fmt.Errorf("storage.CreateUserRewardDebit transaction error: %w", err)
+	}
 	return &item, nil
 }
 
+// releaseRewardInventories gives back the granted but not claimed amount of the inventories backing the reward type.
+// The allocations of the released amounts are negative
+func (sa *Adapter) releaseRewardInventories(sessionContext mongo.SessionContext, orgID string, rewardType string, amount int) ([]model.InventoryAllocation, error) {
+	item, err := sa.GetRewardTypeByType(sessionContext, orgID, rewardType)
+	if err != nil {
+		return nil, err
+	}
+	if item == nil || !item.InventoryBacked {
+		return nil, nil
+	}
+
+	inventories, err := sa.GetRewardInventories(sessionContext, orgID, nil, &rewardType, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	var allocations []model.InventoryAllocation
+	for _, inventory := range inventories {
+		released := min(amount, inventory.AmountGranted-inventory.AmountClaimed)
+		if released <= 0 {
+			continue
+		}
+		inventory.AmountGranted -= released
+		_, err = sa.UpdateRewardInventory(sessionContext, orgID, inventory.ID, inventory)
+		if err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, model.InventoryAllocation{InventoryID: inventory.ID, RewardType: rewardType, Amount: -released})
+		amount -= released
+		if amount == 0 {
+			break
+		}
+	}
+	return allocations, nil
+}
+
 // GetUserRewardsAmount Gets user's rewards amount
-func (sa *Adapter) GetUserRewardsAmount(orgID string, userID string, rewardType *string) ([]model.RewardTypeAmount, error) {
+func (sa *Adapter) GetUserRewardsAmount(ctx context.Context, orgID string, userID string, rewardType *string) ([]model.RewardTypeAmount, error) {
 	pipeline := []bson.M{
 		{"$match": bson.M{"org_id": orgID, "user_id": userID}},
 	}
@@ -595,9 +941,9 @@ func (sa *Adapter) GetUserRewardsAmount(orgID string, userID string, rewardType
 	pipeline = append(pipeline, bson.M{"$group": bson.M{"_id": "$reward_type", "amount": bson.M{"$sum": "$amount"}}})
 
 	var result []model.RewardTypeAmount
-	err := sa.db.rewardHistory.Aggregate(pipeline, &result, nil)
+	err := sa.db.rewardHistory.Aggregate(ctx, pipeline, &result, nil)
 	if err != nil {
-		log.Printf("storage.GetUserRewardsAmount error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetUserRewardsAmount error: %s", err)
 		return nil, fmt.Errorf("storage.GetUserRewardsAmount error: %s", err)
 	}
 
@@ -605,7 +951,7 @@ func (sa *Adapter) GetUserRewardsAmount(orgID string, userID string, rewardType
 }
 
 // GetUserClaimsAmount Gets user's claims amount
-func (sa *Adapter) GetUserClaimsAmount(orgID string, userID string, rewardType *string) ([]model.RewardTypeAmount, error) {
+func (sa *Adapter) GetUserClaimsAmount(ctx context.Context, orgID string, userID string, rewardType *string) ([]model.RewardTypeAmount, error) {
 	pipeline := []bson.M{
 		{"$match": bson.M{"org_id": orgID, "user_id": userID}},
 		{"$unwind": bson.M{"path": "$items"}},
@@ -617,21 +963,22 @@ func (sa *Adapter) GetUserClaimsAmount(orgID string, userID string, rewardType *
 	pipeline = append(pipeline, bson.M{"$group": bson.M{"_id": "$items.reward_type", "amount": bson.M{"$sum": "$items.amount"}}})
 
 	var result []model.RewardTypeAmount
-	err := sa.db.rewardClaims.Aggregate(pipeline, &result, nil)
+	err := sa.db.rewardClaims.Aggregate(ctx, pipeline, &result, nil)
 	if err != nil {
-		log.Printf("storage.GetUserClaimsAmount error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetUserClaimsAmount error: %s", err)
 		return nil, fmt.Errorf("storage.GetUserClaimsAmount error: %s", err)
 	}
 
 	return result, nil
 }
 
-// GetRewardQuantityState Gets reward quantities state for the current moment
-func (sa *Adapter) GetRewardQuantityState(orgID string, rewardType string, inStock *bool) (*model.RewardQuantityState, error) {
+// GetRewardQuantityState Gets reward quantities state for the current moment. The quantities are zero if the reward type has no inventories
+func (sa *Adapter) GetRewardQuantityState(ctx context.Context, orgID string, rewardType string, inStock *bool) (*model.RewardQuantityState, error) {
 
-	inventories, err := sa.GetRewardInventories(orgID, nil, &rewardType, inStock, nil, nil, nil, nil)
+	archived := false
+	inventories, err := sa.GetRewardInventories(ctx, orgID, nil, &rewardType, inStock, nil, nil, &archived, nil, nil)
 	if err != nil {
-		log.Printf("storage.GetRewardQuantityState error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetRewardQuantityState error: %s", err)
 		return nil, fmt.Errorf("storage.GetRewardQuantityState error: %s", err)
 	}
 
@@ -639,35 +986,41 @@ func (sa *Adapter) GetRewardQuantityState(orgID string, rewardType string, inSto
 	var grantedQuantity int = 0
 	var grantableQuantity int = 0
 	var claimableQuantity int = 0
-	if len(inventories) > 0 {
-		for _, inventory := range inventories {
-			totalQuantity += inventory.AmountTotal
-			grantedQuantity += inventory.AmountGranted
-			if inventory.InStock {
-				claimableQuantity += inventory.AmountTotal - inventory.AmountClaimed
-			}
+	for _, inventory := range inventories {
+		totalQuantity += inventory.AmountTotal
+		grantedQuantity += inventory.AmountGranted
+		if inventory.InStock {
+			claimableQuantity += inventory.AmountTotal - inventory.AmountClaimed
 		}
-		grantableQuantity = totalQuantity - grantedQuantity
-
-		return &model.RewardQuantityState{
-			RewardType:        rewardType,
-			GrantableQuantity: grantableQuantity,
-			ClaimableQuantity: claimableQuantity,
-		}, nil
 	}
+	grantableQuantity = totalQuantity - grantedQuantity
 
-	return nil, nil
+	return &model.RewardQuantityState{
+		RewardType:        rewardType,
+		InventoryBacked:   true,
+		GrantableQuantity: &grantableQuantity,
+		ClaimableQuantity: &claimableQuantity,
+	}, nil
 }
 
 // GetRewardClaims Gets all reward claims
-func (sa *Adapter) GetRewardClaims(orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error) {
-	filter := bson.D{
-		primitive.E{Key: "org_id", Value: orgID},
+func (sa *Adapter) GetRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error) {
+	filter := rewardClaimsFilter(orgID, ids, userID, rewardType, status)
+
+	findOptions := options.FindOptions{
+		Sort: bson.D{{Key: "date_created", Value: -1}},
+	}
+	if limit != nil {
+		findOptions.SetLimit(*limit)
 	}
+	if offset != nil {
+		findOptions.SetSkip(*offset)
+	}
+
 	var result []model.RewardClaim
-	err := sa.db.rewardClaims.Find(filter, &result, nil)
+	err := sa.db.rewardClaims.Find(ctx, filter, &result, &findOptions)
 	if err != nil {
-		log.Printf("storage.getRewardClaims error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.getRewardClaims error: %s", err)
 		return nil, fmt.Errorf("storage.getRewardClaims error: %s", err)
 	}
 	if result == nil {
@@ -676,85 +1029,131 @@ func (sa *Adapter) GetRewardClaims(orgID string, ids []string, userID *string, r
 	return result, nil
 }
 
-// GetRewardClaim Gets a reward claim by id
-func (sa *Adapter) GetRewardClaim(orgID string, id string) (*model.RewardClaim, error) {
+// GetRewardClaimsByLocation Gets the reward claims to be picked up at a location
+func (sa *Adapter) GetRewardClaimsByLocation(ctx context.Context, orgID string, locationID string, slotID *string, status *string) ([]model.RewardClaim, error) {
 	filter := bson.D{
 		primitive.E{Key: "org_id", Value: orgID},
-		primitive.E{Key: "_id", Value: id},
+		primitive.E{Key: "pickup.location_id", Value: locationID},
+	}
+
+	if slotID != nil {
+		filter = append(filter, primitive.E{Key: "pickup.slot_id", Value: *slotID})
 	}
+
+	if status != nil {
+		filter = append(filter, primitive.E{Key: "status", Value: *status})
+	}
+
 	var result []model.RewardClaim
-	err := sa.db.rewardClaims.Find(filter, &result, nil)
+	err := sa.db.rewardClaims.Find(ctx, filter, &result, &options.FindOptions{
+		Sort: bson.D{{Key: "date_created", Value: 1}},
+	})
 	if err != nil {
-		return nil, err
+		logging.FromContext(ctx).Errorf("storage.GetRewardClaimsByLocation error: %s", err)
+		return nil, fmt.Errorf("storage.GetRewardClaimsByLocation error: %s", err)
 	}
-	if result == nil || len(result) == 0 {
-		log.Printf("storage.getRewardClaim error: %s", err)
-		return nil, fmt.Errorf("storage.getRewardClaim error: %s", err)
+	if result == nil {
+		result = []model.RewardClaim{}
 	}
-	return &result[0], nil
+	return result, nil
 }
 
-// CreateRewardClaim creates a new reward claim
-func (sa *Adapter) CreateRewardClaim(orgID string, item model.RewardClaim) (*model.RewardClaim, error) {
+// countRewardClaimsBySlot counts the reward claims booked for a pickup time slot
+func (sa *Adapter) countRewardClaimsBySlot(ctx context.Context, orgID string, locationID string, slotID string) (int64, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "pickup.location_id", Value: locationID},
+		primitive.E{Key: "pickup.slot_id", Value: slotID},
+	}
+	count, err := sa.db.rewardClaims.CountDocuments(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.countRewardClaimsBySlot error: %s", err)
+		return 0, fmt.Errorf("storage.countRewardClaimsBySlot error: %s", err)
+	}
+	return count, nil
+}
+
+// GetRewardClaim Gets a reward claim by id
+func (sa *Adapter) GetRewardClaim(ctx context.Context, orgID string, id string) (*model.RewardClaim, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	var result []model.RewardClaim
+	err := sa.db.rewardClaims.Find(ctx, filter, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil || len(result) == 0 {
+		logging.FromContext(ctx).Errorf("storage.getRewardClaim error: unable to find reward claim with id: %s", id)
+		return nil, model.NewNotFoundError("unable to find reward claim with id: %s", id)
+	}
+	return &result[0], nil
+}
+
+// CreateRewardClaim creates a new reward claim
+func (sa *Adapter) CreateRewardClaim(ctx context.Context, orgID string, item model.RewardClaim) (*model.RewardClaim, error) {
 	now := time.Now().UTC()
 	item.ID = uuid.NewString()
 	item.OrgID = orgID
 	item.DateCreated = now
 	item.DateUpdated = now
 
-	err := sa.db.dbClient.UseSession(context.Background(), func(sessionContext mongo.SessionContext) error {
+	depleted := map[string]int{} // reward type -> depleted inventories
+	err := sa.db.dbClient.UseSession(ctx, func(sessionContext mongo.SessionContext) error {
 		err := sessionContext.StartTransaction()
 		if err != nil {
-			log.Printf("error starting a transaction - %s", err)
+			logging.FromContext(sessionContext).Errorf("error starting a transaction - %s", err)
 			return err
 		}
 
-		for _, claimEntry := range item.Items {
-			claimDepleted := false
-			inventories, err := sa.GetRewardInventories(orgID, nil, &claimEntry.RewardType, nil, nil, &claimDepleted, nil, nil)
+		err = sa.checkClaimBalance(sessionContext, orgID, item)
+		if err != nil {
+			abortTransaction(sessionContext)
+			return err
+		}
+		if item.Purchase != nil {
+			err = sa.checkPurchaseLimit(sessionContext, orgID, item)
+			if err != nil {
+				abortTransaction(sessionContext)
+				return err
+			}
+		}
+		if item.Pickup != nil && item.Pickup.SlotID != "" {
+			err = sa.bookPickupSlot(sessionContext, orgID, item.Pickup.LocationID, item.Pickup.SlotID)
 			if err != nil {
-				log.Printf("storage.CreateUserReward error: %s", err)
-				return fmt.Errorf("storage.CreateRewardClaim error: %s", err)
+				abortTransaction(sessionContext)
+				return err
 			}
+		}
 
-			if len(inventories) > 0 {
-				accumulatedAmount := 0
-				remaingAmnount := claimEntry.Amount
-				for _, inventory := range inventories {
-					claimableAmount := inventory.GetClaimableAmount()
-					if claimableAmount > 0 {
-						if claimableAmount < remaingAmnount {
-							inventory.AmountClaimed += claimableAmount
-							accumulatedAmount += claimableAmount
-							remaingAmnount -= claimableAmount
-						} else {
-							inventory.AmountClaimed += remaingAmnount
-							accumulatedAmount += remaingAmnount
-							remaingAmnount -= remaingAmnount
-						}
-						_, err = sa.UpdateRewardInventoryWithContext(sessionContext, orgID, inventory.ID, inventory)
-						if err != nil {
-							abortTransaction(sessionContext)
-							log.Printf("storage.CreateUserReward error: %s", err)
-							return fmt.Errorf("storage.CreateUserReward error: %s", err)
-						}
-						if accumulatedAmount == claimEntry.Amount {
-							break
-						}
-					}
-				}
+		locationID := ""
+		if item.Pickup != nil {
+			locationID = item.Pickup.LocationID
+		}
 
-				if accumulatedAmount < claimEntry.Amount {
-					abortTransaction(sessionContext)
-					log.Printf("storage.CreateRewardClaim insuficient amount in the inventory for: %s", claimEntry.RewardType)
-					return fmt.Errorf("storage.CreateRewardClaim insuficient amount in the inventory for: %s", claimEntry.RewardType)
-				}
+		item.Allocations = []model.InventoryAllocation{}
+		for _, claimEntry := range item.Items {
+			allocations, count, err := sa.claimRewardInventories(sessionContext, orgID, claimEntry.RewardType, claimEntry.Amount, locationID)
+			if err != nil {
+				return err
+			}
+			depleted[claimEntry.RewardType] += count
+			item.Allocations = append(item.Allocations, allocations...)
+		}
+
+		if item.Purchase != nil {
+			allocations, count, err := sa.claimRewardInventories(sessionContext, orgID, item.Purchase.RewardType, item.Purchase.Quantity, locationID)
+			if err != nil {
+				return err
 			}
+			depleted[item.Purchase.RewardType] += count
+			item.Allocations = append(item.Allocations, allocations...)
 		}
 
-		_, err = sa.db.rewardClaims.InsertOneWithContext(sessionContext, &item)
+		_, err = sa.db.rewardClaims.InsertOne(sessionContext, &item)
 		if err != nil {
-			log.Printf("storage.CreateRewardClaim error: %s", err)
+			logging.FromContext(sessionContext).Errorf("storage.CreateRewardClaim error: %s", err)
 			return fmt.Errorf("storage.CreateRewardClaim error: %s", err)
 		}
 
@@ -762,7 +1161,7 @@ func (sa *Adapter) CreateRewardClaim(orgID string, item model.RewardClaim) (*mod
 		err = sessionContext.CommitTransaction(sessionContext)
 		if err != nil {
 			abortTransaction(sessionContext)
-			fmt.Println(err)
+			logging.FromContext(sessionContext).Errorf("storage.CreateRewardClaim commit error: %s", err)
 			return err
 		}
 
@@ -770,33 +1169,248 @@ func (sa *Adapter) CreateRewardClaim(orgID string, item model.RewardClaim) (*mod
 	})
 
 	if err != nil {
-		log.Printf("storage.CreateRewardClaim transaction error: %s", err)
-		return nil, fmt.Errorf("storage.CreateRewardClaim transaction error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.CreateRewardClaim transaction error: %s", err)
+		if isTransactionConflict(err) {
+			return nil, model.NewConflictError("another claim of the user is in progress")
+		}
+		return nil, fmt.Errorf("storage.CreateRewardClaim transaction error: %w", err)
+	}
+	for rewardType, count := range depleted {
+		if count > 0 {
+			metrics.InventoryDepletions.WithLabelValues("claim", rewardType).Add(float64(count))
+		}
 	}
 
 	return &item, nil
 }
 
-// UpdateRewardClaim updates a reward claim
-func (sa *Adapter) UpdateRewardClaim(orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error) {
-	return sa.UpdateRewardClaimWithContext(nil, orgID, id, item)
+// checkClaimBalance checks the balance of the user covers the claim within the transaction. The wallet of the user is
+// written first, so a concurrent claim of the same user conflicts instead of spending the same balance
+func (sa *Adapter) checkClaimBalance(sessionContext mongo.SessionContext, orgID string, item model.RewardClaim) error {
+	balances, err := sa.lockUserBalances(sessionContext, orgID, item.UserID)
+	if err != nil {
+		logging.FromContext(sessionContext).Errorf("storage.CreateRewardClaim error: %s", err)
+		return err
+	}
+
+	amounts := item.GetAmounts()
+	checked := map[string]bool{}
+	for _, claimEntry := range item.Items {
+		if checked[claimEntry.RewardType] {
+			continue
+		}
+		checked[claimEntry.RewardType] = true
+		amount := amounts[claimEntry.RewardType]
+		if balance := balances[claimEntry.RewardType]; balance < amount {
+			return model.NewInsufficientBalanceError("not enough %s. Expected: %d, but have: %d", claimEntry.RewardType, amount, balance)
+		}
+	}
+	return nil
 }
 
-// UpdateRewardClaimWithContext updates a reward claim with a context
-func (sa *Adapter) UpdateRewardClaimWithContext(ctx context.Context, orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error) {
-	if ctx == nil {
-		ctx = context.Background()
+// lockUserBalances writes the wallet of the user within the transaction and gives the balances by reward type. The
+// concurrent transactions which spend the balance of the same user conflict on the wallet
+func (sa *Adapter) lockUserBalances(sessionContext mongo.SessionContext, orgID string, userID string) (map[string]int, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "user_id", Value: userID},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "date_updated", Value: time.Now().UTC()},
+		}},
+		primitive.E{Key: "$setOnInsert", Value: bson.D{
+			primitive.E{Key: "_id", Value: uuid.NewString()},
+		}},
+	}
+	_, err := sa.db.rewardWallets.UpdateOne(sessionContext, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return nil, err
+	}
+
+	rewardsAmount, err := sa.GetUserRewardsAmount(sessionContext, orgID, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	claimsAmount, err := sa.GetUserClaimsAmount(sessionContext, orgID, userID, nil)
+	if err != nil {
+		return nil, err
+	}
+	balances := map[string]int{}
+	for _, amount := range rewardsAmount {
+		balances[amount.RewardType] += amount.Amount
+	}
+	for _, amount := range claimsAmount {
+		balances[amount.RewardType] -= amount.Amount
+	}
+	return balances, nil
+}
+
+// checkPurchaseLimit checks the purchase is within the user limit of the catalog item. It runs after the wallet of the
+// user is written, so concurrent purchases of the same user conflict instead of exceeding the limit together
+func (sa *Adapter) checkPurchaseLimit(sessionContext mongo.SessionContext, orgID string, item model.RewardClaim) error {
+	catalogItem, err := sa.GetRewardCatalogItem(sessionContext, orgID, item.Purchase.CatalogItemID)
+	if err != nil {
+		return err
+	}
+	if catalogItem.UserLimit == 0 {
+		return nil
+	}
+
+	purchased, err := sa.getUserPurchasedQuantity(sessionContext, orgID, item.UserID, catalogItem.ID)
+	if err != nil {
+		return err
+	}
+	return catalogItem.CheckUserLimit(purchased, item.Purchase.Quantity)
+}
+
+// bookPickupSlot reserves a place in the pickup slot within the transaction. The booked count starts from the claims
+// already made for the slot and is increased only while it is below the capacity, so parallel claims cannot overbook it
+func (sa *Adapter) bookPickupSlot(sessionContext mongo.SessionContext, orgID string, locationID string, slotID string) error {
+	location, err := sa.GetPickupLocation(sessionContext, orgID, locationID)
+	if err != nil {
+		return err
+	}
+	slot := location.GetSlot(slotID)
+	if slot == nil {
+		return model.NewValidationError("pickup location %s has no slot %s", locationID, slotID)
+	}
+	if slot.Capacity <= 0 {
+		return nil
+	}
+
+	booked, err := sa.countRewardClaimsBySlot(sessionContext, orgID, locationID, slotID)
+	if err != nil {
+		return err
+	}
+	filter := bson.D{
+		primitive.E{Key: "_id", Value: fmt.Sprintf("%s/%s/%s", orgID, locationID, slotID)},
+		primitive.E{Key: "org_id", Value: orgID},
+	}
+	insert := bson.D{
+		primitive.E{Key: "$setOnInsert", Value: bson.D{
+			primitive.E{Key: "location_id", Value: locationID},
+			primitive.E{Key: "slot_id", Value: slotID},
+			primitive.E{Key: "booked", Value: booked},
+		}},
+	}
+	_, err = sa.db.pickupBookings.UpdateOne(sessionContext, filter, insert, options.Update().SetUpsert(true))
+	if err != nil {
+		logging.FromContext(sessionContext).Errorf("storage.bookPickupSlot error: %s", err)
+		return err
+	}
+
+	filter = append(filter, primitive.E{Key: "booked", Value: bson.M{"$lt": slot.Capacity}})
+	update := bson.D{
+		primitive.E{Key: "$inc", Value: bson.D{
+			primitive.E{Key: "booked", Value: 1},
+		}},
+	}
+	result, err := sa.db.pickupBookings.UpdateOne(sessionContext, filter, update, nil)
+	if err != nil {
+		logging.FromContext(sessionContext).Errorf("storage.bookPickupSlot error: %s", err)
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return model.NewConflictError("pickup slot %s is full", slotID)
+	}
+	return nil
+}
+
+// isTransactionConflict tells a transaction failed because a concurrent transaction wrote the same documents
+func isTransactionConflict(err error) bool {
+	var serverErr mongo.ServerError
+	if errors.As(err, &serverErr) && serverErr.HasErrorLabel("TransientTransactionError") {
+		return true
+	}
+	return mongo.IsDuplicateKeyError(err)
+}
+
+// claimRewardInventories draws the claimed amount from the inventories of the reward type within the transaction.
+// Only the inventories stocked at the pickup location are used if a location is chosen. It gives the number of inventories the claim depleted
+func (sa *Adapter) claimRewardInventories(sessionContext mongo.SessionContext, orgID string, rewardType string, amount int, locationID string) ([]model.InventoryAllocation, int, error) {
+	claimDepleted := false
+	archived := false
+	inventories, err := sa.GetRewardInventories(sessionContext, orgID, nil, &rewardType, nil, nil, &claimDepleted, &archived, nil, nil)
+	if err != nil {
+		abortTransaction(sessionContext)
+		logging.FromContext(sessionContext).Errorf("storage.CreateRewardClaim error: %s", err)
+		return nil, 0, fmt.Errorf("storage.CreateRewardClaim error: %s", err)
+	}
+
+	stocked := []model.RewardInventory{}
+	for _, inventory := range inventories {
+		if locationID == "" || inventory.IsStockedAt(locationID) {
+			stocked = append(stocked, inventory)
+		}
+	}
+
+	claimable := func(inventory model.RewardInventory) int { return inventory.GetClaimableAmount() }
+	allocations, err := sa.allocateRewardInventories(sessionContext, orgID, rewardType, stocked, amount, claimable)
+	if err != nil {
+		abortTransaction(sessionContext)
+		logging.FromContext(sessionContext).Errorf("storage.CreateRewardClaim error: %s", err)
+		return nil, 0, err
+	}
+
+	depleted := 0
+	for _, allocation := range allocations {
+		inventory := findInventory(stocked, allocation.InventoryID)
+		inventory.AmountClaimed += allocation.Amount
+		_, err = sa.UpdateRewardInventory(sessionContext, orgID, inventory.ID, *inventory)
+		if err != nil {
+			abortTransaction(sessionContext)
+			logging.FromContext(sessionContext).Errorf("storage.CreateRewardClaim error: %s", err)
+			return nil, 0, fmt.Errorf("storage.CreateRewardClaim error: %s", err)
+		}
+		if inventory.AmountTotal <= inventory.AmountClaimed {
+			depleted++
+		}
 	}
+	return allocations, depleted, nil
+}
+
+// allocateRewardInventories plans how amount is drawn from the inventories with the allocation strategy of the reward type.
+// Nothing is drawn for reward types which are not inventory backed. Inventory backed types fail if the inventories are short
+func (sa *Adapter) allocateRewardInventories(ctx context.Context, orgID string, rewardType string, inventories []model.RewardInventory, amount int, available func(model.RewardInventory) int) ([]model.InventoryAllocation, error) {
+	item, err := sa.GetRewardTypeByType(ctx, orgID, rewardType)
+	if err != nil {
+		return nil, err
+	}
+	if !item.InventoryBacked {
+		return nil, nil
+	}
+	strategy, err := model.NewAllocationStrategy(*item)
+	if err != nil {
+		return nil, err
+	}
+	return model.Allocate(strategy, rewardType, inventories, amount, available)
+}
+
+// findInventory gives the inventory with the id from the list
+func findInventory(inventories []model.RewardInventory, id string) *model.RewardInventory {
+	for i := range inventories {
+		if inventories[i].ID == id {
+			return &inventories[i]
+		}
+	}
+	return nil
+}
+
+// UpdateRewardClaim updates a reward claim
+func (sa *Adapter) UpdateRewardClaim(ctx context.Context, orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error) {
 
 	jsonID := item.ID
 	if jsonID != id {
-		return nil, fmt.Errorf("storage.updateRewardClaim attempt to override another object")
+		return nil, model.NewValidationError("the id of the item does not match the id in the path")
 	}
 
+	// a fulfilled claim does not change and the pickup code is valid only while the claim is approved
 	now := time.Now().UTC()
 	filter := bson.D{
 		primitive.E{Key: "_id", Value: id},
 		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "status", Value: bson.M{"$ne": model.RewardClaimStatusFulfilled}},
 	}
 	update := bson.D{
 		primitive.E{Key: "$set", Value: bson.D{
@@ -805,58 +1419,1362 @@ func (sa *Adapter) UpdateRewardClaimWithContext(ctx context.Context, orgID strin
 			primitive.E{Key: "date_updated", Value: now},
 		}},
 	}
-	_, err := sa.db.rewardClaims.UpdateOneWithContext(ctx, filter, update, nil)
+	if item.Status != model.RewardClaimStatusApproved {
+		update = append(update, primitive.E{Key: "$unset", Value: bson.D{
+			primitive.E{Key: "pickup_code", Value: ""},
+		}})
+	}
+	result, err := sa.db.rewardClaims.UpdateOne(ctx, filter, update, nil)
 	if err != nil {
-		log.Printf("storage.updateRewardClaim error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.updateRewardClaim error: %s", err)
 		return nil, fmt.Errorf("storage.updateRewardClaim error: %s", err)
 	}
+	if result.MatchedCount == 0 {
+		return nil, model.NewConflictError("claim %s is fulfilled or does not exist", id)
+	}
 
 	item.DateUpdated = now
 
 	return &item, nil
 }
 
+// SetRewardClaimPickupCode sets the pickup code of an approved reward claim if it doesn't have one yet
+func (sa *Adapter) SetRewardClaimPickupCode(ctx context.Context, orgID string, id string, code string) error {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+		primitive.E{Key: "status", Value: model.RewardClaimStatusApproved},
+		primitive.E{Key: "pickup_code", Value: bson.M{"$exists": false}},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "pickup_code", Value: code},
+			primitive.E{Key: "date_updated", Value: time.Now().UTC()},
+		}},
+	}
+	_, err := sa.db.rewardClaims.UpdateOne(ctx, filter, update, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.SetRewardClaimPickupCode error: %s", err)
+		return fmt.Errorf("storage.SetRewardClaimPickupCode error: %s", err)
+	}
+	return nil
+}
+
+// GetRewardClaimByPickupCode Gets a reward claim by its pickup code. Returns nil if there is no such claim
+func (sa *Adapter) GetRewardClaimByPickupCode(ctx context.Context, orgID string, code string) (*model.RewardClaim, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "pickup_code", Value: code},
+	}
+	var result []model.RewardClaim
+	err := sa.db.rewardClaims.Find(ctx, filter, &result, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetRewardClaimByPickupCode error: %s", err)
+		return nil, fmt.Errorf("storage.GetRewardClaimByPickupCode error: %s", err)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return &result[0], nil
+}
+
+// FulfillRewardClaim moves an approved reward claim to fulfilled. Returns false if the claim is not approved
+func (sa *Adapter) FulfillRewardClaim(ctx context.Context, orgID string, id string) (bool, error) {
+	now := time.Now().UTC()
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+		primitive.E{Key: "status", Value: model.RewardClaimStatusApproved},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "status", Value: model.RewardClaimStatusFulfilled},
+			primitive.E{Key: "date_fulfilled", Value: now},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+	}
+	result, err := sa.db.rewardClaims.UpdateOne(ctx, filter, update, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.FulfillRewardClaim error: %s", err)
+		return false, fmt.Errorf("storage.FulfillRewardClaim error: %s", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// GetRewardClaimPickupAttempts Gets the pickup attempts of a reward claim
+func (sa *Adapter) GetRewardClaimPickupAttempts(ctx context.Context, orgID string, claimID string) ([]model.RewardClaimPickupAttempt, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "claim_id", Value: claimID},
+	}
+	var result []model.RewardClaimPickupAttempt
+	err := sa.db.rewardPickups.Find(ctx, filter, &result, &options.FindOptions{
+		Sort: bson.D{{Key: "date_created", Value: 1}},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetRewardClaimPickupAttempts error: %s", err)
+		return nil, fmt.Errorf("storage.GetRewardClaimPickupAttempts error: %s", err)
+	}
+	if result == nil {
+		result = []model.RewardClaimPickupAttempt{}
+	}
+	return result, nil
+}
+
+// CreateRewardClaimPickupAttempt records a pickup attempt
+func (sa *Adapter) CreateRewardClaimPickupAttempt(ctx context.Context, orgID string, item model.RewardClaimPickupAttempt) (*model.RewardClaimPickupAttempt, error) {
+	item.ID = uuid.NewString()
+	item.OrgID = orgID
+	item.DateCreated = time.Now().UTC()
+	_, err := sa.db.rewardPickups.InsertOne(ctx, &item)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.CreateRewardClaimPickupAttempt error: %s", err)
+		return nil, fmt.Errorf("storage.CreateRewardClaimPickupAttempt error: %s", err)
+	}
+	return &item, nil
+}
+
 // DeleteRewardClaim deletes a reward claim
-func (sa *Adapter) DeleteRewardClaim(orgID string, id string) error {
-	filter := bson.D{primitive.E{Key: "_id", Value: id}}
-	_, err := sa.db.rewardClaims.DeleteOne(filter, nil)
+func (sa *Adapter) DeleteRewardClaim(ctx context.Context, orgID string, id string) error {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	_, err := sa.db.rewardClaims.DeleteOne(ctx, filter, nil)
 	if err != nil {
-		log.Printf("storage.deleteRewardClaim error: %s", err)
+		logging.FromContext(ctx).Errorf("storage.deleteRewardClaim error: %s", err)
 		return fmt.Errorf("storage.deleteRewardClaim error: %s", err)
 	}
 
 	return nil
 }
 
-func abortTransaction(sessionContext mongo.SessionContext) {
-	err := sessionContext.AbortTransaction(sessionContext)
+// GetRewardCatalogItems Gets all reward catalog items
+func (sa *Adapter) GetRewardCatalogItems(ctx context.Context, orgID string, active *bool) ([]model.RewardCatalogItem, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+	}
+
+	if active != nil {
+		filter = append(filter, primitive.E{Key: "active", Value: *active})
+	}
+
+	var result []model.RewardCatalogItem
+	err := sa.db.rewardCatalog.Find(ctx, filter, &result, &options.FindOptions{
+		Sort: bson.D{{Key: "date_created", Value: 1}},
+	})
 	if err != nil {
-		log.Printf("error on aborting a transaction - %s", err)
+		logging.FromContext(ctx).Errorf("storage.GetRewardCatalogItems error: %s", err)
+		return nil, fmt.Errorf("storage.GetRewardCatalogItems error: %s", err)
+	}
+	if result == nil {
+		result = []model.RewardCatalogItem{}
 	}
+	return result, nil
 }
 
-// SetListener sets the upper layer storage listener for sending collection changed callbacks
-func (sa *Adapter) SetListener(listener Listener) {
-	sa.db.listener = listener
+// GetRewardCatalogItem Gets a reward catalog item by id
+func (sa *Adapter) GetRewardCatalogItem(ctx context.Context, orgID string, id string) (*model.RewardCatalogItem, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	var result []model.RewardCatalogItem
+	err := sa.db.rewardCatalog.Find(ctx, filter, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		logging.FromContext(ctx).Errorf("storage.GetRewardCatalogItem error: unable to find catalog item with id: %s", id)
+		return nil, model.NewNotFoundError("unable to find catalog item with id: %s", id)
+	}
+	return &result[0], nil
 }
 
-// Event
-
-func (m *database) onDataChanged(changeDoc map[string]interface{}) {
-	if changeDoc == nil {
-		return
+// CreateRewardCatalogItem creates a new reward catalog item
+func (sa *Adapter) CreateRewardCatalogItem(ctx context.Context, orgID string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error) {
+	now := time.Now().UTC()
+	item.ID = uuid.NewString()
+	item.OrgID = orgID
+	item.DateCreated = now
+	item.DateUpdated = now
+	_, err := sa.db.rewardCatalog.InsertOne(ctx, &item)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.CreateRewardCatalogItem error: %s", err)
+		return nil, fmt.Errorf("storage.CreateRewardCatalogItem error: %s", err)
 	}
-	log.Printf("onDataChanged: %+v\n", changeDoc)
-	ns := changeDoc["ns"]
-	if ns == nil {
-		return
+	return &item, nil
+}
+
+// UpdateRewardCatalogItem updates a reward catalog item
+func (sa *Adapter) UpdateRewardCatalogItem(ctx context.Context, orgID string, id string, item model.RewardCatalogItem) (*model.RewardCatalogItem, error) {
+	jsonID := item.ID
+	if jsonID != id {
+		return nil, model.NewValidationError("the id of the item does not match the id in the path")
 	}
-	nsMap := ns.(map[string]interface{})
-	coll := nsMap["coll"]
 
-	if "reward_types" == coll {
-		if m.listener != nil {
-			m.listener.OnRewardTypesChanged()
+	now := time.Now().UTC()
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "display_name", Value: item.DisplayName},
+			primitive.E{Key: "description", Value: item.Description},
+			primitive.E{Key: "price", Value: item.Price},
+			primitive.E{Key: "active", Value: item.Active},
+			primitive.E{Key: "available_from", Value: item.AvailableFrom},
+			primitive.E{Key: "available_to", Value: item.AvailableTo},
+			primitive.E{Key: "user_limit", Value: item.UserLimit},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+	}
+	_, err := sa.db.rewardCatalog.UpdateOne(ctx, filter, update, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.UpdateRewardCatalogItem error: %s", err)
+		return nil, fmt.Errorf("storage.UpdateRewardCatalogItem error: %s", err)
+	}
+
+	item.DateUpdated = now
+
+	return &item, nil
+}
+
+// DeleteRewardCatalogItem deletes a reward catalog item
+func (sa *Adapter) DeleteRewardCatalogItem(ctx context.Context, orgID string, id string) error {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	_, err := sa.db.rewardCatalog.DeleteOne(ctx, filter, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.DeleteRewardCatalogItem error: %s", err)
+		return fmt.Errorf("storage.DeleteRewardCatalogItem error: %s", err)
+	}
+
+	return nil
+}
+
+// getUserPurchasedQuantity gives the quantity of a catalog item the user has already bought
+func (sa *Adapter) getUserPurchasedQuantity(ctx context.Context, orgID string, userID string, catalogItemID string) (int, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"org_id": orgID, "user_id": userID, "purchase.catalog_item_id": catalogItemID}},
+		{"$group": bson.M{"_id": "$purchase.catalog_item_id", "amount": bson.M{"$sum": "$purchase.quantity"}}},
+	}
+
+	var result []model.RewardTypeAmount
+	err := sa.db.rewardClaims.Aggregate(ctx, pipeline, &result, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.getUserPurchasedQuantity error: %s", err)
+		return 0, fmt.Errorf("storage.getUserPurchasedQuantity error: %s", err)
+	}
+	if len(result) == 0 {
+		return 0, nil
+	}
+	return result[0].Amount, nil
+}
+
+// GetPickupLocations Gets all pickup locations
+func (sa *Adapter) GetPickupLocations(ctx context.Context, orgID string) ([]model.PickupLocation, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+	}
+	var result []model.PickupLocation
+	err := sa.db.pickupLocations.Find(ctx, filter, &result, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetPickupLocations error: %s", err)
+		return nil, fmt.Errorf("storage.GetPickupLocations error: %s", err)
+	}
+	if result == nil {
+		result = []model.PickupLocation{}
+	}
+	return result, nil
+}
+
+// GetPickupLocation Gets a pickup location by id
+func (sa *Adapter) GetPickupLocation(ctx context.Context, orgID string, id string) (*model.PickupLocation, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	var result []model.PickupLocation
+	err := sa.db.pickupLocations.Find(ctx, filter, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		logging.FromContext(ctx).Errorf("storage.GetPickupLocation error: unable to find pickup location with id: %s", id)
+		return nil, model.NewNotFoundError("unable to find pickup location with id: %s", id)
+	}
+	return &result[0], nil
+}
+
+// CreatePickupLocation creates a new pickup location
+func (sa *Adapter) CreatePickupLocation(ctx context.Context, orgID string, item model.PickupLocation) (*model.PickupLocation, error) {
+	now := time.Now().UTC()
+	item.ID = uuid.NewString()
+	item.OrgID = orgID
+	item.DateCreated = now
+	item.DateUpdated = now
+	setPickupSlotIDs(item.Slots)
+	_, err := sa.db.pickupLocations.InsertOne(ctx, &item)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.CreatePickupLocation error: %s", err)
+		return nil, fmt.Errorf("storage.CreatePickupLocation error: %s", err)
+	}
+	return &item, nil
+}
+
+// UpdatePickupLocation updates a pickup location
+func (sa *Adapter) UpdatePickupLocation(ctx context.Context, orgID string, id string, item model.PickupLocation) (*model.PickupLocation, error) {
+	jsonID := item.ID
+	if jsonID != id {
+		return nil, model.NewValidationError("the id of the item does not match the id in the path")
+	}
+
+	setPickupSlotIDs(item.Slots)
+
+	now := time.Now().UTC()
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "name", Value: item.Name},
+			primitive.E{Key: "address", Value: item.Address},
+			primitive.E{Key: "opening_hours", Value: item.OpeningHours},
+			primitive.E{Key: "active", Value: item.Active},
+			primitive.E{Key: "slots", Value: item.Slots},
+			primitive.E{Key: "description", Value: item.Description},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+	}
+	_, err := sa.db.pickupLocations.UpdateOne(ctx, filter, update, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.UpdatePickupLocation error: %s", err)
+		return nil, fmt.Errorf("storage.UpdatePickupLocation error: %s", err)
+	}
+
+	item.DateUpdated = now
+
+	return &item, nil
+}
+
+// DeletePickupLocation deletes a pickup location
+func (sa *Adapter) DeletePickupLocation(ctx context.Context, orgID string, id string) error {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	_, err := sa.db.pickupLocations.DeleteOne(ctx, filter, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.DeletePickupLocation error: %s", err)
+		return fmt.Errorf("storage.DeletePickupLocation error: %s", err)
+	}
+
+	return nil
+}
+
+// GetAuthorizationPolicies Gets all admin authorization policies
+func (sa *Adapter) GetAuthorizationPolicies(ctx context.Context) ([]model.AuthorizationPolicy, error) {
+	filter := bson.D{}
+	var result []model.AuthorizationPolicy
+	err := sa.db.authorizationPolicies.Find(ctx, filter, &result, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetAuthorizationPolicies error: %s", err)
+		return nil, fmt.Errorf("storage.GetAuthorizationPolicies error: %s", err)
+	}
+	if result == nil {
+		result = []model.AuthorizationPolicy{}
+	}
+	return result, nil
+}
+
+// GetInternalCredentials Gets all internal credentials
+func (sa *Adapter) GetInternalCredentials(ctx context.Context, orgID string) ([]model.InternalCredential, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+	}
+	var result []model.InternalCredential
+	err := sa.db.internalCredentials.Find(ctx, filter, &result, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetInternalCredentials error: %s", err)
+		return nil, fmt.Errorf("storage.GetInternalCredentials error: %s", err)
+	}
+	if result == nil {
+		result = []model.InternalCredential{}
+	}
+	return result, nil
+}
+
+// GetInternalCredential Gets an internal credential by id
+func (sa *Adapter) GetInternalCredential(ctx context.Context, orgID string, id string) (*model.InternalCredential, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+	}
+	var result []model.InternalCredential
+	err := sa.db.internalCredentials.Find(ctx, filter, &result, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		logging.FromContext(ctx).Errorf("storage.GetInternalCredential error: unable to find internal credential with id: %s", id)
+		return nil, model.NewNotFoundError("unable to find internal credential with id: %s", id)
+	}
+	return &result[0], nil
+}
+
+// GetInternalCredentialByKeyHash Gets an internal credential by the hash of its key. Returns nil if there is no such credential.
+func (sa *Adapter) GetInternalCredentialByKeyHash(ctx context.Context, keyHash string) (*model.InternalCredential, error) {
+	filter := bson.D{
+		primitive.E{Key: "key_hash", Value: keyHash},
+	}
+	var result []model.InternalCredential
+	err := sa.db.internalCredentials.Find(ctx, filter, &result, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetInternalCredentialByKeyHash error: %s", err)
+		return nil, fmt.Errorf("storage.GetInternalCredentialByKeyHash error: %s", err)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return &result[0], nil
+}
+
+// CreateInternalCredential creates a new internal credential
+func (sa *Adapter) CreateInternalCredential(ctx context.Context, orgID string, item model.InternalCredential) (*model.InternalCredential, error) {
+	now := time.Now().UTC()
+	item.ID = uuid.NewString()
+	item.OrgID = orgID
+	item.DateCreated = now
+	item.DateUpdated = now
+	_, err := sa.db.internalCredentials.InsertOne(ctx, &item)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.CreateInternalCredential error: %s", err)
+		return nil, fmt.Errorf("storage.CreateInternalCredential error: %s", err)
+	}
+	return &item, nil
+}
+
+// UpdateInternalCredentialKey replaces the key of an active internal credential
+func (sa *Adapter) UpdateInternalCredentialKey(ctx context.Context, orgID string, id string, keyHash string, keyPrefix string) error {
+	now := time.Now().UTC()
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+		primitive.E{Key: "date_revoked", Value: nil},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "key_hash", Value: keyHash},
+			primitive.E{Key: "key_prefix", Value: keyPrefix},
+			primitive.E{Key: "date_rotated", Value: now},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+	}
+	result, err := sa.db.internalCredentials.UpdateOne(ctx, filter, update, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.UpdateInternalCredentialKey error: %s", err)
+		return fmt.Errorf("storage.UpdateInternalCredentialKey error: %s", err)
+	}
+	if result.MatchedCount == 0 {
+		return model.NewNotFoundError("no active internal credential with id: %s", id)
+	}
+	return nil
+}
+
+// RevokeInternalCredential revokes an internal credential
+func (sa *Adapter) RevokeInternalCredential(ctx context.Context, orgID string, id string) error {
+	now := time.Now().UTC()
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "_id", Value: id},
+		primitive.E{Key: "date_revoked", Value: nil},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "date_revoked", Value: now},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+	}
+	result, err := sa.db.internalCredentials.UpdateOne(ctx, filter, update, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.RevokeInternalCredential error: %s", err)
+		return fmt.Errorf("storage.RevokeInternalCredential error: %s", err)
+	}
+	if result.MatchedCount == 0 {
+		return model.NewNotFoundError("no active internal credential with id: %s", id)
+	}
+	return nil
+}
+
+// GetAuditLogEntries Gets the audit log entries of an org, most recent first
+func (sa *Adapter) GetAuditLogEntries(ctx context.Context, orgID string, resource *string, resourceID *string, actor *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64) ([]model.AuditLogEntry, error) {
+	filter := auditLogFilter(orgID, resource, resourceID, actor, startDate, endDate)
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "date_created", Value: -1}})
+	if limit != nil {
+		findOptions.SetLimit(*limit)
+	}
+	if offset != nil {
+		findOptions.SetSkip(*offset)
+	}
+
+	var result []model.AuditLogEntry
+	err := sa.db.auditLog.Find(ctx, filter, &result, findOptions)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetAuditLogEntries error: %s", err)
+		return nil, fmt.Errorf("storage.GetAuditLogEntries error: %s", err)
+	}
+	if result == nil {
+		result = []model.AuditLogEntry{}
+	}
+	return result, nil
+}
+
+// auditLogFilter gives the filter of the audit log entries of the org
+func auditLogFilter(orgID string, resource *string, resourceID *string, actor *string, startDate *time.Time, endDate *time.Time) bson.D {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+	}
+
+	if resource != nil {
+		filter = append(filter, primitive.E{Key: "resource", Value: *resource})
+	}
+	if resourceID != nil {
+		filter = append(filter, primitive.E{Key: "resource_id", Value: *resourceID})
+	}
+	if actor != nil {
+		filter = append(filter, primitive.E{Key: "actor", Value: *actor})
+	}
+	if startDate != nil || endDate != nil {
+		dateFilter := bson.M{}
+		if startDate != nil {
+			dateFilter["$gte"] = *startDate
+		}
+		if endDate != nil {
+			dateFilter["$lte"] = *endDate
+		}
+		filter = append(filter, primitive.E{Key: "date_created", Value: dateFilter})
+	}
+	return filter
+}
+
+// CreateAuditLogEntry appends an entry to the audit log
+func (sa *Adapter) CreateAuditLogEntry(ctx context.Context, orgID string, item model.AuditLogEntry) (*model.AuditLogEntry, error) {
+	item.ID = uuid.NewString()
+	item.OrgID = orgID
+	item.DateCreated = time.Now().UTC()
+	_, err := sa.db.auditLog.InsertOne(ctx, &item)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.CreateAuditLogEntry error: %s", err)
+		return nil, fmt.Errorf("storage.CreateAuditLogEntry error: %s", err)
+	}
+	return &item, nil
+}
+
+// GetLeaderboardProfile Gets the leaderboard profile of a user. It is nil if the user has not set it yet
+func (sa *Adapter) GetLeaderboardProfile(ctx context.Context, orgID string, userID string) (*model.LeaderboardProfile, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "user_id", Value: userID},
+	}
+	var result []model.LeaderboardProfile
+	err := sa.db.leaderboardProfiles.Find(ctx, filter, &result, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetLeaderboardProfile error: %s", err)
+		return nil, fmt.Errorf("storage.GetLeaderboardProfile error: %s", err)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	return &result[0], nil
+}
+
+// SaveLeaderboardProfile creates or updates the leaderboard profile of a user and applies it to the scores of the user
+func (sa *Adapter) SaveLeaderboardProfile(ctx context.Context, orgID string, item model.LeaderboardProfile) (*model.LeaderboardProfile, error) {
+	now := time.Now().UTC()
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "user_id", Value: item.UserID},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "opted_in", Value: item.OptedIn},
+			primitive.E{Key: "display_handle", Value: item.DisplayHandle},
+			primitive.E{Key: "date_updated", Value: now},
+		}},
+		primitive.E{Key: "$setOnInsert", Value: bson.D{
+			primitive.E{Key: "_id", Value: uuid.NewString()},
+			primitive.E{Key: "date_created", Value: now},
+		}},
+	}
+	_, err := sa.db.leaderboardProfiles.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.SaveLeaderboardProfile error: %s", err)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, model.NewConflictError("display handle %s is already taken", item.DisplayHandle)
+		}
+		return nil, fmt.Errorf("storage.SaveLeaderboardProfile error: %s", err)
+	}
+
+	scoresUpdate := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "opted_in", Value: item.OptedIn},
+			primitive.E{Key: "display_handle", Value: item.DisplayHandle},
+		}},
+	}
+	_, err = sa.db.leaderboardScores.UpdateMany(ctx, filter, scoresUpdate, nil)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.SaveLeaderboardProfile error: %s", err)
+		return nil, fmt.Errorf("storage.SaveLeaderboardProfile error: %s", err)
+	}
+
+	return sa.GetLeaderboardProfile(ctx, orgID, item.UserID)
+}
+
+// GetLeaderboardScores Gets the scores of a leaderboard ordered by amount descending
+func (sa *Adapter) GetLeaderboardScores(ctx context.Context, orgID string, rewardType string, buildingBlock string, window string, windowStart time.Time, optedIn *bool, limit int64) ([]model.LeaderboardScore, error) {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+		primitive.E{Key: "reward_type", Value: rewardType},
+		primitive.E{Key: "building_block", Value: buildingBlock},
+		primitive.E{Key: "window", Value: window},
+		primitive.E{Key: "window_start", Value: windowStart},
+	}
+	if optedIn != nil {
+		filter = append(filter, primitive.E{Key: "opted_in", Value: *optedIn})
+	}
+
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "amount", Value: -1}, {Key: "user_id", Value: 1}})
+	findOptions.SetLimit(limit)
+
+	var result []model.LeaderboardScore
+	err := sa.db.leaderboardScores.Find(ctx, filter, &result, findOptions)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetLeaderboardScores error: %s", err)
+		return nil, fmt.Errorf("storage.GetLeaderboardScores error: %s", err)
+	}
+	if result == nil {
+		result = []model.LeaderboardScore{}
+	}
+	return result, nil
+}
+
+// addLeaderboardScores adds a granted reward to the leaderboard scores of all time windows within the transaction
+func (sa *Adapter) addLeaderboardScores(sessionContext mongo.SessionContext, orgID string, item model.Reward) error {
+	profile, err := sa.GetLeaderboardProfile(sessionContext, orgID, item.UserID)
+	if err != nil {
+		return err
+	}
+	optedIn, displayHandle := false, ""
+	if profile != nil {
+		optedIn, displayHandle = profile.OptedIn, profile.DisplayHandle
+	}
+
+	for _, window := range model.LeaderboardWindows {
+		windowStart, err := model.LeaderboardWindowStart(window, item.DateCreated)
+		if err != nil {
+			return err
+		}
+		for _, buildingBlock := range leaderboardBuildingBlocks(item.BuildingBlock) {
+			filter := bson.D{
+				primitive.E{Key: "org_id", Value: orgID},
+				primitive.E{Key: "user_id", Value: item.UserID},
+				primitive.E{Key: "reward_type", Value: item.RewardType},
+				primitive.E{Key: "building_block", Value: buildingBlock},
+				primitive.E{Key: "window", Value: window},
+				primitive.E{Key: "window_start", Value: windowStart},
+			}
+			update := bson.D{
+				primitive.E{Key: "$inc", Value: bson.D{
+					primitive.E{Key: "amount", Value: item.Amount},
+				}},
+				primitive.E{Key: "$set", Value: bson.D{
+					primitive.E{Key: "opted_in", Value: optedIn},
+					primitive.E{Key: "display_handle", Value: displayHandle},
+					primitive.E{Key: "date_updated", Value: item.DateCreated},
+				}},
+				primitive.E{Key: "$setOnInsert", Value: bson.D{
+					primitive.E{Key: "_id", Value: uuid.NewString()},
+				}},
+			}
+			_, err = sa.db.leaderboardScores.UpdateOne(sessionContext, filter, update, options.Update().SetUpsert(true))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// leaderboardDay is the amount a user got on a UTC day from a building block
+type leaderboardDay struct {
+	Key struct {
+		UserID        string `bson:"user_id"`
+		RewardType    string `bson:"reward_type"`
+		BuildingBlock string `bson:"building_block"`
+		Day           string `bson:"day"`
+	} `bson:"_id"`
+	Amount int `bson:"amount"`
+}
+
+// RebuildLeaderboardScores recomputes the leaderboard scores of an org from the reward history. It gives the number of scores
+func (sa *Adapter) RebuildLeaderboardScores(ctx context.Context, orgID string) (int, error) {
+	scores, err := sa.db.computeLeaderboardScores(ctx, orgID)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.RebuildLeaderboardScores error: %s", err)
+		return 0, fmt.Errorf("storage.RebuildLeaderboardScores error: %w", err)
+	}
+
+	err = sa.db.replaceLeaderboardScores(ctx, orgID, scores)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.RebuildLeaderboardScores transaction error: %s", err)
+		return 0, fmt.Errorf("storage.RebuildLeaderboardScores transaction error: %w", err)
+	}
+	return len(scores), nil
+}
+
+// computeLeaderboardScores sums the reward history of an org into the leaderboard scores
+func (m *database) computeLeaderboardScores(ctx context.Context, orgID string) ([]model.LeaderboardScore, error) {
+	// all windows start on a day, so the history is summed per day and the windows are filled from the days
+	pipeline := []bson.M{
+		{"$match": bson.M{"org_id": orgID}},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"user_id":        "$user_id",
+				"reward_type":    "$reward_type",
+				"building_block": "$building_block",
+				"day":            bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$date_created"}},
+			},
+			"amount": bson.M{"$sum": "$amount"},
+		}},
+	}
+	var days []leaderboardDay
+	err := m.rewardHistory.Aggregate(ctx, pipeline, &days, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []model.LeaderboardProfile
+	err = m.leaderboardProfiles.Find(ctx, bson.D{primitive.E{Key: "org_id", Value: orgID}}, &profiles, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildLeaderboardScores(orgID, days, profiles, time.Now().UTC())
+}
+
+// replaceLeaderboardScores replaces the leaderboard scores of an org in a transaction
+func (m *database) replaceLeaderboardScores(ctx context.Context, orgID string, scores []model.LeaderboardScore) error {
+	return m.dbClient.UseSession(ctx, func(sessionContext mongo.SessionContext) error {
+		err := sessionContext.StartTransaction()
+		if err != nil {
+			logging.FromContext(sessionContext).Errorf("error starting a transaction - %s", err)
+			return err
+		}
+
+		_, err = m.leaderboardScores.DeleteMany(sessionContext, bson.D{primitive.E{Key: "org_id", Value: orgID}}, nil)
+		if err != nil {
+			abortTransaction(sessionContext)
+			return err
+		}
+
+		if len(scores) > 0 {
+			documents := make([]interface{}, len(scores))
+			for i := range scores {
+				documents[i] = scores[i]
+			}
+			_, err = m.leaderboardScores.InsertMany(sessionContext, documents, nil)
+			if err != nil {
+				abortTransaction(sessionContext)
+				return err
+			}
+		}
+
+		err = sessionContext.CommitTransaction(sessionContext)
+		if err != nil {
+			abortTransaction(sessionContext)
+			return err
+		}
+		return nil
+	})
+}
+
+// buildLeaderboardScores sums the daily amounts into the scores of all time windows
+func buildLeaderboardScores(orgID string, days []leaderboardDay, profiles []model.LeaderboardProfile, now time.Time) ([]model.LeaderboardScore, error) {
+	profileMapping := map[string]model.LeaderboardProfile{}
+	for _, profile := range profiles {
+		profileMapping[profile.UserID] = profile
+	}
+
+	type scoreKey struct {
+		userID, rewardType, buildingBlock, window string
+		windowStart                               time.Time
+	}
+	scoreMapping := map[scoreKey]*model.LeaderboardScore{}
+	scores := []*model.LeaderboardScore{}
+	for _, day := range days {
+		date, err := time.Parse("2006-01-02", day.Key.Day)
+		if err != nil {
+			return nil, fmt.Errorf("invalid history day %s: %s", day.Key.Day, err)
+		}
+		for _, window := range model.LeaderboardWindows {
+			windowStart, err := model.LeaderboardWindowStart(window, date)
+			if err != nil {
+				return nil, err
+			}
+			for _, buildingBlock := range leaderboardBuildingBlocks(day.Key.BuildingBlock) {
+				key := scoreKey{day.Key.UserID, day.Key.RewardType, buildingBlock, window, windowStart}
+				score := scoreMapping[key]
+				if score == nil {
+					profile := profileMapping[day.Key.UserID]
+					score = &model.LeaderboardScore{ID: uuid.NewString(), OrgID: orgID, UserID: day.Key.UserID, RewardType: day.Key.RewardType,
+						BuildingBlock: buildingBlock, Window: window, WindowStart: windowStart, OptedIn: profile.OptedIn,
+						DisplayHandle: profile.DisplayHandle, DateUpdated: now}
+					scoreMapping[key] = score
+					scores = append(scores, score)
+				}
+				score.Amount += day.Amount
+			}
+		}
+	}
+
+	result := make([]model.LeaderboardScore, len(scores))
+	for i, score := range scores {
+		result[i] = *score
+	}
+	return result, nil
+}
+
+// leaderboardBuildingBlocks gives the building blocks whose scores a reward is added to
+func leaderboardBuildingBlocks(buildingBlock string) []string {
+	if buildingBlock == model.LeaderboardAllBuildingBlocks {
+		return []string{model.LeaderboardAllBuildingBlocks}
+	}
+	return []string{model.LeaderboardAllBuildingBlocks, buildingBlock}
+}
+
+// analyticsMatch gives the $match stage of the documents of an org created within the date range of the query
+func analyticsMatch(orgID string, query model.AnalyticsQuery, rewardTypeField string) bson.M {
+	match := bson.M{
+		"org_id":       orgID,
+		"date_created": bson.M{"$gte": query.StartDate, "$lt": query.EndDate},
+	}
+	if query.RewardType != nil {
+		match[rewardTypeField] = *query.RewardType
+	}
+	return bson.M{"$match": match}
+}
+
+// analyticsBucketKey gives the expression of the bucket key of the date created in the timezone of the query
+func analyticsBucketKey(query model.AnalyticsQuery) bson.M {
+	return bson.M{"$dateToString": bson.M{"format": query.BucketFormat(), "date": "$date_created", "timezone": query.Timezone}}
+}
+
+// grantsAnalyticsPipeline groups the reward history by bucket, operation code and building block
+func grantsAnalyticsPipeline(orgID string, query model.AnalyticsQuery) []bson.M {
+	return []bson.M{
+		analyticsMatch(orgID, query, "reward_type"),
+		{"$group": bson.M{
+			"_id":    bson.M{"bucket": analyticsBucketKey(query), "code": "$code", "building_block": "$building_block"},
+			"count":  bson.M{"$sum": 1},
+			"amount": bson.M{"$sum": "$amount"},
+		}},
+	}
+}
+
+// GetGrantsAnalytics Gets the number and the amount of the granted rewards per bucket, operation code and building block
+func (sa *Adapter) GetGrantsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.GrantsBucket, error) {
+	pipeline := grantsAnalyticsPipeline(orgID, query)
+	var groups []struct {
+		Key struct {
+			Bucket        string `bson:"bucket"`
+			Code          string `bson:"code"`
+			BuildingBlock string `bson:"building_block"`
+		} `bson:"_id"`
+		Count  int `bson:"count"`
+		Amount int `bson:"amount"`
+	}
+	err := sa.db.rewardHistory.Aggregate(ctx, pipeline, &groups, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetGrantsAnalytics error: %s", err)
+		return nil, fmt.Errorf("storage.GetGrantsAnalytics error: %s", err)
+	}
+
+	result := make([]model.GrantsBucket, 0, len(groups))
+	for _, group := range groups {
+		bucketStart, err := query.BucketStart(group.Key.Bucket)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("storage.GetGrantsAnalytics error: %s", err)
+			return nil, fmt.Errorf("storage.GetGrantsAnalytics error: %s", err)
+		}
+		result = append(result, model.GrantsBucket{BucketStart: bucketStart, Code: group.Key.Code,
+			BuildingBlock: group.Key.BuildingBlock, Count: group.Count, Amount: group.Amount})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].BucketStart.Equal(result[j].BucketStart) {
+			return result[i].BucketStart.Before(result[j].BucketStart)
+		}
+		if result[i].BuildingBlock != result[j].BuildingBlock {
+			return result[i].BuildingBlock < result[j].BuildingBlock
+		}
+		return result[i].Code < result[j].Code
+	})
+	return result, nil
+}
+
+// earnersAnalyticsPipeline counts the distinct users of the reward history by bucket
+func earnersAnalyticsPipeline(orgID string, query model.AnalyticsQuery) []bson.M {
+	return []bson.M{
+		analyticsMatch(orgID, query, "reward_type"),
+		{"$group": bson.M{"_id": bson.M{"bucket": analyticsBucketKey(query), "user_id": "$user_id"}}},
+		{"$group": bson.M{"_id": "$_id.bucket", "count": bson.M{"$sum": 1}}},
+	}
+}
+
+// GetEarnersAnalytics Gets the number of distinct users who got rewards per bucket
+func (sa *Adapter) GetEarnersAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.EarnersBucket, error) {
+	pipeline := earnersAnalyticsPipeline(orgID, query)
+	var groups []struct {
+		Bucket string `bson:"_id"`
+		Count  int    `bson:"count"`
+	}
+	err := sa.db.rewardHistory.Aggregate(ctx, pipeline, &groups, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetEarnersAnalytics error: %s", err)
+		return nil, fmt.Errorf("storage.GetEarnersAnalytics error: %s", err)
+	}
+
+	result := make([]model.EarnersBucket, 0, len(groups))
+	for _, group := range groups {
+		bucketStart, err := query.BucketStart(group.Bucket)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("storage.GetEarnersAnalytics error: %s", err)
+			return nil, fmt.Errorf("storage.GetEarnersAnalytics error: %s", err)
+		}
+		result = append(result, model.EarnersBucket{BucketStart: bucketStart, UniqueEarners: group.Count})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].BucketStart.Before(result[j].BucketStart) })
+	return result, nil
+}
+
+// claimsAnalyticsPipeline groups the claims by bucket and status
+func claimsAnalyticsPipeline(orgID string, query model.AnalyticsQuery) []bson.M {
+	return []bson.M{
+		analyticsMatch(orgID, query, "items.reward_type"),
+		{"$group": bson.M{
+			"_id":    bson.M{"bucket": analyticsBucketKey(query), "status": "$status"},
+			"count":  bson.M{"$sum": 1},
+			"amount": bson.M{"$sum": bson.M{"$sum": "$items.amount"}},
+		}},
+	}
+}
+
+// GetClaimsAnalytics Gets the number and the amount of the claims per bucket and status
+func (sa *Adapter) GetClaimsAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.ClaimsBucket, error) {
+	pipeline := claimsAnalyticsPipeline(orgID, query)
+	var groups []struct {
+		Key struct {
+			Bucket string `bson:"bucket"`
+			Status string `bson:"status"`
+		} `bson:"_id"`
+		Count  int `bson:"count"`
+		Amount int `bson:"amount"`
+	}
+	err := sa.db.rewardClaims.Aggregate(ctx, pipeline, &groups, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetClaimsAnalytics error: %s", err)
+		return nil, fmt.Errorf("storage.GetClaimsAnalytics error: %s", err)
+	}
+
+	result := make([]model.ClaimsBucket, 0, len(groups))
+	for _, group := range groups {
+		bucketStart, err := query.BucketStart(group.Key.Bucket)
+		if err != nil {
+			logging.FromContext(ctx).Errorf("storage.GetClaimsAnalytics error: %s", err)
+			return nil, fmt.Errorf("storage.GetClaimsAnalytics error: %s", err)
+		}
+		result = append(result, model.ClaimsBucket{BucketStart: bucketStart, Status: group.Key.Status, Count: group.Count, Amount: group.Amount})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if !result[i].BucketStart.Equal(result[j].BucketStart) {
+			return result[i].BucketStart.Before(result[j].BucketStart)
+		}
+		return result[i].Status < result[j].Status
+	})
+	return result, nil
+}
+
+// GetRedemptionAnalytics Gets the granted and the claimed amount per reward type within the date range
+func (sa *Adapter) GetRedemptionAnalytics(ctx context.Context, orgID string, query model.AnalyticsQuery) ([]model.RedemptionSummary, error) {
+	type amountGroup struct {
+		RewardType string `bson:"_id"`
+		Amount     int    `bson:"amount"`
+		Users      int    `bson:"users"`
+	}
+
+	grantsPipeline := []bson.M{
+		analyticsMatch(orgID, query, "reward_type"),
+		{"$group": bson.M{"_id": "$reward_type", "amount": bson.M{"$sum": "$amount"}, "users": bson.M{"$addToSet": "$user_id"}}},
+		{"$project": bson.M{"amount": 1, "users": bson.M{"$size": "$users"}}},
+	}
+	var grants []amountGroup
+	err := sa.db.rewardHistory.Aggregate(ctx, grantsPipeline, &grants, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetRedemptionAnalytics error: %s", err)
+		return nil, fmt.Errorf("storage.GetRedemptionAnalytics error: %s", err)
+	}
+
+	claimsPipeline := []bson.M{
+		analyticsMatch(orgID, query, "items.reward_type"),
+		{"$unwind": bson.M{"path": "$items"}},
+	}
+	if query.RewardType != nil {
+		claimsPipeline = append(claimsPipeline, bson.M{"$match": bson.M{"items.reward_type": *query.RewardType}})
+	}
+	claimsPipeline = append(claimsPipeline,
+		bson.M{"$group": bson.M{"_id": "$items.reward_type", "amount": bson.M{"$sum": "$items.amount"}, "users": bson.M{"$addToSet": "$user_id"}}},
+		bson.M{"$project": bson.M{"amount": 1, "users": bson.M{"$size": "$users"}}})
+	var claims []amountGroup
+	err = sa.db.rewardClaims.Aggregate(ctx, claimsPipeline, &claims, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.GetRedemptionAnalytics error: %s", err)
+		return nil, fmt.Errorf("storage.GetRedemptionAnalytics error: %s", err)
+	}
+
+	summaries := map[string]*model.RedemptionSummary{}
+	summary := func(rewardType string) *model.RedemptionSummary {
+		if summaries[rewardType] == nil {
+			summaries[rewardType] = &model.RedemptionSummary{RewardType: rewardType}
+		}
+		return summaries[rewardType]
+	}
+	for _, grant := range grants {
+		item := summary(grant.RewardType)
+		item.GrantedAmount, item.UniqueEarners = grant.Amount, grant.Users
+	}
+	for _, claim := range claims {
+		item := summary(claim.RewardType)
+		item.ClaimedAmount, item.UniqueClaimers = claim.Amount, claim.Users
+	}
+
+	result := make([]model.RedemptionSummary, 0, len(summaries))
+	for _, item := range summaries {
+		item.SetRedemptionRate()
+		result = append(result, *item)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].RewardType < result[j].RewardType })
+	return result, nil
+}
+
+// rewardHistoryFilter gives the filter of the reward history of an org
+func rewardHistoryFilter(orgID string, userID *string, rewardType *string, code *string, buildingBlock *string, startDate *time.Time, endDate *time.Time) bson.D {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+	}
+
+	if userID != nil {
+		filter = append(filter, primitive.E{Key: "user_id", Value: *userID})
+	}
+
+	if rewardType != nil {
+		filter = append(filter, primitive.E{Key: "reward_type", Value: *rewardType})
+	}
+
+	if code != nil {
+		filter = append(filter, primitive.E{Key: "code", Value: *code})
+	}
+
+	if buildingBlock != nil {
+		filter = append(filter, primitive.E{Key: "building_block", Value: *buildingBlock})
+	}
+
+	if startDate != nil || endDate != nil {
+		dateFilter := bson.M{}
+		if startDate != nil {
+			dateFilter["$gte"] = *startDate
+		}
+		if endDate != nil {
+			dateFilter["$lte"] = *endDate
+		}
+		filter = append(filter, primitive.E{Key: "date_created", Value: dateFilter})
+	}
+	return filter
+}
+
+// rewardClaimsFilter gives the filter of the reward claims of an org
+func rewardClaimsFilter(orgID string, ids []string, userID *string, rewardType *string, status *string) bson.D {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+	}
+
+	if len(ids) > 0 {
+		filter = append(filter, primitive.E{Key: "_id", Value: bson.M{"$in": ids}})
+	}
+
+	if userID != nil {
+		filter = append(filter, primitive.E{Key: "user_id", Value: *userID})
+	}
+
+	if rewardType != nil {
+		filter = append(filter, primitive.E{Key: "items.reward_type", Value: *rewardType})
+	}
+
+	if status != nil {
+		filter = append(filter, primitive.E{Key: "status", Value: *status})
+	}
+	return filter
+}
+
+// rewardInventoriesFilter gives the filter of the reward inventories of an org
+func rewardInventoriesFilter(orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool) bson.D {
+	filter := bson.D{
+		primitive.E{Key: "org_id", Value: orgID},
+	}
+
+	if len(ids) > 0 {
+		filter = append(filter, primitive.E{Key: "_id", Value: bson.M{"$in": ids}})
+	}
+
+	if rewardType != nil {
+		filter = append(filter, primitive.E{Key: "reward_type", Value: *rewardType})
+	}
+
+	if inStock != nil {
+		filter = append(filter, primitive.E{Key: "in_stock", Value: *inStock})
+	}
+
+	if grantDepleted != nil {
+		filter = append(filter, primitive.E{Key: "grant_depleted", Value: *grantDepleted})
+	}
+
+	if claimDepleted != nil {
+		filter = append(filter, primitive.E{Key: "claim_depleted", Value: *claimDepleted})
+	}
+
+	if archived != nil {
+		filter = append(filter, archivedFilter(*archived))
+	}
+	return filter
+}
+
+// exportFindOptions gives the options of an export, which goes through the documents in the order they were created
+func exportFindOptions(limit *int64, offset *int64) *options.FindOptions {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "date_created", Value: 1}, {Key: "_id", Value: 1}})
+	findOptions.SetBatchSize(exportBatchSize)
+	if limit != nil {
+		findOptions.SetLimit(*limit)
+	}
+	if offset != nil {
+		findOptions.SetSkip(*offset)
+	}
+	return findOptions
+}
+
+// ExportRewardHistory passes the reward history entries of an org to each one at a time
+func (sa *Adapter) ExportRewardHistory(ctx context.Context, orgID string, userID *string, rewardType *string, code *string, buildingBlock *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64, each func(model.Reward) error) error {
+	filter := rewardHistoryFilter(orgID, userID, rewardType, code, buildingBlock, startDate, endDate)
+	err := sa.db.rewardHistory.FindEach(ctx, filter, exportFindOptions(limit, offset), func(cursor *mongo.Cursor) error {
+		var item model.Reward
+		err := cursor.Decode(&item)
+		if err != nil {
+			return err
+		}
+		return each(item)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.ExportRewardHistory error: %s", err)
+		return fmt.Errorf("storage.ExportRewardHistory error: %w", err)
+	}
+	return nil
+}
+
+// ExportRewardClaims passes the reward claims of an org to each one at a time
+func (sa *Adapter) ExportRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64, each func(model.RewardClaim) error) error {
+	filter := rewardClaimsFilter(orgID, ids, userID, rewardType, status)
+	err := sa.db.rewardClaims.FindEach(ctx, filter, exportFindOptions(limit, offset), func(cursor *mongo.Cursor) error {
+		var item model.RewardClaim
+		err := cursor.Decode(&item)
+		if err != nil {
+			return err
+		}
+		return each(item)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.ExportRewardClaims error: %s", err)
+		return fmt.Errorf("storage.ExportRewardClaims error: %w", err)
+	}
+	return nil
+}
+
+// ExportRewardInventories passes the reward inventories of an org to each one at a time
+func (sa *Adapter) ExportRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64, each func(model.RewardInventory) error) error {
+	filter := rewardInventoriesFilter(orgID, ids, rewardType, inStock, grantDepleted, claimDepleted, archived)
+	err := sa.db.rewardInventories.FindEach(ctx, filter, exportFindOptions(limit, offset), func(cursor *mongo.Cursor) error {
+		var item model.RewardInventory
+		err := cursor.Decode(&item)
+		if err != nil {
+			return err
+		}
+		return each(item)
+	})
+	if err != nil {
+		logging.FromContext(ctx).Errorf("storage.ExportRewardInventories error: %s", err)
+		return fmt.Errorf("storage.ExportRewardInventories error: %w", err)
+	}
+	return nil
+}
+
+// ApplyImport creates and updates the entities of an import in a single transaction. It gives the stored entities
+func (sa *Adapter) ApplyImport(ctx context.Context, orgID string, batch model.ImportBatch) (*model.ImportBatch, error) {
+	applied := model.ImportBatch{}
+	err := sa.db.dbClient.UseSession(ctx, func(sessionContext mongo.SessionContext) error {
+		err := sessionContext.StartTransaction()
+		if err != nil {
+			logging.FromContext(sessionContext).Errorf("error starting a transaction - %s", err)
+			return err
+		}
+
+		applied = model.ImportBatch{}
+		err = sa.applyImport(sessionContext, orgID, batch, &applied)
+		if err != nil {
+			abortTransaction(sessionContext)
+			return err
+		}
+
+		err = sessionContext.CommitTransaction(sessionContext)
+		if err != nil {
+			abortTransaction(sessionContext)
+			logging.FromContext(sessionContext).Errorf("storage.ApplyImport error: %s", err)
+			return fmt.Errorf("storage.ApplyImport error: %s", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &applied, nil
+}
+
+func (sa *Adapter) applyImport(ctx context.Context, orgID string, batch model.ImportBatch, applied *model.ImportBatch) error {
+	inventoryIDs := map[string]string{}
+	for _, item := range batch.CreateInventories {
+		sourceID := item.ID
+		created, err := sa.CreateRewardInventory(ctx, orgID, item)
+		if err != nil {
+			return err
+		}
+		if sourceID != "" {
+			inventoryIDs[sourceID] = created.ID
+		}
+		applied.CreateInventories = append(applied.CreateInventories, *created)
+	}
+	for _, item := range batch.UpdateInventories {
+		updated, err := sa.UpdateRewardInventory(ctx, orgID, item.ID, item)
+		if err != nil {
+			return err
+		}
+		applied.UpdateInventories = append(applied.UpdateInventories, *updated)
+	}
+
+	for _, item := range batch.CreateTypes {
+		item.PreferredInventoryIDs = remapIDs(item.PreferredInventoryIDs, inventoryIDs)
+		created, err := sa.CreateRewardType(ctx, orgID, item)
+		if err != nil {
+			return err
+		}
+		applied.CreateTypes = append(applied.CreateTypes, *created)
+	}
+	for _, item := range batch.UpdateTypes {
+		item.PreferredInventoryIDs = remapIDs(item.PreferredInventoryIDs, inventoryIDs)
+		updated, err := sa.UpdateRewardType(ctx, orgID, item.ID, item)
+		if err != nil {
+			return err
+		}
+		applied.UpdateTypes = append(applied.UpdateTypes, *updated)
+	}
+
+	for _, item := range batch.CreateOperations {
+		created, err := sa.CreateRewardOperation(ctx, orgID, item)
+		if err != nil {
+			return err
+		}
+		applied.CreateOperations = append(applied.CreateOperations, *created)
+	}
+	for _, item := range batch.UpdateOperations {
+		updated, err := sa.UpdateRewardOperation(ctx, orgID, item.ID, item)
+		if err != nil {
+			return err
+		}
+		applied.UpdateOperations = append(applied.UpdateOperations, *updated)
+	}
+	return nil
+}
+
+// remapIDs replaces the ids which are in the mapping
+func remapIDs(ids []string, mapping map[string]string) []string {
+	if len(ids) == 0 {
+		return ids
+	}
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = id
+		if mappedID, ok := mapping[id]; ok {
+			result[i] = mappedID
+		}
+	}
+	return result
+}
+
+func setPickupSlotIDs(slots []model.PickupSlot) {
+	for i := range slots {
+		if slots[i].ID == "" {
+			slots[i].ID = uuid.NewString()
+		}
+	}
+}
+
+func abortTransaction(sessionContext mongo.SessionContext) {
+	metrics.TransactionAborts.Inc()
+	err := sessionContext.AbortTransaction(sessionContext)
+	if err != nil {
+		logging.FromContext(sessionContext).Errorf("error on aborting a transaction - %s", err)
+	}
+}
+
+// SetListener sets the upper layer storage listener for sending collection changed callbacks
+func (sa *Adapter) SetListener(listener Listener) {
+	sa.db.listener = listener
+}
+
+// Event
+
+func (m *database) onDataChanged(changeDoc map[string]interface{}) {
+	if changeDoc == nil {
+		return
+	}
+	logging.Logger().Debugf("onDataChanged: %+v", changeDoc)
+	ns := changeDoc["ns"]
+	if ns == nil {
+		return
+	}
+	nsMap := ns.(map[string]interface{})
+	coll := nsMap["coll"]
+
+	if "reward_types" == coll {
+		if m.listener != nil {
+			m.listener.OnRewardTypesChanged()
+		}
+	} else if "authorization_policies" == coll {
+		if m.listener != nil {
+			m.listener.OnAuthorizationPoliciesChanged()
 		}
 	}
 }
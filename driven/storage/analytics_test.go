@@ -0,0 +1,93 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"reflect"
+	"rewards/core/model"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAnalyticsPipelines(t *testing.T) {
+	now := time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)
+	start := now.AddDate(0, 0, -14)
+	rewardType := "points"
+	query, err := model.NewAnalyticsQuery(model.AnalyticsBucketWeek, "America/Chicago", &start, nil, &rewardType, now)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	bucketKey := bson.M{"$dateToString": bson.M{"format": "%G-W%V", "date": "$date_created", "timezone": "America/Chicago"}}
+	dateRange := bson.M{"$gte": start, "$lt": now}
+	tests := []struct {
+		name     string
+		pipeline []bson.M
+		expected []bson.M
+	}{
+		{"grants", grantsAnalyticsPipeline("org", *query), []bson.M{
+			{"$match": bson.M{"org_id": "org", "date_created": dateRange, "reward_type": "points"}},
+			{"$group": bson.M{
+				"_id":    bson.M{"bucket": bucketKey, "code": "$code", "building_block": "$building_block"},
+				"count":  bson.M{"$sum": 1},
+				"amount": bson.M{"$sum": "$amount"},
+			}},
+		}},
+		{"earners", earnersAnalyticsPipeline("org", *query), []bson.M{
+			{"$match": bson.M{"org_id": "org", "date_created": dateRange, "reward_type": "points"}},
+			{"$group": bson.M{"_id": bson.M{"bucket": bucketKey, "user_id": "$user_id"}}},
+			{"$group": bson.M{"_id": "$_id.bucket", "count": bson.M{"$sum": 1}}},
+		}},
+		{"claims", claimsAnalyticsPipeline("org", *query), []bson.M{
+			{"$match": bson.M{"org_id": "org", "date_created": dateRange, "items.reward_type": "points"}},
+			{"$group": bson.M{
+				"_id":    bson.M{"bucket": bucketKey, "status": "$status"},
+				"count":  bson.M{"$sum": 1},
+				"amount": bson.M{"$sum": bson.M{"$sum": "$items.amount"}},
+			}},
+		}},
+	}
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.pipeline, test.expected) {
+			t.Errorf("%s: expected the pipeline %v, got %v", test.name, test.expected, test.pipeline)
+		}
+	}
+}
+
+func TestAnalyticsBucketKeyFormats(t *testing.T) {
+	now := time.Date(2024, 3, 11, 12, 0, 0, 0, time.UTC)
+	formats := map[string]string{
+		model.AnalyticsBucketHour: "%Y-%m-%dT%H",
+		model.AnalyticsBucketDay:  "%Y-%m-%d",
+		model.AnalyticsBucketWeek: "%G-W%V",
+	}
+	for bucket, format := range formats {
+		query, err := model.NewAnalyticsQuery(bucket, "", nil, nil, nil, now)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %s", bucket, err)
+		}
+		expected := bson.M{"$dateToString": bson.M{"format": format, "date": "$date_created", "timezone": "UTC"}}
+		if key := analyticsBucketKey(*query); !reflect.DeepEqual(key, expected) {
+			t.Errorf("%s: expected the bucket key %v, got %v", bucket, expected, key)
+		}
+
+		match := analyticsMatch("org", *query, "reward_type")["$match"].(bson.M)
+		if _, ok := match["reward_type"]; ok {
+			t.Errorf("%s: expected no reward type filter without a reward type, got %v", bucket, match)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAuditLogFilter(t *testing.T) {
+	resource, resourceID, actor := "types", "type-a", "admin"
+	startDate := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)
+
+	filter := auditLogFilter("org", nil, nil, nil, nil, nil)
+	expected := bson.D{primitive.E{Key: "org_id", Value: "org"}}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("expected only the org filter, got %v", filter)
+	}
+
+	filter = auditLogFilter("org", &resource, &resourceID, &actor, &startDate, &endDate)
+	expected = bson.D{
+		primitive.E{Key: "org_id", Value: "org"},
+		primitive.E{Key: "resource", Value: resource},
+		primitive.E{Key: "resource_id", Value: resourceID},
+		primitive.E{Key: "actor", Value: actor},
+		primitive.E{Key: "date_created", Value: bson.M{"$gte": startDate, "$lte": endDate}},
+	}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("expected all the filters, got %v", filter)
+	}
+
+	filter = auditLogFilter("org", nil, nil, nil, nil, &endDate)
+	expected = bson.D{
+		primitive.E{Key: "org_id", Value: "org"},
+		primitive.E{Key: "date_created", Value: bson.M{"$lte": endDate}},
+	}
+	if !reflect.DeepEqual(filter, expected) {
+		t.Errorf("expected an open start date, got %v", filter)
+	}
+}
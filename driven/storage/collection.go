@@ -17,7 +17,8 @@ package storage
 import (
 	"context"
 	"errors"
-	"log"
+	"rewards/utils/logging"
+	"rewards/utils/metrics"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -28,13 +29,15 @@ import (
 type collectionWrapper struct {
 	database *database
 	coll     *mongo.Collection
-}
 
-func (collWrapper *collectionWrapper) Find(filter interface{}, result interface{}, findOptions *options.FindOptions) error {
-	return collWrapper.FindWithContext(context.Background(), filter, result, findOptions)
+	orgScoped bool // every filter must select a single org
 }
 
-func (collWrapper *collectionWrapper) FindWithContext(ctx context.Context, filter interface{}, result interface{}, findOptions *options.FindOptions) error {
+func (collWrapper *collectionWrapper) Find(ctx context.Context, filter interface{}, result interface{}, findOptions *options.FindOptions) error {
+	if err := collWrapper.checkOrgScope(ctx, filter); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
@@ -43,20 +46,60 @@ func (collWrapper *collectionWrapper) FindWithContext(ctx context.Context, filte
 		filter = bson.D{}
 	}
 
+	start := time.Now()
 	cur, err := collWrapper.coll.Find(ctx, filter, findOptions)
 
 	if err == nil {
 		err = cur.All(ctx, result)
 	}
+	collWrapper.observe("find", start, err)
 
 	return err
 }
 
-func (collWrapper *collectionWrapper) FindOne(filter interface{}, result interface{}, findOptions *options.FindOneOptions) error {
-	return collWrapper.FindOneWithContext(context.Background(), filter, result, findOptions)
+// FindEach decodes the matching documents one by one through a cursor, so large results are not loaded
+// into memory at once. The iteration stops at the first error of each or when ctx is done. The mongo timeout
+// applies to every batch of the cursor rather than to the whole iteration.
+func (collWrapper *collectionWrapper) FindEach(ctx context.Context, filter interface{}, findOptions *options.FindOptions, each func(cursor *mongo.Cursor) error) error {
+	if err := collWrapper.checkOrgScope(ctx, filter); err != nil {
+		return err
+	}
+
+	if filter == nil {
+		filter = bson.D{}
+	}
+
+	// only the query is observed, the iteration goes at the pace of the caller
+	findContext, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
+	start := time.Now()
+	cur, err := collWrapper.coll.Find(findContext, filter, findOptions)
+	collWrapper.observe("find_each", start, err)
+	cancel()
+	if err != nil {
+		return err
+	}
+	defer cur.Close(context.Background())
+
+	for {
+		nextContext, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
+		next := cur.Next(nextContext)
+		cancel()
+		if !next {
+			break
+		}
+		err = each(cur)
+		if err != nil {
+			return err
+		}
+	}
+	return cur.Err()
 }
 
-func (collWrapper *collectionWrapper) FindOneWithContext(ctx context.Context, filter interface{}, result interface{}, findOptions *options.FindOneOptions) error {
+func (collWrapper *collectionWrapper) FindOne(ctx context.Context, filter interface{}, result interface{}, findOptions *options.FindOneOptions) error {
+	if err := collWrapper.checkOrgScope(ctx, filter); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
@@ -64,7 +107,13 @@ func (collWrapper *collectionWrapper) FindOneWithContext(ctx context.Context, fi
 		findOptions = options.FindOne() // crash if not added!
 	}
 
+	start := time.Now()
 	singleResult := collWrapper.coll.FindOne(ctx, filter, findOptions)
+	if singleResult.Err() == mongo.ErrNoDocuments {
+		collWrapper.observe("find_one", start, nil)
+	} else {
+		collWrapper.observe("find_one", start, singleResult.Err())
+	}
 	if singleResult.Err() != nil {
 		return singleResult.Err()
 	}
@@ -75,11 +124,11 @@ func (collWrapper *collectionWrapper) FindOneWithContext(ctx context.Context, fi
 	return nil
 }
 
-func (collWrapper *collectionWrapper) ReplaceOne(filter interface{}, replacement interface{}, replaceOptions *options.ReplaceOptions) error {
-	return collWrapper.ReplaceOneWithContext(context.Background(), filter, replacement, replaceOptions)
-}
+func (collWrapper *collectionWrapper) ReplaceOne(ctx context.Context, filter interface{}, replacement interface{}, replaceOptions *options.ReplaceOptions) error {
+	if err := collWrapper.checkOrgScope(ctx, filter); err != nil {
+		return err
+	}
 
-func (collWrapper *collectionWrapper) ReplaceOneWithContext(ctx context.Context, filter interface{}, replacement interface{}, replaceOptions *options.ReplaceOptions) error {
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
@@ -90,7 +139,9 @@ func (collWrapper *collectionWrapper) ReplaceOneWithContext(ctx context.Context,
 		replaceOptions = options.Replace() // crash if not added!
 	}
 
+	start := time.Now()
 	res, err := collWrapper.coll.ReplaceOne(ctx, filter, replacement, replaceOptions)
+	collWrapper.observe("replace_one", start, err)
 	if err != nil {
 		return err
 	}
@@ -104,14 +155,12 @@ func (collWrapper *collectionWrapper) ReplaceOneWithContext(ctx context.Context,
 	return nil
 }
 
-func (collWrapper *collectionWrapper) InsertOne(data interface{}) (interface{}, error) {
-	return collWrapper.InsertOneWithContext(context.Background(), data)
-}
-
-func (collWrapper *collectionWrapper) InsertOneWithContext(ctx context.Context, data interface{}) (interface{}, error) {
+func (collWrapper *collectionWrapper) InsertOne(ctx context.Context, data interface{}) (interface{}, error) {
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 
+	start := time.Now()
 	ins, err := collWrapper.coll.InsertOne(ctx, data)
+	collWrapper.observe("insert_one", start, err)
 	cancel()
 
 	if err == nil {
@@ -123,15 +172,13 @@ func (collWrapper *collectionWrapper) InsertOneWithContext(ctx context.Context,
 	return nil, err
 }
 
-func (collWrapper *collectionWrapper) InsertMany(documents []interface{}, opts *options.InsertManyOptions) (*mongo.InsertManyResult, error) {
-	return collWrapper.InsertManyWithContext(context.Background(), documents, opts)
-}
-
-func (collWrapper *collectionWrapper) InsertManyWithContext(ctx context.Context, documents []interface{}, opts *options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+func (collWrapper *collectionWrapper) InsertMany(ctx context.Context, documents []interface{}, opts *options.InsertManyOptions) (*mongo.InsertManyResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
+	start := time.Now()
 	result, err := collWrapper.coll.InsertMany(ctx, documents, opts)
+	collWrapper.observe("insert_many", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -139,15 +186,17 @@ func (collWrapper *collectionWrapper) InsertManyWithContext(ctx context.Context,
 	return result, nil
 }
 
-func (collWrapper *collectionWrapper) DeleteMany(filter interface{}, opts *options.DeleteOptions) (*mongo.DeleteResult, error) {
-	return collWrapper.DeleteManyWithContext(context.Background(), filter, opts)
-}
+func (collWrapper *collectionWrapper) DeleteMany(ctx context.Context, filter interface{}, opts *options.DeleteOptions) (*mongo.DeleteResult, error) {
+	if err := collWrapper.checkOrgScope(ctx, filter); err != nil {
+		return nil, err
+	}
 
-func (collWrapper *collectionWrapper) DeleteManyWithContext(ctx context.Context, filter interface{}, opts *options.DeleteOptions) (*mongo.DeleteResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
+	start := time.Now()
 	result, err := collWrapper.coll.DeleteMany(ctx, filter, opts)
+	collWrapper.observe("delete_many", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -155,15 +204,17 @@ func (collWrapper *collectionWrapper) DeleteManyWithContext(ctx context.Context,
 	return result, nil
 }
 
-func (collWrapper *collectionWrapper) DeleteOne(filter interface{}, opts *options.DeleteOptions) (*mongo.DeleteResult, error) {
-	return collWrapper.DeleteOneWithContext(context.Background(), filter, opts)
-}
+func (collWrapper *collectionWrapper) DeleteOne(ctx context.Context, filter interface{}, opts *options.DeleteOptions) (*mongo.DeleteResult, error) {
+	if err := collWrapper.checkOrgScope(ctx, filter); err != nil {
+		return nil, err
+	}
 
-func (collWrapper *collectionWrapper) DeleteOneWithContext(ctx context.Context, filter interface{}, opts *options.DeleteOptions) (*mongo.DeleteResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
+	start := time.Now()
 	result, err := collWrapper.coll.DeleteOne(ctx, filter, opts)
+	collWrapper.observe("delete_one", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -171,15 +222,17 @@ func (collWrapper *collectionWrapper) DeleteOneWithContext(ctx context.Context,
 	return result, nil
 }
 
-func (collWrapper *collectionWrapper) UpdateOne(filter interface{}, update interface{}, opts *options.UpdateOptions) (*mongo.UpdateResult, error) {
-	return collWrapper.UpdateOneWithContext(context.Background(), filter, update, opts)
-}
+func (collWrapper *collectionWrapper) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := collWrapper.checkOrgScope(ctx, filter); err != nil {
+		return nil, err
+	}
 
-func (collWrapper *collectionWrapper) UpdateOneWithContext(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (*mongo.UpdateResult, error) {
 	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
+	start := time.Now()
 	updateResult, err := collWrapper.coll.UpdateOne(ctx, filter, update, opts)
+	collWrapper.observe("update_one", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -187,15 +240,39 @@ func (collWrapper *collectionWrapper) UpdateOneWithContext(ctx context.Context,
 	return updateResult, nil
 }
 
-func (collWrapper *collectionWrapper) CountDocuments(filter interface{}) (int64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), collWrapper.database.mongoTimeout)
+func (collWrapper *collectionWrapper) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts *options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if err := collWrapper.checkOrgScope(ctx, filter); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
+	defer cancel()
+
+	start := time.Now()
+	updateResult, err := collWrapper.coll.UpdateMany(ctx, filter, update, opts)
+	collWrapper.observe("update_many", start, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return updateResult, nil
+}
+
+func (collWrapper *collectionWrapper) CountDocuments(ctx context.Context, filter interface{}) (int64, error) {
+	if err := collWrapper.checkOrgScope(ctx, filter); err != nil {
+		return -1, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, collWrapper.database.mongoTimeout)
 	defer cancel()
 
 	if filter == nil {
 		filter = bson.D{}
 	}
 
+	start := time.Now()
 	count, err := collWrapper.coll.CountDocuments(ctx, filter)
+	collWrapper.observe("count", start, err)
 
 	if err != nil {
 		return -1, err
@@ -212,25 +289,32 @@ func (collWrapper *collectionWrapper) Watch(pipeline interface{}) error {
 	opts = options.ChangeStream()
 	opts.SetFullDocument(options.UpdateLookup)
 
-	ctx := context.Background()
+	ctx := collWrapper.database.watchContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
 	cur, err := collWrapper.coll.Watch(ctx, pipeline, opts)
 	if err != nil {
-		log.Printf("error watching: %s\n", err)
+		logging.Logger().Errorf("error watching: %s", err)
 		return err
 	}
-	defer cur.Close(ctx)
+	defer cur.Close(context.Background())
+
+	name := collWrapper.coll.Name()
+	collWrapper.database.setStreamOpen(name, true)
+	defer collWrapper.database.setStreamOpen(name, false)
 
 	var changeDoc map[string]interface{}
-	log.Println("waiting for changes")
+	logging.Logger().Info("waiting for changes")
 	for cur.Next(ctx) {
 		if e := cur.Decode(&changeDoc); e != nil {
-			log.Printf("error decoding: %s\n", e)
+			logging.Logger().Errorf("error decoding: %s", e)
 		}
 		collWrapper.database.onDataChanged(changeDoc)
 	}
 
-	if err := cur.Err(); err != nil {
-		log.Printf("error cur.Err(): %s\n", err)
+	if err := cur.Err(); err != nil && ctx.Err() == nil {
+		logging.Logger().Errorf("error cur.Err(): %s", err)
 		return err
 	}
 	return nil
@@ -242,14 +326,14 @@ func (collWrapper *collectionWrapper) ListIndexes() ([]bson.M, error) {
 
 	indexes, err := collWrapper.coll.Indexes().List(ctx, nil)
 	if err != nil {
-		log.Printf("error getting indexes list: %s\n", err)
+		logging.Logger().Errorf("error getting indexes list: %s", err)
 		return nil, err
 	}
 
 	var list []bson.M
 	err = indexes.All(ctx, &list)
 	if err != nil {
-		log.Printf("error iterating indexes list: %s\n", err)
+		logging.Logger().Errorf("error iterating indexes list: %s", err)
 		return nil, err
 	}
 	return list, nil
@@ -292,15 +376,21 @@ func (collWrapper *collectionWrapper) DropIndex(name string) error {
 	return err
 }
 
-func (collWrapper *collectionWrapper) Aggregate(pipeline interface{}, result interface{}, ops *options.AggregateOptions) error {
-	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*15000)
+func (collWrapper *collectionWrapper) Aggregate(ctx context.Context, pipeline interface{}, result interface{}, ops *options.AggregateOptions) error {
+	if err := collWrapper.checkOrgScope(ctx, pipeline); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond*15000)
 	defer cancel()
 
+	start := time.Now()
 	cursor, err := collWrapper.coll.Aggregate(ctx, pipeline, ops)
 
 	if err == nil {
 		err = cursor.All(ctx, result)
 	}
+	collWrapper.observe("aggregate", start, err)
 
 	return err
 }
@@ -315,3 +405,50 @@ func (collWrapper *collectionWrapper) Drop() error {
 	}
 	return nil
 }
+
+// observe records the latency of an operation which started at start and its failure if err is set
+func (collWrapper *collectionWrapper) observe(operation string, start time.Time, err error) {
+	metrics.ObserveStorageOperation(collWrapper.coll.Name(), operation, start, err)
+}
+
+var errMissingOrgScope = errors.New("the filter of an org scoped collection must contain a non empty org_id")
+
+// checkOrgScope denies queries on org scoped collections which are not limited to a single org.
+// Aggregation pipelines must start with a $match stage on org_id.
+func (collWrapper *collectionWrapper) checkOrgScope(ctx context.Context, filter interface{}) error {
+	if !collWrapper.orgScoped {
+		return nil
+	}
+	if !hasOrgScope(filter) {
+		logging.FromContext(ctx).Errorf("%s: %s", collWrapper.coll.Name(), errMissingOrgScope)
+		return errMissingOrgScope
+	}
+	return nil
+}
+
+func hasOrgScope(filter interface{}) bool {
+	switch value := filter.(type) {
+	case bson.D:
+		for _, e := range value {
+			if e.Key == "org_id" {
+				return isOrgID(e.Value)
+			}
+		}
+	case bson.M:
+		return isOrgID(value["org_id"])
+	case []bson.M:
+		if len(value) > 0 {
+			return hasOrgScope(value[0]["$match"])
+		}
+	case []bson.D:
+		if len(value) > 0 && len(value[0]) > 0 && value[0][0].Key == "$match" {
+			return hasOrgScope(value[0][0].Value)
+		}
+	}
+	return false
+}
+
+func isOrgID(value interface{}) bool {
+	orgID, ok := value.(string)
+	return ok && len(orgID) > 0
+}
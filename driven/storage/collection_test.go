@@ -0,0 +1,90 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestHasOrgScope(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter interface{}
+		scoped bool
+	}{
+		{"nil filter", nil, false},
+		{"empty filter", bson.D{}, false},
+		{"id only", bson.D{primitive.E{Key: "_id", Value: "1"}}, false},
+		{"empty org", bson.D{primitive.E{Key: "org_id", Value: ""}, primitive.E{Key: "_id", Value: "1"}}, false},
+		{"org and id", bson.D{primitive.E{Key: "org_id", Value: "org"}, primitive.E{Key: "_id", Value: "1"}}, true},
+		{"id and org", bson.D{primitive.E{Key: "_id", Value: "1"}, primitive.E{Key: "org_id", Value: "org"}}, true},
+		{"org operator", bson.D{primitive.E{Key: "org_id", Value: bson.M{"$in": []string{"a", "b"}}}}, false},
+		{"map filter", bson.M{"org_id": "org"}, true},
+		{"map filter without org", bson.M{"user_id": "user"}, false},
+		{"pipeline", []bson.M{{"$match": bson.M{"org_id": "org"}}, {"$group": bson.M{"_id": "$user_id"}}}, true},
+		{"pipeline with late match", []bson.M{{"$group": bson.M{"_id": "$user_id"}}, {"$match": bson.M{"org_id": "org"}}}, false},
+		{"empty pipeline", []bson.M{}, false},
+		{"document pipeline", []bson.D{{primitive.E{Key: "$match", Value: bson.D{primitive.E{Key: "org_id", Value: "org"}}}}}, true},
+	}
+
+	for _, test := range tests {
+		if scoped := hasOrgScope(test.filter); scoped != test.scoped {
+			t.Errorf("%s: expected %t, got %t", test.name, test.scoped, scoped)
+		}
+	}
+}
+
+func TestCheckOrgScope(t *testing.T) {
+	unscoped := &collectionWrapper{}
+	if err := unscoped.checkOrgScope(context.Background(), bson.D{}); err != nil {
+		t.Errorf("expected collections which are not org scoped to accept any filter, got %s", err)
+	}
+
+	scoped := &collectionWrapper{orgScoped: true}
+	if err := scoped.checkOrgScope(context.Background(), bson.D{primitive.E{Key: "org_id", Value: "org"}}); err != nil {
+		t.Errorf("expected an org filter to be accepted, got %s", err)
+	}
+}
+
+func TestFindIsCancelled(t *testing.T) {
+	// the client connects lazily, so the query waits for a server until the context is done
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(context.Background())
+
+	collWrapper := &collectionWrapper{database: &database{mongoTimeout: time.Minute}, coll: client.Database("rewards").Collection("reward_types")}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	var result []bson.M
+	err = collWrapper.Find(ctx, bson.D{}, &result, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the query to be cancelled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("expected the query to stop right away, it took %s", time.Since(start))
+	}
+}
@@ -18,7 +18,8 @@ import (
 	"context"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"log"
+	"rewards/utils/logging"
+	"sync"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
@@ -28,6 +29,7 @@ import (
 // Listener listens for storage updates
 type Listener interface {
 	OnRewardTypesChanged()
+	OnAuthorizationPoliciesChanged()
 }
 
 type database struct {
@@ -37,6 +39,8 @@ type database struct {
 	mongoDBName  string
 	mongoTimeout time.Duration
 
+	defaultOrgID string
+
 	db       *mongo.Database
 	dbClient *mongo.Client
 
@@ -45,11 +49,32 @@ type database struct {
 	rewardInventories *collectionWrapper
 	rewardHistory     *collectionWrapper
 	rewardClaims      *collectionWrapper
+	rewardWallets     *collectionWrapper
+	rewardCatalog     *collectionWrapper
+	rewardPickups     *collectionWrapper
+	pickupLocations   *collectionWrapper
+	pickupBookings    *collectionWrapper
+
+	authorizationPolicies *collectionWrapper
+	internalCredentials   *collectionWrapper
+	auditLog              *collectionWrapper
+
+	leaderboardScores   *collectionWrapper
+	leaderboardProfiles *collectionWrapper
+
+	migrations *collectionWrapper
+
+	// the change streams are closed when the storage stops
+	watchContext context.Context
+	stopWatching context.CancelFunc
+	watchers     sync.WaitGroup
+	streamsLock  sync.RWMutex
+	streams      map[string]bool // collection name -> the change stream is open
 }
 
 func (m *database) start() error {
 
-	log.Println("database -> start")
+	logging.Logger().Info("database -> start")
 
 	//connect to the database
 	clientOptions := options.Client().ApplyURI(m.mongoDBAuth)
@@ -70,38 +95,92 @@ func (m *database) start() error {
 
 	//apply checks
 	db := client.Database(m.mongoDBName)
+	m.watchContext, m.stopWatching = context.WithCancel(context.Background())
+	m.streams = map[string]bool{}
 
-	rewardTypes := &collectionWrapper{database: m, coll: db.Collection("reward_types")}
+	rewardTypes := &collectionWrapper{database: m, coll: db.Collection("reward_types"), orgScoped: true}
 	err = m.applyRewardTypesChecks(rewardTypes)
 	if err != nil {
 		return err
 	}
-	go rewardTypes.Watch(nil)
+	m.watch(rewardTypes)
 
-	rewardOperations := &collectionWrapper{database: m, coll: db.Collection("reward_operations")}
+	rewardOperations := &collectionWrapper{database: m, coll: db.Collection("reward_operations"), orgScoped: true}
 	err = m.applyRewardOperationsChecks(rewardOperations)
 	if err != nil {
 		return err
 	}
 
-	rewardInventories := &collectionWrapper{database: m, coll: db.Collection("reward_inventories")}
+	rewardInventories := &collectionWrapper{database: m, coll: db.Collection("reward_inventories"), orgScoped: true}
 	err = m.applyRewardInventoriesChecks(rewardInventories)
 	if err != nil {
 		return err
 	}
 
-	rewardHistory := &collectionWrapper{database: m, coll: db.Collection("reward_history")}
+	rewardHistory := &collectionWrapper{database: m, coll: db.Collection("reward_history"), orgScoped: true}
 	err = m.applyRewardHistoryChecks(rewardHistory)
 	if err != nil {
 		return err
 	}
 
-	rewardClaims := &collectionWrapper{database: m, coll: db.Collection("reward_claims")}
+	rewardClaims := &collectionWrapper{database: m, coll: db.Collection("reward_claims"), orgScoped: true}
 	err = m.applyRewardClaimsChecks(rewardClaims)
 	if err != nil {
 		return err
 	}
 
+	rewardWallets := &collectionWrapper{database: m, coll: db.Collection("reward_wallets"), orgScoped: true}
+	err = m.applyRewardWalletsChecks(rewardWallets)
+	if err != nil {
+		return err
+	}
+
+	rewardCatalog := &collectionWrapper{database: m, coll: db.Collection("reward_catalog"), orgScoped: true}
+	err = m.applyRewardCatalogChecks(rewardCatalog)
+	if err != nil {
+		return err
+	}
+
+	rewardPickups := &collectionWrapper{database: m, coll: db.Collection("reward_claim_pickups"), orgScoped: true}
+	err = m.applyRewardPickupsChecks(rewardPickups)
+	if err != nil {
+		return err
+	}
+
+	pickupLocations := &collectionWrapper{database: m, coll: db.Collection("pickup_locations"), orgScoped: true}
+	err = m.applyPickupLocationsChecks(pickupLocations)
+	if err != nil {
+		return err
+	}
+
+	// the booked count of the pickup slots with capacity, keyed by org, location and slot
+	pickupBookings := &collectionWrapper{database: m, coll: db.Collection("pickup_slot_bookings"), orgScoped: true}
+
+	authorizationPolicies := &collectionWrapper{database: m, coll: db.Collection("authorization_policies")}
+	err = m.applyAuthorizationPoliciesChecks(authorizationPolicies)
+	if err != nil {
+		return err
+	}
+	m.watch(authorizationPolicies)
+
+	internalCredentials := &collectionWrapper{database: m, coll: db.Collection("internal_credentials")}
+	err = m.applyInternalCredentialsChecks(internalCredentials)
+	if err != nil {
+		return err
+	}
+
+	auditLog := &collectionWrapper{database: m, coll: db.Collection("audit_log"), orgScoped: true}
+	err = m.applyAuditLogChecks(auditLog)
+	if err != nil {
+		return err
+	}
+
+	// the indexes of the leaderboards are created by the migrations
+	leaderboardScores := &collectionWrapper{database: m, coll: db.Collection("leaderboard_scores"), orgScoped: true}
+	leaderboardProfiles := &collectionWrapper{database: m, coll: db.Collection("leaderboard_profiles"), orgScoped: true}
+
+	migrations := &collectionWrapper{database: m, coll: db.Collection("migrations")}
+
 	//asign the db, db client and the collections
 	m.db = db
 	m.dbClient = client
@@ -111,12 +190,75 @@ func (m *database) start() error {
 	m.rewardHistory = rewardHistory
 	m.rewardOperations = rewardOperations
 	m.rewardClaims = rewardClaims
+	m.rewardWallets = rewardWallets
+	m.rewardCatalog = rewardCatalog
+	m.rewardPickups = rewardPickups
+	m.pickupLocations = pickupLocations
+	m.pickupBookings = pickupBookings
+	m.authorizationPolicies = authorizationPolicies
+	m.internalCredentials = internalCredentials
+	m.auditLog = auditLog
+	m.leaderboardScores = leaderboardScores
+	m.leaderboardProfiles = leaderboardProfiles
+	m.migrations = migrations
 
 	return nil
 }
 
+// stop closes the change streams and disconnects from the database. It waits for the change streams
+// until the context is done
+func (m *database) stop(ctx context.Context) error {
+	logging.FromContext(ctx).Info("database -> stop")
+
+	if m.stopWatching != nil {
+		m.stopWatching()
+	}
+	closed := make(chan struct{})
+	go func() {
+		m.watchers.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-ctx.Done():
+		logging.FromContext(ctx).Warnf("database -> change streams are not closed: %s", ctx.Err())
+	}
+
+	if m.dbClient == nil {
+		return nil
+	}
+	return m.dbClient.Disconnect(ctx)
+}
+
+// watch watches the collection changes in the background until the database stops
+func (m *database) watch(collWrapper *collectionWrapper) {
+	m.setStreamOpen(collWrapper.coll.Name(), false)
+	m.watchers.Add(1)
+	go func() {
+		defer m.watchers.Done()
+		collWrapper.Watch(nil)
+	}()
+}
+
+func (m *database) setStreamOpen(name string, open bool) {
+	m.streamsLock.Lock()
+	defer m.streamsLock.Unlock()
+	m.streams[name] = open
+}
+
+// changeStreams gives the status of the change streams by collection name
+func (m *database) changeStreams() map[string]bool {
+	m.streamsLock.RLock()
+	defer m.streamsLock.RUnlock()
+	streams := make(map[string]bool, len(m.streams))
+	for name, open := range m.streams {
+		streams[name] = open
+	}
+	return streams
+}
+
 func (m *database) applyRewardTypesChecks(posts *collectionWrapper) error {
-	log.Println("apply reward_types checks.....")
+	logging.Logger().Info("apply reward_types checks.....")
 
 	indexes, _ := posts.ListIndexes()
 	indexMapping := map[string]interface{}{}
@@ -158,12 +300,12 @@ func (m *database) applyRewardTypesChecks(posts *collectionWrapper) error {
 		}
 	}
 
-	log.Println("reward_types checks passed")
+	logging.Logger().Info("reward_types checks passed")
 	return nil
 }
 
 func (m *database) applyRewardOperationsChecks(posts *collectionWrapper) error {
-	log.Println("apply reward_operations checks.....")
+	logging.Logger().Info("apply reward_operations checks.....")
 
 	indexes, _ := posts.ListIndexes()
 	indexMapping := map[string]interface{}{}
@@ -215,12 +357,12 @@ func (m *database) applyRewardOperationsChecks(posts *collectionWrapper) error {
 		}
 	}
 
-	log.Println("reward_operations checks passed")
+	logging.Logger().Info("reward_operations checks passed")
 	return nil
 }
 
 func (m *database) applyRewardInventoriesChecks(posts *collectionWrapper) error {
-	log.Println("apply reward_inventories checks.....")
+	logging.Logger().Info("apply reward_inventories checks.....")
 
 	indexes, _ := posts.ListIndexes()
 	indexMapping := map[string]interface{}{}
@@ -311,6 +453,16 @@ func (m *database) applyRewardInventoriesChecks(posts *collectionWrapper) error
 		}
 	}
 
+	if indexMapping["location_ids_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "location_ids", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
 	if indexMapping["in_stock_1"] == nil {
 		err := posts.AddIndex(
 			bson.D{
@@ -321,12 +473,12 @@ func (m *database) applyRewardInventoriesChecks(posts *collectionWrapper) error
 		}
 	}
 
-	log.Println("reward_inventories checks passed")
+	logging.Logger().Info("reward_inventories checks passed")
 	return nil
 }
 
 func (m *database) applyRewardHistoryChecks(posts *collectionWrapper) error {
-	log.Println("apply reward_history checks.....")
+	logging.Logger().Info("apply reward_history checks.....")
 
 	indexes, _ := posts.ListIndexes()
 	indexMapping := map[string]interface{}{}
@@ -388,12 +540,12 @@ func (m *database) applyRewardHistoryChecks(posts *collectionWrapper) error {
 		}
 	}
 
-	log.Println("reward_history checks passed")
+	logging.Logger().Info("reward_history checks passed")
 	return nil
 }
 
 func (m *database) applyRewardClaimsChecks(posts *collectionWrapper) error {
-	log.Println("apply reward_claims checks.....")
+	logging.Logger().Info("apply reward_claims checks.....")
 
 	indexes, _ := posts.ListIndexes()
 	indexMapping := map[string]interface{}{}
@@ -435,6 +587,300 @@ func (m *database) applyRewardClaimsChecks(posts *collectionWrapper) error {
 		}
 	}
 
-	log.Println("reward_claims checks passed")
+	if indexMapping["purchase.catalog_item_id_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "purchase.catalog_item_id", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexMapping["pickup.location_id_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "pickup.location_id", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexMapping["pickup_code_1"] == nil {
+		err := posts.AddIndexWithOptions(
+			bson.D{
+				primitive.E{Key: "pickup_code", Value: 1},
+			}, options.Index().SetUnique(true).SetSparse(true))
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Logger().Info("reward_claims checks passed")
+	return nil
+}
+
+func (m *database) applyRewardWalletsChecks(posts *collectionWrapper) error {
+	logging.Logger().Info("apply reward_wallets checks.....")
+
+	indexes, _ := posts.ListIndexes()
+	indexMapping := map[string]interface{}{}
+	if indexes != nil {
+
+		for _, index := range indexes {
+			name := index["name"].(string)
+			indexMapping[name] = index
+		}
+	}
+
+	if indexMapping["org_id_1_user_id_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "org_id", Value: 1},
+				primitive.E{Key: "user_id", Value: 1},
+			}, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Logger().Info("reward_wallets checks passed")
+	return nil
+}
+
+func (m *database) applyRewardCatalogChecks(posts *collectionWrapper) error {
+	logging.Logger().Info("apply reward_catalog checks.....")
+
+	indexes, _ := posts.ListIndexes()
+	indexMapping := map[string]interface{}{}
+	if indexes != nil {
+
+		for _, index := range indexes {
+			name := index["name"].(string)
+			indexMapping[name] = index
+		}
+	}
+
+	if indexMapping["org_id_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "org_id", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexMapping["reward_type_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "reward_type", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexMapping["active_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "active", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Logger().Info("reward_catalog checks passed")
+	return nil
+}
+
+func (m *database) applyRewardPickupsChecks(posts *collectionWrapper) error {
+	logging.Logger().Info("apply reward_claim_pickups checks.....")
+
+	indexes, _ := posts.ListIndexes()
+	indexMapping := map[string]interface{}{}
+	if indexes != nil {
+
+		for _, index := range indexes {
+			name := index["name"].(string)
+			indexMapping[name] = index
+		}
+	}
+
+	if indexMapping["org_id_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "org_id", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexMapping["claim_id_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "claim_id", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexMapping["date_created_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "date_created", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Logger().Info("reward_claim_pickups checks passed")
+	return nil
+}
+
+func (m *database) applyPickupLocationsChecks(posts *collectionWrapper) error {
+	logging.Logger().Info("apply pickup_locations checks.....")
+
+	indexes, _ := posts.ListIndexes()
+	indexMapping := map[string]interface{}{}
+	if indexes != nil {
+
+		for _, index := range indexes {
+			name := index["name"].(string)
+			indexMapping[name] = index
+		}
+	}
+
+	if indexMapping["org_id_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "org_id", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Logger().Info("pickup_locations checks passed")
+	return nil
+}
+
+func (m *database) applyAuthorizationPoliciesChecks(posts *collectionWrapper) error {
+	logging.Logger().Info("apply authorization_policies checks.....")
+
+	indexes, _ := posts.ListIndexes()
+	indexMapping := map[string]interface{}{}
+	if indexes != nil {
+
+		for _, index := range indexes {
+			name := index["name"].(string)
+			indexMapping[name] = index
+		}
+	}
+
+	if indexMapping["subject_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "subject", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Logger().Info("authorization_policies checks passed")
+	return nil
+}
+
+func (m *database) applyInternalCredentialsChecks(posts *collectionWrapper) error {
+	logging.Logger().Info("apply internal_credentials checks.....")
+
+	indexes, _ := posts.ListIndexes()
+	indexMapping := map[string]interface{}{}
+	if indexes != nil {
+
+		for _, index := range indexes {
+			name := index["name"].(string)
+			indexMapping[name] = index
+		}
+	}
+
+	if indexMapping["key_hash_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "key_hash", Value: 1},
+			}, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexMapping["building_block_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "building_block", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Logger().Info("internal_credentials checks passed")
+	return nil
+}
+
+func (m *database) applyAuditLogChecks(posts *collectionWrapper) error {
+	logging.Logger().Info("apply audit_log checks.....")
+
+	indexes, _ := posts.ListIndexes()
+	indexMapping := map[string]interface{}{}
+	if indexes != nil {
+
+		for _, index := range indexes {
+			name := index["name"].(string)
+			indexMapping[name] = index
+		}
+	}
+
+	if indexMapping["org_id_1_date_created_-1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "org_id", Value: 1},
+				primitive.E{Key: "date_created", Value: -1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexMapping["org_id_1_resource_1_resource_id_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "org_id", Value: 1},
+				primitive.E{Key: "resource", Value: 1},
+				primitive.E{Key: "resource_id", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	if indexMapping["org_id_1_actor_1"] == nil {
+		err := posts.AddIndex(
+			bson.D{
+				primitive.E{Key: "org_id", Value: 1},
+				primitive.E{Key: "actor", Value: 1},
+			}, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Logger().Info("audit_log checks passed")
 	return nil
 }
@@ -0,0 +1,30 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"testing"
+)
+
+func TestRemapIDs(t *testing.T) {
+	mapping := map[string]string{"source-a": "target-a"}
+	ids := remapIDs([]string{"source-a", "target-b"}, mapping)
+	if len(ids) != 2 || ids[0] != "target-a" || ids[1] != "target-b" {
+		t.Errorf("expected only the mapped id to be replaced, got %v", ids)
+	}
+	if ids := remapIDs(nil, mapping); ids != nil {
+		t.Errorf("expected no ids, got %v", ids)
+	}
+}
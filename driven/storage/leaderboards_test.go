@@ -0,0 +1,76 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"rewards/core/model"
+	"testing"
+	"time"
+)
+
+func testLeaderboardDay(userID string, buildingBlock string, day string, amount int) leaderboardDay {
+	item := leaderboardDay{Amount: amount}
+	item.Key.UserID = userID
+	item.Key.RewardType = "points"
+	item.Key.BuildingBlock = buildingBlock
+	item.Key.Day = day
+	return item
+}
+
+func TestBuildLeaderboardScores(t *testing.T) {
+	days := []leaderboardDay{
+		testLeaderboardDay("user", "events", "2024-03-11", 5),
+		testLeaderboardDay("user", "groups", "2024-03-12", 3),
+		testLeaderboardDay("user", "events", "2024-02-28", 2),
+	}
+	profiles := []model.LeaderboardProfile{{UserID: "user", OptedIn: true, DisplayHandle: "earner"}}
+
+	scores, err := buildLeaderboardScores("org", days, profiles, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	amounts := map[string]int{}
+	for _, score := range scores {
+		if !score.OptedIn || score.DisplayHandle != "earner" || score.OrgID != "org" {
+			t.Errorf("expected the profile and the org on the score, got %+v", score)
+		}
+		amounts[score.Window+"|"+score.BuildingBlock+"|"+score.WindowStart.Format("2006-01-02")] += score.Amount
+	}
+
+	expected := map[string]int{
+		"all_time||0001-01-01":       10,
+		"all_time|events|0001-01-01": 7,
+		"all_time|groups|0001-01-01": 3,
+		"month||2024-03-01":          8,
+		"month||2024-02-01":          2,
+		"week||2024-03-11":           8,
+		"week||2024-02-26":           2,
+		"day||2024-03-12":            3,
+		"day|groups|2024-03-12":      3,
+	}
+	for key, amount := range expected {
+		if amounts[key] != amount {
+			t.Errorf("%s: expected %d, got %d", key, amount, amounts[key])
+		}
+	}
+}
+
+func TestBuildLeaderboardScoresInvalidDay(t *testing.T) {
+	_, err := buildLeaderboardScores("org", []leaderboardDay{testLeaderboardDay("user", "events", "11/03/2024", 1)}, nil, time.Now())
+	if err == nil {
+		t.Error("expected an error for an invalid day")
+	}
+}
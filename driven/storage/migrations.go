@@ -0,0 +1,616 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"rewards/utils/logging"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migration statuses reported by a migration run
+const (
+	MigrationStatusApplied string = "applied"
+	MigrationStatusPending string = "pending"
+	MigrationStatusFailed  string = "failed"
+)
+
+const (
+	migrationLockID      string        = "lock"
+	migrationLockTTL     time.Duration = 10 * time.Minute
+	migrationLockRenewal time.Duration = migrationLockTTL / 5
+)
+
+var errMigrationsLockLost = errors.New("the migrations lock was lost")
+
+// migration is a versioned up-only change of the stored data or of the collection indexes.
+// Every migration is applied once and recorded in the migrations collection. In dry run mode
+// the migration must not change anything and only describes the changes it would make.
+type migration struct {
+	version     int
+	description string
+	up          func(ctx context.Context, m *database, dryRun bool) ([]string, error)
+}
+
+// migrationRecord is the entry stored for an applied migration
+type migrationRecord struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	DateApplied time.Time `bson:"date_applied"`
+}
+
+// migrationLock is the entry stored while a migration run is in progress
+type migrationLock struct {
+	ID           string    `bson:"_id"`
+	Owner        string    `bson:"owner"`
+	DateAcquired time.Time `bson:"date_acquired"`
+	DateExpires  time.Time `bson:"date_expires"`
+}
+
+// MigrationResult describes the outcome of a pending migration
+type MigrationResult struct {
+	Version     int      `json:"version"`
+	Description string   `json:"description"`
+	Status      string   `json:"status"`
+	Changes     []string `json:"changes"`
+	Error       string   `json:"error,omitempty"`
+}
+
+// migrations lists all migrations ordered by version. New migrations must be appended with the next version,
+// the version of a released migration never changes.
+var migrations = []migration{
+	{version: 1, description: "unique reward types and reward operation codes per org", up: migrateUniqueRewardTypesAndCodes},
+	{version: 2, description: "backfill org_id of the documents created before org isolation", up: migrateBackfillOrgID},
+	{version: 3, description: "backfill inventory_backed of the reward types", up: migrateBackfillInventoryBacked},
+	{version: 4, description: "build the leaderboard scores from the reward history", up: migrateBuildLeaderboardScores},
+	{version: 5, description: "unique leaderboard scores per user and window and the ranking index", up: migrateLeaderboardScoresIndexes},
+	{version: 6, description: "unique leaderboard profiles per user and display handles per org", up: migrateLeaderboardProfilesIndexes},
+}
+
+// duplicateGroup is a group of documents which share a key that must be unique
+type duplicateGroup struct {
+	Key   bson.M   `bson:"_id"`
+	IDs   []string `bson:"ids"`
+	Count int      `bson:"count"`
+}
+
+func (d duplicateGroup) String() string {
+	keys := make([]string, 0, len(d.Key))
+	for _, field := range []string{"org_id", "user_id", "reward_type", "code", "building_block", "window", "window_start", "display_handle"} {
+		if value, ok := d.Key[field]; ok {
+			keys = append(keys, fmt.Sprintf("%s=%v", field, value))
+		}
+	}
+	return fmt.Sprintf("%s (%d documents: %s)", strings.Join(keys, " "), d.Count, strings.Join(d.IDs, ", "))
+}
+
+// pendingMigrations returns the migrations which are not applied yet in version order
+func pendingMigrations(all []migration, applied []migrationRecord) []migration {
+	appliedVersions := map[int]bool{}
+	for _, record := range applied {
+		appliedVersions[record.Version] = true
+	}
+
+	pending := []migration{}
+	for _, migration := range all {
+		if !appliedVersions[migration.version] {
+			pending = append(pending, migration)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].version < pending[j].version })
+	return pending
+}
+
+// migrate applies the pending migrations in order. A failing migration stops the run and stays
+// pending, so it is retried on the next run once the data is fixed. In dry run mode nothing is
+// changed and the result describes what would be applied. The run stops once the migrations lock is lost.
+func (m *database) migrate(dryRun bool) ([]MigrationResult, error) {
+	logging.Logger().Infof("apply migrations (dry run: %t).....", dryRun)
+
+	ctx := context.Background()
+	if !dryRun {
+		lock, err := m.lockMigrations()
+		if err != nil {
+			return nil, err
+		}
+		defer m.unlockMigrations(lock.Owner)
+
+		var cancel context.CancelCauseFunc
+		ctx, cancel = context.WithCancelCause(ctx)
+		defer cancel(nil)
+		go m.renewMigrationsLock(ctx, lock, cancel)
+	}
+
+	var applied []migrationRecord
+	err := m.migrations.Find(ctx, bson.D{primitive.E{Key: "_id", Value: bson.M{"$ne": migrationLockID}}}, &applied, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []MigrationResult{}
+	for _, migration := range pendingMigrations(migrations, applied) {
+		if ctx.Err() != nil {
+			return results, context.Cause(ctx)
+		}
+		logging.Logger().Infof("apply migration %d: %s", migration.version, migration.description)
+		result := MigrationResult{Version: migration.version, Description: migration.description, Status: MigrationStatusPending}
+
+		result.Changes, err = migration.up(ctx, m, dryRun)
+		if ctx.Err() != nil {
+			return results, context.Cause(ctx)
+		}
+		if err != nil {
+			logging.Logger().Warnf("migration %d failed and stays pending: %s", migration.version, err)
+			result.Status = MigrationStatusFailed
+			result.Error = err.Error()
+			results = append(results, result)
+			break
+		}
+
+		if !dryRun {
+			record := migrationRecord{Version: migration.version, Description: migration.description, DateApplied: time.Now().UTC()}
+			_, err = m.migrations.InsertOne(ctx, &record)
+			if err != nil {
+				return results, err
+			}
+			result.Status = MigrationStatusApplied
+			logging.Logger().Infof("migration %d applied", migration.version)
+		}
+		results = append(results, result)
+	}
+
+	logging.Logger().Info("migrations checked")
+	return results, nil
+}
+
+// lockMigrations acquires the migrations lock so replicas which start together do not apply the
+// same migrations. It waits while another replica holds the lock and takes over an expired lock.
+func (m *database) lockMigrations() (migrationLock, error) {
+	hostname, _ := os.Hostname()
+	owner := fmt.Sprintf("%s:%d", hostname, os.Getpid())
+
+	deadline := time.Now().Add(migrationLockTTL)
+	for {
+		now := time.Now().UTC()
+		lock := migrationLock{ID: migrationLockID, Owner: owner, DateAcquired: now, DateExpires: now.Add(migrationLockTTL)}
+		_, err := m.migrations.InsertOne(context.Background(), &lock)
+		if err == nil {
+			return lock, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return migrationLock{}, err
+		}
+
+		//release the lock of a replica which stopped while migrating
+		expired := bson.D{
+			primitive.E{Key: "_id", Value: migrationLockID},
+			primitive.E{Key: "date_expires", Value: bson.M{"$lt": now}},
+		}
+		result, err := m.migrations.DeleteOne(context.Background(), expired, nil)
+		if err != nil {
+			return migrationLock{}, err
+		}
+		if result.DeletedCount > 0 {
+			logging.Logger().Warn("released an expired migrations lock")
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return migrationLock{}, errors.New("timeout waiting for the migrations lock")
+		}
+		logging.Logger().Info("waiting for the migrations lock.....")
+		time.Sleep(time.Second)
+	}
+}
+
+// renewMigrationsLock extends the migrations lock until the run is done. The run is cancelled once the lock
+// is taken over by another replica or may expire before the next renewal.
+func (m *database) renewMigrationsLock(ctx context.Context, lock migrationLock, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(migrationLockRenewal)
+	defer ticker.Stop()
+
+	filter := bson.D{
+		primitive.E{Key: "_id", Value: migrationLockID},
+		primitive.E{Key: "owner", Value: lock.Owner},
+	}
+	expires := lock.DateExpires
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now().UTC()
+		update := bson.D{
+			primitive.E{Key: "$set", Value: bson.D{
+				primitive.E{Key: "date_expires", Value: now.Add(migrationLockTTL)},
+			}},
+		}
+		updateContext, updateCancel := context.WithTimeout(ctx, time.Millisecond*15000)
+		result, err := m.migrations.UpdateOne(updateContext, filter, update, nil)
+		updateCancel()
+		if err != nil {
+			logging.Logger().Warnf("error renewing the migrations lock: %s", err)
+			if now.Add(migrationLockRenewal).After(expires) {
+				cancel(errMigrationsLockLost)
+				return
+			}
+			continue
+		}
+		if result.MatchedCount == 0 {
+			cancel(errMigrationsLockLost)
+			return
+		}
+		expires = now.Add(migrationLockTTL)
+	}
+}
+
+func (m *database) unlockMigrations(owner string) {
+	filter := bson.D{
+		primitive.E{Key: "_id", Value: migrationLockID},
+		primitive.E{Key: "owner", Value: owner},
+	}
+	_, err := m.migrations.DeleteOne(context.Background(), filter, nil)
+	if err != nil {
+		logging.Logger().Errorf("error releasing the migrations lock: %s", err)
+	}
+}
+
+// findDuplicates finds the documents of the collection matching the filter which share the values of the given
+// fields. It looks through all orgs, so the org scope check of the collection wrapper is bypassed.
+func findDuplicates(ctx context.Context, collWrapper *collectionWrapper, filter bson.D, fields ...string) ([]duplicateGroup, error) {
+	key := bson.M{}
+	for _, field := range fields {
+		key[field] = "$" + field
+	}
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$group": bson.M{"_id": key, "ids": bson.M{"$push": "$_id"}, "count": bson.M{"$sum": 1}}},
+		{"$match": bson.M{"count": bson.M{"$gt": 1}}},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond*15000)
+	defer cancel()
+
+	cursor, err := collWrapper.coll.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, err
+	}
+	var result []duplicateGroup
+	err = cursor.All(ctx, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// migrateBackfillOrgID sets the org_id of the documents which were created before the data was
+// separated by org. They are assigned to the default org. The reward types and operations which would
+// break the unique indexes of the default org are reported and must be resolved first.
+func migrateBackfillOrgID(ctx context.Context, m *database, dryRun bool) ([]string, error) {
+	filter := bson.D{
+		primitive.E{Key: "$or", Value: bson.A{
+			bson.M{"org_id": bson.M{"$exists": false}},
+			bson.M{"org_id": nil},
+			bson.M{"org_id": ""},
+		}},
+	}
+	update := bson.D{
+		primitive.E{Key: "$set", Value: bson.D{
+			primitive.E{Key: "org_id", Value: m.defaultOrgID},
+		}},
+	}
+
+	changes := []string{}
+	if len(m.defaultOrgID) > 0 {
+		defaultOrgFilter := bson.D{
+			primitive.E{Key: "$or", Value: bson.A{
+				filter,
+				bson.M{"org_id": m.defaultOrgID},
+			}},
+		}
+		typeConflicts, err := findDuplicates(ctx, m.rewardTypes, defaultOrgFilter, "reward_type")
+		if err != nil {
+			return nil, err
+		}
+		operationConflicts, err := findDuplicates(ctx, m.rewardOperations, defaultOrgFilter, "code", "building_block")
+		if err != nil {
+			return nil, err
+		}
+
+		if len(typeConflicts) > 0 || len(operationConflicts) > 0 {
+			for _, conflict := range typeConflicts {
+				logging.Logger().Warnf("conflicting reward type: %s", conflict)
+				changes = append(changes, fmt.Sprintf("conflicting reward type: %s", conflict))
+			}
+			for _, conflict := range operationConflicts {
+				logging.Logger().Warnf("conflicting reward operation: %s", conflict)
+				changes = append(changes, fmt.Sprintf("conflicting reward operation: %s", conflict))
+			}
+			return changes, fmt.Errorf("found %d reward types and %d reward operation codes which already exist in org %s", len(typeConflicts), len(operationConflicts), m.defaultOrgID)
+		}
+	}
+
+	for _, collWrapper := range []*collectionWrapper{m.rewardTypes, m.rewardOperations, m.rewardInventories, m.rewardHistory, m.rewardClaims} {
+		countContext, cancel := context.WithTimeout(ctx, time.Millisecond*15000)
+		count, err := collWrapper.coll.CountDocuments(countContext, filter)
+		cancel()
+		if err != nil {
+			return changes, err
+		}
+		if count == 0 {
+			continue
+		}
+		if len(m.defaultOrgID) == 0 {
+			return changes, fmt.Errorf("%d documents of %s have no org_id and no default org is configured", count, collWrapper.coll.Name())
+		}
+
+		if !dryRun {
+			updateContext, cancel := context.WithTimeout(ctx, time.Millisecond*15000)
+			_, err = collWrapper.coll.UpdateMany(updateContext, filter, update)
+			cancel()
+			if err != nil {
+				return changes, err
+			}
+		}
+		changes = append(changes, fmt.Sprintf("set org_id %s on %d documents of %s", m.defaultOrgID, count, collWrapper.coll.Name()))
+	}
+	return changes, nil
+}
+
+// migrateUniqueRewardTypesAndCodes creates unique indexes on (org_id, reward_type) of the reward types
+// and on (org_id, code, building_block) of the reward operations. Existing duplicates are reported and
+// must be resolved before the indexes can be created.
+func migrateUniqueRewardTypesAndCodes(ctx context.Context, m *database, dryRun bool) ([]string, error) {
+	typeDuplicates, err := findDuplicates(ctx, m.rewardTypes, bson.D{}, "org_id", "reward_type")
+	if err != nil {
+		return nil, err
+	}
+	operationDuplicates, err := findDuplicates(ctx, m.rewardOperations, bson.D{}, "org_id", "code", "building_block")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(typeDuplicates) > 0 || len(operationDuplicates) > 0 {
+		changes := []string{}
+		for _, duplicate := range typeDuplicates {
+			logging.Logger().Warnf("duplicate reward type: %s", duplicate)
+			changes = append(changes, fmt.Sprintf("duplicate reward type: %s", duplicate))
+		}
+		for _, duplicate := range operationDuplicates {
+			logging.Logger().Warnf("duplicate reward operation: %s", duplicate)
+			changes = append(changes, fmt.Sprintf("duplicate reward operation: %s", duplicate))
+		}
+		return changes, fmt.Errorf("found %d duplicate reward types and %d duplicate reward operation codes", len(typeDuplicates), len(operationDuplicates))
+	}
+
+	changes := []string{
+		"create unique index org_id_1_reward_type_1 on reward_types",
+		"create unique index org_id_1_code_1_building_block_1 on reward_operations",
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	err = m.rewardTypes.AddIndexWithOptions(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "reward_type", Value: 1},
+		}, options.Index().SetUnique(true).SetName("org_id_1_reward_type_1"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.rewardOperations.AddIndexWithOptions(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "code", Value: 1},
+			primitive.E{Key: "building_block", Value: 1},
+		}, options.Index().SetUnique(true).SetName("org_id_1_code_1_building_block_1"))
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// migrateBackfillInventoryBacked sets inventory_backed of the reward types which were created before it
+// was declared. Currencies were never drawn from the inventories, all other reward types were.
+func migrateBackfillInventoryBacked(ctx context.Context, m *database, dryRun bool) ([]string, error) {
+	changes := []string{}
+	for _, inventoryBacked := range []bool{true, false} {
+		filter := bson.D{
+			primitive.E{Key: "inventory_backed", Value: bson.M{"$exists": false}},
+			primitive.E{Key: "currency", Value: bson.M{"$ne": inventoryBacked}},
+		}
+		update := bson.D{
+			primitive.E{Key: "$set", Value: bson.D{
+				primitive.E{Key: "inventory_backed", Value: inventoryBacked},
+			}},
+		}
+
+		countContext, cancel := context.WithTimeout(ctx, time.Millisecond*15000)
+		count, err := m.rewardTypes.coll.CountDocuments(countContext, filter)
+		cancel()
+		if err != nil {
+			return changes, err
+		}
+		if count == 0 {
+			continue
+		}
+
+		if !dryRun {
+			updateContext, cancel := context.WithTimeout(ctx, time.Millisecond*15000)
+			_, err = m.rewardTypes.coll.UpdateMany(updateContext, filter, update)
+			cancel()
+			if err != nil {
+				return changes, err
+			}
+		}
+		changes = append(changes, fmt.Sprintf("set inventory_backed %t on %d reward types", inventoryBacked, count))
+	}
+	return changes, nil
+}
+
+// migrateBuildLeaderboardScores computes the leaderboard scores of the rewards which were granted before the
+// scores were maintained with every grant
+func migrateBuildLeaderboardScores(ctx context.Context, m *database, dryRun bool) ([]string, error) {
+	distinctContext, cancel := context.WithTimeout(ctx, time.Millisecond*15000)
+	orgIDs, err := m.rewardHistory.coll.Distinct(distinctContext, "org_id", bson.D{})
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []string{}
+	for _, value := range orgIDs {
+		orgID, ok := value.(string)
+		if !ok || orgID == "" {
+			continue
+		}
+
+		scores, err := m.computeLeaderboardScores(ctx, orgID)
+		if err != nil {
+			return changes, err
+		}
+		if !dryRun {
+			err = m.replaceLeaderboardScores(ctx, orgID, scores)
+			if err != nil {
+				return changes, err
+			}
+		}
+		changes = append(changes, fmt.Sprintf("build %d leaderboard scores of org %s", len(scores), orgID))
+	}
+	return changes, nil
+}
+
+// migrateLeaderboardScoresIndexes creates the unique index on the score of a user in a leaderboard window and the
+// index the leaderboards are ranked by. The scores which share a window are reported and can be dropped by a rebuild.
+func migrateLeaderboardScoresIndexes(ctx context.Context, m *database, dryRun bool) ([]string, error) {
+	duplicates, err := findDuplicates(ctx, m.leaderboardScores, bson.D{}, "org_id", "user_id", "reward_type", "building_block", "window", "window_start")
+	if err != nil {
+		return nil, err
+	}
+	if len(duplicates) > 0 {
+		changes := []string{}
+		for _, duplicate := range duplicates {
+			logging.Logger().Warnf("duplicate leaderboard score: %s", duplicate)
+			changes = append(changes, fmt.Sprintf("duplicate leaderboard score: %s", duplicate))
+		}
+		return changes, fmt.Errorf("found %d duplicate leaderboard scores, rebuild the leaderboards of their orgs", len(duplicates))
+	}
+
+	changes := []string{
+		"create unique index org_id_1_user_id_1_reward_type_1_building_block_1_window_1_window_start_1 on leaderboard_scores",
+		"create index org_id_1_reward_type_1_building_block_1_window_1_window_start_1_opted_in_1_amount_-1 on leaderboard_scores",
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	err = m.leaderboardScores.AddIndexWithOptions(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "user_id", Value: 1},
+			primitive.E{Key: "reward_type", Value: 1},
+			primitive.E{Key: "building_block", Value: 1},
+			primitive.E{Key: "window", Value: 1},
+			primitive.E{Key: "window_start", Value: 1},
+		}, options.Index().SetUnique(true).SetName("org_id_1_user_id_1_reward_type_1_building_block_1_window_1_window_start_1"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.leaderboardScores.AddIndexWithOptions(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "reward_type", Value: 1},
+			primitive.E{Key: "building_block", Value: 1},
+			primitive.E{Key: "window", Value: 1},
+			primitive.E{Key: "window_start", Value: 1},
+			primitive.E{Key: "opted_in", Value: 1},
+			primitive.E{Key: "amount", Value: -1},
+		}, options.Index().SetName("org_id_1_reward_type_1_building_block_1_window_1_window_start_1_opted_in_1_amount_-1"))
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// migrateLeaderboardProfilesIndexes creates the unique indexes on the profile of a user and on the display handles
+// of an org. The profiles without a handle are not indexed by the handle. Existing duplicates are reported and
+// must be resolved before the indexes can be created.
+func migrateLeaderboardProfilesIndexes(ctx context.Context, m *database, dryRun bool) ([]string, error) {
+	profileDuplicates, err := findDuplicates(ctx, m.leaderboardProfiles, bson.D{}, "org_id", "user_id")
+	if err != nil {
+		return nil, err
+	}
+	handleFilter := bson.D{primitive.E{Key: "display_handle", Value: bson.M{"$gt": ""}}}
+	handleDuplicates, err := findDuplicates(ctx, m.leaderboardProfiles, handleFilter, "org_id", "display_handle")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(profileDuplicates) > 0 || len(handleDuplicates) > 0 {
+		changes := []string{}
+		for _, duplicate := range profileDuplicates {
+			logging.Logger().Warnf("duplicate leaderboard profile: %s", duplicate)
+			changes = append(changes, fmt.Sprintf("duplicate leaderboard profile: %s", duplicate))
+		}
+		for _, duplicate := range handleDuplicates {
+			logging.Logger().Warnf("duplicate display handle: %s", duplicate)
+			changes = append(changes, fmt.Sprintf("duplicate display handle: %s", duplicate))
+		}
+		return changes, fmt.Errorf("found %d duplicate leaderboard profiles and %d duplicate display handles", len(profileDuplicates), len(handleDuplicates))
+	}
+
+	changes := []string{
+		"create unique index org_id_1_user_id_1 on leaderboard_profiles",
+		"create unique index org_id_1_display_handle_1 on leaderboard_profiles",
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	err = m.leaderboardProfiles.AddIndexWithOptions(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "user_id", Value: 1},
+		}, options.Index().SetUnique(true).SetName("org_id_1_user_id_1"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.leaderboardProfiles.AddIndexWithOptions(
+		bson.D{
+			primitive.E{Key: "org_id", Value: 1},
+			primitive.E{Key: "display_handle", Value: 1},
+		}, options.Index().SetUnique(true).SetName("org_id_1_display_handle_1").
+			SetPartialFilterExpression(bson.M{"display_handle": bson.M{"$gt": ""}}))
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
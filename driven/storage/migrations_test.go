@@ -0,0 +1,83 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMigrationVersions(t *testing.T) {
+	for i, migration := range migrations {
+		if migration.version != i+1 {
+			t.Errorf("expected migration %d to have version %d, got %d", i, i+1, migration.version)
+		}
+		if migration.up == nil || len(migration.description) == 0 {
+			t.Errorf("migration %d must have a description and an up function", migration.version)
+		}
+	}
+}
+
+func TestReleasedMigrationVersions(t *testing.T) {
+	//the recorded versions of the released migrations must keep meaning the same change
+	released := map[int]string{
+		1: "unique reward types and reward operation codes per org",
+		2: "backfill org_id of the documents created before org isolation",
+		3: "backfill inventory_backed of the reward types",
+		4: "build the leaderboard scores from the reward history",
+		5: "unique leaderboard scores per user and window and the ranking index",
+		6: "unique leaderboard profiles per user and display handles per org",
+	}
+	for _, migration := range migrations {
+		description, ok := released[migration.version]
+		if ok && migration.description != description {
+			t.Errorf("expected migration %d to be %q, got %q", migration.version, description, migration.description)
+		}
+	}
+}
+
+func TestPendingMigrations(t *testing.T) {
+	all := []migration{{version: 3}, {version: 1}, {version: 2}}
+
+	pending := pendingMigrations(all, nil)
+	if len(pending) != 3 || pending[0].version != 1 || pending[1].version != 2 || pending[2].version != 3 {
+		t.Errorf("expected all migrations in version order, got %v", pending)
+	}
+
+	pending = pendingMigrations(all, []migrationRecord{{Version: 1}, {Version: 3}})
+	if len(pending) != 1 || pending[0].version != 2 {
+		t.Errorf("expected only migration 2 to be pending, got %v", pending)
+	}
+
+	pending = pendingMigrations(all, []migrationRecord{{Version: 1}, {Version: 2}, {Version: 3}})
+	if len(pending) != 0 {
+		t.Errorf("expected no pending migrations, got %v", pending)
+	}
+}
+
+func TestDuplicateGroupString(t *testing.T) {
+	group := duplicateGroup{Key: bson.M{"code": "quiz", "org_id": "org", "building_block": "content"}, IDs: []string{"1", "2"}, Count: 2}
+	expected := "org_id=org code=quiz building_block=content (2 documents: 1, 2)"
+	if group.String() != expected {
+		t.Errorf("expected %q, got %q", expected, group.String())
+	}
+
+	group = duplicateGroup{Key: bson.M{"display_handle": "earner", "org_id": "org"}, IDs: []string{"1", "2"}, Count: 2}
+	expected = "org_id=org display_handle=earner (2 documents: 1, 2)"
+	if group.String() != expected {
+		t.Errorf("expected %q, got %q", expected, group.String())
+	}
+}
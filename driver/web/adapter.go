@@ -15,29 +15,37 @@
 package web
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"rewards/core"
 	"rewards/core/model"
 	"rewards/driver/web/rest"
 	"rewards/utils"
+	"rewards/utils/logging"
+	"rewards/utils/metrics"
 	"strings"
+	"time"
 
 	"github.com/rokwire/core-auth-library-go/tokenauth"
 
-	"github.com/casbin/casbin"
 	"github.com/gorilla/mux"
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
+// readHeaderTimeout limits reading the request headers so slow clients cannot hold the connections
+const readHeaderTimeout = 10 * time.Second
+
 // Adapter entity
 type Adapter struct {
 	host              string
 	port              string
 	rewardsServiceURL string
 	auth              *Auth
-	authorization     *casbin.Enforcer
+	authorization     *Authorization
+	auditor           *auditor
+	server            *http.Server
 
 	apisHandler         rest.ApisHandler
 	adminApisHandler    rest.AdminApisHandler
@@ -67,15 +75,19 @@ type Adapter struct {
 // @in header
 // @name GROUP
 
-// Start starts the module
-func (we Adapter) Start() {
+// Start starts the module. It serves the requests until the adapter is shut down
+func (we Adapter) Start() error {
 
 	router := mux.NewRouter().StrictSlash(true)
 
 	subrouter := router.PathPrefix("/rewards").Subrouter()
+	subrouter.Use(requestMiddleware, metricsMiddleware)
+	subrouter.Handle("/metrics", metrics.Handler()).Methods("GET")
 	subrouter.PathPrefix("/doc/ui").Handler(we.serveDocUI())
 	subrouter.HandleFunc("/doc", we.serveDoc)
 	subrouter.HandleFunc("/version", we.wrapFunc(we.apisHandler.Version)).Methods("GET")
+	subrouter.HandleFunc("/health/live", we.apisHandler.GetLive).Methods("GET")
+	subrouter.HandleFunc("/health/ready", we.apisHandler.GetReady).Methods("GET")
 
 	// handle apis
 	apiRouter := subrouter.PathPrefix("/api").Subrouter()
@@ -89,6 +101,12 @@ func (we Adapter) Start() {
 	apiRouter.HandleFunc("/user/history", we.userAuthWrapFunc(we.apisHandler.GetUserRewardsHistory)).Methods("GET")
 	apiRouter.HandleFunc("/user/claims", we.userAuthWrapFunc(we.apisHandler.GetUserRewardClaim)).Methods("GET")
 	apiRouter.HandleFunc("/user/claims", we.userAuthWrapFunc(we.apisHandler.CreateUserRewardClaim)).Methods("POST")
+	apiRouter.HandleFunc("/user/claims/{id}/qr", we.userAuthWrapFunc(we.apisHandler.GetUserRewardClaimQRCode)).Methods("GET")
+	apiRouter.HandleFunc("/user/catalog", we.userAuthWrapFunc(we.apisHandler.GetRewardCatalog)).Methods("GET")
+	apiRouter.HandleFunc("/user/locations", we.userAuthWrapFunc(we.apisHandler.GetPickupLocations)).Methods("GET")
+	apiRouter.HandleFunc("/user/leaderboards/{reward_type}", we.userAuthWrapFunc(we.apisHandler.GetLeaderboard)).Methods("GET")
+	apiRouter.HandleFunc("/user/leaderboard-profile", we.userAuthWrapFunc(we.apisHandler.GetLeaderboardProfile)).Methods("GET")
+	apiRouter.HandleFunc("/user/leaderboard-profile", we.userAuthWrapFunc(we.apisHandler.UpdateLeaderboardProfile)).Methods("PUT")
 
 	// handle student guide admin apis
 	adminSubRouter := apiRouter.PathPrefix("/admin").Subrouter()
@@ -103,18 +121,93 @@ func (we Adapter) Start() {
 	adminSubRouter.HandleFunc("/operations/{id}", we.adminAuthWrapFunc(we.adminApisHandler.GetRewardOperation)).Methods("GET")
 	adminSubRouter.HandleFunc("/operations/{id}", we.adminAuthWrapFunc(we.adminApisHandler.UpdateRewardOperation)).Methods("PUT")
 	adminSubRouter.HandleFunc("/operations/{id}", we.adminAuthWrapFunc(we.adminApisHandler.DeleteRewardOperation)).Methods("DELETE")
+	adminSubRouter.HandleFunc("/operations/{id}/archive", we.adminAuthWrapFunc(we.adminApisHandler.ArchiveRewardOperation)).Methods("POST")
+	adminSubRouter.HandleFunc("/operations/{id}/unarchive", we.adminAuthWrapFunc(we.adminApisHandler.UnarchiveRewardOperation)).Methods("POST")
 
 	adminSubRouter.HandleFunc("/inventories", we.adminAuthWrapFunc(we.adminApisHandler.GetRewardInventories)).Methods("GET")
 	adminSubRouter.HandleFunc("/inventories", we.adminAuthWrapFunc(we.adminApisHandler.CreateRewardInventory)).Methods("POST")
 	adminSubRouter.HandleFunc("/inventories/{id}", we.adminAuthWrapFunc(we.adminApisHandler.GetRewardInventory)).Methods("GET")
 	adminSubRouter.HandleFunc("/inventories/{id}", we.adminAuthWrapFunc(we.adminApisHandler.UpdateRewardInventory)).Methods("PUT")
+	adminSubRouter.HandleFunc("/inventories/{id}/archive", we.adminAuthWrapFunc(we.adminApisHandler.ArchiveRewardInventory)).Methods("POST")
+	adminSubRouter.HandleFunc("/inventories/{id}/unarchive", we.adminAuthWrapFunc(we.adminApisHandler.UnarchiveRewardInventory)).Methods("POST")
 
 	adminSubRouter.HandleFunc("/claims", we.adminAuthWrapFunc(we.adminApisHandler.GetRewardClaims)).Methods("GET")
 	adminSubRouter.HandleFunc("/claims", we.adminAuthWrapFunc(we.adminApisHandler.CreateRewardClaim)).Methods("POST")
+	adminSubRouter.HandleFunc("/claims/pickup", we.adminAuthWrapFunc(we.adminApisHandler.RedeemRewardClaimPickupCode)).Methods("POST")
 	adminSubRouter.HandleFunc("/claims/{id}", we.adminAuthWrapFunc(we.adminApisHandler.GetRewardClaim)).Methods("GET")
 	adminSubRouter.HandleFunc("/claims/{id}", we.adminAuthWrapFunc(we.adminApisHandler.UpdateRewardClaim)).Methods("PUT")
+	adminSubRouter.HandleFunc("/claims/{id}/pickups", we.adminAuthWrapFunc(we.adminApisHandler.GetRewardClaimPickupAttempts)).Methods("GET")
+
+	adminSubRouter.HandleFunc("/catalog", we.adminAuthWrapFunc(we.adminApisHandler.GetRewardCatalogItems)).Methods("GET")
+	adminSubRouter.HandleFunc("/catalog", we.adminAuthWrapFunc(we.adminApisHandler.CreateRewardCatalogItem)).Methods("POST")
+	adminSubRouter.HandleFunc("/catalog/{id}", we.adminAuthWrapFunc(we.adminApisHandler.GetRewardCatalogItem)).Methods("GET")
+	adminSubRouter.HandleFunc("/catalog/{id}", we.adminAuthWrapFunc(we.adminApisHandler.UpdateRewardCatalogItem)).Methods("PUT")
+	adminSubRouter.HandleFunc("/catalog/{id}", we.adminAuthWrapFunc(we.adminApisHandler.DeleteRewardCatalogItem)).Methods("DELETE")
+
+	adminSubRouter.HandleFunc("/locations", we.adminAuthWrapFunc(we.adminApisHandler.GetPickupLocations)).Methods("GET")
+	adminSubRouter.HandleFunc("/locations", we.adminAuthWrapFunc(we.adminApisHandler.CreatePickupLocation)).Methods("POST")
+	adminSubRouter.HandleFunc("/locations/{id}", we.adminAuthWrapFunc(we.adminApisHandler.GetPickupLocation)).Methods("GET")
+	adminSubRouter.HandleFunc("/locations/{id}", we.adminAuthWrapFunc(we.adminApisHandler.UpdatePickupLocation)).Methods("PUT")
+	adminSubRouter.HandleFunc("/locations/{id}", we.adminAuthWrapFunc(we.adminApisHandler.DeletePickupLocation)).Methods("DELETE")
+	adminSubRouter.HandleFunc("/locations/{id}/claims", we.adminAuthWrapFunc(we.adminApisHandler.GetPickupLocationClaims)).Methods("GET")
+
+	adminSubRouter.HandleFunc("/credentials", we.adminAuthWrapFunc(we.adminApisHandler.GetInternalCredentials)).Methods("GET")
+	adminSubRouter.HandleFunc("/credentials", we.adminAuthWrapFunc(we.adminApisHandler.CreateInternalCredential)).Methods("POST")
+	adminSubRouter.HandleFunc("/credentials/{id}", we.adminAuthWrapFunc(we.adminApisHandler.GetInternalCredential)).Methods("GET")
+	adminSubRouter.HandleFunc("/credentials/{id}/rotate", we.adminAuthWrapFunc(we.adminApisHandler.RotateInternalCredential)).Methods("POST")
+	adminSubRouter.HandleFunc("/credentials/{id}/revoke", we.adminAuthWrapFunc(we.adminApisHandler.RevokeInternalCredential)).Methods("POST")
+
+	adminSubRouter.HandleFunc("/leaderboards/rebuild", we.adminAuthWrapFunc(we.adminApisHandler.RebuildLeaderboards)).Methods("POST")
+	adminSubRouter.HandleFunc("/leaderboards/{reward_type}", we.adminAuthWrapFunc(we.adminApisHandler.GetLeaderboard)).Methods("GET")
+
+	adminSubRouter.HandleFunc("/analytics/grants", we.adminAuthWrapFunc(we.adminApisHandler.GetGrantsAnalytics)).Methods("GET")
+	adminSubRouter.HandleFunc("/analytics/earners", we.adminAuthWrapFunc(we.adminApisHandler.GetEarnersAnalytics)).Methods("GET")
+	adminSubRouter.HandleFunc("/analytics/claims", we.adminAuthWrapFunc(we.adminApisHandler.GetClaimsAnalytics)).Methods("GET")
+	adminSubRouter.HandleFunc("/analytics/redemption", we.adminAuthWrapFunc(we.adminApisHandler.GetRedemptionAnalytics)).Methods("GET")
+
+	adminSubRouter.HandleFunc("/export/history", we.adminAuthWrapFunc(we.adminApisHandler.ExportRewardHistory)).Methods("GET")
+	adminSubRouter.HandleFunc("/export/claims", we.adminAuthWrapFunc(we.adminApisHandler.ExportRewardClaims)).Methods("GET")
+	adminSubRouter.HandleFunc("/export/inventories", we.adminAuthWrapFunc(we.adminApisHandler.ExportRewardInventories)).Methods("GET")
+
+	adminSubRouter.HandleFunc("/import/{kind}", we.adminAuthWrapFunc(we.adminApisHandler.ImportRewards)).Methods("POST")
+
+	adminSubRouter.HandleFunc("/config/export", we.adminAuthWrapFunc(we.adminApisHandler.ExportOrgConfig)).Methods("GET")
+	adminSubRouter.HandleFunc("/config/import", we.adminAuthWrapFunc(we.adminApisHandler.ImportOrgConfig)).Methods("POST")
+
+	adminSubRouter.HandleFunc("/audit", we.adminAuthWrapFunc(we.adminApisHandler.GetAuditLogEntries)).Methods("GET")
+
+	adminSubRouter.HandleFunc("/authorization/reload", we.adminAuthWrapFunc(we.reloadAuthorization)).Methods("POST")
+
+	we.server.Handler = router
+	err := we.server.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
 
-	log.Fatal(http.ListenAndServe(":"+we.port, router))
+// Shutdown stops accepting requests and waits for the in-flight requests until the context is done
+func (we Adapter) Shutdown(ctx context.Context) error {
+	return we.server.Shutdown(ctx)
+}
+
+// reloadAuthorization Reloads the admin authorization policy from its source
+// @Description Reloads the admin authorization policy from its source
+// @Tags Admin
+// @ID AdminReloadAuthorization
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/authorization/reload [post]
+func (we Adapter) reloadAuthorization(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	err := we.authorization.Reload()
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on web.reloadAuthorization: %s", err)
+		rest.HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
 }
 
 func (we Adapter) serveDoc(w http.ResponseWriter, r *http.Request) {
@@ -127,6 +220,15 @@ func (we Adapter) serveDocUI() http.Handler {
 	return httpSwagger.Handler(httpSwagger.URL(url))
 }
 
+// requestMiddleware gives every request an id and a log in its context and returns the id in the response
+func requestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctx := logging.NewRequestContext(req.Context(), req)
+		w.Header().Set(logging.RequestIDHeader, logging.RequestID(ctx))
+		next.ServeHTTP(w, req.WithContext(ctx))
+	})
+}
+
 func (we Adapter) wrapFunc(handler http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		utils.LogRequest(req)
@@ -148,7 +250,7 @@ func (we Adapter) apiKeyOrTokenWrapFunc(handler apiKeysAuthFunc) http.HandlerFun
 			return
 		}
 
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		rest.HandleError(w, model.NewUnauthorizedError("invalid or missing token"))
 	}
 }
 
@@ -163,7 +265,7 @@ func (we Adapter) userAuthWrapFunc(handler userAuthFunc) http.HandlerFunc {
 			handler(claims, w, req)
 			return
 		}
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		rest.HandleError(w, model.NewUnauthorizedError("invalid or missing token"))
 	}
 }
 
@@ -180,38 +282,34 @@ func (we Adapter) adminAuthWrapFunc(handler adminAuthFunc) http.HandlerFunc {
 		if coreAuth {
 			permissions := strings.Split(claims.Permissions, ",")
 
-			HasAccess := false
-			for _, s := range permissions {
-				HasAccess = we.authorization.Enforce(s, obj, act)
-				if HasAccess {
-					break
-				}
-			}
+			HasAccess := we.authorization.Enforce(permissions, obj, act)
 			if HasAccess {
-				handler(claims, w, req)
+				we.auditor.record(model.AuditActorTypeAdmin, claims.Subject, claims.OrgID, w, req, func(w http.ResponseWriter) {
+					handler(claims, w, req)
+				})
 				return
 			}
-			log.Printf("Access control error - Core Subject: %s is trying to apply %s operation for %s\n", claims.Subject, act, obj)
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			logging.FromContext(req.Context()).Warnf("Access control error - Core Subject: %s is trying to apply %s operation for %s", claims.Subject, act, obj)
+			rest.HandleError(w, model.NewForbiddenError("not allowed to %s %s", act, obj))
 			return
 		}
 
-		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		rest.HandleError(w, model.NewUnauthorizedError("invalid or missing token"))
 	}
 }
 
-type internalAPIKeyAuthFunc = func(http.ResponseWriter, *http.Request)
+type internalAPIKeyAuthFunc = func(*model.InternalCredential, http.ResponseWriter, *http.Request)
 
 func (we Adapter) internalAPIKeyAuthWrapFunc(handler internalAPIKeyAuthFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, req *http.Request) {
 		utils.LogRequest(req)
 
-		apiKeyAuthenticated := we.auth.internalAuth.check(w, req)
+		apiKeyAuthenticated, credential := we.auth.internalAuth.check(w, req)
 
 		if apiKeyAuthenticated {
-			handler(w, req)
-		} else {
-			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			we.auditor.record(model.AuditActorTypeInternal, credential.BuildingBlock, credential.OrgID, w, req, func(w http.ResponseWriter) {
+				handler(credential, w, req)
+			})
 		}
 	}
 }
@@ -219,25 +317,45 @@ func (we Adapter) internalAPIKeyAuthWrapFunc(handler internalAPIKeyAuthFunc) htt
 // NewWebAdapter creates new WebAdapter instance
 func NewWebAdapter(host string, port string, app *core.Application, config model.Config) Adapter {
 	auth := NewAuth(app, config)
-	authorization := casbin.NewEnforcer("driver/web/authorization_model.conf", "driver/web/authorization_policy.csv")
+	policyAdapter := newAuthorizationPolicyAdapter(config, app.Services.GetAuthorizationPolicies)
+	authorization := NewAuthorization(authorizationModelPath, policyAdapter)
 
 	apisHandler := rest.NewApisHandler(app)
 	adminApisHandler := rest.NewAdminApisHandler(app)
 	internalApisHandler := rest.NewInternalApisHandler(app)
-	return Adapter{
+	server := &http.Server{
+		Addr:              ":" + port,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
+	}
+	adapter := Adapter{
 		host:                host,
 		port:                port,
+		server:              server,
 		rewardsServiceURL:   config.RewardsServiceURL,
 		auth:                auth,
 		authorization:       authorization,
+		auditor:             newAuditor(app),
 		apisHandler:         apisHandler,
 		adminApisHandler:    adminApisHandler,
 		internalApisHandler: internalApisHandler,
 		app:                 app,
 	}
+	app.AddListener(&AppListener{adapter: &adapter})
+	return adapter
 }
 
 // AppListener implements core.ApplicationListener interface
 type AppListener struct {
 	adapter *Adapter
 }
+
+// OnAuthorizationPoliciesChanged reloads the admin authorization policy
+func (al *AppListener) OnAuthorizationPoliciesChanged() {
+	err := al.adapter.authorization.Reload()
+	if err != nil {
+		logging.Logger().Errorf("Error on web.OnAuthorizationPoliciesChanged: %s", err)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"rewards/core"
+	"rewards/core/model"
+	"rewards/utils/logging"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// auditRedactedFields which are never written to the audit log
+var auditRedactedFields = []string{"key"}
+
+// auditLoader gives the current state of a resource
+type auditLoader func(ctx context.Context, orgID string, id string) (interface{}, error)
+
+// auditor records the admin and internal mutations in the audit log
+type auditor struct {
+	app     *core.Application
+	loaders map[string]auditLoader
+}
+
+func newAuditor(app *core.Application) *auditor {
+	services := app.Services
+	loaders := map[string]auditLoader{
+		"types": func(ctx context.Context, orgID string, id string) (interface{}, error) {
+			return services.GetRewardType(ctx, orgID, id)
+		},
+		"operations": func(ctx context.Context, orgID string, id string) (interface{}, error) {
+			return services.GetRewardOperationByID(ctx, orgID, id)
+		},
+		"inventories": func(ctx context.Context, orgID string, id string) (interface{}, error) {
+			return services.GetRewardInventory(ctx, orgID, id)
+		},
+		"claims": func(ctx context.Context, orgID string, id string) (interface{}, error) {
+			return services.GetRewardClaim(ctx, orgID, id)
+		},
+		"catalog": func(ctx context.Context, orgID string, id string) (interface{}, error) {
+			return services.GetRewardCatalogItem(ctx, orgID, id)
+		},
+		"locations": func(ctx context.Context, orgID string, id string) (interface{}, error) {
+			return services.GetPickupLocation(ctx, orgID, id)
+		},
+		"credentials": func(ctx context.Context, orgID string, id string) (interface{}, error) {
+			return services.GetInternalCredential(ctx, orgID, id)
+		},
+	}
+	return &auditor{app: app, loaders: loaders}
+}
+
+// record calls the handler and appends an audit log entry if it has mutated a resource successfully
+func (a *auditor) record(actorType string, actor string, orgID string, w http.ResponseWriter, req *http.Request, handler func(w http.ResponseWriter)) {
+	// the request id is set by the request middleware, the handlers called directly get one here
+	requestID := logging.RequestID(req.Context())
+	if requestID == "" {
+		requestID = uuid.NewString()
+		w.Header().Set(logging.RequestIDHeader, requestID)
+	}
+
+	if !isMutation(req.Method) {
+		handler(w)
+		return
+	}
+
+	resource, action := auditResourceAndAction(req)
+	resourceID := mux.Vars(req)["id"]
+	loader := a.loaders[resource]
+
+	var before map[string]interface{}
+	if loader != nil && resourceID != "" {
+		before = a.load(req.Context(), loader, orgID, resourceID)
+	}
+
+	recorder := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+	handler(recorder)
+	if recorder.status >= http.StatusBadRequest {
+		return
+	}
+
+	// the mutation is done, so it is recorded even if the client has gone away meanwhile
+	ctx := context.WithoutCancel(req.Context())
+
+	var after map[string]interface{}
+	if loader != nil && resourceID != "" && action != "delete" {
+		after = a.load(ctx, loader, orgID, resourceID)
+	} else if action != "delete" {
+		after = toAuditState(recorder.body.Bytes())
+	}
+	if resourceID == "" && after != nil {
+		if id, ok := after["id"].(string); ok {
+			resourceID = id
+		}
+	}
+
+	entry := model.AuditLogEntry{ActorType: actorType, Actor: actor, Action: action, Resource: resource,
+		ResourceID: resourceID, Method: req.Method, Path: req.URL.Path, Status: recorder.status,
+		Before: before, After: after, Changes: auditChanges(before, after), RequestID: requestID}
+	_, err := a.app.Services.CreateAuditLogEntry(ctx, orgID, entry)
+	if err != nil {
+		logging.FromContext(ctx).Errorf("Error on web.auditor.record(%s %s): %s", req.Method, req.URL.Path, err)
+	}
+}
+
+func (a *auditor) load(ctx context.Context, loader auditLoader, orgID string, id string) map[string]interface{} {
+	item, err := loader(ctx, orgID, id)
+	if err != nil || item == nil || reflect.ValueOf(item).IsNil() {
+		return nil
+	}
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil
+	}
+	return toAuditState(data)
+}
+
+func isMutation(method string) bool {
+	return method == http.MethodPost || method == http.MethodPut || method == http.MethodDelete
+}
+
+// auditResourceAndAction gives the resource and the action for a request like /admin/{resource}/{id}/{action}
+func auditResourceAndAction(req *http.Request) (string, string) {
+	path := req.URL.Path
+	if route := mux.CurrentRoute(req); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			path = template
+		}
+	}
+
+	var segments []string
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		if part == "admin" || part == "int" {
+			segments = parts[i+1:]
+			break
+		}
+	}
+	if len(segments) == 0 {
+		return "", strings.ToLower(req.Method)
+	}
+
+	resource := segments[0]
+	if last := segments[len(segments)-1]; len(segments) > 1 && !strings.HasPrefix(last, "{") {
+		return resource, last
+	}
+
+	switch req.Method {
+	case http.MethodPost:
+		return resource, "create"
+	case http.MethodPut:
+		return resource, "update"
+	case http.MethodDelete:
+		return resource, "delete"
+	}
+	return resource, strings.ToLower(req.Method)
+}
+
+func toAuditState(data []byte) map[string]interface{} {
+	var state map[string]interface{}
+	if len(data) == 0 || json.Unmarshal(data, &state) != nil {
+		return nil
+	}
+	for _, field := range auditRedactedFields {
+		delete(state, field)
+	}
+	return state
+}
+
+// auditChanges gives the top level fields which differ between the before and after state
+func auditChanges(before map[string]interface{}, after map[string]interface{}) []model.AuditChange {
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+
+	var changes []model.AuditChange
+	for _, field := range names {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, model.AuditChange{Field: field, Before: before[field], After: after[field]})
+		}
+	}
+	return changes
+}
+
+// auditResponseWriter keeps the status and the body written by the handler
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
@@ -0,0 +1,272 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"rewards/driver/web/rest"
+	"rewards/utils/logging"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rokwire/core-auth-library-go/tokenauth"
+)
+
+// auditStorage keeps the reward types, the internal credentials and the audit log in memory
+type auditStorage struct {
+	credentialStorage
+
+	types   []model.RewardType
+	entries []model.AuditLogEntry
+
+	query []interface{}
+}
+
+func (s *auditStorage) GetRewardType(ctx context.Context, orgID string, id string) (*model.RewardType, error) {
+	for _, item := range s.types {
+		if item.OrgID == orgID && item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *auditStorage) CreateAuditLogEntry(ctx context.Context, orgID string, item model.AuditLogEntry) (*model.AuditLogEntry, error) {
+	item.OrgID = orgID
+	s.entries = append(s.entries, item)
+	return &item, nil
+}
+
+func (s *auditStorage) GetAuditLogEntries(ctx context.Context, orgID string, resource *string, resourceID *string, actor *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64) ([]model.AuditLogEntry, error) {
+	s.query = []interface{}{orgID, resource, resourceID, actor, startDate, endDate, limit, offset}
+	return s.entries, nil
+}
+
+// newAuditRouter routes the admin requests of the test handlers through the auditor
+func newAuditRouter(storage *auditStorage, middleware bool, handlers map[string]func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request)) *mux.Router {
+	app, _ := newCredentialAuth(storage)
+	auditor := newAuditor(app)
+	claims := &tokenauth.Claims{OrgID: "org-a"}
+	claims.Subject = "admin"
+
+	router := mux.NewRouter()
+	subrouter := router.PathPrefix("/rewards/api/admin").Subrouter()
+	if middleware {
+		subrouter.Use(requestMiddleware)
+	}
+	for route, handler := range handlers {
+		parts := strings.SplitN(route, " ", 2)
+		handler := handler
+		subrouter.HandleFunc(parts[1], func(w http.ResponseWriter, req *http.Request) {
+			auditor.record(model.AuditActorTypeAdmin, claims.Subject, claims.OrgID, w, req, func(w http.ResponseWriter) {
+				handler(claims, w, req)
+			})
+		}).Methods(parts[0])
+	}
+	return router
+}
+
+func newAuditStorage() *auditStorage {
+	return &auditStorage{types: []model.RewardType{
+		{ID: "type-a", OrgID: "org-a", RewardType: "tshirt", DisplayName: "T-Shirt", Active: true},
+	}}
+}
+
+func TestAuditRecordsBeforeAndAfter(t *testing.T) {
+	storage := newAuditStorage()
+	router := newAuditRouter(storage, false, map[string]func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request){
+		"PUT /types/{id}": func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+			storage.types[0].DisplayName = "Shirt"
+			w.WriteHeader(http.StatusOK)
+		},
+		"DELETE /types/{id}": func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+			storage.types = nil
+			w.WriteHeader(http.StatusOK)
+		},
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/rewards/api/admin/types/type-a", nil))
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/rewards/api/admin/types/type-a", nil))
+
+	if len(storage.entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", len(storage.entries))
+	}
+
+	update := storage.entries[0]
+	if update.Action != "update" || update.Resource != "types" || update.ResourceID != "type-a" || update.Actor != "admin" ||
+		update.ActorType != model.AuditActorTypeAdmin || update.OrgID != "org-a" || update.Status != http.StatusOK {
+		t.Errorf("unexpected update entry %+v", update)
+	}
+	if update.Before["display_name"] != "T-Shirt" || update.After["display_name"] != "Shirt" {
+		t.Errorf("expected the state before and after the update, got %v and %v", update.Before, update.After)
+	}
+	if len(update.Changes) != 1 || update.Changes[0].Field != "display_name" {
+		t.Errorf("expected only the display name to change, got %+v", update.Changes)
+	}
+
+	deletion := storage.entries[1]
+	if deletion.Action != "delete" || deletion.Before["display_name"] != "Shirt" || deletion.After != nil {
+		t.Errorf("expected the state before the delete and none after, got %+v", deletion)
+	}
+}
+
+func TestAuditSkipsFailedMutations(t *testing.T) {
+	storage := newAuditStorage()
+	router := newAuditRouter(storage, false, map[string]func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request){
+		"PUT /types/{id}": func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+			rest.HandleError(w, model.NewValidationError("invalid reward type"))
+		},
+		"DELETE /types/{id}": func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		},
+		"GET /types/{id}": func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		},
+	})
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete, http.MethodGet} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/rewards/api/admin/types/type-a", nil))
+	}
+	if len(storage.entries) != 0 {
+		t.Errorf("expected no audit entries for failed mutations and reads, got %+v", storage.entries)
+	}
+}
+
+func TestAuditRedactsCredentialKeys(t *testing.T) {
+	storage := newAuditStorage()
+	app, _ := newCredentialAuth(storage)
+	handler := rest.NewAdminApisHandler(app)
+	router := newAuditRouter(storage, false, map[string]func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request){
+		"POST /credentials":             handler.CreateInternalCredential,
+		"POST /credentials/{id}/rotate": handler.RotateInternalCredential,
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rewards/api/admin/credentials", strings.NewReader(`{"building_block":"events"}`)))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"key":"`) {
+		t.Fatalf("expected the key in the create response, got %d - %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/rewards/api/admin/credentials/"+storage.credentials[0].ID+"/rotate", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the credential to be rotated, got %d - %s", w.Code, w.Body.String())
+	}
+
+	if len(storage.entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %d", len(storage.entries))
+	}
+	for _, entry := range storage.entries {
+		if entry.After == nil || entry.After["key_prefix"] == nil {
+			t.Errorf("expected the credential state in the %s entry, got %v", entry.Action, entry.After)
+		}
+		if _, ok := entry.After["key"]; ok {
+			t.Errorf("expected the key to be redacted from the %s entry", entry.Action)
+		}
+		for _, change := range entry.Changes {
+			if change.Field == "key" {
+				t.Errorf("expected the key to be redacted from the %s changes", entry.Action)
+			}
+		}
+	}
+	if storage.entries[0].Action != "create" || storage.entries[0].ResourceID != storage.credentials[0].ID {
+		t.Errorf("expected the id of the created credential, got %+v", storage.entries[0])
+	}
+	if storage.entries[1].Action != "rotate" {
+		t.Errorf("expected the rotate action, got %s", storage.entries[1].Action)
+	}
+}
+
+func TestAuditRequestID(t *testing.T) {
+	handlers := map[string]func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request){
+		"PUT /types/{id}": func(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		},
+	}
+
+	// the id of the caller is kept through the request middleware
+	storage := newAuditStorage()
+	router := newAuditRouter(storage, true, handlers)
+	r := httptest.NewRequest(http.MethodPut, "/rewards/api/admin/types/type-a", nil)
+	r.Header.Set(logging.RequestIDHeader, "request-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	if w.Header().Get(logging.RequestIDHeader) != "request-1" {
+		t.Errorf("expected the request id in the response, got %s", w.Header().Get(logging.RequestIDHeader))
+	}
+	if len(storage.entries) != 1 || storage.entries[0].RequestID != "request-1" {
+		t.Errorf("expected the request id in the audit entry, got %+v", storage.entries)
+	}
+
+	// the auditor gives one when the request has gone around the middleware
+	storage = newAuditStorage()
+	router = newAuditRouter(storage, false, handlers)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/rewards/api/admin/types/type-a", nil))
+	requestID := w.Header().Get(logging.RequestIDHeader)
+	if requestID == "" {
+		t.Fatal("expected a generated request id in the response")
+	}
+	if len(storage.entries) != 1 || storage.entries[0].RequestID != requestID {
+		t.Errorf("expected the generated request id %s in the audit entry, got %+v", requestID, storage.entries)
+	}
+}
+
+func TestAuditLogFilters(t *testing.T) {
+	storage := newAuditStorage()
+	app, _ := newCredentialAuth(storage)
+	handler := rest.NewAdminApisHandler(app)
+	claims := &tokenauth.Claims{OrgID: "org-a"}
+
+	r := httptest.NewRequest(http.MethodGet, "/rewards/api/admin/audit?resource=types&resource_id=type-a&actor=admin&start_date=2024-03-01T00:00:00Z&end_date=2024-03-31T00:00:00Z&limit=5&offset=10", nil)
+	w := httptest.NewRecorder()
+	handler.GetAuditLogEntries(claims, w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	query := storage.query
+	resource, resourceID, actor := query[1].(*string), query[2].(*string), query[3].(*string)
+	startDate, endDate := query[4].(*time.Time), query[5].(*time.Time)
+	limit, offset := query[6].(*int64), query[7].(*int64)
+	if query[0] != "org-a" || resource == nil || *resource != "types" || resourceID == nil || *resourceID != "type-a" || actor == nil || *actor != "admin" {
+		t.Errorf("expected the org, resource and actor filters, got %v", query)
+	}
+	if startDate == nil || !startDate.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) || endDate == nil || !endDate.Equal(time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the date filters, got %v and %v", startDate, endDate)
+	}
+	if limit == nil || *limit != 5 || offset == nil || *offset != 10 {
+		t.Errorf("expected the paging, got %v and %v", limit, offset)
+	}
+
+	for _, param := range []string{"start_date=yesterday", "end_date=2024-03-31"} {
+		storage.query = nil
+		w = httptest.NewRecorder()
+		handler.GetAuditLogEntries(claims, w, httptest.NewRequest(http.MethodGet, "/rewards/api/admin/audit?"+param, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", param, http.StatusBadRequest, w.Code)
+		}
+		if storage.query != nil {
+			t.Errorf("%s: expected no storage query", param)
+		}
+	}
+}
@@ -15,12 +15,12 @@
 package web
 
 import (
-	"fmt"
-	"log"
 	"net/http"
 	"rewards/core"
 	"rewards/core/model"
 	web "rewards/driver/web/auth"
+	"rewards/driver/web/rest"
+	"rewards/utils/logging"
 )
 
 // Auth handler
@@ -35,7 +35,7 @@ func (auth *Auth) clientIDCheck(w http.ResponseWriter, r *http.Request) bool {
 		clientID = "edu.illinois.rokwire"
 	}
 
-	log.Println(fmt.Sprintf("400 - Bad Request"))
+	logging.FromContext(r.Context()).Error("400 - Bad Request")
 	w.WriteHeader(http.StatusBadRequest)
 	w.Write([]byte("Bad Request"))
 	return false
@@ -44,42 +44,47 @@ func (auth *Auth) clientIDCheck(w http.ResponseWriter, r *http.Request) bool {
 // NewAuth creates new auth handler
 func NewAuth(app *core.Application, config model.Config) *Auth {
 	coreAuth := web.NewCoreAuth(app, config)
-	internalAuth := newInternalAuth(config)
+	internalAuth := newInternalAuth(app)
 	auth := Auth{coreAuth: coreAuth, internalAuth: internalAuth}
 	return &auth
 }
 
 // InternalAuth handling the internal calls fromother BBs
 type InternalAuth struct {
-	internalAPIKey string
+	app *core.Application
 }
 
-func newInternalAuth(config model.Config) *InternalAuth {
-	auth := InternalAuth{internalAPIKey: config.InternalAPIKey}
+func newInternalAuth(app *core.Application) *InternalAuth {
+	auth := InternalAuth{app: app}
 	return &auth
 }
 
-func (auth *InternalAuth) check(w http.ResponseWriter, r *http.Request) bool {
+// check authenticates the building block by its own api key
+func (auth *InternalAuth) check(w http.ResponseWriter, r *http.Request) (bool, *model.InternalCredential) {
 	apiKey := r.Header.Get("INTERNAL-API-KEY")
 	//check if there is api key in the header
 	if len(apiKey) == 0 {
 		//no key, so return 400
-		log.Println(fmt.Sprintf("400 - Bad Request"))
+		logging.FromContext(r.Context()).Error("400 - Bad Request")
 
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(http.StatusText(http.StatusUnauthorized)))
-		return false
+		rest.HandleError(w, model.NewUnauthorizedError("missing internal api key"))
+		return false, nil
 	}
 
-	exist := auth.internalAPIKey == apiKey
+	credential, err := auth.app.Services.AuthenticateInternalCredential(r.Context(), apiKey)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("error authenticating internal api key: %s", err)
 
-	if !exist {
-		//not exist, so return 401
-		log.Println(fmt.Sprintf("401 - Unauthorized for key %s", apiKey))
+		rest.HandleError(w, err)
+		return false, nil
+	}
+
+	if credential == nil {
+		//not exist or revoked, so return 401
+		logging.FromContext(r.Context()).Error("401 - Unauthorized for an unknown or revoked internal api key")
 
-		w.WriteHeader(http.StatusUnauthorized)
-		w.Write([]byte(http.StatusText(http.StatusUnauthorized)))
-		return false
+		rest.HandleError(w, model.NewUnauthorizedError("invalid internal api key"))
+		return false, nil
 	}
-	return true
+	return true, credential
 }
@@ -15,14 +15,13 @@
 package web
 
 import (
-	"log"
 	"net/http"
 	"rewards/core"
 	"rewards/core/model"
+	"rewards/utils/logging"
 
 	"github.com/rokwire/core-auth-library-go/authservice"
 	"github.com/rokwire/core-auth-library-go/tokenauth"
-	"github.com/rokwire/logging-library-go/logs"
 )
 
 // CoreAuth implementation
@@ -38,14 +37,17 @@ func NewCoreAuth(app *core.Application, config model.Config) *CoreAuth {
 		AuthServicesHost: config.CoreBBHost,
 	}
 
-	serviceLoader, err := authservice.NewRemoteAuthDataLoader(remoteConfig, []string{"core"}, logs.NewLogger("groupsbb", &logs.LoggerOpts{}))
+	serviceLoader, err := authservice.NewRemoteAuthDataLoader(remoteConfig, []string{"core"}, logging.Logger())
+	if err != nil {
+		logging.Logger().Fatalf("Error initializing remote auth data loader: %v", err)
+	}
 	authService, err := authservice.NewAuthService("rewards", config.RewardsServiceURL, serviceLoader)
 	if err != nil {
-		log.Fatalf("Error initializing auth service: %v", err)
+		logging.Logger().Fatalf("Error initializing auth service: %v", err)
 	}
 	tokenAuth, err := tokenauth.NewTokenAuth(true, authService, nil, nil)
 	if err != nil {
-		log.Fatalf("Error intitializing token auth: %v", err)
+		logging.Logger().Fatalf("Error intitializing token auth: %v", err)
 	}
 
 	auth := CoreAuth{app: app, tokenAuth: tokenAuth}
@@ -56,7 +58,7 @@ func NewCoreAuth(app *core.Application, config model.Config) *CoreAuth {
 func (ca CoreAuth) Check(r *http.Request) (bool, *tokenauth.Claims) {
 	claims, err := ca.tokenAuth.CheckRequestTokens(r)
 	if err != nil {
-		log.Printf("error validate token: %s", err)
+		logging.FromContext(r.Context()).Errorf("error validate token: %s", err)
 		return false, nil
 	}
 
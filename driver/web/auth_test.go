@@ -0,0 +1,200 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core"
+	"rewards/core/model"
+	cacheadapter "rewards/driven/cache"
+	"rewards/driver/web/rest"
+	"rewards/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// credentialStorage is an in memory storage of the internal credentials
+type credentialStorage struct {
+	core.Storage
+
+	credentials []model.InternalCredential
+}
+
+func (s *credentialStorage) CreateInternalCredential(ctx context.Context, orgID string, item model.InternalCredential) (*model.InternalCredential, error) {
+	item.ID = item.BuildingBlock + "-credential"
+	item.OrgID = orgID
+	s.credentials = append(s.credentials, item)
+	return &item, nil
+}
+
+func (s *credentialStorage) GetInternalCredential(ctx context.Context, orgID string, id string) (*model.InternalCredential, error) {
+	for _, item := range s.credentials {
+		if item.OrgID == orgID && item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, model.NewNotFoundError("internal credential %s", id)
+}
+
+func (s *credentialStorage) GetInternalCredentialByKeyHash(ctx context.Context, keyHash string) (*model.InternalCredential, error) {
+	for _, item := range s.credentials {
+		if item.KeyHash == keyHash {
+			return &item, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *credentialStorage) UpdateInternalCredentialKey(ctx context.Context, orgID string, id string, keyHash string, keyPrefix string) error {
+	for i, item := range s.credentials {
+		if item.OrgID == orgID && item.ID == id {
+			now := time.Now().UTC()
+			s.credentials[i].KeyHash = keyHash
+			s.credentials[i].KeyPrefix = keyPrefix
+			s.credentials[i].DateRotated = &now
+			return nil
+		}
+	}
+	return model.NewNotFoundError("internal credential %s", id)
+}
+
+func (s *credentialStorage) RevokeInternalCredential(ctx context.Context, orgID string, id string) error {
+	for i, item := range s.credentials {
+		if item.OrgID == orgID && item.ID == id {
+			now := time.Now().UTC()
+			s.credentials[i].DateRevoked = &now
+			return nil
+		}
+	}
+	return model.NewNotFoundError("internal credential %s", id)
+}
+
+func newCredentialAuth(storage core.Storage) (*core.Application, *InternalAuth) {
+	app := core.NewApplication("test", "test", storage, cacheadapter.NewCacheAdapter(""))
+	return app, newInternalAuth(app)
+}
+
+func checkInternalKey(auth *InternalAuth, key string) (int, *model.InternalCredential) {
+	r := httptest.NewRequest(http.MethodGet, "/rewards/int/stats", nil)
+	if key != "" {
+		r.Header.Set("INTERNAL-API-KEY", key)
+	}
+	w := httptest.NewRecorder()
+	ok, credential := auth.check(w, r)
+	if ok {
+		return http.StatusOK, credential
+	}
+	return w.Code, credential
+}
+
+func TestInternalAuthKeys(t *testing.T) {
+	storage := &credentialStorage{}
+	app, auth := newCredentialAuth(storage)
+
+	credential, err := app.Services.CreateInternalCredential(context.Background(), "org-a", model.InternalCredential{BuildingBlock: "events"})
+	if err != nil {
+		t.Fatalf("unexpected create error: %s", err)
+	}
+	key := credential.Key
+	if key == "" {
+		t.Fatal("expected the key to be returned on create")
+	}
+
+	// only the hash and the prefix of the key are stored
+	stored := storage.credentials[0]
+	if stored.Key != "" || stored.KeyHash != utils.HashKey(key) || stored.KeyHash == key {
+		t.Errorf("expected only the hash of the key to be stored, got %+v", stored)
+	}
+	if !strings.HasPrefix(key, stored.KeyPrefix) || len(stored.KeyPrefix) >= len(key) {
+		t.Errorf("expected a short prefix of the key to be stored, got %s", stored.KeyPrefix)
+	}
+
+	status, authenticated := checkInternalKey(auth, key)
+	if status != http.StatusOK || authenticated == nil || authenticated.OrgID != "org-a" || authenticated.BuildingBlock != "events" {
+		t.Fatalf("expected the key to authenticate the org-a events credential, got %d %+v", status, authenticated)
+	}
+
+	status, _ = checkInternalKey(auth, "")
+	if status != http.StatusUnauthorized {
+		t.Errorf("expected status %d for a missing key, got %d", http.StatusUnauthorized, status)
+	}
+	status, _ = checkInternalKey(auth, "unknown-key")
+	if status != http.StatusUnauthorized {
+		t.Errorf("expected status %d for an unknown key, got %d", http.StatusUnauthorized, status)
+	}
+
+	rotated, err := app.Services.RotateInternalCredential(context.Background(), "org-a", credential.ID)
+	if err != nil {
+		t.Fatalf("unexpected rotate error: %s", err)
+	}
+	if rotated.Key == "" || rotated.Key == key {
+		t.Fatalf("expected a new key on rotate, got %s", rotated.Key)
+	}
+	if storage.credentials[0].KeyHash != utils.HashKey(rotated.Key) {
+		t.Error("expected the hash of the rotated key to be stored")
+	}
+	status, _ = checkInternalKey(auth, key)
+	if status != http.StatusUnauthorized {
+		t.Errorf("expected status %d for the key before the rotation, got %d", http.StatusUnauthorized, status)
+	}
+	status, _ = checkInternalKey(auth, rotated.Key)
+	if status != http.StatusOK {
+		t.Errorf("expected the rotated key to work, got %d", status)
+	}
+
+	err = app.Services.RevokeInternalCredential(context.Background(), "org-a", credential.ID)
+	if err != nil {
+		t.Fatalf("unexpected revoke error: %s", err)
+	}
+	status, _ = checkInternalKey(auth, rotated.Key)
+	if status != http.StatusUnauthorized {
+		t.Errorf("expected status %d for a revoked key, got %d", http.StatusUnauthorized, status)
+	}
+}
+
+func TestInternalAuthCredentialBinding(t *testing.T) {
+	storage := &credentialStorage{}
+	app, auth := newCredentialAuth(storage)
+	handler := rest.NewInternalApisHandler(app)
+
+	credential, err := app.Services.CreateInternalCredential(context.Background(), "org-a", model.InternalCredential{BuildingBlock: "events"})
+	if err != nil {
+		t.Fatalf("unexpected create error: %s", err)
+	}
+	_, authenticated := checkInternalKey(auth, credential.Key)
+	if authenticated == nil {
+		t.Fatal("expected the key to authenticate")
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"other org", `{"org_id":"org-b","user_id":"user","code":"attend","building_block":"events"}`},
+		{"other building block", `{"org_id":"org-a","user_id":"user","code":"attend","building_block":"groups"}`},
+		{"other building block in the credential org", `{"user_id":"user","code":"attend","building_block":"groups"}`},
+	}
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/rewards/int/reward_history", strings.NewReader(test.body))
+		w := httptest.NewRecorder()
+		handler.CreateReward(authenticated, w, r)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("%s: expected status %d, got %d - %s", test.name, http.StatusForbidden, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rewards/core/model"
+	"rewards/utils/logging"
+	"sync"
+
+	"github.com/casbin/casbin"
+	casbinmodel "github.com/casbin/casbin/model"
+	"github.com/casbin/casbin/persist"
+	fileadapter "github.com/casbin/casbin/persist/file-adapter"
+)
+
+const (
+	authorizationModelPath         = "driver/web/authorization_model.conf"
+	defaultAuthorizationPolicyPath = "driver/web/authorization_policy.csv"
+
+	// AuthorizationPolicySourceFile loads the admin policy from a csv file
+	AuthorizationPolicySourceFile = "file"
+	// AuthorizationPolicySourceMongo loads the admin policy from the authorization_policies collection
+	AuthorizationPolicySourceMongo = "mongo"
+)
+
+var errReadOnlyPolicy = errors.New("the authorization policy is read only")
+
+// Authorization enforces the admin access control policy
+type Authorization struct {
+	enforcer *casbin.SyncedEnforcer
+}
+
+// Enforce checks if any of the permissions grants the action on the object
+func (a *Authorization) Enforce(permissions []string, obj string, act string) bool {
+	for _, permission := range permissions {
+		if a.enforcer.Enforce(permission, obj, act) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reload reloads the policy from its source without restarting the service
+func (a *Authorization) Reload() error {
+	err := a.enforcer.LoadPolicy()
+	if err != nil {
+		return fmt.Errorf("error reloading the authorization policy - %s", err)
+	}
+	logging.Logger().Info("authorization policy reloaded")
+	return nil
+}
+
+// NewAuthorization creates new Authorization for the model file and policy adapter
+func NewAuthorization(modelPath string, adapter persist.Adapter) *Authorization {
+	enforcer := casbin.NewSyncedEnforcer(modelPath, adapter)
+	return &Authorization{enforcer: enforcer}
+}
+
+func newAuthorizationPolicyAdapter(config model.Config, loader policyLoader) persist.Adapter {
+	policyPath := config.AuthorizationPolicyPath
+	if policyPath == "" {
+		policyPath = defaultAuthorizationPolicyPath
+	}
+	fileAdapter := fileadapter.NewAdapter(policyPath)
+
+	switch config.AuthorizationPolicySource {
+	case "", AuthorizationPolicySourceFile:
+		logging.Logger().Infof("loading the authorization policy from %s", policyPath)
+		return fileAdapter
+	case AuthorizationPolicySourceMongo:
+		logging.Logger().Info("loading the authorization policy from the authorization_policies collection")
+		return &storagePolicyAdapter{load: loader, fallback: fileAdapter}
+	default:
+		logging.Logger().Fatalf("unknown authorization policy source: %s", config.AuthorizationPolicySource)
+	}
+	return nil
+}
+
+// policyLoader gives the stored authorization policies
+type policyLoader func(ctx context.Context) ([]model.AuthorizationPolicy, error)
+
+// storagePolicyAdapter is a read only casbin adapter over the stored authorization policies.
+// It falls back to the policy file while nothing is stored and keeps the last loaded rules if storage fails.
+type storagePolicyAdapter struct {
+	load     policyLoader
+	fallback persist.Adapter
+
+	lock     sync.Mutex
+	lastGood [][]string
+}
+
+// LoadPolicy loads all policy rules from the storage
+func (a *storagePolicyAdapter) LoadPolicy(m casbinmodel.Model) error {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	// casbin loads the policy outside of any request
+	policies, err := a.load(context.Background())
+	if err != nil {
+		for _, rule := range a.lastGood {
+			m["p"]["p"].Policy = append(m["p"]["p"].Policy, rule)
+		}
+		return fmt.Errorf("error loading the authorization policies, keeping the last loaded ones - %s", err)
+	}
+
+	if len(policies) == 0 && a.fallback != nil {
+		logging.Logger().Info("no stored authorization policies, falling back to the policy file")
+		a.lastGood = nil
+		return a.fallback.LoadPolicy(m)
+	}
+
+	rules := make([][]string, 0, len(policies))
+	for _, policy := range policies {
+		if policy.Subject == "" || policy.Object == "" || policy.Action == "" {
+			logging.Logger().Warnf("skipping incomplete authorization policy %s", policy.ID)
+			continue
+		}
+		rules = append(rules, []string{policy.Subject, policy.Object, policy.Action})
+	}
+	for _, rule := range rules {
+		m["p"]["p"].Policy = append(m["p"]["p"].Policy, rule)
+	}
+	a.lastGood = rules
+	return nil
+}
+
+// SavePolicy is not supported, the policies are managed in the storage
+func (a *storagePolicyAdapter) SavePolicy(m casbinmodel.Model) error {
+	return errReadOnlyPolicy
+}
+
+// AddPolicy is not supported, the policies are managed in the storage
+func (a *storagePolicyAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return errReadOnlyPolicy
+}
+
+// RemovePolicy is not supported, the policies are managed in the storage
+func (a *storagePolicyAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return errReadOnlyPolicy
+}
+
+// RemoveFilteredPolicy is not supported, the policies are managed in the storage
+func (a *storagePolicyAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return errReadOnlyPolicy
+}
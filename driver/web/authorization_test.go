@@ -0,0 +1,174 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"context"
+	"errors"
+	"rewards/core/model"
+	"testing"
+
+	fileadapter "github.com/casbin/casbin/persist/file-adapter"
+)
+
+const (
+	testModelPath  = "authorization_model.conf"
+	testPolicyPath = "authorization_policy.csv"
+)
+
+func TestAuthorizationPolicyFile(t *testing.T) {
+	authorization := NewAuthorization(testModelPath, fileadapter.NewAdapter(testPolicyPath))
+
+	tests := []struct {
+		permission string
+		method     string
+		path       string
+		allowed    bool
+	}{
+		{"all_admin_rewards", "DELETE", "/rewards/api/admin/types/1", true},
+		{"all_admin_rewards", "POST", "/rewards/api/admin/authorization/reload", true},
+
+		{"rewards_viewer", "GET", "/rewards/api/admin/types", true},
+		{"rewards_viewer", "GET", "/rewards/api/admin/claims/1/pickups", true},
+		{"rewards_viewer", "GET", "/rewards/api/admin/locations/1/claims", true},
+		{"rewards_viewer", "POST", "/rewards/api/admin/types", false},
+		{"rewards_viewer", "PUT", "/rewards/api/admin/claims/1", false},
+		{"rewards_viewer", "POST", "/rewards/api/admin/authorization/reload", false},
+		{"rewards_viewer", "GET", "/rewards/api/admin/catalog/1", true},
+		{"rewards_viewer", "GET", "/rewards/api/admin/leaderboards/coins", true},
+		{"rewards_viewer", "GET", "/rewards/api/admin/analytics/grants", true},
+		{"rewards_viewer", "GET", "/rewards/api/admin/credentials", false},
+		{"rewards_viewer", "GET", "/rewards/api/admin/credentials/1", false},
+		{"rewards_viewer", "GET", "/rewards/api/admin/audit", false},
+		{"rewards_viewer", "GET", "/rewards/api/admin/export/history", false},
+		{"rewards_viewer", "GET", "/rewards/api/admin/config/export", false},
+		{"rewards_viewer", "POST", "/rewards/api/admin/leaderboards/rebuild", false},
+
+		{"rewards_inventory_manager", "GET", "/rewards/api/admin/inventories", true},
+		{"rewards_inventory_manager", "POST", "/rewards/api/admin/inventories", true},
+		{"rewards_inventory_manager", "PUT", "/rewards/api/admin/inventories/1", true},
+		{"rewards_inventory_manager", "GET", "/rewards/api/admin/types/1", true},
+		{"rewards_inventory_manager", "PUT", "/rewards/api/admin/types/1", false},
+		{"rewards_inventory_manager", "GET", "/rewards/api/admin/claims", false},
+		{"rewards_inventory_manager", "POST", "/rewards/api/admin/operations", false},
+		{"rewards_inventory_manager", "POST", "/rewards/api/admin/catalog", true},
+		{"rewards_inventory_manager", "DELETE", "/rewards/api/admin/catalog/1", true},
+		{"rewards_inventory_manager", "POST", "/rewards/api/admin/import/inventories", false},
+		{"rewards_inventory_manager", "POST", "/rewards/api/admin/config/import", false},
+
+		{"rewards_claims_fulfiller", "GET", "/rewards/api/admin/claims", true},
+		{"rewards_claims_fulfiller", "PUT", "/rewards/api/admin/claims/1", true},
+		{"rewards_claims_fulfiller", "POST", "/rewards/api/admin/claims/pickup", true},
+		{"rewards_claims_fulfiller", "GET", "/rewards/api/admin/locations/1/claims", true},
+		{"rewards_claims_fulfiller", "POST", "/rewards/api/admin/claims", false},
+		{"rewards_claims_fulfiller", "PUT", "/rewards/api/admin/inventories/1", false},
+		{"rewards_claims_fulfiller", "POST", "/rewards/api/admin/locations", false},
+
+		{"rewards_operations_editor", "POST", "/rewards/api/admin/operations", true},
+		{"rewards_operations_editor", "PUT", "/rewards/api/admin/operations/1", true},
+		{"rewards_operations_editor", "DELETE", "/rewards/api/admin/operations/1", true},
+		{"rewards_operations_editor", "GET", "/rewards/api/admin/types", true},
+		{"rewards_operations_editor", "POST", "/rewards/api/admin/types", false},
+		{"rewards_operations_editor", "GET", "/rewards/api/admin/inventories", false},
+		{"rewards_operations_editor", "GET", "/rewards/api/admin/analytics/claims", true},
+		{"rewards_operations_editor", "POST", "/rewards/api/admin/leaderboards/rebuild", false},
+
+		{"unknown", "GET", "/rewards/api/admin/types", false},
+	}
+
+	for _, test := range tests {
+		allowed := authorization.Enforce([]string{test.permission}, test.path, test.method)
+		if allowed != test.allowed {
+			t.Errorf("%s %s %s: expected allowed=%t, got %t", test.permission, test.method, test.path, test.allowed, allowed)
+		}
+	}
+}
+
+func TestAuthorizationAnyPermission(t *testing.T) {
+	authorization := NewAuthorization(testModelPath, fileadapter.NewAdapter(testPolicyPath))
+
+	permissions := []string{"rewards_viewer", "rewards_claims_fulfiller"}
+	if !authorization.Enforce(permissions, "/rewards/api/admin/claims/1", "PUT") {
+		t.Error("expected access through the second permission")
+	}
+	if authorization.Enforce(permissions, "/rewards/api/admin/inventories/1", "PUT") {
+		t.Error("expected no access for permissions without the rule")
+	}
+}
+
+func TestStoragePolicyAdapterReload(t *testing.T) {
+	policies := []model.AuthorizationPolicy{
+		{ID: "1", Subject: "rewards_viewer", Object: "/rewards/api/admin/*", Action: "(GET)"},
+	}
+	var loadErr error
+	loader := func(ctx context.Context) ([]model.AuthorizationPolicy, error) {
+		return policies, loadErr
+	}
+
+	authorization := NewAuthorization(testModelPath, &storagePolicyAdapter{load: loader})
+	if !authorization.Enforce([]string{"rewards_viewer"}, "/rewards/api/admin/claims", "GET") {
+		t.Fatal("expected the stored policy to be loaded")
+	}
+
+	// revoke the viewer and grant the claims fulfiller
+	policies = []model.AuthorizationPolicy{
+		{ID: "2", Subject: "rewards_claims_fulfiller", Object: "/rewards/api/admin/claims", Action: "(GET)"},
+	}
+	err := authorization.Reload()
+	if err != nil {
+		t.Fatalf("unexpected reload error: %s", err)
+	}
+	if authorization.Enforce([]string{"rewards_viewer"}, "/rewards/api/admin/claims", "GET") {
+		t.Error("expected the removed policy to be gone after reload")
+	}
+	if !authorization.Enforce([]string{"rewards_claims_fulfiller"}, "/rewards/api/admin/claims", "GET") {
+		t.Error("expected the new policy to apply after reload")
+	}
+
+	// storage failure keeps the last loaded policy
+	loadErr = errors.New("storage is down")
+	err = authorization.Reload()
+	if err == nil {
+		t.Error("expected reload error")
+	}
+	if !authorization.Enforce([]string{"rewards_claims_fulfiller"}, "/rewards/api/admin/claims", "GET") {
+		t.Error("expected the last loaded policy to be kept")
+	}
+}
+
+func TestStoragePolicyAdapterFallback(t *testing.T) {
+	loader := func(ctx context.Context) ([]model.AuthorizationPolicy, error) {
+		return []model.AuthorizationPolicy{}, nil
+	}
+
+	adapter := &storagePolicyAdapter{load: loader, fallback: fileadapter.NewAdapter(testPolicyPath)}
+	authorization := NewAuthorization(testModelPath, adapter)
+	if !authorization.Enforce([]string{"all_admin_rewards"}, "/rewards/api/admin/types", "POST") {
+		t.Error("expected the policy file to be used while nothing is stored")
+	}
+}
+
+func TestStoragePolicyAdapterSkipsIncomplete(t *testing.T) {
+	loader := func(ctx context.Context) ([]model.AuthorizationPolicy, error) {
+		return []model.AuthorizationPolicy{
+			{ID: "1", Subject: "rewards_viewer", Object: "", Action: "(GET)"},
+		}, nil
+	}
+
+	authorization := NewAuthorization(testModelPath, &storagePolicyAdapter{load: loader})
+	if authorization.Enforce([]string{"rewards_viewer"}, "/rewards/api/admin/types", "GET") {
+		t.Error("expected incomplete policies to be skipped")
+	}
+}
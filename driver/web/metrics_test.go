@@ -0,0 +1,60 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"rewards/utils/metrics"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	subrouter := router.PathPrefix("/rewards").Subrouter()
+	subrouter.Use(metricsMiddleware)
+	subrouter.Handle("/metrics", metrics.Handler()).Methods("GET")
+	subrouter.HandleFunc("/api/admin/metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := w.(http.Flusher); !ok {
+			t.Error("expected the recorder to flush for the streaming handlers")
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}).Methods("GET")
+
+	for _, id := range []string{"1", "2"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rewards/api/admin/metrics-test/"+id, nil))
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected status %d, got %d", http.StatusNotFound, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rewards/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	body := w.Body.String()
+	expected := `rewards_http_requests_total{method="GET",route="/rewards/api/admin/metrics-test/{id}",status="404"} 2`
+	if !strings.Contains(body, expected) {
+		t.Errorf("expected %s in the metrics, got %s", expected, body)
+	}
+	if !strings.Contains(body, `rewards_http_request_duration_seconds_count{method="GET",route="/rewards/api/admin/metrics-test/{id}"} 2`) {
+		t.Errorf("expected the request latencies by route template, got %s", body)
+	}
+}
@@ -16,12 +16,13 @@ package rest
 
 import (
 	"encoding/json"
-	"io/ioutil"
-	"log"
+	"fmt"
 	"net/http"
 	"rewards/core"
 	"rewards/core/model"
+	"rewards/utils/logging"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rokwire/core-auth-library-go/tokenauth"
@@ -40,10 +41,10 @@ type AdminApisHandler struct {
 // @Security AdminUserAuth
 // @Router /admin/types [get]
 func (h AdminApisHandler) GetRewardTypes(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
-	resData, err := h.app.Services.GetRewardTypes(claims.OrgID)
+	resData, err := h.app.Services.GetRewardTypes(r.Context(), claims.OrgID)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardTypes(): %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardTypes(): %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -53,8 +54,8 @@ func (h AdminApisHandler) GetRewardTypes(claims *tokenauth.Claims, w http.Respon
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on marshal reward types: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on marshal reward types: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -76,17 +77,17 @@ func (h AdminApisHandler) GetRewardType(claims *tokenauth.Claims, w http.Respons
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	resData, err := h.app.Services.GetRewardType(claims.OrgID, id)
+	resData, err := h.app.Services.GetRewardType(r.Context(), claims.OrgID, id)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardType(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardType(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -95,11 +96,24 @@ func (h AdminApisHandler) GetRewardType(claims *tokenauth.Claims, w http.Respons
 	w.Write(data)
 }
 
+// updateRewardTypeBody wrapper
+type updateRewardTypeBody struct {
+	DisplayName string `json:"display_name"`
+	Active      bool   `json:"active"`
+	Currency    bool   `json:"currency"`
+	Description string `json:"description"`
+
+	InventoryBacked *bool `json:"inventory_backed"` // defaults to true for all reward types except currencies
+
+	AllocationStrategy    string   `json:"allocation_strategy"`
+	PreferredInventoryIDs []string `json:"preferred_inventory_ids"`
+} //@name updateRewardTypeBody
+
 // UpdateRewardType Updates a reward type with the specified id
 // @Description Updates a reward type with the specified id
 // @Tags Admin
 // @ID AdminUpdateRewardType
-// @Param data body model.RewardType true "body json"
+// @Param data body updateRewardTypeBody true "body json"
 // @Accept json
 // @Produce json
 // @Success 200 {object} model.RewardType
@@ -109,32 +123,34 @@ func (h AdminApisHandler) UpdateRewardType(claims *tokenauth.Claims, w http.Resp
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	data, err := ioutil.ReadAll(r.Body)
+	var body updateRewardTypeBody
+	err := decodeJSONBody(r, &body)
 	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
-	var item model.RewardType
-	err = json.Unmarshal(data, &item)
-	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	inventoryBacked := !body.Currency
+	if body.InventoryBacked != nil {
+		inventoryBacked = *body.InventoryBacked
 	}
 
-	resData, err := h.app.Services.UpdateRewardType(claims.OrgID, id, item)
+	item := model.RewardType{ID: id, DisplayName: body.DisplayName, Active: body.Active, Currency: body.Currency,
+		Description: body.Description, InventoryBacked: inventoryBacked, AllocationStrategy: body.AllocationStrategy,
+		PreferredInventoryIDs: body.PreferredInventoryIDs}
+
+	resData, err := h.app.Services.UpdateRewardType(r.Context(), claims.OrgID, id, item)
 	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -143,43 +159,59 @@ func (h AdminApisHandler) UpdateRewardType(claims *tokenauth.Claims, w http.Resp
 	w.Write(jsonData)
 }
 
+// createRewardTypeBody wrapper
+type createRewardTypeBody struct {
+	RewardType  string `json:"reward_type" validate:"required"`
+	DisplayName string `json:"display_name"`
+	Active      bool   `json:"active"`
+	Currency    bool   `json:"currency"`
+	Description string `json:"description"`
+
+	InventoryBacked *bool `json:"inventory_backed"` // defaults to true for all reward types except currencies
+
+	AllocationStrategy    string   `json:"allocation_strategy"`
+	PreferredInventoryIDs []string `json:"preferred_inventory_ids"`
+} //@name createRewardTypeBody
+
 // CreateRewardType Create a new reward type
 // @Description Create a new reward type
 // @Tags Admin
 // @ID AdminCreateRewardType
-// @Param data body model.RewardType true "body json"
+// @Param data body createRewardTypeBody true "body json"
 // @Accept json
 // @Success 200 {object} model.RewardType
 // @Security AdminUserAuth
 // @Router /admin/types [post]
 func (h AdminApisHandler) CreateRewardType(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
 
-	data, err := ioutil.ReadAll(r.Body)
+	var body createRewardTypeBody
+	err := decodeJSONBody(r, &body)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardType: %s", err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardType: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	var item model.RewardType
-	err = json.Unmarshal(data, &item)
-	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardType: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
+	inventoryBacked := !body.Currency
+	if body.InventoryBacked != nil {
+		inventoryBacked = *body.InventoryBacked
 	}
 
-	createdItem, err := h.app.Services.CreateRewardType(claims.OrgID, item)
+	item := model.RewardType{RewardType: body.RewardType, DisplayName: body.DisplayName, Active: body.Active,
+		Currency: body.Currency, Description: body.Description, InventoryBacked: inventoryBacked,
+		AllocationStrategy: body.AllocationStrategy, PreferredInventoryIDs: body.PreferredInventoryIDs}
+
+	createdItem, err := h.app.Services.CreateRewardType(r.Context(), claims.OrgID, item)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardType: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardType: %s", err)
+		HandleError(w, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardType: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardType: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -199,10 +231,10 @@ func (h AdminApisHandler) DeleteRewardType(claims *tokenauth.Claims, w http.Resp
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	err := h.app.Services.DeleteRewardType(claims.OrgID, id)
+	err := h.app.Services.DeleteRewardType(r.Context(), claims.OrgID, id)
 	if err != nil {
-		log.Printf("Error on adminapis.DeleteRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.DeleteRewardType(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -211,28 +243,32 @@ func (h AdminApisHandler) DeleteRewardType(claims *tokenauth.Claims, w http.Resp
 }
 
 // GetRewardOperations Retrieves  all reward operations
-// @Description Retrieves  all reward types
+// @Description Retrieves  all reward operations. The archived operations are excluded unless requested
+// @Param archived query string false "archived - possible values: missing or 0 - not archived, 1 - archived"
 // @Tags Admin
 // @ID AdminGetRewardOperations
 // @Success 200 {array} model.RewardOperation
 // @Security AdminUserAuth
 // @Router /admin/operations [get]
 func (h AdminApisHandler) GetRewardOperations(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
-	resData, err := h.app.Services.GetRewardTypes(claims.OrgID)
+	notArchived := false
+	archived := getBoolQueryParam(r, "archived", &notArchived)
+
+	resData, err := h.app.Services.GetRewardOperations(r.Context(), claims.OrgID, archived)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardTypes(): %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardOperations(): %s", err)
+		HandleError(w, err)
 		return
 	}
 
 	if resData == nil {
-		resData = []model.RewardType{}
+		resData = []model.RewardOperation{}
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on marshal reward types: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on marshal reward operations: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -254,17 +290,17 @@ func (h AdminApisHandler) GetRewardOperation(claims *tokenauth.Claims, w http.Re
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	resData, err := h.app.Services.GetRewardType(claims.OrgID, id)
+	resData, err := h.app.Services.GetRewardOperationByID(r.Context(), claims.OrgID, id)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -273,11 +309,17 @@ func (h AdminApisHandler) GetRewardOperation(claims *tokenauth.Claims, w http.Re
 	w.Write(data)
 }
 
+// updateRewardOperationBody wrapper
+type updateRewardOperationBody struct {
+	Amount      int    `json:"amount" validate:"gt=0"`
+	Description string `json:"description"`
+} //@name updateRewardOperationBody
+
 // UpdateRewardOperation Updates a reward operation with the specified id
 // @Description Updates a reward operation with the specified id
 // @Tags Admin
 // @ID AdminUpdateRewardOperation
-// @Param data body model.RewardOperation true "body json"
+// @Param data body updateRewardOperationBody true "body json"
 // @Accept json
 // @Produce json
 // @Success 200 {object} model.RewardOperation
@@ -287,32 +329,27 @@ func (h AdminApisHandler) UpdateRewardOperation(claims *tokenauth.Claims, w http
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	data, err := ioutil.ReadAll(r.Body)
+	var body updateRewardOperationBody
+	err := decodeJSONBody(r, &body)
 	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
-	var item model.RewardType
-	err = json.Unmarshal(data, &item)
-	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	item := model.RewardOperation{ID: id, Amount: body.Amount, Description: body.Description}
 
-	resData, err := h.app.Services.UpdateRewardType(claims.OrgID, id, item)
+	resData, err := h.app.Services.UpdateRewardOperation(r.Context(), claims.OrgID, id, item)
 	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -321,43 +358,48 @@ func (h AdminApisHandler) UpdateRewardOperation(claims *tokenauth.Claims, w http
 	w.Write(jsonData)
 }
 
+// createRewardOperationBody wrapper
+type createRewardOperationBody struct {
+	RewardType    string `json:"reward_type" validate:"required"`
+	Code          string `json:"code" validate:"required"`
+	BuildingBlock string `json:"building_block" validate:"required"`
+	Amount        int    `json:"amount" validate:"gt=0"`
+	Description   string `json:"description"`
+} //@name createRewardOperationBody
+
 // CreateRewardOperation Create a new operation
 // @Description Create a new operation type
 // @Tags Admin
 // @ID AdminCreateRewardOperation
-// @Param data body model.RewardOperation true "body json"
+// @Param data body createRewardOperationBody true "body json"
 // @Accept json
 // @Success 200 {object} model.RewardOperation
 // @Security AdminUserAuth
 // @Router /admin/operations [post]
 func (h AdminApisHandler) CreateRewardOperation(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
 
-	data, err := ioutil.ReadAll(r.Body)
+	var body createRewardOperationBody
+	err := decodeJSONBody(r, &body)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardType: %s", err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardOperation: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	var item model.RewardType
-	err = json.Unmarshal(data, &item)
-	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardType: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	item := model.RewardOperation{RewardType: body.RewardType, Code: body.Code, BuildingBlock: body.BuildingBlock,
+		Amount: body.Amount, Description: body.Description}
 
-	createdItem, err := h.app.Services.CreateRewardType(claims.OrgID, item)
+	createdItem, err := h.app.Services.CreateRewardOperation(r.Context(), claims.OrgID, item)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardType: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardOperation: %s", err)
+		HandleError(w, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardType: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardOperation: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -377,15 +419,75 @@ func (h AdminApisHandler) DeleteRewardOperation(claims *tokenauth.Claims, w http
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	err := h.app.Services.DeleteRewardType(claims.OrgID, id)
+	err := h.app.Services.DeleteRewardOperation(r.Context(), claims.OrgID, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.DeleteRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+// ArchiveRewardOperation Archives a reward operation. An archived operation is hidden from the listings and does not grant rewards
+// @Description Archives a reward operation. An archived operation is hidden from the listings and does not grant rewards
+// @Tags Admin
+// @ID AdminArchiveRewardOperation
+// @Success 200 {object} model.RewardOperation
+// @Security AdminUserAuth
+// @Router /admin/operations/{id}/archive [post]
+func (h AdminApisHandler) ArchiveRewardOperation(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resData, err := h.app.Services.ArchiveRewardOperation(r.Context(), claims.OrgID, id, true)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ArchiveRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ArchiveRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// UnarchiveRewardOperation Unarchives a reward operation
+// @Description Unarchives a reward operation
+// @Tags Admin
+// @ID AdminUnarchiveRewardOperation
+// @Success 200 {object} model.RewardOperation
+// @Security AdminUserAuth
+// @Router /admin/operations/{id}/unarchive [post]
+func (h AdminApisHandler) UnarchiveRewardOperation(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resData, err := h.app.Services.ArchiveRewardOperation(r.Context(), claims.OrgID, id, false)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UnarchiveRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.DeleteRewardType(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UnarchiveRewardOperation(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
+	w.Write(data)
 }
 
 // GetRewardInventories Retrieves  all reward inventories
@@ -394,6 +496,7 @@ func (h AdminApisHandler) DeleteRewardOperation(claims *tokenauth.Claims, w http
 // @Param in_stock query string false "in_stock - possible values: missing (e.g no filter), 0- false, 1- true"
 // @Param grant_depleted query string false "grant_depleted - possible values: missing (e.g no filter), 0- false, 1- true"
 // @Param claim_depleted query string false "claim_depleted - possible values: missing (e.g no filter), 0- false, 1- true"
+// @Param archived query string false "archived - possible values: missing or 0 - not archived, 1 - archived"
 // @Param limit query string false "limit - limit the result"
 // @Param offset query string false "offset"
 // @Tags Admin
@@ -407,6 +510,8 @@ func (h AdminApisHandler) GetRewardInventories(claims *tokenauth.Claims, w http.
 	inStock := getBoolQueryParam(r, "in_stock", nil)
 	grantDepleted := getBoolQueryParam(r, "grant_depleted", nil)
 	claimDepleted := getBoolQueryParam(r, "claim_depleted", nil)
+	notArchived := false
+	archived := getBoolQueryParam(r, "archived", &notArchived)
 	limitFilter := getInt64QueryParam(r, "limit")
 	offsetFilter := getInt64QueryParam(r, "offset")
 
@@ -417,10 +522,10 @@ func (h AdminApisHandler) GetRewardInventories(claims *tokenauth.Claims, w http.
 		IDs = strings.Split(extIDs, ",")
 	}
 
-	resData, err := h.app.Services.GetRewardInventories(claims.OrgID, IDs, rewardType, inStock, grantDepleted, claimDepleted, limitFilter, offsetFilter)
+	resData, err := h.app.Services.GetRewardInventories(r.Context(), claims.OrgID, IDs, rewardType, inStock, grantDepleted, claimDepleted, archived, limitFilter, offsetFilter)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardInventories: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardInventories: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -430,8 +535,8 @@ func (h AdminApisHandler) GetRewardInventories(claims *tokenauth.Claims, w http.
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardInventories: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardInventories: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -453,17 +558,17 @@ func (h AdminApisHandler) GetRewardInventory(claims *tokenauth.Claims, w http.Re
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	resData, err := h.app.Services.GetRewardInventory(claims.OrgID, id)
+	resData, err := h.app.Services.GetRewardInventory(r.Context(), claims.OrgID, id)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardInventory(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardInventory(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.GetRewardInventory(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardInventory(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -472,11 +577,21 @@ func (h AdminApisHandler) GetRewardInventory(claims *tokenauth.Claims, w http.Re
 	w.Write(data)
 }
 
+// updateRewardInventoryBody wrapper
+type updateRewardInventoryBody struct {
+	InStock       bool     `json:"in_stock"`
+	AmountTotal   int      `json:"amount_total" validate:"gt=0"`
+	AmountGranted int      `json:"amount_granted" validate:"gte=0,ltefield=AmountTotal"`
+	AmountClaimed int      `json:"amount_claimed" validate:"gte=0,ltefield=AmountTotal"`
+	LocationIDs   []string `json:"location_ids"`
+	Description   string   `json:"description"`
+} //@name updateRewardInventoryBody
+
 // UpdateRewardInventory Updates a reward inventory with the specified id
 // @Description Updates a reward inventory with the specified id
 // @Tags Admin
 // @ID AdminUpdateRewardInventory
-// @Param data body model.RewardInventory true "body json"
+// @Param data body updateRewardInventoryBody true "body json"
 // @Accept json
 // @Produce json
 // @Success 200 {object} model.RewardInventory
@@ -486,32 +601,29 @@ func (h AdminApisHandler) UpdateRewardInventory(claims *tokenauth.Claims, w http
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	data, err := ioutil.ReadAll(r.Body)
+	var body updateRewardInventoryBody
+	err := decodeJSONBody(r, &body)
 	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardInventory(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardInventory(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
-	var item model.RewardInventory
-	err = json.Unmarshal(data, &item)
-	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardInventory(%s): %s", id, err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	item := model.RewardInventory{ID: id, OrgID: claims.OrgID, InStock: body.InStock, AmountTotal: body.AmountTotal,
+		AmountGranted: body.AmountGranted, AmountClaimed: body.AmountClaimed, LocationIDs: body.LocationIDs,
+		Description: body.Description}
 
-	resData, err := h.app.Services.UpdateRewardInventory(claims.OrgID, id, item)
+	resData, err := h.app.Services.UpdateRewardInventory(r.Context(), claims.OrgID, id, item)
 	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardInventory(%s): %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardInventory(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.UpdateRewardInventory(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardInventory(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -520,49 +632,114 @@ func (h AdminApisHandler) UpdateRewardInventory(claims *tokenauth.Claims, w http
 	w.Write(jsonData)
 }
 
+// createRewardInventoryBody wrapper
+type createRewardInventoryBody struct {
+	RewardType  string   `json:"reward_type" validate:"required"`
+	InStock     bool     `json:"in_stock"`
+	AmountTotal int      `json:"amount_total" validate:"gt=0"`
+	LocationIDs []string `json:"location_ids"`
+	Description string   `json:"description"`
+} //@name createRewardInventoryBody
+
 // CreateRewardInventory Create a new reward inventory
 // @Description Create a new reward inventory
 // @Tags Admin
 // @ID AdminCreateRewardInventory
-// @Param data body model.RewardInventory true "body json"
+// @Param data body createRewardInventoryBody true "body json"
 // @Accept json
 // @Success 200 {object} model.RewardInventory
 // @Security AdminUserAuth
 // @Router /admin/inventories [post]
 func (h AdminApisHandler) CreateRewardInventory(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
 
-	data, err := ioutil.ReadAll(r.Body)
+	var body createRewardInventoryBody
+	err := decodeJSONBody(r, &body)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardInventory: %s", err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardInventory: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	var item model.RewardInventory
-	err = json.Unmarshal(data, &item)
+	item := model.RewardInventory{RewardType: body.RewardType, InStock: body.InStock, AmountTotal: body.AmountTotal,
+		LocationIDs: body.LocationIDs, Description: body.Description}
+
+	createdItem, err := h.app.Services.CreateRewardInventory(r.Context(), claims.OrgID, item)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardInventory: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardInventory: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	createdItem, err := h.app.Services.CreateRewardInventory(claims.OrgID, item)
+	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardInventory: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardInventory: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	jsonData, err := json.Marshal(createdItem)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// ArchiveRewardInventory Archives a reward inventory. An archived inventory is hidden from the listings and is not drawn from by grants and claims
+// @Description Archives a reward inventory. An archived inventory is hidden from the listings and is not drawn from by grants and claims
+// @Tags Admin
+// @ID AdminArchiveRewardInventory
+// @Success 200 {object} model.RewardInventory
+// @Security AdminUserAuth
+// @Router /admin/inventories/{id}/archive [post]
+func (h AdminApisHandler) ArchiveRewardInventory(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resData, err := h.app.Services.ArchiveRewardInventory(r.Context(), claims.OrgID, id, true)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ArchiveRewardInventory(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.CreateRewardInventory: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ArchiveRewardInventory(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	w.Write(jsonData)
+	w.Write(data)
+}
+
+// UnarchiveRewardInventory Unarchives a reward inventory
+// @Description Unarchives a reward inventory
+// @Tags Admin
+// @ID AdminUnarchiveRewardInventory
+// @Success 200 {object} model.RewardInventory
+// @Security AdminUserAuth
+// @Router /admin/inventories/{id}/unarchive [post]
+func (h AdminApisHandler) UnarchiveRewardInventory(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resData, err := h.app.Services.ArchiveRewardInventory(r.Context(), claims.OrgID, id, false)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UnarchiveRewardInventory(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UnarchiveRewardInventory(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
 }
 
 // GetRewardClaims Retrieves  all reward claims
@@ -592,10 +769,10 @@ func (h AdminApisHandler) GetRewardClaims(claims *tokenauth.Claims, w http.Respo
 		IDs = strings.Split(extIDs, ",")
 	}
 
-	resData, err := h.app.Services.GetRewardClaims(claims.OrgID, IDs, userID, rewardType, status, limitFilter, offsetFilter)
+	resData, err := h.app.Services.GetRewardClaims(r.Context(), claims.OrgID, IDs, userID, rewardType, status, limitFilter, offsetFilter)
 	if err != nil {
-		log.Printf("Error on adminapis.getRewardClaims: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.getRewardClaims: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -605,8 +782,8 @@ func (h AdminApisHandler) GetRewardClaims(claims *tokenauth.Claims, w http.Respo
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.getRewardClaims: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.getRewardClaims: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -628,17 +805,17 @@ func (h AdminApisHandler) GetRewardClaim(claims *tokenauth.Claims, w http.Respon
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	resData, err := h.app.Services.GetRewardClaim(claims.OrgID, id)
+	resData, err := h.app.Services.GetRewardClaim(r.Context(), claims.OrgID, id)
 	if err != nil {
-		log.Printf("Error on adminapis.getRewardClaim(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.getRewardClaim(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.getRewardClaim(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.getRewardClaim(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -647,11 +824,17 @@ func (h AdminApisHandler) GetRewardClaim(claims *tokenauth.Claims, w http.Respon
 	w.Write(data)
 }
 
+// updateRewardClaimBody wrapper
+type updateRewardClaimBody struct {
+	Status      string `json:"status" validate:"required,oneof=pending approved"`
+	Description string `json:"description"`
+} //@name updateRewardClaimBody
+
 // UpdateRewardClaim Updates a reward claim with the specified id
 // @Description Updates a reward claim with the specified id
 // @Tags Admin
 // @ID AdminUpdateRewardClaim
-// @Param data body model.RewardClaim true "body json"
+// @Param data body updateRewardClaimBody true "body json"
 // @Accept json
 // @Produce json
 // @Success 200 {object} model.RewardClaim
@@ -661,32 +844,27 @@ func (h AdminApisHandler) UpdateRewardClaim(claims *tokenauth.Claims, w http.Res
 	vars := mux.Vars(r)
 	id := vars["id"]
 
-	data, err := ioutil.ReadAll(r.Body)
+	var body updateRewardClaimBody
+	err := decodeJSONBody(r, &body)
 	if err != nil {
-		log.Printf("Error on adminapis.updateRewardClaim(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardClaim(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
-	var item model.RewardClaim
-	err = json.Unmarshal(data, &item)
-	if err != nil {
-		log.Printf("Error on adminapis.updateRewardClaim(%s): %s", id, err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+	item := model.RewardClaim{ID: id, Status: body.Status, Description: body.Description}
 
-	resData, err := h.app.Services.UpdateRewardClaim(claims.OrgID, id, item)
+	resData, err := h.app.Services.UpdateRewardClaim(r.Context(), claims.OrgID, id, item)
 	if err != nil {
-		log.Printf("Error on adminapis.updateRewardClaim(%s): %s", id, err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.updateRewardClaim(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on adminapis.updateRewardClaim(%s): %s", id, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.updateRewardClaim(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -695,43 +873,303 @@ func (h AdminApisHandler) UpdateRewardClaim(claims *tokenauth.Claims, w http.Res
 	w.Write(jsonData)
 }
 
+// createRewardClaimBody wrapper
+type createRewardClaimBody struct {
+	UserID      string                   `json:"user_id" validate:"required"`
+	Items       []rewardClaimItemBody    `json:"items" validate:"required_without=Purchase,unique=RewardType,dive"`
+	Purchase    *rewardClaimPurchaseBody `json:"purchase"`
+	Pickup      *rewardClaimPickupBody   `json:"pickup"`
+	Description string                   `json:"description"`
+} //@name createRewardClaimBody
+
 // CreateRewardClaim Create a new claim inventory
 // @Description Create a new claim inventory
 // @Tags Admin
 // @ID AdminCreateRewardClaim
-// @Param data body model.RewardClaim true "body json"
+// @Param data body createRewardClaimBody true "body json"
 // @Accept json
 // @Success 200 {object} model.RewardInventory
 // @Security AdminUserAuth
 // @Router /admin/claims [post]
 func (h AdminApisHandler) CreateRewardClaim(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
 
-	data, err := ioutil.ReadAll(r.Body)
+	var body createRewardClaimBody
+	err := decodeJSONBody(r, &body)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardClaim: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	item := createUserRewardClaimBody{Items: body.Items, Purchase: body.Purchase, Pickup: body.Pickup,
+		Description: body.Description}.toRewardClaim(body.UserID)
+
+	createdItem, err := h.app.Services.CreateRewardClaim(r.Context(), claims.OrgID, item)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.createRewardClaim: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(createdItem)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.createRewardClaim: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// GetRewardCatalogItems Retrieves  all reward catalog items
+// @Description Retrieves  all reward catalog items
+// @Param active query string false "active - possible values: missing (e.g no filter), 0- false, 1- true"
+// @Tags Admin
+// @ID AdminGetRewardCatalogItems
+// @Success 200 {array} model.RewardCatalogItem
+// @Security AdminUserAuth
+// @Router /admin/catalog [get]
+func (h AdminApisHandler) GetRewardCatalogItems(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	active := getBoolQueryParam(r, "active", nil)
+
+	resData, err := h.app.Services.GetRewardCatalogItems(r.Context(), claims.OrgID, active)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardCatalogItems: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	if resData == nil {
+		resData = []model.RewardCatalogItem{}
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardCatalogItems: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetRewardCatalogItem Retrieves a reward catalog item by id
+// @Description Retrieves a reward catalog item by id
+// @Tags Admin
+// @ID AdminGetRewardCatalogItem
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.RewardCatalogItem
+// @Security AdminUserAuth
+// @Router /admin/catalog/{id} [get]
+func (h AdminApisHandler) GetRewardCatalogItem(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resData, err := h.app.Services.GetRewardCatalogItem(r.Context(), claims.OrgID, id)
 	if err != nil {
-		log.Printf("Error on adminapis.createRewardClaim: %s", err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardCatalogItem(%s): %s", id, err)
+		HandleError(w, err)
 		return
 	}
 
-	var item model.RewardClaim
-	err = json.Unmarshal(data, &item)
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardCatalogItem(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// updateRewardCatalogItemBody wrapper
+type updateRewardCatalogItemBody struct {
+	DisplayName   string                   `json:"display_name" validate:"required"`
+	Description   string                   `json:"description"`
+	Price         []rewardCatalogPriceBody `json:"price" validate:"required,min=1,dive"`
+	Active        bool                     `json:"active"`
+	AvailableFrom *time.Time               `json:"available_from"`
+	AvailableTo   *time.Time               `json:"available_to"`
+	UserLimit     int                      `json:"user_limit" validate:"gte=0"`
+} //@name updateRewardCatalogItemBody
+
+// UpdateRewardCatalogItem Updates a reward catalog item with the specified id
+// @Description Updates a reward catalog item with the specified id
+// @Tags Admin
+// @ID AdminUpdateRewardCatalogItem
+// @Param data body updateRewardCatalogItemBody true "body json"
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.RewardCatalogItem
+// @Security AdminUserAuth
+// @Router /admin/catalog/{id} [put]
+func (h AdminApisHandler) UpdateRewardCatalogItem(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var body updateRewardCatalogItemBody
+	err := decodeJSONBody(r, &body)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardCatalogItem(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	item := model.RewardCatalogItem{ID: id, DisplayName: body.DisplayName, Description: body.Description,
+		Price: toRewardCatalogPrices(body.Price), Active: body.Active, AvailableFrom: body.AvailableFrom,
+		AvailableTo: body.AvailableTo, UserLimit: body.UserLimit}
+
+	resData, err := h.app.Services.UpdateRewardCatalogItem(r.Context(), claims.OrgID, id, item)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardCatalogItem(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdateRewardCatalogItem(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// rewardCatalogPriceBody wrapper
+type rewardCatalogPriceBody struct {
+	RewardType string `json:"reward_type" validate:"required"`
+	Amount     int    `json:"amount" validate:"gt=0"`
+} //@name rewardCatalogPriceBody
+
+func toRewardCatalogPrices(items []rewardCatalogPriceBody) []model.RewardCatalogPrice {
+	prices := make([]model.RewardCatalogPrice, len(items))
+	for i, item := range items {
+		prices[i] = model.RewardCatalogPrice{RewardType: item.RewardType, Amount: item.Amount}
+	}
+	return prices
+}
+
+// createRewardCatalogItemBody wrapper
+type createRewardCatalogItemBody struct {
+	RewardType    string                   `json:"reward_type" validate:"required"`
+	DisplayName   string                   `json:"display_name" validate:"required"`
+	Description   string                   `json:"description"`
+	Price         []rewardCatalogPriceBody `json:"price" validate:"required,min=1,dive"`
+	Active        bool                     `json:"active"`
+	AvailableFrom *time.Time               `json:"available_from"`
+	AvailableTo   *time.Time               `json:"available_to"`
+	UserLimit     int                      `json:"user_limit" validate:"gte=0"`
+} //@name createRewardCatalogItemBody
+
+// CreateRewardCatalogItem Create a new reward catalog item
+// @Description Create a new reward catalog item
+// @Tags Admin
+// @ID AdminCreateRewardCatalogItem
+// @Param data body createRewardCatalogItemBody true "body json"
+// @Accept json
+// @Success 200 {object} model.RewardCatalogItem
+// @Security AdminUserAuth
+// @Router /admin/catalog [post]
+func (h AdminApisHandler) CreateRewardCatalogItem(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+
+	var body createRewardCatalogItemBody
+	err := decodeJSONBody(r, &body)
 	if err != nil {
-		log.Printf("Error on adminapis.createRewardClaim: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardCatalogItem: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	createdItem, err := h.app.Services.CreateRewardClaim(claims.OrgID, item)
+	item := model.RewardCatalogItem{RewardType: body.RewardType, DisplayName: body.DisplayName,
+		Description: body.Description, Price: toRewardCatalogPrices(body.Price), Active: body.Active,
+		AvailableFrom: body.AvailableFrom, AvailableTo: body.AvailableTo, UserLimit: body.UserLimit}
+
+	createdItem, err := h.app.Services.CreateRewardCatalogItem(r.Context(), claims.OrgID, item)
 	if err != nil {
-		log.Printf("Error on adminapis.createRewardClaim: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardCatalogItem: %s", err)
+		HandleError(w, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
-		log.Printf("Error on adminapis.createRewardClaim: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateRewardCatalogItem: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// DeleteRewardCatalogItem Deletes a reward catalog item with the specified id
+// @Description Deletes a reward catalog item with the specified id
+// @Tags Admin
+// @ID AdminDeleteRewardCatalogItem
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/catalog/{id} [delete]
+func (h AdminApisHandler) DeleteRewardCatalogItem(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	err := h.app.Services.DeleteRewardCatalogItem(r.Context(), claims.OrgID, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.DeleteRewardCatalogItem(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+// redeemPickupCodeBody wrapper
+type redeemPickupCodeBody struct {
+	PickupCode string `json:"pickup_code" validate:"required"`
+} //@name redeemPickupCodeBody
+
+// RedeemRewardClaimPickupCode Redeems a pickup code and moves the claim to fulfilled
+// @Description Redeems a pickup code and moves the claim to fulfilled
+// @Tags Admin
+// @ID AdminRedeemRewardClaimPickupCode
+// @Param data body redeemPickupCodeBody true "body json"
+// @Accept json
+// @Success 200 {object} model.RewardClaim
+// @Security AdminUserAuth
+// @Router /admin/claims/pickup [post]
+func (h AdminApisHandler) RedeemRewardClaimPickupCode(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+
+	var item redeemPickupCodeBody
+	err := decodeJSONBody(r, &item)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.RedeemRewardClaimPickupCode: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	claim, err := h.app.Services.RedeemRewardClaimPickupCode(r.Context(), claims.OrgID, claims.Subject, item.PickupCode)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.RedeemRewardClaimPickupCode: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(claim)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.RedeemRewardClaimPickupCode: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -739,3 +1177,1000 @@ func (h AdminApisHandler) CreateRewardClaim(claims *tokenauth.Claims, w http.Res
 	w.WriteHeader(http.StatusOK)
 	w.Write(jsonData)
 }
+
+// GetRewardClaimPickupAttempts Retrieves the pickup attempts of a reward claim
+// @Description Retrieves the pickup attempts of a reward claim
+// @Tags Admin
+// @ID AdminGetRewardClaimPickupAttempts
+// @Success 200 {array} model.RewardClaimPickupAttempt
+// @Security AdminUserAuth
+// @Router /admin/claims/{id}/pickups [get]
+func (h AdminApisHandler) GetRewardClaimPickupAttempts(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resData, err := h.app.Services.GetRewardClaimPickupAttempts(r.Context(), claims.OrgID, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardClaimPickupAttempts(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRewardClaimPickupAttempts(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetPickupLocations Retrieves  all pickup locations
+// @Description Retrieves  all pickup locations
+// @Tags Admin
+// @ID AdminGetPickupLocations
+// @Success 200 {array} model.PickupLocation
+// @Security AdminUserAuth
+// @Router /admin/locations [get]
+func (h AdminApisHandler) GetPickupLocations(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resData, err := h.app.Services.GetPickupLocations(r.Context(), claims.OrgID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetPickupLocations: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	if resData == nil {
+		resData = []model.PickupLocation{}
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetPickupLocations: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetPickupLocation Retrieves a pickup location by id
+// @Description Retrieves a pickup location by id
+// @Tags Admin
+// @ID AdminGetPickupLocation
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.PickupLocation
+// @Security AdminUserAuth
+// @Router /admin/locations/{id} [get]
+func (h AdminApisHandler) GetPickupLocation(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resData, err := h.app.Services.GetPickupLocation(r.Context(), claims.OrgID, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetPickupLocation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetPickupLocation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// pickupSlotBody wrapper
+type pickupSlotBody struct {
+	ID        string    `json:"id"` // keeps the slot of an updated location
+	StartTime time.Time `json:"start_time" validate:"required"`
+	EndTime   time.Time `json:"end_time" validate:"required,gtfield=StartTime"`
+	Capacity  int       `json:"capacity" validate:"gte=0"`
+} //@name pickupSlotBody
+
+// pickupLocationBody wrapper
+type pickupLocationBody struct {
+	Name         string           `json:"name" validate:"required"`
+	Address      string           `json:"address"`
+	OpeningHours string           `json:"opening_hours"`
+	Active       bool             `json:"active"`
+	Slots        []pickupSlotBody `json:"slots" validate:"dive"`
+	Description  string           `json:"description"`
+} //@name pickupLocationBody
+
+func (b pickupLocationBody) toPickupLocation() model.PickupLocation {
+	slots := make([]model.PickupSlot, len(b.Slots))
+	for i, slot := range b.Slots {
+		slots[i] = model.PickupSlot{ID: slot.ID, StartTime: slot.StartTime, EndTime: slot.EndTime, Capacity: slot.Capacity}
+	}
+	return model.PickupLocation{Name: b.Name, Address: b.Address, OpeningHours: b.OpeningHours, Active: b.Active,
+		Slots: slots, Description: b.Description}
+}
+
+// UpdatePickupLocation Updates a pickup location with the specified id
+// @Description Updates a pickup location with the specified id
+// @Tags Admin
+// @ID AdminUpdatePickupLocation
+// @Param data body pickupLocationBody true "body json"
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.PickupLocation
+// @Security AdminUserAuth
+// @Router /admin/locations/{id} [put]
+func (h AdminApisHandler) UpdatePickupLocation(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	var body pickupLocationBody
+	err := decodeJSONBody(r, &body)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdatePickupLocation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	item := body.toPickupLocation()
+	item.ID = id
+
+	resData, err := h.app.Services.UpdatePickupLocation(r.Context(), claims.OrgID, id, item)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdatePickupLocation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.UpdatePickupLocation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// CreatePickupLocation Create a new pickup location
+// @Description Create a new pickup location
+// @Tags Admin
+// @ID AdminCreatePickupLocation
+// @Param data body pickupLocationBody true "body json"
+// @Accept json
+// @Success 200 {object} model.PickupLocation
+// @Security AdminUserAuth
+// @Router /admin/locations [post]
+func (h AdminApisHandler) CreatePickupLocation(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+
+	var body pickupLocationBody
+	err := decodeJSONBody(r, &body)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreatePickupLocation: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	item := body.toPickupLocation()
+
+	createdItem, err := h.app.Services.CreatePickupLocation(r.Context(), claims.OrgID, item)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreatePickupLocation: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(createdItem)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreatePickupLocation: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// DeletePickupLocation Deletes a pickup location with the specified id
+// @Description Deletes a pickup location with the specified id
+// @Tags Admin
+// @ID AdminDeletePickupLocation
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/locations/{id} [delete]
+func (h AdminApisHandler) DeletePickupLocation(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	err := h.app.Services.DeletePickupLocation(r.Context(), claims.OrgID, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.DeletePickupLocation(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetPickupLocationClaims Retrieves the claims scheduled for pickup at a location
+// @Description Retrieves the claims scheduled for pickup at a location
+// @Param slot_id query string false "slot_id - filter by time slot"
+// @Param status query string false "status - filter by claim status"
+// @Tags Admin
+// @ID AdminGetPickupLocationClaims
+// @Success 200 {array} model.RewardClaim
+// @Security AdminUserAuth
+// @Router /admin/locations/{id}/claims [get]
+func (h AdminApisHandler) GetPickupLocationClaims(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	slotID := getStringQueryParam(r, "slot_id")
+	status := getStringQueryParam(r, "status")
+
+	resData, err := h.app.Services.GetRewardClaimsByLocation(r.Context(), claims.OrgID, id, slotID, status)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetPickupLocationClaims(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	if resData == nil {
+		resData = []model.RewardClaim{}
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetPickupLocationClaims(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetInternalCredentials Retrieves  all internal credentials of the building blocks
+// @Description Retrieves  all internal credentials of the building blocks
+// @Tags Admin
+// @ID AdminGetInternalCredentials
+// @Success 200 {array} model.InternalCredential
+// @Security AdminUserAuth
+// @Router /admin/credentials [get]
+func (h AdminApisHandler) GetInternalCredentials(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resData, err := h.app.Services.GetInternalCredentials(r.Context(), claims.OrgID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetInternalCredentials: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	if resData == nil {
+		resData = []model.InternalCredential{}
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetInternalCredentials: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetInternalCredential Retrieves an internal credential by id
+// @Description Retrieves an internal credential by id
+// @Tags Admin
+// @ID AdminGetInternalCredential
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.InternalCredential
+// @Security AdminUserAuth
+// @Router /admin/credentials/{id} [get]
+func (h AdminApisHandler) GetInternalCredential(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resData, err := h.app.Services.GetInternalCredential(r.Context(), claims.OrgID, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetInternalCredential(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetInternalCredential(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// createInternalCredentialBody wrapper
+type createInternalCredentialBody struct {
+	BuildingBlock string `json:"building_block" validate:"required"`
+	Description   string `json:"description"`
+} //@name createInternalCredentialBody
+
+// CreateInternalCredential Create a new internal credential for a building block. The key is returned only once.
+// @Description Create a new internal credential for a building block. The key is returned only once.
+// @Tags Admin
+// @ID AdminCreateInternalCredential
+// @Param data body createInternalCredentialBody true "body json"
+// @Accept json
+// @Success 200 {object} model.InternalCredential
+// @Security AdminUserAuth
+// @Router /admin/credentials [post]
+func (h AdminApisHandler) CreateInternalCredential(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+
+	var body createInternalCredentialBody
+	err := decodeJSONBody(r, &body)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateInternalCredential: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	item := model.InternalCredential{BuildingBlock: body.BuildingBlock, Description: body.Description}
+
+	createdItem, err := h.app.Services.CreateInternalCredential(r.Context(), claims.OrgID, item)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateInternalCredential: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	jsonData, err := json.Marshal(createdItem)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.CreateInternalCredential: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// RotateInternalCredential Replaces the key of an internal credential. The new key is returned only once.
+// @Description Replaces the key of an internal credential. The new key is returned only once.
+// @Tags Admin
+// @ID AdminRotateInternalCredential
+// @Success 200 {object} model.InternalCredential
+// @Security AdminUserAuth
+// @Router /admin/credentials/{id}/rotate [post]
+func (h AdminApisHandler) RotateInternalCredential(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	resData, err := h.app.Services.RotateInternalCredential(r.Context(), claims.OrgID, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.RotateInternalCredential(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.RotateInternalCredential(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// RevokeInternalCredential Revokes an internal credential
+// @Description Revokes an internal credential
+// @Tags Admin
+// @ID AdminRevokeInternalCredential
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/credentials/{id}/revoke [post]
+func (h AdminApisHandler) RevokeInternalCredential(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	err := h.app.Services.RevokeInternalCredential(r.Context(), claims.OrgID, id)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.RevokeInternalCredential(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetAuditLogEntries Retrieves the audit log of the admin and internal mutations
+// @Description Retrieves the audit log of the admin and internal mutations, most recent first
+// @Param resource query string false "resource - filter by resource, e.g. types, inventories, claims"
+// @Param resource_id query string false "resource_id - filter by resource id"
+// @Param actor query string false "actor - filter by admin account id or building block"
+// @Param start_date query string false "start_date - RFC3339 lower bound of the date created"
+// @Param end_date query string false "end_date - RFC3339 upper bound of the date created"
+// @Param limit query string false "limit - limit the result"
+// @Param offset query string false "offset"
+// @Tags Admin
+// @ID AdminGetAuditLogEntries
+// @Success 200 {array} model.AuditLogEntry
+// @Security AdminUserAuth
+// @Router /admin/audit [get]
+func (h AdminApisHandler) GetAuditLogEntries(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resource := getStringQueryParam(r, "resource")
+	resourceID := getStringQueryParam(r, "resource_id")
+	actor := getStringQueryParam(r, "actor")
+	limit := getInt64QueryParam(r, "limit")
+	offset := getInt64QueryParam(r, "offset")
+
+	startDate, err := getTimeQueryParam(r, "start_date")
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetAuditLogEntries: invalid start_date - %s", err)
+		HandleError(w, model.NewValidationError("invalid start_date, expected RFC3339"))
+		return
+	}
+	endDate, err := getTimeQueryParam(r, "end_date")
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetAuditLogEntries: invalid end_date - %s", err)
+		HandleError(w, model.NewValidationError("invalid end_date, expected RFC3339"))
+		return
+	}
+
+	resData, err := h.app.Services.GetAuditLogEntries(r.Context(), claims.OrgID, resource, resourceID, actor, startDate, endDate, limit, offset)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetAuditLogEntries: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetAuditLogEntries: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetLeaderboard Retrieves the leaderboard of a reward type with all users
+// @Description Retrieves the leaderboard of a reward type with all users, including the ones who did not opt in
+// @Param window query string false "window - all_time (default), day, week or month"
+// @Param building_block query string false "building_block - rank only the rewards of the building block"
+// @Param limit query string false "limit - the number of entries, 10 by default and 100 at most"
+// @Tags Admin
+// @ID AdminGetLeaderboard
+// @Success 200 {object} model.Leaderboard
+// @Security AdminUserAuth
+// @Router /admin/leaderboards/{reward_type} [get]
+func (h AdminApisHandler) GetLeaderboard(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	rewardType := vars["reward_type"]
+	window, buildingBlock, limit := getLeaderboardQueryParams(r)
+
+	resData, err := h.app.Services.GetLeaderboard(r.Context(), claims.OrgID, rewardType, buildingBlock, window, true, limit)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetLeaderboard(%s): %s", rewardType, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetLeaderboard(%s): %s", rewardType, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// rebuildLeaderboardsResponse wrapper
+type rebuildLeaderboardsResponse struct {
+	Scores int `json:"scores"`
+} //@name rebuildLeaderboardsResponse
+
+// RebuildLeaderboards Recomputes the leaderboards from the reward history
+// @Description Recomputes the leaderboard scores of the org from the reward history
+// @Tags Admin
+// @ID AdminRebuildLeaderboards
+// @Success 200 {object} rebuildLeaderboardsResponse
+// @Security AdminUserAuth
+// @Router /admin/leaderboards/rebuild [post]
+func (h AdminApisHandler) RebuildLeaderboards(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	scores, err := h.app.Services.RebuildLeaderboards(r.Context(), claims.OrgID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.RebuildLeaderboards: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(rebuildLeaderboardsResponse{Scores: scores})
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.RebuildLeaderboards: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetGrantsAnalytics Retrieves the granted rewards over time
+// @Description Retrieves the number and the amount of the granted rewards per time bucket, operation code and building block
+// @Param bucket query string false "bucket - hour, day (default) or week"
+// @Param timezone query string false "timezone - UTC or the IANA Area/Location name of the timezone of the buckets, UTC by default"
+// @Param start_date query string false "start_date - RFC3339 inclusive start of the range, 30 days before the end by default"
+// @Param end_date query string false "end_date - RFC3339 exclusive end of the range, now by default"
+// @Param reward_type query string false "reward_type - filter by reward type"
+// @Tags Admin
+// @ID AdminGetGrantsAnalytics
+// @Success 200 {array} model.GrantsBucket
+// @Security AdminUserAuth
+// @Router /admin/analytics/grants [get]
+func (h AdminApisHandler) GetGrantsAnalytics(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	query, err := getAnalyticsQuery(r)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetGrantsAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	resData, err := h.app.Services.GetGrantsAnalytics(r.Context(), claims.OrgID, *query)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetGrantsAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetGrantsAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetEarnersAnalytics Retrieves the unique earners over time
+// @Description Retrieves the number of distinct users who got rewards per time bucket
+// @Param bucket query string false "bucket - hour, day (default) or week"
+// @Param timezone query string false "timezone - UTC or the IANA Area/Location name of the timezone of the buckets, UTC by default"
+// @Param start_date query string false "start_date - RFC3339 inclusive start of the range, 30 days before the end by default"
+// @Param end_date query string false "end_date - RFC3339 exclusive end of the range, now by default"
+// @Param reward_type query string false "reward_type - filter by reward type"
+// @Tags Admin
+// @ID AdminGetEarnersAnalytics
+// @Success 200 {array} model.EarnersBucket
+// @Security AdminUserAuth
+// @Router /admin/analytics/earners [get]
+func (h AdminApisHandler) GetEarnersAnalytics(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	query, err := getAnalyticsQuery(r)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetEarnersAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	resData, err := h.app.Services.GetEarnersAnalytics(r.Context(), claims.OrgID, *query)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetEarnersAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetEarnersAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetClaimsAnalytics Retrieves the claims over time
+// @Description Retrieves the number and the amount of the claims per time bucket and status
+// @Param bucket query string false "bucket - hour, day (default) or week"
+// @Param timezone query string false "timezone - UTC or the IANA Area/Location name of the timezone of the buckets, UTC by default"
+// @Param start_date query string false "start_date - RFC3339 inclusive start of the range, 30 days before the end by default"
+// @Param end_date query string false "end_date - RFC3339 exclusive end of the range, now by default"
+// @Param reward_type query string false "reward_type - filter by reward type"
+// @Tags Admin
+// @ID AdminGetClaimsAnalytics
+// @Success 200 {array} model.ClaimsBucket
+// @Security AdminUserAuth
+// @Router /admin/analytics/claims [get]
+func (h AdminApisHandler) GetClaimsAnalytics(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	query, err := getAnalyticsQuery(r)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetClaimsAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	resData, err := h.app.Services.GetClaimsAnalytics(r.Context(), claims.OrgID, *query)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetClaimsAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetClaimsAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetRedemptionAnalytics Retrieves the redemption rate per reward type
+// @Description Retrieves the granted and the claimed amount, the unique earners and claimers and the redemption rate per reward type within the date range. The bucket is not used
+// @Param bucket query string false "bucket - hour, day (default) or week"
+// @Param timezone query string false "timezone - UTC or the IANA Area/Location name of the timezone of the buckets, UTC by default"
+// @Param start_date query string false "start_date - RFC3339 inclusive start of the range, 30 days before the end by default"
+// @Param end_date query string false "end_date - RFC3339 exclusive end of the range, now by default"
+// @Param reward_type query string false "reward_type - filter by reward type"
+// @Tags Admin
+// @ID AdminGetRedemptionAnalytics
+// @Success 200 {array} model.RedemptionSummary
+// @Security AdminUserAuth
+// @Router /admin/analytics/redemption [get]
+func (h AdminApisHandler) GetRedemptionAnalytics(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	query, err := getAnalyticsQuery(r)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRedemptionAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	resData, err := h.app.Services.GetRedemptionAnalytics(r.Context(), claims.OrgID, *query)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRedemptionAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.GetRedemptionAnalytics: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ExportRewardHistory Exports the reward history
+// @Description Streams the reward history as CSV or NDJSON, oldest first
+// @Param format query string false "format - csv (default) or ndjson"
+// @Param user_id query string false "user_id - filter by user"
+// @Param reward_type query string false "reward_type - filter by reward type"
+// @Param code query string false "code - filter by operation code"
+// @Param building_block query string false "building_block - filter by building block"
+// @Param start_date query string false "start_date - RFC3339 inclusive start of the range"
+// @Param end_date query string false "end_date - RFC3339 exclusive end of the range"
+// @Param limit query string false "limit - limit the result"
+// @Param offset query string false "offset"
+// @Tags Admin
+// @ID AdminExportRewardHistory
+// @Produce text/csv,application/x-ndjson
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/export/history [get]
+func (h AdminApisHandler) ExportRewardHistory(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	userID := getStringQueryParam(r, "user_id")
+	rewardType := getStringQueryParam(r, "reward_type")
+	code := getStringQueryParam(r, "code")
+	buildingBlock := getStringQueryParam(r, "building_block")
+	limitFilter := getInt64QueryParam(r, "limit")
+	offsetFilter := getInt64QueryParam(r, "offset")
+
+	startDate, err := getTimeQueryParam(r, "start_date")
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ExportRewardHistory: %s", err)
+		HandleError(w, err)
+		return
+	}
+	endDate, err := getTimeQueryParam(r, "end_date")
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ExportRewardHistory: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	writer, err := newExportWriter(w, r, "history", rewardHistoryCSVHeader)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ExportRewardHistory: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	err = h.app.Services.ExportRewardHistory(r.Context(), claims.OrgID, userID, rewardType, code, buildingBlock, startDate, endDate, limitFilter, offsetFilter, func(item model.Reward) error {
+		return writer.write(item, rewardHistoryCSVRow(item))
+	})
+	writer.finish("adminapis.ExportRewardHistory", err)
+}
+
+// ExportRewardClaims Exports the reward claims
+// @Description Streams the reward claims as CSV or NDJSON, oldest first
+// @Param format query string false "format - csv (default) or ndjson"
+// @Param ids query string false "Coma separated IDs of the desired records"
+// @Param user_id query string false "user_id - filter by user"
+// @Param reward_type query string false "reward_type - filter by reward type"
+// @Param status query string false "status - filter by status"
+// @Param limit query string false "limit - limit the result"
+// @Param offset query string false "offset"
+// @Tags Admin
+// @ID AdminExportRewardClaims
+// @Produce text/csv,application/x-ndjson
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/export/claims [get]
+func (h AdminApisHandler) ExportRewardClaims(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	rewardType := getStringQueryParam(r, "reward_type")
+	userID := getStringQueryParam(r, "user_id")
+	status := getStringQueryParam(r, "status")
+	limitFilter := getInt64QueryParam(r, "limit")
+	offsetFilter := getInt64QueryParam(r, "offset")
+
+	IDs := []string{}
+	IDskeys, ok := r.URL.Query()["ids"]
+	if ok && len(IDskeys[0]) > 0 {
+		IDs = strings.Split(IDskeys[0], ",")
+	}
+
+	writer, err := newExportWriter(w, r, "claims", rewardClaimCSVHeader)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ExportRewardClaims: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	err = h.app.Services.ExportRewardClaims(r.Context(), claims.OrgID, IDs, userID, rewardType, status, limitFilter, offsetFilter, func(item model.RewardClaim) error {
+		return writer.write(item, rewardClaimCSVRow(item))
+	})
+	writer.finish("adminapis.ExportRewardClaims", err)
+}
+
+// ExportRewardInventories Exports the reward inventories
+// @Description Streams the reward inventories as CSV or NDJSON, oldest first
+// @Param format query string false "format - csv (default) or ndjson"
+// @Param ids query string false "Coma separated IDs of the desired records"
+// @Param reward_type query string false "reward_type - filter by reward type"
+// @Param in_stock query string false "in_stock - possible values: missing (e.g no filter), 0- false, 1- true"
+// @Param grant_depleted query string false "grant_depleted - possible values: missing (e.g no filter), 0- false, 1- true"
+// @Param claim_depleted query string false "claim_depleted - possible values: missing (e.g no filter), 0- false, 1- true"
+// @Param archived query string false "archived - possible values: missing or 0 - not archived, 1 - archived"
+// @Param limit query string false "limit - limit the result"
+// @Param offset query string false "offset"
+// @Tags Admin
+// @ID AdminExportRewardInventories
+// @Produce text/csv,application/x-ndjson
+// @Success 200
+// @Security AdminUserAuth
+// @Router /admin/export/inventories [get]
+func (h AdminApisHandler) ExportRewardInventories(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	rewardType := getStringQueryParam(r, "reward_type")
+	inStock := getBoolQueryParam(r, "in_stock", nil)
+	grantDepleted := getBoolQueryParam(r, "grant_depleted", nil)
+	claimDepleted := getBoolQueryParam(r, "claim_depleted", nil)
+	notArchived := false
+	archived := getBoolQueryParam(r, "archived", &notArchived)
+	limitFilter := getInt64QueryParam(r, "limit")
+	offsetFilter := getInt64QueryParam(r, "offset")
+
+	IDs := []string{}
+	IDskeys, ok := r.URL.Query()["ids"]
+	if ok && len(IDskeys[0]) > 0 {
+		IDs = strings.Split(IDskeys[0], ",")
+	}
+
+	writer, err := newExportWriter(w, r, "inventories", rewardInventoryCSVHeader)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ExportRewardInventories: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	err = h.app.Services.ExportRewardInventories(r.Context(), claims.OrgID, IDs, rewardType, inStock, grantDepleted, claimDepleted, archived, limitFilter, offsetFilter, func(item model.RewardInventory) error {
+		return writer.write(item, rewardInventoryCSVRow(item))
+	})
+	writer.finish("adminapis.ExportRewardInventories", err)
+}
+
+// ImportRewards Imports reward types, operations or inventories
+// @Description Validates all the rows of a json array or a csv with a header row and applies them in a single transaction.
+// @Description The reward types are matched by name, the operations by code and building block and the inventories by id.
+// @Description Nothing is applied when a row is invalid or for a dry run
+// @Param kind path string true "kind - types, operations or inventories"
+// @Param dry_run query string false "dry_run - 1 validates the rows without applying them"
+// @Tags Admin
+// @ID AdminImportRewards
+// @Accept json,text/csv
+// @Produce json
+// @Success 200 {object} model.ImportReport
+// @Failure 400 {object} model.ImportReport
+// @Security AdminUserAuth
+// @Router /admin/import/{kind} [post]
+func (h AdminApisHandler) ImportRewards(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	kind := mux.Vars(r)["kind"]
+	notDryRun := false
+	dryRun := getBoolQueryParam(r, "dry_run", &notDryRun)
+
+	var resData *model.ImportReport
+	var err error
+	switch kind {
+	case model.ImportKindTypes:
+		var rows []importRewardTypeRow
+		err = decodeImportRows(r, &rows)
+		if err == nil {
+			items := make([]model.RewardType, len(rows))
+			for i, row := range rows {
+				items[i] = row.toModel()
+			}
+			resData, err = h.app.Services.ImportRewardTypes(r.Context(), claims.OrgID, items, *dryRun)
+		}
+	case model.ImportKindOperations:
+		var rows []importRewardOperationRow
+		err = decodeImportRows(r, &rows)
+		if err == nil {
+			items := make([]model.RewardOperation, len(rows))
+			for i, row := range rows {
+				items[i] = row.toModel()
+			}
+			resData, err = h.app.Services.ImportRewardOperations(r.Context(), claims.OrgID, items, *dryRun)
+		}
+	case model.ImportKindInventories:
+		var rows []importRewardInventoryRow
+		err = decodeImportRows(r, &rows)
+		if err == nil {
+			items := make([]model.RewardInventory, len(rows))
+			for i, row := range rows {
+				items[i] = row.toModel()
+			}
+			resData, err = h.app.Services.ImportRewardInventories(r.Context(), claims.OrgID, items, *dryRun)
+		}
+	default:
+		err = model.NewValidationError("unknown import kind %s, expected %s, %s or %s", kind, model.ImportKindTypes, model.ImportKindOperations, model.ImportKindInventories)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ImportRewards(%s): %s", kind, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ImportRewards(%s): %s", kind, err)
+		HandleError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !resData.Valid {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// ExportOrgConfig Exports the configuration of the org
+// @Description Exports the reward types with their allocation policies, operations and inventory definitions without the counters
+// @Description as a versioned document which can be imported in another environment or org. The archived entities and the
+// @Description authorization policies, which are shared by all orgs, are not exported
+// @Tags Admin
+// @ID AdminExportOrgConfig
+// @Produce json
+// @Success 200 {object} model.OrgConfig
+// @Security AdminUserAuth
+// @Router /admin/config/export [get]
+func (h AdminApisHandler) ExportOrgConfig(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resData, err := h.app.Services.ExportOrgConfig(r.Context(), claims.OrgID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ExportOrgConfig: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ExportOrgConfig: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	fileName := fmt.Sprintf("config-%s-%s.json", claims.OrgID, resData.DateExported.Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// ImportOrgConfig Imports an org configuration
+// @Description Diffs an exported org configuration against the org and applies it in a single transaction. The entities are
+// @Description matched by their natural keys, the matched ones are updated and the others are created with new ids. Nothing is
+// @Description applied when a change is invalid or for a dry run
+// @Param data body model.OrgConfig true "body json"
+// @Param dry_run query string false "dry_run - 1 previews the changes without applying them"
+// @Tags Admin
+// @ID AdminImportOrgConfig
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.OrgConfigImportReport
+// @Failure 400 {object} model.OrgConfigImportReport
+// @Security AdminUserAuth
+// @Router /admin/config/import [post]
+func (h AdminApisHandler) ImportOrgConfig(claims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	notSet := false
+	dryRun := getBoolQueryParam(r, "dry_run", &notSet)
+
+	var requestData model.OrgConfig
+	err := decodeJSONBody(r, &requestData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ImportOrgConfig: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	resData, err := h.app.Services.ImportOrgConfig(r.Context(), claims.OrgID, requestData, *dryRun)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ImportOrgConfig: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on adminapis.ImportOrgConfig: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	status := http.StatusOK
+	if !resData.Valid {
+		status = http.StatusBadRequest
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -16,17 +16,20 @@ package rest
 
 import (
 	"encoding/json"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"rewards/core"
 	"rewards/core/model"
+	"rewards/utils/logging"
 
+	"github.com/gorilla/mux"
 	"github.com/rokwire/core-auth-library-go/tokenauth"
+	"github.com/skip2/go-qrcode"
 )
 
 const maxUploadSize = 15 * 1024 * 1024 // 15 mb
 
+const qrCodeSize = 256 // px
+
 // ApisHandler handles the rest APIs implementation
 type ApisHandler struct {
 	app *core.Application
@@ -43,6 +46,48 @@ func (h ApisHandler) Version(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(h.app.Services.GetVersion()))
 }
 
+// GetLive tells the service is running. It does not check the dependencies so a storage outage does not restart the service
+// @Description Tells the service is running. The dependencies are not checked.
+// @Tags Client
+// @ID HealthLive
+// @Produce json
+// @Success 200 {object} model.HealthReport
+// @Router /health/live [get]
+func (h ApisHandler) GetLive(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, r, model.NewHealthReport())
+}
+
+// GetReady tells the service can serve requests - the storage is reachable and the change streams are open
+// @Description Tells the service can serve requests. Checks the storage connectivity and the change streams.
+// @Tags Client
+// @ID HealthReady
+// @Produce json
+// @Success 200 {object} model.HealthReport
+// @Failure 503 {object} model.HealthReport
+// @Router /health/ready [get]
+func (h ApisHandler) GetReady(w http.ResponseWriter, r *http.Request) {
+	writeHealthReport(w, r, h.app.Services.CheckReadiness(r.Context()))
+}
+
+func writeHealthReport(w http.ResponseWriter, r *http.Request, report *model.HealthReport) {
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.writeHealthReport: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
 // NewApisHandler creates new rest Handler instance
 func NewApisHandler(app *core.Application) ApisHandler {
 	return ApisHandler{app: app}
@@ -66,10 +111,10 @@ func NewInternalApisHandler(app *core.Application) InternalApisHandler {
 // @Security UserAuth
 // @Router /user/balance [get]
 func (h *ApisHandler) GetUserBalance(userClaims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
-	resData, err := h.app.Services.GetUserBalance(userClaims.OrgID, userClaims.Subject)
+	resData, err := h.app.Services.GetUserBalance(r.Context(), userClaims.OrgID, userClaims.Subject)
 	if err != nil {
-		log.Printf("Error on apis.GetUserRewardsAmount(%s): %s", userClaims.Subject, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetUserRewardsAmount(%s): %s", userClaims.Subject, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -79,8 +124,8 @@ func (h *ApisHandler) GetUserBalance(userClaims *tokenauth.Claims, w http.Respon
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on apis.GetUserRewardsAmount(%s): %s", userClaims.Subject, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetUserRewardsAmount(%s): %s", userClaims.Subject, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -108,17 +153,17 @@ func (h *ApisHandler) GetUserRewardsHistory(userClaims *tokenauth.Claims, w http
 	limitFilter := getInt64QueryParam(r, "limit")
 	offsetFilter := getInt64QueryParam(r, "offset")
 
-	resData, err := h.app.Services.GetUserRewardsHistory(userClaims.OrgID, userClaims.Subject, rewardType, code, buildingBlock, limitFilter, offsetFilter)
+	resData, err := h.app.Services.GetUserRewardsHistory(r.Context(), userClaims.OrgID, userClaims.Subject, rewardType, code, buildingBlock, limitFilter, offsetFilter)
 	if err != nil {
-		log.Printf("Error on apis.getUserRewardsHistory(%s): %s", userClaims.Subject, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on apis.getUserRewardsHistory(%s): %s", userClaims.Subject, err)
+		HandleError(w, err)
 		return
 	}
 
 	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on apis.getUserRewardsHistory(%s): %s", userClaims.Subject, err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on apis.getUserRewardsHistory(%s): %s", userClaims.Subject, err)
+		HandleError(w, err)
 		return
 	}
 
@@ -144,17 +189,17 @@ func (h ApisHandler) GetUserRewardClaim(userClaims *tokenauth.Claims, w http.Res
 	limitFilter := getInt64QueryParam(r, "limit")
 	offsetFilter := getInt64QueryParam(r, "offset")
 
-	rewardClaims, err := h.app.Services.GetRewardClaims(userClaims.OrgID, nil, &userClaims.Subject, rewardType, status, limitFilter, offsetFilter)
+	rewardClaims, err := h.app.Services.GetRewardClaims(r.Context(), userClaims.OrgID, nil, &userClaims.Subject, rewardType, status, limitFilter, offsetFilter)
 	if err != nil {
-		log.Printf("Error on apis.GetUserRewardClaim: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetUserRewardClaim: %s", err)
+		HandleError(w, err)
 		return
 	}
 
 	jsonData, err := json.Marshal(rewardClaims)
 	if err != nil {
-		log.Printf("Error on apis.GetUserRewardClaim: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetUserRewardClaim: %s", err)
+		HandleError(w, err)
 		return
 	}
 
@@ -163,47 +208,280 @@ func (h ApisHandler) GetUserRewardClaim(userClaims *tokenauth.Claims, w http.Res
 	w.Write(jsonData)
 }
 
+// rewardClaimItemBody wrapper
+type rewardClaimItemBody struct {
+	RewardType  string `json:"reward_type" validate:"required"`
+	InventoryID string `json:"inventory_id"`
+	Amount      int    `json:"amount" validate:"gt=0"`
+} //@name rewardClaimItemBody
+
+// rewardClaimPurchaseBody wrapper
+type rewardClaimPurchaseBody struct {
+	CatalogItemID string `json:"catalog_item_id" validate:"required"`
+	Quantity      int    `json:"quantity" validate:"gt=0"`
+} //@name rewardClaimPurchaseBody
+
+// rewardClaimPickupBody wrapper
+type rewardClaimPickupBody struct {
+	LocationID string `json:"location_id" validate:"required"`
+	SlotID     string `json:"slot_id"`
+} //@name rewardClaimPickupBody
+
+// createUserRewardClaimBody wrapper
+type createUserRewardClaimBody struct {
+	Items       []rewardClaimItemBody    `json:"items" validate:"required_without=Purchase,unique=RewardType,dive"`
+	Purchase    *rewardClaimPurchaseBody `json:"purchase"`
+	Pickup      *rewardClaimPickupBody   `json:"pickup"`
+	Description string                   `json:"description"`
+} //@name createUserRewardClaimBody
+
+// toRewardClaim creates a new claim of the user. The status is always set by the service
+func (b createUserRewardClaimBody) toRewardClaim(userID string) model.RewardClaim {
+	item := model.RewardClaim{UserID: userID, Description: b.Description}
+	for _, claimItem := range b.Items {
+		item.Items = append(item.Items, model.RewardClaimItem{RewardType: claimItem.RewardType,
+			InventoryID: claimItem.InventoryID, Amount: claimItem.Amount})
+	}
+	if b.Purchase != nil {
+		item.Purchase = &model.RewardClaimPurchase{CatalogItemID: b.Purchase.CatalogItemID, Quantity: b.Purchase.Quantity}
+	}
+	if b.Pickup != nil {
+		item.Pickup = &model.RewardClaimPickup{LocationID: b.Pickup.LocationID, SlotID: b.Pickup.SlotID}
+	}
+	return item
+}
+
 // CreateUserRewardClaim Create a new user claim
 // @Description Create a new claim user claim
 // @Tags Client
 // @ID CreateUserRewardClaim
+// @Param data body createUserRewardClaimBody true "body json"
 // @Accept json
 // @Success 200 {object} model.RewardClaim
 // @Security AdminUserAuth
 // @Router /user/claim [post]
 func (h ApisHandler) CreateUserRewardClaim(userClaims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
 
-	data, err := ioutil.ReadAll(r.Body)
+	var body createUserRewardClaimBody
+	err := decodeJSONBody(r, &body)
 	if err != nil {
-		log.Printf("Error on apis.CreateUserRewardClaim: %s", err)
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on apis.CreateUserRewardClaim: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	var item model.RewardClaim
-	err = json.Unmarshal(data, &item)
+	item := body.toRewardClaim(userClaims.Subject)
+	createdItem, err := h.app.Services.CreateRewardClaim(r.Context(), userClaims.OrgID, item)
 	if err != nil {
-		log.Printf("Error on apis.CreateUserRewardClaim: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on apis.CreateUserRewardClaim: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	item.UserID = userClaims.Subject
-	createdItem, err := h.app.Services.CreateRewardClaim(userClaims.OrgID, item)
+	jsonData, err := json.Marshal(createdItem)
 	if err != nil {
-		log.Printf("Error on apis.CreateUserRewardClaim: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on apis.CreateUserRewardClaim: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	jsonData, err := json.Marshal(createdItem)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(jsonData)
+}
+
+// GetRewardCatalog Retrieves the catalog items which are currently available for redemption
+// @Description Retrieves the catalog items which are currently available for redemption
+// @Tags Client
+// @ID GetRewardCatalog
+// @Success 200 {array} model.RewardCatalogItem
+// @Security UserAuth
+// @Router /user/catalog [get]
+func (h ApisHandler) GetRewardCatalog(userClaims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resData, err := h.app.Services.GetAvailableRewardCatalogItems(r.Context(), userClaims.OrgID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetRewardCatalog: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
 	if err != nil {
-		log.Printf("Error on apis.CreateUserRewardClaim: %s", err)
-		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetRewardCatalog: %s", err)
+		HandleError(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
-	w.Write(jsonData)
+	w.Write(data)
+}
+
+// GetPickupLocations Retrieves the active pickup locations with their time slots
+// @Description Retrieves the active pickup locations with their time slots
+// @Tags Client
+// @ID GetPickupLocations
+// @Success 200 {array} model.PickupLocation
+// @Security UserAuth
+// @Router /user/locations [get]
+func (h ApisHandler) GetPickupLocations(userClaims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resData, err := h.app.Services.GetActivePickupLocations(r.Context(), userClaims.OrgID)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetPickupLocations: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetPickupLocations: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetUserRewardClaimQRCode Gets the pickup code of an approved user claim as a QR code
+// @Description Gets the pickup code of an approved user claim as a QR code
+// @Tags Client
+// @ID GetUserRewardClaimQRCode
+// @Produce png
+// @Success 200
+// @Security UserAuth
+// @Router /user/claims/{id}/qr [get]
+func (h ApisHandler) GetUserRewardClaimQRCode(userClaims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id := vars["id"]
+
+	claim, err := h.app.Services.GetRewardClaim(r.Context(), userClaims.OrgID, id)
+	if err != nil || claim.UserID != userClaims.Subject {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetUserRewardClaimQRCode(%s): %s", id, err)
+		HandleError(w, model.NewNotFoundError("unable to find reward claim with id: %s", id))
+		return
+	}
+
+	if claim.Status != model.RewardClaimStatusApproved || claim.PickupCode == "" {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetUserRewardClaimQRCode(%s): the claim is %s", id, claim.Status)
+		HandleError(w, model.NewConflictError("the claim is not approved for pickup"))
+		return
+	}
+
+	png, err := qrcode.Encode(claim.PickupCode, qrcode.Medium, qrCodeSize)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetUserRewardClaimQRCode(%s): %s", id, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.WriteHeader(http.StatusOK)
+	w.Write(png)
+}
+
+// GetLeaderboard Retrieves the leaderboard of a reward type with the users who opted in
+// @Description Retrieves the leaderboard of a reward type. Only the users who opted in are shown by their display handle
+// @Param window query string false "window - all_time (default), day, week or month"
+// @Param building_block query string false "building_block - rank only the rewards of the building block"
+// @Param limit query string false "limit - the number of entries, 10 by default and 100 at most"
+// @Tags Client
+// @ID GetLeaderboard
+// @Success 200 {object} model.Leaderboard
+// @Security UserAuth
+// @Router /user/leaderboards/{reward_type} [get]
+func (h ApisHandler) GetLeaderboard(userClaims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	rewardType := vars["reward_type"]
+	window, buildingBlock, limit := getLeaderboardQueryParams(r)
+
+	resData, err := h.app.Services.GetLeaderboard(r.Context(), userClaims.OrgID, rewardType, buildingBlock, window, false, limit)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetLeaderboard(%s): %s", rewardType, err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetLeaderboard(%s): %s", rewardType, err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// GetLeaderboardProfile Retrieves the leaderboard profile of the user
+// @Description Retrieves the leaderboard profile of the user. Users are not shown on the leaderboards until they opt in
+// @Tags Client
+// @ID GetLeaderboardProfile
+// @Success 200 {object} model.LeaderboardProfile
+// @Security UserAuth
+// @Router /user/leaderboard-profile [get]
+func (h ApisHandler) GetLeaderboardProfile(userClaims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	resData, err := h.app.Services.GetLeaderboardProfile(r.Context(), userClaims.OrgID, userClaims.Subject)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetLeaderboardProfile: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.GetLeaderboardProfile: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// updateLeaderboardProfileBody wrapper
+type updateLeaderboardProfileBody struct {
+	OptedIn       bool   `json:"opted_in"`
+	DisplayHandle string `json:"display_handle"`
+} //@name updateLeaderboardProfileBody
+
+// UpdateLeaderboardProfile Opts the user in or out of the leaderboards
+// @Description Opts the user in or out of the leaderboards and sets the display handle shown on them
+// @Tags Client
+// @ID UpdateLeaderboardProfile
+// @Param data body updateLeaderboardProfileBody true "body json"
+// @Accept json
+// @Success 200 {object} model.LeaderboardProfile
+// @Security UserAuth
+// @Router /user/leaderboard-profile [put]
+func (h ApisHandler) UpdateLeaderboardProfile(userClaims *tokenauth.Claims, w http.ResponseWriter, r *http.Request) {
+	var body updateLeaderboardProfileBody
+	err := decodeJSONBody(r, &body)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.UpdateLeaderboardProfile: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	item := model.LeaderboardProfile{OptedIn: body.OptedIn, DisplayHandle: body.DisplayHandle}
+	resData, err := h.app.Services.UpdateLeaderboardProfile(r.Context(), userClaims.OrgID, userClaims.Subject, item)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.UpdateLeaderboardProfile: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	data, err := json.Marshal(resData)
+	if err != nil {
+		logging.FromContext(r.Context()).Errorf("Error on apis.UpdateLeaderboardProfile: %s", err)
+		HandleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
 }
@@ -0,0 +1,154 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// purchaseStorage keeps the catalog items and records the claims. Like the storage it checks the user limit when the claim is created
+type purchaseStorage struct {
+	*orgStorage
+
+	catalog []model.RewardCatalogItem
+}
+
+func (s *purchaseStorage) GetRewardCatalogItem(ctx context.Context, orgID string, id string) (*model.RewardCatalogItem, error) {
+	for _, item := range s.catalog {
+		if item.OrgID == orgID && item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, model.NewNotFoundError("unable to find catalog item with id: %s", id)
+}
+
+func (s *purchaseStorage) CreateRewardClaim(ctx context.Context, orgID string, item model.RewardClaim) (*model.RewardClaim, error) {
+	catalogItem, err := s.GetRewardCatalogItem(ctx, orgID, item.Purchase.CatalogItemID)
+	if err != nil {
+		return nil, err
+	}
+	purchased := 0
+	for _, claim := range s.claims {
+		if claim.Purchase != nil && claim.UserID == item.UserID && claim.Purchase.CatalogItemID == catalogItem.ID {
+			purchased += claim.Purchase.Quantity
+		}
+	}
+	err = catalogItem.CheckUserLimit(purchased, item.Purchase.Quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	item.OrgID = orgID
+	s.claims = append(s.claims, item)
+	return &item, nil
+}
+
+func newPurchaseStorage() *purchaseStorage {
+	storage := &purchaseStorage{orgStorage: newOrgStorage()}
+	storage.types = append(storage.types, model.RewardType{ID: "type-coins", OrgID: orgA, RewardType: "coins", Currency: true})
+
+	tomorrow := time.Now().UTC().Add(24 * time.Hour)
+	storage.catalog = []model.RewardCatalogItem{
+		{ID: "item-a", OrgID: orgA, RewardType: "tshirt", Active: true, UserLimit: 3,
+			Price: []model.RewardCatalogPrice{{RewardType: "coins", Amount: 5}, {RewardType: "points", Amount: 2}}},
+		{ID: "item-later", OrgID: orgA, RewardType: "tshirt", Active: true, AvailableFrom: &tomorrow,
+			Price: []model.RewardCatalogPrice{{RewardType: "coins", Amount: 5}}},
+		{ID: "item-inactive", OrgID: orgA, RewardType: "tshirt", Active: false,
+			Price: []model.RewardCatalogPrice{{RewardType: "coins", Amount: 5}}},
+	}
+	return storage
+}
+
+func purchase(handler ApisHandler, catalogItemID string, quantity int) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]interface{}{"purchase": map[string]interface{}{"catalog_item_id": catalogItemID, "quantity": quantity}})
+	w := httptest.NewRecorder()
+	handler.CreateUserRewardClaim(orgClaims(orgA), w, httptest.NewRequest(http.MethodPost, "/user/claims", strings.NewReader(string(body))))
+	return w
+}
+
+func TestCatalogPurchaseDebitsThePrice(t *testing.T) {
+	storage := newPurchaseStorage()
+	handler := NewApisHandler(newTestApplication(storage))
+
+	w := purchase(handler, "item-a", 2)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d - %s", w.Code, w.Body.String())
+	}
+
+	claim := storage.claims[len(storage.claims)-1]
+	if claim.Status != model.RewardClaimStatusPending || claim.Purchase.RewardType != "tshirt" {
+		t.Errorf("expected a pending purchase of tshirt, got %s %v", claim.Status, claim.Purchase)
+	}
+	amounts := claim.GetAmounts()
+	if len(amounts) != 2 || amounts["coins"] != 10 || amounts["points"] != 4 {
+		t.Errorf("expected the price times the quantity to be debited, got %v", amounts)
+	}
+}
+
+func TestCatalogPurchaseAvailability(t *testing.T) {
+	storage := newPurchaseStorage()
+	handler := NewApisHandler(newTestApplication(storage))
+
+	for _, id := range []string{"item-later", "item-inactive"} {
+		w := purchase(handler, id, 1)
+		if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "not available") {
+			t.Errorf("%s: expected the item not to be available, got %d %s", id, w.Code, w.Body.String())
+		}
+	}
+	if w := purchase(handler, "item-unknown", 1); w.Code != http.StatusNotFound {
+		t.Errorf("expected an unknown item to be not found, got %d", w.Code)
+	}
+	if len(storage.claims) != 1 {
+		t.Errorf("expected no claims to be created, got %d", len(storage.claims)-1)
+	}
+}
+
+func TestCatalogPurchaseUserLimit(t *testing.T) {
+	storage := newPurchaseStorage()
+	handler := NewApisHandler(newTestApplication(storage))
+
+	if w := purchase(handler, "item-a", 2); w.Code != http.StatusOK {
+		t.Fatalf("expected the first purchase to pass, got %d - %s", w.Code, w.Body.String())
+	}
+	if w := purchase(handler, "item-a", 2); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "limit") {
+		t.Errorf("expected the purchase over the limit to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+	if w := purchase(handler, "item-a", 1); w.Code != http.StatusOK {
+		t.Errorf("expected the purchase up to the limit to pass, got %d - %s", w.Code, w.Body.String())
+	}
+}
+
+func TestCreateRewardCatalogItemRepeatedPrice(t *testing.T) {
+	storage := newOrgStorage()
+	storage.types = append(storage.types, model.RewardType{ID: "type-coins", OrgID: orgA, RewardType: "coins", Currency: true})
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	// each price entry would be checked against the whole balance
+	body := `{"reward_type": "tshirt", "display_name": "T-shirt", "active": true,
+		"price": [{"reward_type": "coins", "amount": 5}, {"reward_type": "coins", "amount": 5}]}`
+	w := httptest.NewRecorder()
+	handler.CreateRewardCatalogItem(orgClaims(orgA), w, httptest.NewRequest(http.MethodPost, "/admin/catalog", strings.NewReader(body)))
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "repeated") {
+		t.Errorf("expected the repeated price to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -16,7 +16,9 @@ package rest
 
 import (
 	"net/http"
+	"rewards/core/model"
 	"strconv"
+	"time"
 )
 
 func getStringQueryParam(r *http.Request, paramName string) *string {
@@ -62,3 +64,60 @@ func getBoolQueryParam(r *http.Request, paramName string, defaultValue *bool) *b
 	}
 	return defaultValue
 }
+
+func getTimeQueryParam(r *http.Request, paramName string) (*time.Time, error) {
+	params, ok := r.URL.Query()[paramName]
+	if ok && len(params[0]) > 0 {
+		val, err := time.Parse(time.RFC3339, params[0])
+		if err != nil {
+			return nil, err
+		}
+		return &val, nil
+	}
+	return nil, nil
+}
+
+const (
+	defaultLeaderboardLimit = 10
+	maxLeaderboardLimit     = 100
+)
+
+// getLeaderboardQueryParams gives the window, the building block and the limit of a leaderboard request
+func getLeaderboardQueryParams(r *http.Request) (string, string, int64) {
+	window := model.LeaderboardWindowAllTime
+	if value := getStringQueryParam(r, "window"); value != nil {
+		window = *value
+	}
+	buildingBlock := model.LeaderboardAllBuildingBlocks
+	if value := getStringQueryParam(r, "building_block"); value != nil {
+		buildingBlock = *value
+	}
+	limit := getIntQueryParam(r, "limit", defaultLeaderboardLimit)
+	if limit > maxLeaderboardLimit {
+		limit = maxLeaderboardLimit
+	}
+	return window, buildingBlock, int64(limit)
+}
+
+// getAnalyticsQuery gives the bucket, the timezone, the date range and the reward type of an analytics request
+func getAnalyticsQuery(r *http.Request) (*model.AnalyticsQuery, error) {
+	bucket := ""
+	if value := getStringQueryParam(r, "bucket"); value != nil {
+		bucket = *value
+	}
+	timezone := ""
+	if value := getStringQueryParam(r, "timezone"); value != nil {
+		timezone = *value
+	}
+	startDate, err := getTimeQueryParam(r, "start_date")
+	if err != nil {
+		return nil, model.NewValidationError("invalid start_date, expected RFC3339")
+	}
+	endDate, err := getTimeQueryParam(r, "end_date")
+	if err != nil {
+		return nil, model.NewValidationError("invalid end_date, expected RFC3339")
+	}
+	rewardType := getStringQueryParam(r, "reward_type")
+
+	return model.NewAnalyticsQuery(bucket, timezone, startDate, endDate, rewardType, time.Now().UTC())
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"rewards/utils/logging"
+	"strings"
+	"testing"
+)
+
+// contextStorage records the context the reward types are read with
+type contextStorage struct {
+	*orgStorage
+
+	requestID string
+	err       error
+}
+
+func (s *contextStorage) GetRewardTypes(ctx context.Context, orgID string) ([]model.RewardType, error) {
+	s.requestID = logging.RequestID(ctx)
+	s.err = ctx.Err()
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.orgStorage.GetRewardTypes(ctx, orgID)
+}
+
+func TestRequestContextReachesStorage(t *testing.T) {
+	storage := &contextStorage{orgStorage: newOrgStorage()}
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/types", nil)
+	r.Header.Set(logging.RequestIDHeader, "request-1")
+	r = r.WithContext(logging.NewRequestContext(r.Context(), r))
+	w := httptest.NewRecorder()
+	handler.GetRewardTypes(orgClaims(orgA), w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d - %s", w.Code, w.Body.String())
+	}
+	if storage.requestID != "request-1" {
+		t.Errorf("expected the storage to get the request id, got %q", storage.requestID)
+	}
+
+	// a client which has gone away cancels the storage calls, a new application has nothing cached
+	storage = &contextStorage{orgStorage: newOrgStorage()}
+	handler = NewAdminApisHandler(newTestApplication(storage))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r = httptest.NewRequest(http.MethodGet, "/admin/types", nil).WithContext(ctx)
+	w = httptest.NewRecorder()
+	handler.GetRewardTypes(orgClaims(orgA), w, r)
+
+	if storage.err != context.Canceled {
+		t.Errorf("expected the storage to get the cancelled context, got %v", storage.err)
+	}
+	if w.Code == http.StatusOK {
+		t.Error("expected the cancelled request to fail")
+	}
+}
+
+// pickupStorage records the context the pickup attempts are written with
+type pickupStorage struct {
+	*orgStorage
+
+	attempts   []model.RewardClaimPickupAttempt
+	attemptErr error
+}
+
+func (s *pickupStorage) GetRewardClaimByPickupCode(ctx context.Context, orgID string, code string) (*model.RewardClaim, error) {
+	return nil, ctx.Err()
+}
+
+func (s *pickupStorage) CreateRewardClaimPickupAttempt(ctx context.Context, orgID string, item model.RewardClaimPickupAttempt) (*model.RewardClaimPickupAttempt, error) {
+	s.attemptErr = ctx.Err()
+	s.attempts = append(s.attempts, item)
+	return &item, nil
+}
+
+func TestCancelledPickupAttemptIsRecorded(t *testing.T) {
+	storage := &pickupStorage{orgStorage: newOrgStorage()}
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := httptest.NewRequest(http.MethodPost, "/admin/claims/pickup", strings.NewReader(`{"pickup_code":"ABCDEFGH"}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	handler.RedeemRewardClaimPickupCode(orgClaims(orgA), w, r)
+
+	if w.Code == http.StatusOK {
+		t.Error("expected the cancelled request to fail")
+	}
+	if len(storage.attempts) != 1 {
+		t.Fatalf("expected the attempt to be recorded, got %d attempts", len(storage.attempts))
+	}
+	if storage.attemptErr != nil {
+		t.Errorf("expected the attempt to be written with a live context, got %v", storage.attemptErr)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"rewards/core/model"
+)
+
+// errorStatuses maps the domain error codes to http statuses
+var errorStatuses = map[string]int{
+	model.ErrorCodeNotFound:              http.StatusNotFound,
+	model.ErrorCodeValidation:            http.StatusBadRequest,
+	model.ErrorCodeInsufficientBalance:   http.StatusUnprocessableEntity,
+	model.ErrorCodeInsufficientInventory: http.StatusConflict,
+	model.ErrorCodeConflict:              http.StatusConflict,
+	model.ErrorCodeForbidden:             http.StatusForbidden,
+	model.ErrorCodeUnauthorized:          http.StatusUnauthorized,
+}
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Code    string             `json:"code"`
+	Message string             `json:"message"`
+	Fields  []model.FieldError `json:"fields,omitempty"`
+
+	References []model.Reference `json:"references,omitempty"`
+} // @name ErrorResponse
+
+// HandleError writes err with the status of its code. Errors which are not domain errors
+// are reported as internal errors without their message as it may contain storage details.
+func HandleError(w http.ResponseWriter, err error) {
+	response := ErrorResponse{Code: model.ErrorCodeInternal, Message: http.StatusText(http.StatusInternalServerError)}
+	status := http.StatusInternalServerError
+
+	if domainErr := model.AsError(err); domainErr != nil {
+		if domainStatus, ok := errorStatuses[domainErr.Code]; ok {
+			response = ErrorResponse{Code: domainErr.Code, Message: domainErr.Message, Fields: domainErr.Fields, References: domainErr.References}
+			status = domainStatus
+		}
+	}
+
+	data, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,117 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"strings"
+	"testing"
+)
+
+func TestHandleError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    string
+		message string
+	}{
+		{"not found", model.NewNotFoundError("unable to find reward type: tshirt"), http.StatusNotFound, model.ErrorCodeNotFound, "unable to find reward type: tshirt"},
+		{"validation", model.NewValidationError("missing name"), http.StatusBadRequest, model.ErrorCodeValidation, "missing name"},
+		{"insufficient balance", model.NewInsufficientBalanceError("not enough coins"), http.StatusUnprocessableEntity, model.ErrorCodeInsufficientBalance, "not enough coins"},
+		{"insufficient inventory", model.NewInsufficientInventoryError("not enough tshirt"), http.StatusConflict, model.ErrorCodeInsufficientInventory, "not enough tshirt"},
+		{"conflict", model.NewConflictError("pickup slot is full"), http.StatusConflict, model.ErrorCodeConflict, "pickup slot is full"},
+		{"forbidden", model.NewForbiddenError("not allowed"), http.StatusForbidden, model.ErrorCodeForbidden, "not allowed"},
+		{"wrapped", fmt.Errorf("Error on app.createRewardClaim() - %w", model.NewInsufficientBalanceError("not enough coins")), http.StatusUnprocessableEntity, model.ErrorCodeInsufficientBalance, "not enough coins"},
+		{"internal", errors.New("connection(mongo:27017) incomplete read of message header"), http.StatusInternalServerError, model.ErrorCodeInternal, http.StatusText(http.StatusInternalServerError)},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		HandleError(w, test.err)
+
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d", test.name, test.status, w.Code)
+		}
+		var body ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid error body %s - %s", test.name, w.Body.String(), err)
+		}
+		if body.Code != test.code || body.Message != test.message {
+			t.Errorf("%s: expected %s %q, got %s %q", test.name, test.code, test.message, body.Code, body.Message)
+		}
+	}
+}
+
+func TestCreateRewardInsufficientInventory(t *testing.T) {
+	storage := newOrgStorage()
+	storage.inventories[0].AmountGranted = storage.inventories[0].AmountTotal
+	handler := NewInternalApisHandler(newTestApplication(storage))
+
+	credential := &model.InternalCredential{ID: "credential", OrgID: orgA, BuildingBlock: "events"}
+	r := httptest.NewRequest(http.MethodPost, "/int/reward", strings.NewReader(`{"user_id":"user","code":"attend"}`))
+	w := httptest.NewRecorder()
+	handler.CreateReward(credential, w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d - %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), model.ErrorCodeInsufficientInventory) {
+		t.Errorf("expected the %s code, got %s", model.ErrorCodeInsufficientInventory, w.Body.String())
+	}
+}
+
+func TestCreateRewardArchivedOperation(t *testing.T) {
+	storage := newOrgStorage()
+	storage.operations[0].Archived = true
+	handler := NewInternalApisHandler(newTestApplication(storage))
+
+	credential := &model.InternalCredential{ID: "credential", OrgID: orgA, BuildingBlock: "events"}
+	r := httptest.NewRequest(http.MethodPost, "/int/reward", strings.NewReader(`{"user_id":"user","code":"attend"}`))
+	w := httptest.NewRecorder()
+	handler.CreateReward(credential, w, r)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected status %d, got %d - %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "archived") {
+		t.Errorf("expected the archived operation to be reported, got %s", w.Body.String())
+	}
+	if len(storage.history) != 1 {
+		t.Errorf("expected no reward to be granted, got %d history entries", len(storage.history))
+	}
+}
+
+func TestServicesCreateRewardArchivedOperation(t *testing.T) {
+	storage := newOrgStorage()
+	storage.operations[0].Archived = true
+	app := newTestApplication(storage)
+
+	// the admin apis, the import and rewardsctl grant through the services
+	_, err := app.Services.CreateReward(context.Background(), orgA, model.Reward{UserID: "user", RewardType: "tshirt",
+		Code: "attend", BuildingBlock: "events", Amount: 1})
+	if !model.IsErrorCode(err, model.ErrorCodeConflict) {
+		t.Errorf("expected a conflict error, got %v", err)
+	}
+	if len(storage.history) != 1 {
+		t.Errorf("expected no reward to be granted, got %d history entries", len(storage.history))
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"rewards/core/model"
+	"rewards/utils/logging"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	exportFormatCSV    string = "csv"
+	exportFormatNDJSON string = "ndjson"
+
+	// exportFlushRows is the number of rows written between two flushes to the client
+	exportFlushRows int = 100
+)
+
+// exportWriter streams the exported records to the client. The headers are written on the first record so
+// that an error before anything is sent can still be returned as a regular error response
+type exportWriter struct {
+	w         http.ResponseWriter
+	ctx       context.Context
+	format    string
+	name      string
+	csvHeader []string
+
+	csv     *csv.Writer
+	started bool
+	rows    int
+}
+
+// newExportWriter creates an export writer for the format requested with the "format" query param
+func newExportWriter(w http.ResponseWriter, r *http.Request, name string, csvHeader []string) (*exportWriter, error) {
+	format := exportFormatCSV
+	if value := getStringQueryParam(r, "format"); value != nil {
+		format = *value
+	}
+	if format != exportFormatCSV && format != exportFormatNDJSON {
+		return nil, model.NewValidationError("unknown export format %s, expected %s or %s", format, exportFormatCSV, exportFormatNDJSON)
+	}
+	return &exportWriter{w: w, ctx: r.Context(), format: format, name: name, csvHeader: csvHeader}, nil
+}
+
+func (ew *exportWriter) start() error {
+	if ew.started {
+		return nil
+	}
+	ew.started = true
+
+	contentType := "text/csv; charset=utf-8"
+	if ew.format == exportFormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	fileName := fmt.Sprintf("%s-%s.%s", ew.name, time.Now().UTC().Format("20060102T150405Z"), ew.format)
+	ew.w.Header().Set("Content-Type", contentType)
+	ew.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", fileName))
+	ew.w.WriteHeader(http.StatusOK)
+
+	// the export streams as long as the storage gives records so the server write timeout does not apply
+	err := http.NewResponseController(ew.w).SetWriteDeadline(time.Time{})
+	if err != nil && !errors.Is(err, http.ErrNotSupported) {
+		logging.FromContext(ew.ctx).Errorf("Error on exportWriter.start(%s): %s", ew.name, err)
+	}
+
+	if ew.format == exportFormatCSV {
+		ew.csv = csv.NewWriter(ew.w)
+		return ew.csv.Write(ew.csvHeader)
+	}
+	return nil
+}
+
+// write writes a record, the csv row is used for the csv format and the record itself for the ndjson format
+func (ew *exportWriter) write(record interface{}, csvRow []string) error {
+	err := ew.start()
+	if err != nil {
+		return err
+	}
+
+	if ew.format == exportFormatCSV {
+		err = ew.csv.Write(csvRow)
+	} else {
+		var data []byte
+		data, err = json.Marshal(record)
+		if err == nil {
+			_, err = ew.w.Write(append(data, '\n'))
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	ew.rows++
+	if ew.rows%exportFlushRows == 0 {
+		return ew.flush()
+	}
+	return nil
+}
+
+func (ew *exportWriter) flush() error {
+	if ew.csv != nil {
+		ew.csv.Flush()
+		if err := ew.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := ew.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// finish completes the export. An error before the first record is returned as an error response, after it
+// the status is already sent so the export is only cut short
+func (ew *exportWriter) finish(operation string, err error) {
+	if err != nil {
+		logging.FromContext(ew.ctx).Errorf("Error on %s: %s", operation, err)
+		if !ew.started {
+			HandleError(ew.w, err)
+			return
+		}
+		logging.FromContext(ew.ctx).Warnf("%s: the export is truncated after %d rows", operation, ew.rows)
+		ew.flush()
+		return
+	}
+
+	err = ew.start()
+	if err == nil {
+		err = ew.flush()
+	}
+	if err != nil {
+		logging.FromContext(ew.ctx).Errorf("Error on %s: %s", operation, err)
+	}
+}
+
+var rewardHistoryCSVHeader = []string{"id", "user_id", "reward_type", "code", "building_block", "amount", "description", "date_created", "allocations"}
+
+func rewardHistoryCSVRow(item model.Reward) []string {
+	return []string{item.ID, item.UserID, item.RewardType, item.Code, item.BuildingBlock, strconv.Itoa(item.Amount),
+		item.Description, exportTime(&item.DateCreated), exportAllocations(item.Allocations)}
+}
+
+var rewardClaimCSVHeader = []string{"id", "user_id", "status", "items", "catalog_item_id", "pickup_location_id", "pickup_slot_id",
+	"description", "date_created", "date_updated", "date_fulfilled", "allocations"}
+
+func rewardClaimCSVRow(item model.RewardClaim) []string {
+	items := make([]string, len(item.Items))
+	for i, claimItem := range item.Items {
+		items[i] = fmt.Sprintf("%s:%d", claimItem.RewardType, claimItem.Amount)
+	}
+	var catalogItemID, locationID, slotID string
+	if item.Purchase != nil {
+		catalogItemID = item.Purchase.CatalogItemID
+	}
+	if item.Pickup != nil {
+		locationID = item.Pickup.LocationID
+		slotID = item.Pickup.SlotID
+	}
+	return []string{item.ID, item.UserID, item.Status, strings.Join(items, ";"), catalogItemID, locationID, slotID,
+		item.Description, exportTime(&item.DateCreated), exportTime(&item.DateUpdated), exportTime(item.DateFulfilled),
+		exportAllocations(item.Allocations)}
+}
+
+var rewardInventoryCSVHeader = []string{"id", "reward_type", "in_stock", "amount_total", "amount_granted", "amount_claimed",
+	"grant_depleted", "claim_depleted", "location_ids", "description", "archived", "date_created", "date_updated"}
+
+func rewardInventoryCSVRow(item model.RewardInventory) []string {
+	return []string{item.ID, item.RewardType, strconv.FormatBool(item.InStock), strconv.Itoa(item.AmountTotal),
+		strconv.Itoa(item.AmountGranted), strconv.Itoa(item.AmountClaimed), strconv.FormatBool(item.GrantDepleted),
+		strconv.FormatBool(item.ClaimDepleted), strings.Join(item.LocationIDs, ";"), item.Description,
+		strconv.FormatBool(item.Archived), exportTime(&item.DateCreated), exportTime(&item.DateUpdated)}
+}
+
+func exportTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// exportAllocations formats the allocations as inventory_id:amount pairs
+func exportAllocations(allocations []model.InventoryAllocation) string {
+	values := make([]string, len(allocations))
+	for i, allocation := range allocations {
+		values[i] = fmt.Sprintf("%s:%d", allocation.InventoryID, allocation.Amount)
+	}
+	return strings.Join(values, ";")
+}
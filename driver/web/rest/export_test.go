@@ -0,0 +1,128 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+type exportStorage struct {
+	*orgStorage
+
+	failAfter int // the export fails after this number of records when it is positive
+}
+
+func (s *exportStorage) ExportRewardHistory(ctx context.Context, orgID string, userID *string, rewardType *string, code *string, buildingBlock *string, startDate *time.Time, endDate *time.Time, limit *int64, offset *int64, each func(model.Reward) error) error {
+	s.request(orgID)
+	for i, item := range s.history {
+		if s.failAfter > 0 && i == s.failAfter {
+			return errors.New("cursor failed")
+		}
+		if err := each(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *exportStorage) ExportRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64, each func(model.RewardClaim) error) error {
+	s.request(orgID)
+	for _, item := range s.claims {
+		if err := each(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExportRewardHistoryCSV(t *testing.T) {
+	storage := &exportStorage{orgStorage: newOrgStorage()}
+	storage.history = append(storage.history, model.Reward{ID: "reward-b", OrgID: orgA, UserID: "user", RewardType: "tshirt", Amount: 2,
+		Description: "quoted, \"value\"", Allocations: []model.InventoryAllocation{{InventoryID: "inventory-a", Amount: 2}}})
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	w := httptest.NewRecorder()
+	handler.ExportRewardHistory(orgClaims(orgA), w, httptest.NewRequest(http.MethodGet, "/admin/export/history", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/csv") {
+		t.Errorf("expected a csv content type, got %s", contentType)
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.Contains(disposition, "history-") {
+		t.Errorf("expected an attachment file name, got %s", disposition)
+	}
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid csv: %s", err)
+	}
+	if len(rows) != 3 || rows[0][0] != "id" {
+		t.Fatalf("expected the header and 2 rows, got %v", rows)
+	}
+	if rows[2][6] != "quoted, \"value\"" || rows[2][8] != "inventory-a:2" {
+		t.Errorf("unexpected row %v", rows[2])
+	}
+}
+
+func TestExportRewardClaimsNDJSON(t *testing.T) {
+	storage := &exportStorage{orgStorage: newOrgStorage()}
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	w := httptest.NewRecorder()
+	handler.ExportRewardClaims(orgClaims(orgA), w, httptest.NewRequest(http.MethodGet, "/admin/export/claims?format=ndjson", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("expected an ndjson export, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	lines := strings.Split(strings.TrimSpace(w.Body.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 line, got %v", lines)
+	}
+	var claim model.RewardClaim
+	err := json.Unmarshal([]byte(lines[0]), &claim)
+	if err != nil || claim.ID != "claim-a" {
+		t.Errorf("unexpected line %s - %v", lines[0], err)
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	storage := &exportStorage{orgStorage: newOrgStorage()}
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	w := httptest.NewRecorder()
+	handler.ExportRewardHistory(orgClaims(orgA), w, httptest.NewRequest(http.MethodGet, "/admin/export/history?format=xml", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status %d for an unknown format, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	storage.history = append(storage.history, model.Reward{ID: "reward-b", OrgID: orgA, UserID: "user", RewardType: "tshirt", Amount: 2})
+	storage.failAfter = 1
+	w = httptest.NewRecorder()
+	handler.ExportRewardHistory(orgClaims(orgA), w, httptest.NewRequest(http.MethodGet, "/admin/export/history", nil))
+	rows, _ := csv.NewReader(w.Body).ReadAll()
+	if w.Code != http.StatusOK || len(rows) != 2 {
+		t.Errorf("expected the export cut short after the first row, got %d %v", w.Code, rows)
+	}
+}
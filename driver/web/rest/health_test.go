@@ -0,0 +1,91 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"testing"
+)
+
+// healthStorage reports the storage connectivity and the change streams
+type healthStorage struct {
+	*orgStorage
+	pingErr error
+	streams map[string]bool
+}
+
+func (s *healthStorage) Ping(ctx context.Context) error {
+	return s.pingErr
+}
+
+func (s *healthStorage) GetChangeStreams() map[string]bool {
+	return s.streams
+}
+
+func TestGetReady(t *testing.T) {
+	tests := []struct {
+		name    string
+		pingErr error
+		streams map[string]bool
+		status  int
+		failed  string
+	}{
+		{"ready", nil, map[string]bool{"reward_types": true, "authorization_policies": true}, http.StatusOK, ""},
+		{"mongo unreachable", errors.New("server selection timeout"), map[string]bool{"reward_types": true}, http.StatusServiceUnavailable, "mongo"},
+		{"change stream closed", nil, map[string]bool{"reward_types": true, "authorization_policies": false}, http.StatusServiceUnavailable, "change_stream.authorization_policies"},
+	}
+
+	for _, test := range tests {
+		storage := &healthStorage{orgStorage: newOrgStorage(), pingErr: test.pingErr, streams: test.streams}
+		handler := NewApisHandler(newTestApplication(storage))
+
+		w := httptest.NewRecorder()
+		handler.GetReady(w, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d - %s", test.name, test.status, w.Code, w.Body.String())
+			continue
+		}
+
+		var report model.HealthReport
+		err := json.Unmarshal(w.Body.Bytes(), &report)
+		if err != nil {
+			t.Fatalf("%s: invalid report: %s", test.name, err)
+		}
+		if len(report.Checks) != len(test.streams)+1 {
+			t.Errorf("%s: expected the mongo and change stream checks, got %v", test.name, report.Checks)
+		}
+		for name, result := range report.Checks {
+			if (name == test.failed) == (result == model.HealthStatusOK) {
+				t.Errorf("%s: unexpected %s check result %s", test.name, name, result)
+			}
+		}
+	}
+}
+
+func TestGetLive(t *testing.T) {
+	storage := &healthStorage{orgStorage: newOrgStorage(), pingErr: errors.New("server selection timeout")}
+	handler := NewApisHandler(newTestApplication(storage))
+
+	w := httptest.NewRecorder()
+	handler.GetLive(w, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected the service to be live while the storage is down, got %d - %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"rewards/core/model"
+	"strconv"
+	"strings"
+)
+
+// importListSeparator separates the values of the list columns of a csv import
+const importListSeparator = ";"
+
+type importRewardTypeRow struct {
+	RewardType  string `json:"reward_type"`
+	DisplayName string `json:"display_name"`
+	Active      bool   `json:"active"`
+	Currency    bool   `json:"currency"`
+	Description string `json:"description"`
+
+	InventoryBacked *bool `json:"inventory_backed"` // defaults to true for all reward types except currencies
+
+	AllocationStrategy    string   `json:"allocation_strategy"`
+	PreferredInventoryIDs []string `json:"preferred_inventory_ids"`
+} //@name importRewardTypeRow
+
+type importRewardOperationRow struct {
+	RewardType    string `json:"reward_type"`
+	Code          string `json:"code"`
+	BuildingBlock string `json:"building_block"`
+	Amount        int    `json:"amount"`
+	Description   string `json:"description"`
+} //@name importRewardOperationRow
+
+type importRewardInventoryRow struct {
+	ID          string   `json:"id"` // updates the inventory with the id, a new inventory is created without it
+	RewardType  string   `json:"reward_type"`
+	InStock     bool     `json:"in_stock"`
+	AmountTotal int      `json:"amount_total"`
+	LocationIDs []string `json:"location_ids"`
+	Description string   `json:"description"`
+} //@name importRewardInventoryRow
+
+// decodeImportRows decodes a json array or, for the text/csv content type, a csv with a header row of the
+// json field names into rows which must be a pointer to a slice of row structs
+func decodeImportRows(r *http.Request, rows interface{}) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return model.NewValidationError("invalid request body - %s", err)
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return errMissingBody
+	}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		rowType := reflect.TypeOf(rows).Elem().Elem()
+		body, err = csvToJSON(body, rowType)
+		if err != nil {
+			return err
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(rows)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+			field := strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldPrefix), `"`)
+			return model.NewFieldValidationError([]model.FieldError{{Field: field, Message: "unknown field"}})
+		}
+		return model.NewValidationError("invalid request body - %s", err)
+	}
+	return nil
+}
+
+// csvToJSON converts the csv records to a json array. The values are converted by the kind of the row struct
+// field with the column json name and the empty values are left out
+func csvToJSON(data []byte, rowType reflect.Type) ([]byte, error) {
+	fieldKinds := map[string]reflect.Type{}
+	for i := 0; i < rowType.NumField(); i++ {
+		field := rowType.Field(i)
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		fieldKinds[name] = field.Type
+	}
+
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, model.NewValidationError("invalid csv - %s", err)
+	}
+	if len(records) == 0 {
+		return nil, errMissingBody
+	}
+
+	header := records[0]
+	for _, column := range header {
+		if _, ok := fieldKinds[column]; !ok {
+			return nil, model.NewFieldValidationError([]model.FieldError{{Field: column, Message: "unknown field"}})
+		}
+	}
+
+	items := make([]map[string]interface{}, 0, len(records)-1)
+	for i, record := range records[1:] {
+		item := map[string]interface{}{}
+		for j, value := range record {
+			if value == "" {
+				continue
+			}
+			converted, err := csvValue(value, fieldKinds[header[j]])
+			if err != nil {
+				// the header is the first line so the data rows are numbered from 1
+				return nil, model.NewValidationError("row %d: %s %s", i+1, header[j], err)
+			}
+			item[header[j]] = converted
+		}
+		items = append(items, item)
+	}
+	return json.Marshal(items)
+}
+
+func csvValue(value string, fieldType reflect.Type) (interface{}, error) {
+	if fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	switch fieldType.Kind() {
+	case reflect.Bool:
+		converted, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false")
+		}
+		return converted, nil
+	case reflect.Int, reflect.Int64:
+		converted, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be a whole number")
+		}
+		return converted, nil
+	case reflect.Slice:
+		return strings.Split(value, importListSeparator), nil
+	}
+	return value, nil
+}
+
+func (row importRewardTypeRow) toModel() model.RewardType {
+	inventoryBacked := !row.Currency
+	if row.InventoryBacked != nil {
+		inventoryBacked = *row.InventoryBacked
+	}
+	return model.RewardType{RewardType: row.RewardType, DisplayName: row.DisplayName, Active: row.Active, Currency: row.Currency,
+		Description: row.Description, InventoryBacked: inventoryBacked, AllocationStrategy: row.AllocationStrategy,
+		PreferredInventoryIDs: row.PreferredInventoryIDs}
+}
+
+func (row importRewardOperationRow) toModel() model.RewardOperation {
+	return model.RewardOperation{RewardType: row.RewardType, Code: row.Code, BuildingBlock: row.BuildingBlock,
+		Amount: row.Amount, Description: row.Description}
+}
+
+func (row importRewardInventoryRow) toModel() model.RewardInventory {
+	return model.RewardInventory{ID: row.ID, RewardType: row.RewardType, InStock: row.InStock, AmountTotal: row.AmountTotal,
+		LocationIDs: row.LocationIDs, Description: row.Description}
+}
@@ -0,0 +1,116 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+type importStorage struct {
+	*orgStorage
+
+	applied []model.ImportBatch
+}
+
+func (s *importStorage) GetRewardOperationByCode(ctx context.Context, orgID string, code string, buildingBlock string) (*model.RewardOperation, error) {
+	item, err := s.orgStorage.GetRewardOperationByCode(ctx, orgID, code, buildingBlock)
+	if err != nil {
+		return nil, model.NewNotFoundError("unable to find reward operation with code: %s for %s", code, buildingBlock)
+	}
+	return item, nil
+}
+
+func (s *importStorage) ApplyImport(ctx context.Context, orgID string, batch model.ImportBatch) (*model.ImportBatch, error) {
+	s.request(orgID)
+	s.applied = append(s.applied, batch)
+	applied := batch
+	applied.CreateOperations = nil
+	for i, item := range batch.CreateOperations {
+		item.ID = fmt.Sprintf("operation-%d", i)
+		applied.CreateOperations = append(applied.CreateOperations, item)
+	}
+	return &applied, nil
+}
+
+func importRequest(handler *AdminApisHandler, kind string, query string, contentType string, body string) (*httptest.ResponseRecorder, model.ImportReport) {
+	r := httptest.NewRequest(http.MethodPost, "/admin/import/"+kind+query, strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	r = mux.SetURLVars(r, map[string]string{"kind": kind})
+	w := httptest.NewRecorder()
+	handler.ImportRewards(orgClaims(orgA), w, r)
+
+	var report model.ImportReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+	return w, report
+}
+
+func TestImportRewardOperationsCSV(t *testing.T) {
+	storage := &importStorage{orgStorage: newOrgStorage()}
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	body := "reward_type,code,building_block,amount,description\n" +
+		"tshirt,attend,events,2,\"attend, again\"\n" +
+		"tshirt,volunteer,events,5,\n"
+	w, report := importRequest(&handler, model.ImportKindOperations, "", "text/csv", body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if !report.Applied || report.Created != 1 || report.Updated != 1 || len(storage.applied) != 1 {
+		t.Fatalf("expected 1 created and 1 updated operation to be applied, got %s", w.Body.String())
+	}
+	if report.Rows[0].Action != model.ImportActionUpdate || report.Rows[0].ID != "operation-a" {
+		t.Errorf("expected the attend operation to be updated, got %+v", report.Rows[0])
+	}
+	if report.Rows[1].Action != model.ImportActionCreate || report.Rows[1].ID != "operation-0" {
+		t.Errorf("expected the volunteer operation to be created, got %+v", report.Rows[1])
+	}
+	if storage.applied[0].UpdateOperations[0].Description != "attend, again" {
+		t.Errorf("expected the quoted description, got %+v", storage.applied[0].UpdateOperations[0])
+	}
+}
+
+func TestImportValidatesAllRows(t *testing.T) {
+	storage := &importStorage{orgStorage: newOrgStorage()}
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	body := `[{"reward_type":"points","currency":true},{"reward_type":"coins","currency":true,"inventory_backed":true},{"reward_type":"points"}]`
+	w, report := importRequest(&handler, model.ImportKindTypes, "", "application/json", body)
+	if w.Code != http.StatusBadRequest || report.Valid || report.Failed != 2 || len(storage.applied) != 0 {
+		t.Fatalf("expected 2 invalid rows and nothing applied, got %d %s", w.Code, w.Body.String())
+	}
+	if len(report.Rows[0].Errors) != 0 || len(report.Rows[1].Errors) != 1 || len(report.Rows[2].Errors) != 1 {
+		t.Errorf("unexpected row errors %s", w.Body.String())
+	}
+
+	body = `[{"reward_type":"points","currency":true}]`
+	w, report = importRequest(&handler, model.ImportKindTypes, "?dry_run=1", "application/json", body)
+	if w.Code != http.StatusOK || !report.Valid || report.Applied || report.Created != 1 || len(storage.applied) != 0 {
+		t.Errorf("expected a valid dry run which is not applied, got %d %s", w.Code, w.Body.String())
+	}
+
+	w, _ = importRequest(&handler, model.ImportKindInventories, "", "text/csv", "reward_type,amount_total\ntshirt,ten\n")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "row 1: amount_total") {
+		t.Errorf("expected the invalid csv value to be reported, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -16,11 +16,10 @@ package rest
 
 import (
 	"encoding/json"
-	"io/ioutil"
-	"log"
 	"net/http"
 	"rewards/core"
 	"rewards/core/model"
+	"rewards/utils/logging"
 )
 
 // InternalApisHandler handles the rest internal APIs implementation
@@ -31,9 +30,9 @@ type InternalApisHandler struct {
 // createRewardHistoryEntryBody wrapper
 type createRewardHistoryEntryBody struct {
 	OrgID         string `json:"org_id"`
-	UserID        string `json:"user_id"`
+	UserID        string `json:"user_id" validate:"required"`
 	RewardType    string `json:"reward_type"`
-	RewardCode    string `json:"code"`
+	RewardCode    string `json:"code" validate:"required"`
 	BuildingBlock string `json:"building_block"`
 	Description   string `json:"description"`
 } //@name createRewardHistoryEntryBody
@@ -46,32 +45,43 @@ type createRewardHistoryEntryBody struct {
 // @Success 200 {object} model.Reward
 // @Security InternalApiAuth
 // @Router /int/reward_history [post]
-func (h InternalApisHandler) CreateReward(w http.ResponseWriter, r *http.Request) {
+func (h InternalApisHandler) CreateReward(credential *model.InternalCredential, w http.ResponseWriter, r *http.Request) {
 
-	data, err := ioutil.ReadAll(r.Body)
+	var item createRewardHistoryEntryBody
+	err := decodeJSONBody(r, &item)
 	if err != nil {
-		log.Printf("Error on internalapis.CreateReward: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on internalapis.CreateReward: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	var item createRewardHistoryEntryBody
-	err = json.Unmarshal(data, &item)
-	if err != nil {
-		log.Printf("Error on internalapis.CreateReward: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// the caller may only grant rewards in its own org and for the operations of its own building block
+	if item.OrgID == "" {
+		item.OrgID = credential.OrgID
+	}
+	if item.OrgID != credential.OrgID {
+		logging.FromContext(r.Context()).Errorf("Error on internalapis.CreateReward: %s is not allowed to act in org %s", credential.BuildingBlock, item.OrgID)
+		HandleError(w, model.NewForbiddenError("not allowed to act in org %s", item.OrgID))
+		return
+	}
+	if item.BuildingBlock == "" {
+		item.BuildingBlock = credential.BuildingBlock
+	}
+	if item.BuildingBlock != credential.BuildingBlock {
+		logging.FromContext(r.Context()).Errorf("Error on internalapis.CreateReward: %s is not allowed to grant rewards for %s", credential.BuildingBlock, item.BuildingBlock)
+		HandleError(w, model.NewForbiddenError("not allowed to grant rewards for %s", item.BuildingBlock))
 		return
 	}
 
-	operation, err := h.app.Services.GetRewardOperationByCode(item.OrgID, item.RewardCode)
+	operation, err := h.app.Services.GetRewardOperationByCode(r.Context(), item.OrgID, item.RewardCode, item.BuildingBlock)
 	if err != nil {
-		log.Printf("Error on internalapis.CreateReward: Reward operation not found. Error: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on internalapis.CreateReward: Reward operation not found. Error: %s", err)
+		HandleError(w, err)
 		return
 	}
 
 	if operation != nil && item.BuildingBlock == operation.BuildingBlock && item.RewardCode == operation.Code && operation.Amount > 0 {
-		createdItem, err := h.app.Services.CreateReward(item.OrgID, model.Reward{
+		createdItem, err := h.app.Services.CreateReward(r.Context(), item.OrgID, model.Reward{
 			UserID:        item.UserID,
 			RewardType:    operation.RewardType,
 			Code:          operation.Code,
@@ -80,15 +90,15 @@ func (h InternalApisHandler) CreateReward(w http.ResponseWriter, r *http.Request
 			Amount:        operation.Amount,
 		})
 		if err != nil {
-			log.Printf("Error on internalapis.CreateReward: %s", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Errorf("Error on internalapis.CreateReward: %s", err)
+			HandleError(w, err)
 			return
 		}
 
 		jsonData, err := json.Marshal(createdItem)
 		if err != nil {
-			log.Printf("Error on internalapis.CreateReward: %s", err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Errorf("Error on internalapis.CreateReward: %s", err)
+			HandleError(w, err)
 			return
 		}
 
@@ -98,8 +108,8 @@ func (h InternalApisHandler) CreateReward(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	log.Printf("Error on internalapis.CreateReward: Unable to find reward operation for the described code, type and building block or the amount of the operation is zero")
-	http.Error(w, "Error on internalapis.CreateReward: Unable to find reward operation for the described code, type and building block or the amount of the operation is zero", http.StatusInternalServerError)
+	logging.FromContext(r.Context()).Errorf("Error on internalapis.CreateReward: Unable to find reward operation for the described code, type and building block or the amount of the operation is zero")
+	HandleError(w, model.NewValidationError("unable to find reward operation for the described code and building block or the amount of the operation is zero"))
 }
 
 // getRewardStatsBody wrapper
@@ -115,37 +125,40 @@ type getRewardStatsBody struct {
 // @Success 200 {array} model.RewardQuantityState
 // @Security InternalApiAuth
 // @Router /int/reward_history [post]
-func (h InternalApisHandler) GetRewardStats(w http.ResponseWriter, r *http.Request) {
+func (h InternalApisHandler) GetRewardStats(credential *model.InternalCredential, w http.ResponseWriter, r *http.Request) {
 
-	data, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		log.Printf("Error on internalapis.GetRewardStats: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// the body is optional, the org of the caller is used by default
+	var item getRewardStatsBody
+	err := decodeJSONBody(r, &item)
+	if err != nil && err != errMissingBody {
+		logging.FromContext(r.Context()).Errorf("Error on internalapis.GetRewardStats: %s", err)
+		HandleError(w, err)
 		return
 	}
 
-	var item getRewardStatsBody
-	err = json.Unmarshal(data, &item)
-	if err != nil {
-		log.Printf("Error on internalapis.GetRewardStats: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if item.OrgID == "" {
+		item.OrgID = credential.OrgID
+	}
+	if item.OrgID != credential.OrgID {
+		logging.FromContext(r.Context()).Errorf("Error on internalapis.GetRewardStats: %s is not allowed to act in org %s", credential.BuildingBlock, item.OrgID)
+		HandleError(w, model.NewForbiddenError("not allowed to act in org %s", item.OrgID))
 		return
 	}
 
-	types, err := h.app.Services.GetRewardTypes(item.OrgID)
+	types, err := h.app.Services.GetRewardTypes(r.Context(), item.OrgID)
 	if err != nil {
-		log.Printf("Error on internalapis.GetRewardStats: Reward types not found. Error: %s", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		logging.FromContext(r.Context()).Errorf("Error on internalapis.GetRewardStats: Reward types not found. Error: %s", err)
+		HandleError(w, err)
 		return
 	}
 
 	result := []model.RewardQuantityState{}
 	if len(types) > 0 {
 		for _, rewardType := range types {
-			quantity, err := h.app.Services.GetRewardQuantity(item.OrgID, rewardType.RewardType)
+			quantity, err := h.app.Services.GetRewardQuantity(r.Context(), item.OrgID, rewardType.RewardType)
 			if err != nil {
-				log.Printf("Error on internalapis.GetRewardStats: %s", err)
-				http.Error(w, err.Error(), http.StatusBadRequest)
+				logging.FromContext(r.Context()).Errorf("Error on internalapis.GetRewardStats: %s", err)
+				HandleError(w, err)
 				return
 			}
 			if quantity != nil {
@@ -156,8 +169,8 @@ func (h InternalApisHandler) GetRewardStats(w http.ResponseWriter, r *http.Reque
 
 	jsonData, err := json.Marshal(result)
 	if err != nil {
-		log.Printf("Error on internalapis.GetRewardStats: %s", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Errorf("Error on internalapis.GetRewardStats: %s", err)
+		HandleError(w, err)
 		return
 	}
 
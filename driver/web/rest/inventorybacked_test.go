@@ -0,0 +1,92 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"strings"
+	"testing"
+)
+
+func TestCreateRewardInventoryBacked(t *testing.T) {
+	tests := []struct {
+		name            string
+		inventoryBacked bool
+		inventories     []model.RewardInventory
+		status          int
+	}{
+		{"backed with stock", true, []model.RewardInventory{{ID: "inventory-a", OrgID: orgA, RewardType: "tshirt", InStock: true, AmountTotal: 10}}, http.StatusOK},
+		{"backed without inventory", true, nil, http.StatusConflict},
+		{"backed out of stock", true, []model.RewardInventory{{ID: "inventory-a", OrgID: orgA, RewardType: "tshirt", InStock: true, AmountTotal: 1, AmountGranted: 1}}, http.StatusConflict},
+		{"unbacked without inventory", false, nil, http.StatusOK},
+	}
+
+	credential := &model.InternalCredential{ID: "credential", OrgID: orgA, BuildingBlock: "events"}
+	for _, test := range tests {
+		storage := newOrgStorage()
+		storage.types[0].InventoryBacked = test.inventoryBacked
+		storage.inventories = test.inventories
+		handler := NewInternalApisHandler(newTestApplication(storage))
+
+		r := httptest.NewRequest(http.MethodPost, "/int/reward", strings.NewReader(`{"user_id":"user","code":"attend"}`))
+		w := httptest.NewRecorder()
+		handler.CreateReward(credential, w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d - %s", test.name, test.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestGetRewardStatsUnbacked(t *testing.T) {
+	storage := newOrgStorage()
+	storage.types = append(storage.types, model.RewardType{ID: "type-points", OrgID: orgA, RewardType: "points", Currency: true})
+	handler := NewInternalApisHandler(newTestApplication(storage))
+
+	credential := &model.InternalCredential{ID: "credential", OrgID: orgA, BuildingBlock: "events"}
+	r := httptest.NewRequest(http.MethodGet, "/int/stats", nil)
+	w := httptest.NewRecorder()
+	handler.GetRewardStats(credential, w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var stats []map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &stats)
+	if err != nil {
+		t.Fatalf("invalid stats: %s", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("expected stats for both reward types, got %s", w.Body.String())
+	}
+	for _, state := range stats {
+		switch state["reward_type"] {
+		case "tshirt":
+			if state["inventory_backed"] != true || state["grantable_quantity"] != float64(10) {
+				t.Errorf("expected the tshirt quantities, got %v", state)
+			}
+		case "points":
+			_, hasQuantity := state["grantable_quantity"]
+			if state["inventory_backed"] != false || hasQuantity {
+				t.Errorf("expected no quantities for the points, got %v", state)
+			}
+		default:
+			t.Errorf("unexpected reward type %v", state["reward_type"])
+		}
+	}
+}
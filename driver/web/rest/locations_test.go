@@ -0,0 +1,166 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"strings"
+	"testing"
+	"time"
+)
+
+// locationStorage keeps the pickup locations and records the claims. Like the storage it books the slot capacity when the claim is created
+type locationStorage struct {
+	*orgStorage
+
+	locations []model.PickupLocation
+	booked    map[string]int // location/slot -> booked claims
+}
+
+func (s *locationStorage) GetPickupLocation(ctx context.Context, orgID string, id string) (*model.PickupLocation, error) {
+	for _, item := range s.locations {
+		if item.OrgID == orgID && item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, model.NewNotFoundError("unable to find pickup location with id: %s", id)
+}
+
+func (s *locationStorage) CreatePickupLocation(ctx context.Context, orgID string, item model.PickupLocation) (*model.PickupLocation, error) {
+	item.OrgID = orgID
+	s.locations = append(s.locations, item)
+	return &item, nil
+}
+
+func (s *locationStorage) CreateRewardClaim(ctx context.Context, orgID string, item model.RewardClaim) (*model.RewardClaim, error) {
+	if item.Pickup != nil && item.Pickup.SlotID != "" {
+		location, err := s.GetPickupLocation(ctx, orgID, item.Pickup.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		slot := location.GetSlot(item.Pickup.SlotID)
+		key := item.Pickup.LocationID + "/" + item.Pickup.SlotID
+		if slot.Capacity > 0 && s.booked[key] >= slot.Capacity {
+			return nil, model.NewConflictError("pickup slot %s is full", slot.ID)
+		}
+		s.booked[key]++
+	}
+
+	item.OrgID = orgID
+	s.claims = append(s.claims, item)
+	return &item, nil
+}
+
+func newLocationStorage() *locationStorage {
+	storage := &locationStorage{orgStorage: newOrgStorage(), booked: map[string]int{}}
+	storage.inventories[0].LocationIDs = []string{"location-a"}
+
+	now := time.Now().UTC()
+	storage.locations = []model.PickupLocation{
+		{ID: "location-a", OrgID: orgA, Name: "Union", Active: true, Slots: []model.PickupSlot{
+			{ID: "slot-open", StartTime: now, EndTime: now.Add(time.Hour), Capacity: 2},
+			{ID: "slot-passed", StartTime: now.Add(-2 * time.Hour), EndTime: now.Add(-time.Hour), Capacity: 2},
+		}},
+		{ID: "location-empty", OrgID: orgA, Name: "Library", Active: true},
+		{ID: "location-closed", OrgID: orgA, Name: "Stadium", Active: false},
+	}
+	return storage
+}
+
+func claimAtLocation(handler ApisHandler, locationID string, slotID string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"items":[{"reward_type":"tshirt","amount":1}],"pickup":{"location_id":%q,"slot_id":%q}}`, locationID, slotID)
+	w := httptest.NewRecorder()
+	handler.CreateUserRewardClaim(orgClaims(orgA), w, httptest.NewRequest(http.MethodPost, "/user/claims", strings.NewReader(body)))
+	return w
+}
+
+func TestClaimPickupLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		slot     string
+		status   int
+	}{
+		{"stocked location", "location-a", "", http.StatusOK},
+		{"open slot", "location-a", "slot-open", http.StatusOK},
+		{"inactive location", "location-closed", "", http.StatusBadRequest},
+		{"unknown location", "location-unknown", "", http.StatusNotFound},
+		{"unknown slot", "location-a", "slot-unknown", http.StatusBadRequest},
+		{"passed slot", "location-a", "slot-passed", http.StatusBadRequest},
+		{"location without the reward", "location-empty", "", http.StatusConflict},
+	}
+
+	for _, test := range tests {
+		storage := newLocationStorage()
+		handler := NewApisHandler(newTestApplication(storage))
+
+		w := claimAtLocation(handler, test.location, test.slot)
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d - %s", test.name, test.status, w.Code, w.Body.String())
+		}
+		created := len(storage.claims) > 1
+		if created != (test.status == http.StatusOK) {
+			t.Errorf("%s: expected the claim to be created=%t", test.name, test.status == http.StatusOK)
+		}
+	}
+}
+
+func TestClaimPickupSlotCapacity(t *testing.T) {
+	storage := newLocationStorage()
+	handler := NewApisHandler(newTestApplication(storage))
+
+	for i := 0; i < 2; i++ {
+		if w := claimAtLocation(handler, "location-a", "slot-open"); w.Code != http.StatusOK {
+			t.Fatalf("expected booking %d to pass, got %d - %s", i+1, w.Code, w.Body.String())
+		}
+	}
+	w := claimAtLocation(handler, "location-a", "slot-open")
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "full") {
+		t.Errorf("expected the full slot to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+	if storage.booked["location-a/slot-open"] != 2 {
+		t.Errorf("expected 2 bookings, got %d", storage.booked["location-a/slot-open"])
+	}
+}
+
+func TestCreatePickupLocationValidation(t *testing.T) {
+	now := time.Now().UTC()
+	tests := []struct {
+		name   string
+		slot   model.PickupSlot
+		status int
+	}{
+		{"valid slot", model.PickupSlot{StartTime: now, EndTime: now.Add(time.Hour), Capacity: 5}, http.StatusOK},
+		{"end before start", model.PickupSlot{StartTime: now, EndTime: now.Add(-time.Hour)}, http.StatusBadRequest},
+		{"negative capacity", model.PickupSlot{StartTime: now, EndTime: now.Add(time.Hour), Capacity: -1}, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		storage := newLocationStorage()
+		handler := NewAdminApisHandler(newTestApplication(storage))
+
+		body, _ := json.Marshal(map[string]interface{}{"name": "Gym", "active": true, "slots": []model.PickupSlot{test.slot}})
+		w := httptest.NewRecorder()
+		handler.CreatePickupLocation(orgClaims(orgA), w, httptest.NewRequest(http.MethodPost, "/admin/locations", strings.NewReader(string(body))))
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d - %s", test.name, test.status, w.Code, w.Body.String())
+		}
+	}
+}
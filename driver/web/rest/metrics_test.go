@@ -0,0 +1,57 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"rewards/utils/metrics"
+	"strings"
+	"testing"
+)
+
+// scrapeMetric gives the value line of the metric series or an empty string
+func scrapeMetric(t *testing.T, series string) string {
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		if strings.HasPrefix(line, series+" ") {
+			return strings.TrimPrefix(line, series+" ")
+		}
+	}
+	return ""
+}
+
+func TestGrantMetrics(t *testing.T) {
+	series := `rewards_grants_total{code="attend"}`
+	before := scrapeMetric(t, series)
+
+	storage := newOrgStorage()
+	handler := NewInternalApisHandler(newTestApplication(storage))
+	credential := &model.InternalCredential{ID: "credential", OrgID: orgA, BuildingBlock: "events"}
+
+	r := httptest.NewRequest(http.MethodPost, "/int/reward", strings.NewReader(`{"user_id":"user","code":"attend"}`))
+	w := httptest.NewRecorder()
+	handler.CreateReward(credential, w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	after := scrapeMetric(t, series)
+	if after == "" || after == before {
+		t.Errorf("expected the grant to be counted, got %q before and %q after", before, after)
+	}
+}
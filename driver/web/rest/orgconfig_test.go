@@ -0,0 +1,223 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"strings"
+	"testing"
+)
+
+type configStorage struct {
+	*importStorage
+
+	policies []model.AuthorizationPolicy
+}
+
+func newConfigStorage() *configStorage {
+	storage := &configStorage{importStorage: &importStorage{orgStorage: newOrgStorage()}}
+	storage.types[0].AllocationStrategy = model.AllocationStrategySpecificFirst
+	storage.types[0].PreferredInventoryIDs = []string{"inventory-a"}
+	storage.inventories[0].AmountGranted = 4
+	storage.inventories[0].Description = "fall"
+	storage.policies = []model.AuthorizationPolicy{{ID: "policy", Subject: "rewards_admin", Object: "/rewards/api/admin/*", Action: "(GET)"}}
+	return storage
+}
+
+func (s *configStorage) GetRewardOperations(ctx context.Context, orgID string, archived *bool) ([]model.RewardOperation, error) {
+	s.request(orgID)
+	result := []model.RewardOperation{}
+	for _, item := range s.operations {
+		if item.OrgID == orgID {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (s *configStorage) GetAuthorizationPolicies(ctx context.Context) ([]model.AuthorizationPolicy, error) {
+	return s.policies, nil
+}
+
+func (s *configStorage) ApplyImport(ctx context.Context, orgID string, batch model.ImportBatch) (*model.ImportBatch, error) {
+	s.request(orgID)
+	s.applied = append(s.applied, batch)
+	applied := batch
+	applied.CreateInventories, applied.CreateTypes, applied.CreateOperations = nil, nil, nil
+	for _, item := range batch.CreateInventories {
+		item.ID = "new-" + item.ID
+		applied.CreateInventories = append(applied.CreateInventories, item)
+	}
+	for _, item := range batch.CreateTypes {
+		item.ID = "new-" + item.ID
+		applied.CreateTypes = append(applied.CreateTypes, item)
+	}
+	for _, item := range batch.CreateOperations {
+		item.ID = "new-" + item.ID
+		applied.CreateOperations = append(applied.CreateOperations, item)
+	}
+	return &applied, nil
+}
+
+func importOrgConfig(handler *AdminApisHandler, orgID string, query string, config model.OrgConfig) (*httptest.ResponseRecorder, model.OrgConfigImportReport) {
+	body, _ := json.Marshal(config)
+	r := httptest.NewRequest(http.MethodPost, "/admin/config/import"+query, strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	handler.ImportOrgConfig(orgClaims(orgID), w, r)
+
+	var report model.OrgConfigImportReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+	return w, report
+}
+
+func TestOrgConfigPromotion(t *testing.T) {
+	storage := newConfigStorage()
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	w := httptest.NewRecorder()
+	handler.ExportOrgConfig(orgClaims(orgA), w, httptest.NewRequest(http.MethodGet, "/admin/config/export", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d - %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "amount_granted") {
+		t.Errorf("expected the inventories without counters, got %s", w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "policies") {
+		t.Errorf("expected the authorization policies shared by all orgs not to be exported, got %s", w.Body.String())
+	}
+	var config model.OrgConfig
+	err := json.Unmarshal(w.Body.Bytes(), &config)
+	if err != nil || config.Version != model.OrgConfigVersion || len(config.RewardTypes) != 1 || len(config.Inventories) != 1 {
+		t.Fatalf("unexpected configuration %s - %v", w.Body.String(), err)
+	}
+
+	// the other org has nothing so everything is created
+	w, report := importOrgConfig(&handler, orgB, "?dry_run=1", config)
+	if w.Code != http.StatusOK || !report.Valid || report.Applied || len(storage.applied) != 0 {
+		t.Fatalf("expected a valid dry run, got %d %s", w.Code, w.Body.String())
+	}
+	for _, change := range report.Changes {
+		if change.Action != model.OrgConfigActionCreate {
+			t.Errorf("expected create of %s %s, got %s", change.Resource, change.Key, change.Action)
+		}
+	}
+
+	w, report = importOrgConfig(&handler, orgB, "", config)
+	if w.Code != http.StatusOK || !report.Applied || len(storage.applied) != 1 {
+		t.Fatalf("expected the configuration to be applied, got %d %s", w.Code, w.Body.String())
+	}
+	if report.IDMapping["inventory-a"] != "new-inventory-a" || report.IDMapping["type-a"] != "new-type-a" {
+		t.Errorf("expected the created ids to be mapped, got %v", report.IDMapping)
+	}
+	batch := storage.applied[0]
+	if len(batch.CreateInventories) != 1 || batch.CreateInventories[0].AmountGranted != 0 {
+		t.Errorf("expected the inventory to be created without counters, got %+v", batch.CreateInventories)
+	}
+}
+
+func TestOrgConfigDiff(t *testing.T) {
+	storage := newConfigStorage()
+	handler := NewAdminApisHandler(newTestApplication(storage))
+	config, err := handler.app.Services.ExportOrgConfig(context.Background(), orgA)
+	if err != nil {
+		t.Fatalf("unexpected error %s", err)
+	}
+
+	config.Operations[0].Amount = 3
+	config.Inventories[0].AmountTotal = 2
+	w, report := importOrgConfig(&handler, orgA, "?dry_run=1", *config)
+	if w.Code != http.StatusBadRequest || report.Valid {
+		t.Fatalf("expected the inventory below its granted amount to be invalid, got %d %s", w.Code, w.Body.String())
+	}
+
+	changes := map[string]model.OrgConfigChange{}
+	for _, change := range report.Changes {
+		changes[change.Resource+" "+change.Key] = change
+	}
+	if change := changes["types tshirt"]; change.Action != model.OrgConfigActionUnchanged || change.TargetID != "type-a" {
+		t.Errorf("expected the reward type to be unchanged, got %+v", change)
+	}
+	if change := changes["operations attend/events"]; change.Action != model.OrgConfigActionUpdate || len(change.Fields) != 1 || change.Fields[0] != "amount" {
+		t.Errorf("expected the operation amount to be updated, got %+v", change)
+	}
+	if change := changes["inventories tshirt/fall"]; len(change.Errors) != 1 {
+		t.Errorf("expected the inventory amount error, got %+v", change)
+	}
+
+	config.Version = model.OrgConfigVersion + 1
+	w, _ = importOrgConfig(&handler, orgA, "", *config)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected an unsupported version to be rejected, got %d", w.Code)
+	}
+}
+
+func TestOrgConfigInventoriesWithoutNaturalKey(t *testing.T) {
+	storage := newConfigStorage()
+	storage.inventories[0].Description = ""
+	storage.inventories = append(storage.inventories, model.RewardInventory{ID: "inventory-b", OrgID: orgA, RewardType: "tshirt", InStock: true, AmountTotal: 5})
+	handler := NewAdminApisHandler(newTestApplication(storage))
+	config, err := handler.app.Services.ExportOrgConfig(context.Background(), orgA)
+	if err != nil || len(config.Inventories) != 2 {
+		t.Fatalf("expected both inventories to be exported, got %v - %v", config, err)
+	}
+
+	// the own export is matched by the ids
+	w, report := importOrgConfig(&handler, orgA, "?dry_run=1", *config)
+	if w.Code != http.StatusOK || !report.Valid {
+		t.Fatalf("expected the own export to be valid, got %d %s", w.Code, w.Body.String())
+	}
+	for _, change := range report.Changes {
+		if change.Resource == model.ImportKindInventories && (change.Action != model.OrgConfigActionUnchanged || change.TargetID != change.SourceID) {
+			t.Errorf("expected the inventory %s to be matched by its id, got %+v", change.SourceID, change)
+		}
+	}
+
+	// an org without the inventories gets both
+	w, report = importOrgConfig(&handler, orgB, "", *config)
+	if w.Code != http.StatusOK || !report.Applied {
+		t.Fatalf("expected the configuration to be applied to the new org, got %d %s", w.Code, w.Body.String())
+	}
+	if len(storage.applied[0].CreateInventories) != 2 {
+		t.Errorf("expected both inventories to be created, got %+v", storage.applied[0].CreateInventories)
+	}
+
+	// an org with one of them cannot tell which one it is
+	storage.applied = nil
+	storage.inventories = append(storage.inventories, model.RewardInventory{ID: "inventory-c", OrgID: orgB, RewardType: "tshirt", InStock: true, AmountTotal: 10})
+	w, report = importOrgConfig(&handler, orgB, "", *config)
+	if w.Code != http.StatusBadRequest || report.Valid || len(storage.applied) != 0 {
+		t.Errorf("expected the ambiguous inventories to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOrgConfigImportRejectsPolicies(t *testing.T) {
+	storage := newConfigStorage()
+	handler := NewAdminApisHandler(newTestApplication(storage))
+
+	// an org admin must not add authorization policies which apply to all orgs
+	body := `{"version": 1, "org_id": "org-a", "reward_types": [], "operations": [], "inventories": [],
+		"policies": [{"subject": "rewards_viewer", "object": "/rewards/api/admin/*", "action": "(GET)"}]}`
+	r := httptest.NewRequest(http.MethodPost, "/admin/config/import?apply_policies=1", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ImportOrgConfig(orgClaims(orgB), w, r)
+
+	if w.Code != http.StatusBadRequest || len(storage.applied) != 0 {
+		t.Errorf("expected a configuration with policies to be rejected, got %d %s", w.Code, w.Body.String())
+	}
+}
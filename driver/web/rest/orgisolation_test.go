@@ -0,0 +1,304 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core"
+	"rewards/core/model"
+	cacheadapter "rewards/driven/cache"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/rokwire/core-auth-library-go/tokenauth"
+)
+
+const (
+	orgA = "org-a"
+	orgB = "org-b"
+)
+
+// orgStorage is an in memory storage which keeps the data of several orgs and records the orgs it is asked for
+type orgStorage struct {
+	core.Storage
+
+	types       []model.RewardType
+	operations  []model.RewardOperation
+	inventories []model.RewardInventory
+	history     []model.Reward
+	claims      []model.RewardClaim
+
+	requestedOrgs []string
+}
+
+func newOrgStorage() *orgStorage {
+	return &orgStorage{
+		types: []model.RewardType{
+			{ID: "type-a", OrgID: orgA, RewardType: "tshirt", InventoryBacked: true},
+		},
+		operations: []model.RewardOperation{
+			{ID: "operation-a", OrgID: orgA, RewardType: "tshirt", Code: "attend", BuildingBlock: "events", Amount: 1},
+		},
+		inventories: []model.RewardInventory{
+			{ID: "inventory-a", OrgID: orgA, RewardType: "tshirt", InStock: true, AmountTotal: 10},
+		},
+		history: []model.Reward{
+			{ID: "reward-a", OrgID: orgA, UserID: "user", RewardType: "tshirt", Amount: 1},
+		},
+		claims: []model.RewardClaim{
+			{ID: "claim-a", OrgID: orgA, UserID: "user", Status: model.RewardClaimStatusApproved, PickupCode: "ABCDEFGH"},
+		},
+	}
+}
+
+func (s *orgStorage) request(orgID string) {
+	s.requestedOrgs = append(s.requestedOrgs, orgID)
+}
+
+func (s *orgStorage) GetRewardTypes(ctx context.Context, orgID string) ([]model.RewardType, error) {
+	s.request(orgID)
+	result := []model.RewardType{}
+	for _, item := range s.types {
+		if item.OrgID == orgID {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (s *orgStorage) GetRewardType(ctx context.Context, orgID string, id string) (*model.RewardType, error) {
+	s.request(orgID)
+	for _, item := range s.types {
+		if item.OrgID == orgID && item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find reward type with id: %s", id)
+}
+
+func (s *orgStorage) GetRewardTypeByType(ctx context.Context, orgID string, rewardType string) (*model.RewardType, error) {
+	s.request(orgID)
+	for _, item := range s.types {
+		if item.OrgID == orgID && item.RewardType == rewardType {
+			return &item, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *orgStorage) GetRewardOperationByCode(ctx context.Context, orgID string, code string, buildingBlock string) (*model.RewardOperation, error) {
+	s.request(orgID)
+	for _, item := range s.operations {
+		if item.OrgID == orgID && item.Code == code && item.BuildingBlock == buildingBlock {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find reward operation with code: %s", code)
+}
+
+func (s *orgStorage) GetRewardInventories(ctx context.Context, orgID string, ids []string, rewardType *string, inStock *bool, grantDepleted *bool, claimDepleted *bool, archived *bool, limit *int64, offset *int64) ([]model.RewardInventory, error) {
+	s.request(orgID)
+	result := []model.RewardInventory{}
+	for _, item := range s.inventories {
+		if item.OrgID == orgID {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (s *orgStorage) GetRewardInventory(ctx context.Context, orgID string, id string) (*model.RewardInventory, error) {
+	s.request(orgID)
+	for _, item := range s.inventories {
+		if item.OrgID == orgID && item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find reward inventory with id: %s", id)
+}
+
+func (s *orgStorage) GetRewardQuantityState(ctx context.Context, orgID string, rewardType string, inStock *bool) (*model.RewardQuantityState, error) {
+	s.request(orgID)
+	grantable, claimable := 0, 0
+	for _, item := range s.inventories {
+		if item.OrgID == orgID && item.RewardType == rewardType {
+			grantable += item.AmountTotal - item.AmountGranted
+			claimable += item.AmountTotal - item.AmountClaimed
+		}
+	}
+	return &model.RewardQuantityState{RewardType: rewardType, InventoryBacked: true, GrantableQuantity: &grantable, ClaimableQuantity: &claimable}, nil
+}
+
+func (s *orgStorage) CreateUserReward(ctx context.Context, orgID string, item model.Reward) (*model.Reward, error) {
+	s.request(orgID)
+	item.OrgID = orgID
+	s.history = append(s.history, item)
+	return &item, nil
+}
+
+func (s *orgStorage) GetUserRewardsHistory(ctx context.Context, orgID string, userID string, rewardType *string, code *string, buildingBlock *string, limit *int64, offset *int64) ([]model.Reward, error) {
+	s.request(orgID)
+	result := []model.Reward{}
+	for _, item := range s.history {
+		if item.OrgID == orgID && item.UserID == userID {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (s *orgStorage) GetRewardClaims(ctx context.Context, orgID string, ids []string, userID *string, rewardType *string, status *string, limit *int64, offset *int64) ([]model.RewardClaim, error) {
+	s.request(orgID)
+	result := []model.RewardClaim{}
+	for _, item := range s.claims {
+		if item.OrgID == orgID && (userID == nil || item.UserID == *userID) {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+func (s *orgStorage) GetRewardClaim(ctx context.Context, orgID string, id string) (*model.RewardClaim, error) {
+	s.request(orgID)
+	for _, item := range s.claims {
+		if item.OrgID == orgID && item.ID == id {
+			return &item, nil
+		}
+	}
+	return nil, fmt.Errorf("unable to find reward claim with id: %s", id)
+}
+
+func newTestApplication(storage core.Storage) *core.Application {
+	return core.NewApplication("test", "test", storage, cacheadapter.NewCacheAdapter(""))
+}
+
+func orgClaims(orgID string) *tokenauth.Claims {
+	claims := tokenauth.Claims{OrgID: orgID}
+	claims.Subject = "user"
+	return &claims
+}
+
+func TestOrgIsolationAdminAndClientEndpoints(t *testing.T) {
+	storage := newOrgStorage()
+	app := newTestApplication(storage)
+	adminHandler := NewAdminApisHandler(app)
+	clientHandler := NewApisHandler(app)
+
+	tests := []struct {
+		name    string
+		handler func(*tokenauth.Claims, http.ResponseWriter, *http.Request)
+		id      string
+	}{
+		{"admin types", adminHandler.GetRewardTypes, ""},
+		{"admin type", adminHandler.GetRewardType, "type-a"},
+		{"admin inventories", adminHandler.GetRewardInventories, ""},
+		{"admin inventory", adminHandler.GetRewardInventory, "inventory-a"},
+		{"admin claims", adminHandler.GetRewardClaims, ""},
+		{"admin claim", adminHandler.GetRewardClaim, "claim-a"},
+		{"client history", clientHandler.GetUserRewardsHistory, ""},
+		{"client claims", clientHandler.GetUserRewardClaim, ""},
+		{"client claim qr", clientHandler.GetUserRewardClaimQRCode, "claim-a"},
+	}
+
+	for _, test := range tests {
+		storage.requestedOrgs = nil
+
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if test.id != "" {
+			r = mux.SetURLVars(r, map[string]string{"id": test.id})
+		}
+		w := httptest.NewRecorder()
+		test.handler(orgClaims(orgB), w, r)
+
+		body := w.Body.String()
+		if strings.Contains(body, orgA) || strings.Contains(body, "tshirt") || strings.Contains(body, "ABCDEFGH") {
+			t.Errorf("%s: org %s data leaked to org %s: %s", test.name, orgA, orgB, body)
+		}
+		if test.id != "" && w.Code == http.StatusOK {
+			t.Errorf("%s: expected the %s entity to be not found for org %s", test.name, orgA, orgB)
+		}
+		for _, orgID := range storage.requestedOrgs {
+			if orgID != orgB {
+				t.Errorf("%s: storage was queried for org %s by an org %s caller", test.name, orgID, orgB)
+			}
+		}
+	}
+}
+
+func TestOrgIsolationInternalCreateReward(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"other org", `{"org_id":"org-b","user_id":"user","code":"attend","building_block":"events"}`, http.StatusForbidden},
+		{"other building block", `{"org_id":"org-a","user_id":"user","code":"attend","building_block":"groups"}`, http.StatusForbidden},
+		{"own org", `{"org_id":"org-a","user_id":"user","code":"attend","building_block":"events"}`, http.StatusOK},
+		{"org from credential", `{"user_id":"user","code":"attend"}`, http.StatusOK},
+	}
+
+	credential := &model.InternalCredential{ID: "credential", OrgID: orgA, BuildingBlock: "events"}
+	for _, test := range tests {
+		storage := newOrgStorage()
+		handler := NewInternalApisHandler(newTestApplication(storage))
+
+		r := httptest.NewRequest(http.MethodPost, "/int/reward", strings.NewReader(test.body))
+		w := httptest.NewRecorder()
+		handler.CreateReward(credential, w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d - %s", test.name, test.status, w.Code, w.Body.String())
+		}
+		for _, orgID := range storage.requestedOrgs {
+			if orgID != orgA {
+				t.Errorf("%s: storage was queried for org %s by an org %s credential", test.name, orgID, orgA)
+			}
+		}
+		if test.status != http.StatusOK && len(storage.history) != 1 {
+			t.Errorf("%s: expected no reward to be granted", test.name)
+		}
+	}
+}
+
+func TestOrgIsolationInternalGetRewardStats(t *testing.T) {
+	credential := &model.InternalCredential{ID: "credential", OrgID: orgB, BuildingBlock: "events"}
+
+	storage := newOrgStorage()
+	handler := NewInternalApisHandler(newTestApplication(storage))
+
+	r := httptest.NewRequest(http.MethodGet, "/int/stats", strings.NewReader(`{"org_id":"org-a"}`))
+	w := httptest.NewRecorder()
+	handler.GetRewardStats(credential, w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status %d for another org, got %d", http.StatusForbidden, w.Code)
+	}
+	if len(storage.requestedOrgs) > 0 {
+		t.Errorf("expected no storage queries for another org, got %v", storage.requestedOrgs)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/int/stats", nil)
+	w = httptest.NewRecorder()
+	handler.GetRewardStats(credential, w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d for the own org, got %d", http.StatusOK, w.Code)
+	}
+	if strings.Contains(w.Body.String(), "tshirt") {
+		t.Errorf("org %s stats leaked to org %s: %s", orgA, orgB, w.Body.String())
+	}
+}
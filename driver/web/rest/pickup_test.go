@@ -0,0 +1,215 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// claimPickupStorage keeps the pickup codes and the pickup attempts of the claims like the storage does
+type claimPickupStorage struct {
+	*orgStorage
+
+	attempts []model.RewardClaimPickupAttempt
+}
+
+func (s *claimPickupStorage) claim(orgID string, id string) *model.RewardClaim {
+	for i := range s.claims {
+		if s.claims[i].OrgID == orgID && s.claims[i].ID == id {
+			return &s.claims[i]
+		}
+	}
+	return nil
+}
+
+func (s *claimPickupStorage) UpdateRewardClaim(ctx context.Context, orgID string, id string, item model.RewardClaim) (*model.RewardClaim, error) {
+	claim := s.claim(orgID, id)
+	if claim == nil || claim.Status == model.RewardClaimStatusFulfilled {
+		return nil, model.NewConflictError("claim %s is fulfilled or does not exist", id)
+	}
+	claim.Status = item.Status
+	claim.Description = item.Description
+	if item.Status != model.RewardClaimStatusApproved {
+		claim.PickupCode = ""
+	}
+	return &item, nil
+}
+
+func (s *claimPickupStorage) SetRewardClaimPickupCode(ctx context.Context, orgID string, id string, code string) error {
+	claim := s.claim(orgID, id)
+	if claim != nil && claim.Status == model.RewardClaimStatusApproved && claim.PickupCode == "" {
+		claim.PickupCode = code
+	}
+	return nil
+}
+
+func (s *claimPickupStorage) GetRewardClaimByPickupCode(ctx context.Context, orgID string, code string) (*model.RewardClaim, error) {
+	for _, item := range s.claims {
+		if item.OrgID == orgID && item.PickupCode != "" && item.PickupCode == code {
+			return &item, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *claimPickupStorage) FulfillRewardClaim(ctx context.Context, orgID string, id string) (bool, error) {
+	claim := s.claim(orgID, id)
+	if claim == nil || claim.Status != model.RewardClaimStatusApproved {
+		return false, nil
+	}
+	claim.Status = model.RewardClaimStatusFulfilled
+	return true, nil
+}
+
+func (s *claimPickupStorage) CreateRewardClaimPickupAttempt(ctx context.Context, orgID string, item model.RewardClaimPickupAttempt) (*model.RewardClaimPickupAttempt, error) {
+	s.attempts = append(s.attempts, item)
+	return &item, nil
+}
+
+func newClaimPickupStorage() *claimPickupStorage {
+	storage := &claimPickupStorage{orgStorage: newOrgStorage()}
+	storage.claims = append(storage.claims, model.RewardClaim{ID: "claim-b", OrgID: orgA, UserID: "user", Status: model.RewardClaimStatusPending})
+	return storage
+}
+
+func updateClaim(handler AdminApisHandler, id string, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPut, "/admin/claims/"+id, strings.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	handler.UpdateRewardClaim(orgClaims(orgA), w, r)
+	return w
+}
+
+func redeemCode(handler AdminApisHandler, code string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/admin/claims/pickup", strings.NewReader(`{"pickup_code":"`+code+`"}`))
+	w := httptest.NewRecorder()
+	handler.RedeemRewardClaimPickupCode(orgClaims(orgA), w, r)
+	return w
+}
+
+func getQRCode(handler ApisHandler, id string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/user/claims/"+id+"/qr", nil)
+	r = mux.SetURLVars(r, map[string]string{"id": id})
+	w := httptest.NewRecorder()
+	handler.GetUserRewardClaimQRCode(orgClaims(orgA), w, r)
+	return w
+}
+
+func TestApprovedClaimGetsPickupCode(t *testing.T) {
+	storage := newClaimPickupStorage()
+	app := newTestApplication(storage)
+	handler := NewAdminApisHandler(app)
+
+	w := updateClaim(handler, "claim-b", `{"status":"approved"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d - %s", w.Code, w.Body.String())
+	}
+	code := storage.claim(orgA, "claim-b").PickupCode
+	if len(code) != 8 || strings.ToUpper(code) != code {
+		t.Fatalf("expected an 8 character upper case pickup code, got %q", code)
+	}
+	if !strings.Contains(w.Body.String(), code) {
+		t.Errorf("expected the pickup code in the response, got %s", w.Body.String())
+	}
+
+	// approving again keeps the code
+	updateClaim(handler, "claim-b", `{"status":"approved","description":"again"}`)
+	if storage.claim(orgA, "claim-b").PickupCode != code {
+		t.Error("expected the pickup code to be kept while the claim stays approved")
+	}
+
+	// moving back to pending invalidates the code and a new approval gives a new one
+	updateClaim(handler, "claim-b", `{"status":"pending"}`)
+	if storage.claim(orgA, "claim-b").PickupCode != "" {
+		t.Fatal("expected the pickup code to be removed from the pending claim")
+	}
+	w = redeemCode(handler, code)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected the old code to be unknown, got %d - %s", w.Code, w.Body.String())
+	}
+	updateClaim(handler, "claim-b", `{"status":"approved"}`)
+	if storage.claim(orgA, "claim-b").PickupCode == "" {
+		t.Error("expected a new pickup code on the new approval")
+	}
+}
+
+func TestPickupCodeIsRedeemedOnce(t *testing.T) {
+	storage := newClaimPickupStorage()
+	app := newTestApplication(storage)
+	handler := NewAdminApisHandler(app)
+
+	w := redeemCode(handler, " abcdefgh ")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d - %s", w.Code, w.Body.String())
+	}
+	if storage.claim(orgA, "claim-a").Status != model.RewardClaimStatusFulfilled {
+		t.Fatal("expected the claim to be fulfilled")
+	}
+
+	w = redeemCode(handler, "ABCDEFGH")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected the second redemption to conflict, got %d - %s", w.Code, w.Body.String())
+	}
+
+	// a fulfilled claim cannot be approved again to reuse its code
+	w = updateClaim(handler, "claim-a", `{"status":"approved"}`)
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected the fulfilled claim not to change, got %d - %s", w.Code, w.Body.String())
+	}
+	w = redeemCode(handler, "ABCDEFGH")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected the third redemption to conflict, got %d - %s", w.Code, w.Body.String())
+	}
+
+	if len(storage.attempts) != 3 {
+		t.Fatalf("expected 3 recorded attempts, got %d", len(storage.attempts))
+	}
+	if !storage.attempts[0].Success || storage.attempts[1].Success || storage.attempts[2].Success {
+		t.Errorf("expected only the first attempt to succeed, got %v", storage.attempts)
+	}
+}
+
+func TestPickupQRCode(t *testing.T) {
+	storage := newClaimPickupStorage()
+	app := newTestApplication(storage)
+	handler := NewApisHandler(app)
+
+	w := getQRCode(handler, "claim-a")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("expected a png, got %d - %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if !bytes.HasPrefix(w.Body.Bytes(), []byte("\x89PNG")) {
+		t.Error("expected a png body")
+	}
+
+	w = getQRCode(handler, "claim-b")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected no qr code for a pending claim, got %d", w.Code)
+	}
+
+	redeemCode(NewAdminApisHandler(app), "ABCDEFGH")
+	w = getQRCode(handler, "claim-a")
+	if w.Code != http.StatusConflict {
+		t.Errorf("expected no qr code for a fulfilled claim, got %d", w.Code)
+	}
+}
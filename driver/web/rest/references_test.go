@@ -0,0 +1,91 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rewards/core/model"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// referenceStorage reports fixed references and records how the reward type was deleted
+type referenceStorage struct {
+	*orgStorage
+
+	references []model.Reference
+	deleted    string
+}
+
+func (s *referenceStorage) GetRewardTypeReferences(ctx context.Context, orgID string, rewardType string) ([]model.Reference, error) {
+	s.request(orgID)
+	return s.references, nil
+}
+
+func (s *referenceStorage) DeleteRewardType(ctx context.Context, orgID string, id string) error {
+	s.request(orgID)
+	s.deleted = "hard"
+	return nil
+}
+
+func (s *referenceStorage) SoftDeleteRewardType(ctx context.Context, orgID string, id string) error {
+	s.request(orgID)
+	s.deleted = "soft"
+	return nil
+}
+
+func TestDeleteRewardTypeReferences(t *testing.T) {
+	tests := []struct {
+		name       string
+		references []model.Reference
+		status     int
+		deleted    string
+	}{
+		{"unused", nil, http.StatusOK, "hard"},
+		{"history only", []model.Reference{{Resource: "history", Count: 3, IDs: []string{"reward-a"}}, {Resource: "claims", Count: 1, IDs: []string{"claim-a"}}}, http.StatusOK, "soft"},
+		{"in use", []model.Reference{{Resource: "operations", Count: 1, IDs: []string{"operation-a"}}, {Resource: "history", Count: 3, IDs: []string{"reward-a"}}}, http.StatusConflict, ""},
+	}
+
+	for _, test := range tests {
+		storage := &referenceStorage{orgStorage: newOrgStorage(), references: test.references}
+		handler := NewAdminApisHandler(newTestApplication(storage))
+
+		r := mux.SetURLVars(httptest.NewRequest(http.MethodDelete, "/admin/types/type-a", nil), map[string]string{"id": "type-a"})
+		w := httptest.NewRecorder()
+		handler.DeleteRewardType(orgClaims(orgA), w, r)
+
+		if w.Code != test.status {
+			t.Errorf("%s: expected status %d, got %d - %s", test.name, test.status, w.Code, w.Body.String())
+		}
+		if storage.deleted != test.deleted {
+			t.Errorf("%s: expected delete %q, got %q", test.name, test.deleted, storage.deleted)
+		}
+		if test.status != http.StatusConflict {
+			continue
+		}
+
+		var body ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid error body %s - %s", test.name, w.Body.String(), err)
+		}
+		if len(body.References) != 1 || body.References[0].Resource != "operations" || body.References[0].IDs[0] != "operation-a" {
+			t.Errorf("%s: expected the blocking operations reference, got %v", test.name, body.References)
+		}
+	}
+}
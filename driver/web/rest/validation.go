@@ -0,0 +1,140 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"rewards/core/model"
+	"strings"
+	"unicode"
+
+	"gopkg.in/go-playground/validator.v9"
+)
+
+const unknownFieldPrefix = "json: unknown field "
+
+var validate = newValidator()
+
+var errMissingBody = model.NewValidationError("missing request body")
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// report the fields by their json names
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// decodeJSONBody decodes the request body into the body struct, rejects unknown fields and validates it
+func decodeJSONBody(r *http.Request, body interface{}) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	err := decoder.Decode(body)
+	if err == io.EOF {
+		return errMissingBody
+	}
+	if err != nil {
+		if strings.HasPrefix(err.Error(), unknownFieldPrefix) {
+			field := strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldPrefix), `"`)
+			return model.NewFieldValidationError([]model.FieldError{{Field: field, Message: "unknown field"}})
+		}
+		if typeErr, ok := err.(*json.UnmarshalTypeError); ok && typeErr.Field != "" {
+			return model.NewFieldValidationError([]model.FieldError{{Field: typeErr.Field, Message: fmt.Sprintf("must be %s", typeErr.Type)}})
+		}
+		return model.NewValidationError("invalid request body - %s", err)
+	}
+	if decoder.More() {
+		return model.NewValidationError("invalid request body - unexpected data after the json object")
+	}
+
+	return validateBody(body)
+}
+
+// validateBody validates the body struct by its validate tags
+func validateBody(body interface{}) error {
+	err := validate.Struct(body)
+	if err == nil {
+		return nil
+	}
+
+	validationErrs, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return model.NewValidationError("invalid request body - %s", err)
+	}
+
+	fields := make([]model.FieldError, len(validationErrs))
+	for i, validationErr := range validationErrs {
+		fields[i] = model.FieldError{Field: fieldPath(validationErr.Namespace()), Message: fieldMessage(validationErr)}
+	}
+	return model.NewFieldValidationError(fields)
+}
+
+// fieldPath removes the struct name from the namespace - createRewardClaimBody.items[0].amount
+func fieldPath(namespace string) string {
+	parts := strings.SplitN(namespace, ".", 2)
+	if len(parts) < 2 {
+		return namespace
+	}
+	return parts[1]
+}
+
+func fieldMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "is required"
+	case "required_without":
+		return fmt.Sprintf("is required without %s", toSnakeCase(err.Param()))
+	case "gt":
+		return fmt.Sprintf("must be greater than %s", err.Param())
+	case "gte":
+		return fmt.Sprintf("must be greater than or equal to %s", err.Param())
+	case "min":
+		return fmt.Sprintf("must have at least %s items", err.Param())
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", err.Param())
+	case "gtfield":
+		return fmt.Sprintf("must be after %s", toSnakeCase(err.Param()))
+	case "ltefield":
+		return fmt.Sprintf("must not be greater than %s", toSnakeCase(err.Param()))
+	case "unique":
+		return fmt.Sprintf("must not repeat %s", toSnakeCase(err.Param()))
+	}
+	return fmt.Sprintf("failed the %s validation", err.Tag())
+}
+
+// toSnakeCase gives the json name of a struct field name which is referenced by a validate tag - AmountTotal to amount_total
+func toSnakeCase(name string) string {
+	var builder strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				builder.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"rewards/core/model"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		target interface{}
+		fields []model.FieldError
+	}{
+		{"valid type", `{"reward_type":"tshirt","display_name":"T-Shirt"}`, &createRewardTypeBody{}, nil},
+		{"empty reward type", `{"reward_type":""}`, &createRewardTypeBody{},
+			[]model.FieldError{{Field: "reward_type", Message: "is required"}}},
+		{"client set id", `{"id":"1","reward_type":"tshirt"}`, &createRewardTypeBody{},
+			[]model.FieldError{{Field: "id", Message: "unknown field"}}},
+		{"client set status", `{"items":[{"reward_type":"tshirt","amount":1}],"status":"approved"}`, &createUserRewardClaimBody{},
+			[]model.FieldError{{Field: "status", Message: "unknown field"}}},
+		{"negative claim amount", `{"items":[{"reward_type":"tshirt","amount":-1}]}`, &createUserRewardClaimBody{},
+			[]model.FieldError{{Field: "items[0].amount", Message: "must be greater than 0"}}},
+		{"repeated claim reward type", `{"items":[{"reward_type":"tshirt","amount":1},{"reward_type":"tshirt","amount":1}]}`, &createUserRewardClaimBody{},
+			[]model.FieldError{{Field: "items", Message: "must not repeat reward_type"}}},
+		{"claim without items", `{"description":"none"}`, &createUserRewardClaimBody{},
+			[]model.FieldError{{Field: "items", Message: "is required without purchase"}}},
+		{"claim purchase", `{"purchase":{"catalog_item_id":"1","quantity":1}}`, &createUserRewardClaimBody{}, nil},
+		{"invalid purchase", `{"purchase":{"quantity":0}}`, &createUserRewardClaimBody{},
+			[]model.FieldError{{Field: "purchase.catalog_item_id", Message: "is required"}, {Field: "purchase.quantity", Message: "must be greater than 0"}}},
+		{"operation", `{"reward_type":"tshirt","code":"","building_block":"events","amount":0}`, &createRewardOperationBody{},
+			[]model.FieldError{{Field: "code", Message: "is required"}, {Field: "amount", Message: "must be greater than 0"}}},
+		{"inventory over granted", `{"amount_total":5,"amount_granted":6}`, &updateRewardInventoryBody{},
+			[]model.FieldError{{Field: "amount_granted", Message: "must not be greater than amount_total"}}},
+		{"wrong type", `{"amount_total":"5"}`, &createRewardInventoryBody{},
+			[]model.FieldError{{Field: "amount_total", Message: "must be int"}}},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+		err := decodeJSONBody(r, test.target)
+		if test.fields == nil {
+			if err != nil {
+				t.Errorf("%s: unexpected error %s", test.name, err)
+			}
+			continue
+		}
+
+		domainErr := model.AsError(err)
+		if domainErr == nil || domainErr.Code != model.ErrorCodeValidation {
+			t.Errorf("%s: expected a validation error, got %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(domainErr.Fields, test.fields) {
+			t.Errorf("%s: expected fields %v, got %v", test.name, test.fields, domainErr.Fields)
+		}
+	}
+}
+
+func TestDecodeJSONBodyMissing(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	err := decodeJSONBody(r, &createRewardTypeBody{})
+	if err != errMissingBody {
+		t.Errorf("expected the missing body error, got %v", err)
+	}
+}
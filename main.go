@@ -15,8 +15,13 @@
 package main
 
 import (
-	"log"
+	"context"
 	"os"
+	"os/signal"
+	"rewards/utils/logging"
+	"strconv"
+	"syscall"
+	"time"
 
 	"rewards/core"
 	"rewards/core/model"
@@ -38,18 +43,23 @@ func main() {
 		Version = "dev"
 	}
 
-	port := getEnvKey("PORT", true)
-
-	internalAPIKey := getEnvKey("INTERNAL_API_KEY", true)
+	logLevel := getEnvKey("LOG_LEVEL", false)
+	if logLevel != "" {
+		logging.SetLevel(logLevel)
+	}
 
 	//mongoDB adapter
 	mongoDBAuth := getEnvKey("MONGO_AUTH", true)
 	mongoDBName := getEnvKey("MONGO_DATABASE", true)
 	mongoTimeout := getEnvKey("MONGO_TIMEOUT", false)
-	storageAdapter := storage.NewStorageAdapter(mongoDBAuth, mongoDBName, mongoTimeout)
+	defaultOrgID := getEnvKey("DEFAULT_ORG_ID", false)
+	storageAdapter := storage.NewStorageAdapter(mongoDBAuth, mongoDBName, mongoTimeout, defaultOrgID)
+
+	port := getEnvKey("PORT", true)
+
 	err := storageAdapter.Start()
 	if err != nil {
-		log.Fatal("Cannot start the mongoDB adapter - " + err.Error())
+		logging.Logger().Fatal("Cannot start the mongoDB adapter - " + err.Error())
 	}
 
 	defaultCacheExpirationSeconds := getEnvKey("DEFAULT_CACHE_EXPIRATION_SECONDS", false)
@@ -63,16 +73,51 @@ func main() {
 	host := getEnvKey("HOST", true)
 	coreBBHost := getEnvKey("CORE_BB_HOST", true)
 	rewardsServiceURL := getEnvKey("REWARDS_SERVICE_URL", true)
+	authorizationPolicySource := getEnvKey("AUTHORIZATION_POLICY_SOURCE", false)
+	authorizationPolicyPath := getEnvKey("AUTHORIZATION_POLICY_PATH", false)
 
 	config := model.Config{
-		InternalAPIKey:    internalAPIKey,
 		CoreBBHost:        coreBBHost,
 		RewardsServiceURL: rewardsServiceURL,
+
+		AuthorizationPolicySource: authorizationPolicySource,
+		AuthorizationPolicyPath:   authorizationPolicyPath,
+
+		ReadTimeout:  getEnvKeyAsDuration("HTTP_READ_TIMEOUT", 30*time.Second),
+		WriteTimeout: getEnvKeyAsDuration("HTTP_WRITE_TIMEOUT", 60*time.Second),
+		IdleTimeout:  getEnvKeyAsDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
 	}
 
 	webAdapter := driver.NewWebAdapter(host, port, application, config)
 
-	webAdapter.Start()
+	// serve until SIGTERM or SIGINT, then drain the in-flight requests and close the storage
+	shutdownTimeout := getEnvKeyAsDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+	stopped := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+		received := <-signals
+		logging.Logger().Infof("Received %s, shutting down", received)
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		err := webAdapter.Shutdown(ctx)
+		if err != nil {
+			logging.Logger().Errorf("Error on shutting down the web adapter - %s", err)
+		}
+		err = storageAdapter.Stop(ctx)
+		if err != nil {
+			logging.Logger().Errorf("Error on stopping the mongoDB adapter - %s", err)
+		}
+		close(stopped)
+	}()
+
+	err = webAdapter.Start()
+	if err != nil {
+		logging.Logger().Fatal("Cannot start the web adapter - " + err.Error())
+	}
+	<-stopped
+	logging.Logger().Info("Stopped")
 }
 
 func getEnvKeyAsList(key string, required bool) []string {
@@ -81,20 +126,34 @@ func getEnvKeyAsList(key string, required bool) []string {
 	// it is comma separated format
 	stringListValue := strings.Split(stringValue, ",")
 	if len(stringListValue) == 0 && required {
-		log.Fatalf("missing or empty env var: %s", key)
+		logging.Logger().Fatalf("missing or empty env var: %s", key)
 	}
 
 	return stringListValue
 }
 
+// getEnvKeyAsDuration gives the optional env var in seconds or the default value when it is not set or invalid
+func getEnvKeyAsDuration(key string, defaultValue time.Duration) time.Duration {
+	value := getEnvKey(key, false)
+	if value == "" {
+		return defaultValue
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds <= 0 {
+		logging.Logger().Warnf("Invalid %s %s, set default - %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return time.Duration(seconds) * time.Second
+}
+
 func getEnvKey(key string, required bool) string {
 	// get from the environment
 	value, exist := os.LookupEnv(key)
 	if !exist {
 		if required {
-			log.Fatal("No provided environment variable for " + key)
+			logging.Logger().Fatal("No provided environment variable for " + key)
 		} else {
-			log.Printf("No provided environment variable for %s", key)
+			logging.Logger().Infof("No provided environment variable for %s", key)
 		}
 	}
 	return value
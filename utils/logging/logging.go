@@ -0,0 +1,124 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logging gives the structured leveled logger of the service. The log of a request is kept in the
+// request context, so every layer which gets the context logs with the request id
+package logging
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+	"github.com/rokwire/logging-library-go/logs"
+)
+
+const serviceName = "rewards"
+
+// RequestIDHeader is the header of the request id. The id is generated when the caller does not give one
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern is the format of the request ids taken from the callers. They are echoed in the responses and kept
+// in the audit log, so the other ones are replaced by a generated id
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,128}$`)
+
+// backgroundTraceID is logged as the trace id of the logs which do not belong to a request
+const backgroundTraceID = "background"
+
+// sensitiveHeaders are logged as "---"
+var sensitiveHeaders = []string{"Rokwire-Api-Key", "User-Id", "Cookie", "Authorization", "Rokwire-Hs-Api-Key",
+	"Group", "Rokwire-Acc-Id", "Csrf", "Internal-Api-Key"}
+
+var logger = newLogger()
+
+type contextKey struct{}
+
+// requestContext is the request data kept in the context
+type requestContext struct {
+	id  string
+	log *logs.Log
+}
+
+func newLogger() *logs.Logger {
+	return logs.NewLogger(serviceName, &logs.LoggerOpts{JsonFmt: true, SensitiveHeaders: sensitiveHeaders})
+}
+
+// SetLevel sets the lowest logged level - debug, info, warn or error. Unknown levels keep the current level
+func SetLevel(level string) {
+	logger.SetLevel(*logs.LogLevelFromString(level))
+}
+
+// Logger gives the service logger
+func Logger() *logs.Logger {
+	return logger
+}
+
+// NewRequestContext gives a context with the request id and the log of the request. The id is taken from the
+// request id header when it has at most 128 letters, digits, dashes, underscores or dots, else it is generated
+func NewRequestContext(ctx context.Context, r *http.Request) context.Context {
+	id := r.Header.Get(RequestIDHeader)
+	if !requestIDPattern.MatchString(id) {
+		id = uuid.NewString()
+	}
+	request := logs.RequestContext{Method: r.Method, Path: r.URL.Path, Headers: RedactHeaders(r.Header)}
+	return context.WithValue(ctx, contextKey{}, &requestContext{id: id, log: logger.NewLog(id, request)})
+}
+
+// RequestID gives the request id of the context or an empty string outside of a request
+func RequestID(ctx context.Context) string {
+	if request := fromContext(ctx); request != nil {
+		return request.id
+	}
+	return ""
+}
+
+// FromContext gives the log of the request of the context. Outside of a request the log has the background trace id
+func FromContext(ctx context.Context) *logs.Log {
+	if request := fromContext(ctx); request != nil {
+		return request.log
+	}
+	return logger.NewLog(backgroundTraceID, logs.RequestContext{})
+}
+
+func fromContext(ctx context.Context) *requestContext {
+	if ctx == nil {
+		return nil
+	}
+	request, _ := ctx.Value(contextKey{}).(*requestContext)
+	return request
+}
+
+// RedactHeaders gives the headers with the values of the sensitive ones replaced by "---"
+func RedactHeaders(header http.Header) map[string][]string {
+	redacted := make(map[string][]string, len(header))
+	for key, value := range header {
+		if isSensitiveHeader(key) {
+			redacted[key] = []string{"---"}
+		} else {
+			redacted[key] = value
+		}
+	}
+	return redacted
+}
+
+func isSensitiveHeader(key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	for _, sensitive := range sensitiveHeaders {
+		if key == http.CanonicalHeaderKey(sensitive) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 Board of Trustees of the University of Illinois.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewRequestContext(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/rewards/version", nil)
+	r.Header.Set(RequestIDHeader, "request-1")
+	ctx := NewRequestContext(context.Background(), r)
+	if RequestID(ctx) != "request-1" {
+		t.Errorf("expected the request id from the header, got %s", RequestID(ctx))
+	}
+	if FromContext(ctx) != FromContext(ctx) {
+		t.Error("expected the same log for the whole request")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/rewards/version", nil)
+	ctx = NewRequestContext(context.Background(), r)
+	if len(RequestID(ctx)) != 36 {
+		t.Errorf("expected a generated request id, got %s", RequestID(ctx))
+	}
+
+	for _, header := range []string{strings.Repeat("a", 129), "request 1", "request-1\r\nSet-Cookie: a=b", "<script>", "request/1"} {
+		r = httptest.NewRequest(http.MethodGet, "/rewards/version", nil)
+		r.Header.Set(RequestIDHeader, header)
+		ctx = NewRequestContext(context.Background(), r)
+		if RequestID(ctx) == header || len(RequestID(ctx)) != 36 {
+			t.Errorf("expected a generated request id instead of %q, got %s", header, RequestID(ctx))
+		}
+	}
+	for _, header := range []string{strings.Repeat("a", 128), "Trace_1.2-3"} {
+		r = httptest.NewRequest(http.MethodGet, "/rewards/version", nil)
+		r.Header.Set(RequestIDHeader, header)
+		ctx = NewRequestContext(context.Background(), r)
+		if RequestID(ctx) != header {
+			t.Errorf("expected the request id %q from the header, got %s", header, RequestID(ctx))
+		}
+	}
+
+	if RequestID(context.Background()) != "" || FromContext(context.Background()) == nil {
+		t.Error("expected a background log without request id outside of a request")
+	}
+}
+
+func TestRedactHeaders(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer token")
+	header.Set("INTERNAL-API-KEY", "secret")
+	header.Set("Rokwire-Api-Key", "secret")
+	header.Set("Content-Type", "application/json")
+
+	redacted := RedactHeaders(header)
+	for _, key := range []string{"Authorization", "Internal-Api-Key", "Rokwire-Api-Key"} {
+		if len(redacted[key]) != 1 || redacted[key][0] != "---" {
+			t.Errorf("expected %s to be redacted, got %v", key, redacted[key])
+		}
+	}
+	if redacted["Content-Type"][0] != "application/json" {
+		t.Errorf("expected Content-Type to be kept, got %v", redacted["Content-Type"])
+	}
+	if header.Get("Authorization") != "Bearer token" {
+		t.Error("expected the request headers to be unchanged")
+	}
+}
@@ -15,9 +15,13 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"log"
+	"math/big"
 	"net/http"
+	"rewards/utils/logging"
 	"sort"
 	"strconv"
 	"strings"
@@ -67,7 +71,7 @@ func ModifyHTMLContent(input string) string {
 	reader := strings.NewReader(input)
 	doc, err := goquery.NewDocumentFromReader(reader)
 	if err != nil {
-		log.Printf("error creating reader from the html string - %s\n", err)
+		logging.Logger().Errorf("error creating reader from the html string - %s", err)
 		//there is no what to do so return the input
 		return input
 	}
@@ -85,13 +89,13 @@ func ModifyHTMLContent(input string) string {
 				if protocol == "http" || protocol == "https" {
 					//it is a web protocol, so we just need to look for .pdf resources
 					if strings.HasSuffix(href, ".pdf") {
-						log.Printf("modifying.. href - %s\ttext - %s\n", href, text)
+						logging.Logger().Debugf("modifying.. href - %s\ttext - %s", href, text)
 						link.ReplaceWithHtml(text + "(" + href + ")")
 					}
 				} else {
 					//it is not а web protocol, so here we need to apply modifications
 
-					log.Printf("modifying.. href - %s\ttext - %s\n", href, text)
+					logging.Logger().Debugf("modifying.. href - %s\ttext - %s", href, text)
 					link.ReplaceWithHtml(text)
 				}
 			}
@@ -101,13 +105,13 @@ func ModifyHTMLContent(input string) string {
 
 	body := doc.Find("body")
 	if body == nil {
-		log.Printf("body is nil for some reasons - %s\n", input)
+		logging.Logger().Warnf("body is nil for some reasons - %s", input)
 		//there is no what to do so return the input
 		return input
 	}
 	final, err := body.Html()
 	if err != nil {
-		log.Printf("error getting html from body - %s\n", err)
+		logging.Logger().Errorf("error getting html from body - %s", err)
 		//there is no what to do so return the input
 		return input
 	}
@@ -128,20 +132,9 @@ func LogRequest(req *http.Request) {
 		return
 	}
 
-	header := make(map[string][]string)
-	for key, value := range req.Header {
-		var logValue []string
-		//do not log api keys, cookies and Authorization
-		if key == "Rokwire-Api-Key" || key == "User-Id" || key == "Cookie" ||
-			key == "Authorization" || key == "Rokwire-Hs-Api-Key" || key == "Group" ||
-			key == "Rokwire-Acc-Id" || key == "Csrf" {
-			logValue = append(logValue, "---")
-		} else {
-			logValue = value
-		}
-		header[key] = logValue
-	}
-	log.Printf("%s %s %s", method, path, header)
+	//do not log api keys, cookies and Authorization
+	header := logging.RedactHeaders(req.Header)
+	logging.FromContext(req.Context()).Infof("%s %s %s", method, path, header)
 }
 
 // GetLogUUIDValue prepares UUID to be logged.
@@ -163,6 +156,29 @@ func GetLogValue(value string) string {
 	return fmt.Sprintf("***%s", last3)
 }
 
+// codeAlphabet excludes characters which are easy to confuse - 0/O, 1/I/L
+const codeAlphabet = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// GenerateCode generates a random human readable code with the given length
+func GenerateCode(length int) (string, error) {
+	max := big.NewInt(int64(len(codeAlphabet)))
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+// HashKey hashes a random API key for storing
+func HashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
 // Equal compares two slices
 func Equal(a, b []string) bool {
 	if len(a) != len(b) {